@@ -0,0 +1,452 @@
+package analysis
+
+import (
+  "github.com/kedebug/LispEx/ast"
+  "sort"
+)
+
+// ConvertClosures marks the lambdas of a program which can be closed
+// over a flat frame: instead of keeping the whole chain of scopes they
+// are created in, their closures copy the values of the local variables
+// they refer to and keep only the global scope for everything else.
+//
+// a lambda is flat when every local variable it refers to, directly or
+// through nested lambdas, is a parameter or a let/let* binding which is
+// never assigned by set!: those can't change once the closure is created.
+// internal defines and letrec bindings may still be unbound at that time,
+// and the bindings of a scope (the-environment) is evaluated in can be
+// changed through the environment, so lambdas referring to them keep
+// the scope chain.
+func ConvertClosures(nodes []ast.Node) {
+  converter := &converter{assigned: make(map[string]bool)}
+  for _, node := range nodes {
+    converter.collectAssigned(node)
+  }
+  for _, node := range nodes {
+    converter.walk(node, nil, newUses())
+  }
+}
+
+// local variables bound by a lambda or a let form,
+// mapped to whether a closure may capture their value
+type frame struct {
+  vars   map[string]bool
+  parent *frame
+}
+
+func newFrame(parent *frame) *frame {
+  return &frame{vars: make(map[string]bool), parent: parent}
+}
+
+// a nil frame is the top level, where every name is global
+func (self *frame) lookup(name string) (capturable bool, found bool) {
+  for f := self; f != nil; f = f.parent {
+    if capturable, found := f.vars[name]; found {
+      return capturable, true
+    }
+  }
+  return false, false
+}
+
+// names referred to by an expression and not bound within it,
+// opaque if it may access its scope by other means than names
+type uses struct {
+  names  map[string]bool
+  opaque bool
+}
+
+func newUses() *uses {
+  return &uses{names: make(map[string]bool)}
+}
+
+type converter struct {
+  // names assigned anywhere in the program
+  assigned map[string]bool
+  // only collect names, leaving the lambdas as they are
+  dry bool
+}
+
+// the names a lambda refers to without binding them itself, looked
+// up in the scope its closures are created in, sorted
+func FreeNames(lambda *ast.Lambda) []string {
+  converter := &converter{assigned: make(map[string]bool), dry: true}
+  f := newFrame(nil)
+  converter.bindParams(lambda, f)
+  u := newUses()
+  converter.scoped(lambda.Body, f, u)
+  var names []string
+  for name := range u.names {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+func (self *converter) collectAssigned(node ast.Node) {
+  if set, ok := node.(*ast.Set); ok {
+    self.assigned[set.Pattern.Identifier] = true
+  }
+  nodes, _ := children(node)
+  for _, child := range nodes {
+    self.collectAssigned(child)
+  }
+}
+
+func (self *converter) bind(f *frame, name *ast.Name) {
+  f.vars[name.Identifier] = !self.assigned[name.Identifier]
+}
+
+func (self *converter) walk(node ast.Node, env *frame, u *uses) {
+  switch node.(type) {
+  case *ast.Name:
+    u.names[node.(*ast.Name).Identifier] = true
+  case *ast.Set:
+    set := node.(*ast.Set)
+    u.names[set.Pattern.Identifier] = true
+    self.walk(set.Value, env, u)
+  case *ast.TheEnvironment:
+    u.opaque = true
+  case *ast.Go:
+    self.walk(node.(*ast.Go).Expr, env, u)
+  case *ast.Nursery:
+    self.scoped(node.(*ast.Nursery).Body, newFrame(env), u)
+  case *ast.Lambda:
+    self.convert(node.(*ast.Lambda), env, u)
+  case *ast.Let:
+    let := node.(*ast.Let)
+    for _, expr := range let.Exprs {
+      self.walk(expr, env, u)
+    }
+    f := newFrame(env)
+    for _, pattern := range let.Patterns {
+      self.bind(f, pattern)
+    }
+    exposeFrame(f, let.Body)
+    self.scoped(let.Body, f, u)
+  case *ast.LetStar:
+    let := node.(*ast.LetStar)
+    self.walkLetStar(let, 0, env, u)
+  case *ast.LetValues:
+    let := node.(*ast.LetValues)
+    if let.Sequential {
+      self.walkLetStarValues(let, 0, env, u)
+      break
+    }
+    for _, expr := range let.Exprs {
+      self.walk(expr, env, u)
+    }
+    f := newFrame(env)
+    for _, formals := range let.Formals {
+      for _, name := range ast.FormalNames(formals) {
+        self.bind(f, name)
+      }
+    }
+    exposeFrame(f, let.Body)
+    self.scoped(let.Body, f, u)
+  case *ast.Do:
+    loop := node.(*ast.Do)
+    for _, init := range loop.Inits {
+      self.walk(init, env, u)
+    }
+    f := newFrame(env)
+    for _, name := range loop.Vars {
+      self.bind(f, name)
+    }
+    inner := newUses()
+    nodes, _ := children(node)
+    exposeFrame(f, nodes[len(loop.Inits):]...)
+    for _, child := range nodes[len(loop.Inits):] {
+      self.walk(child, f, inner)
+    }
+    self.merge(inner, f, u)
+  case *ast.LetRec:
+    let := node.(*ast.LetRec)
+    f := newFrame(env)
+    for _, pattern := range let.Patterns {
+      f.vars[pattern.Identifier] = false
+    }
+    // the body is evaluated in the scope the inits are closed over
+    for _, name := range definitions(let.Body) {
+      f.vars[name] = false
+    }
+    inner := newUses()
+    for _, expr := range let.Exprs {
+      self.walk(expr, f, inner)
+    }
+    self.scoped(let.Body, f, inner)
+    self.merge(inner, f, u)
+  default:
+    nodes, ok := children(node)
+    if !ok {
+      u.opaque = true
+    }
+    for _, child := range nodes {
+      self.walk(child, env, u)
+    }
+  }
+}
+
+// the init of each binding sees the bindings before it,
+// the body and its definitions see all of them
+func (self *converter) walkLetStar(let *ast.LetStar, i int, env *frame, u *uses) {
+  if i == len(let.Patterns) {
+    if i == 0 {
+      // the body is evaluated in the enclosing scope
+      self.walk(let.Body, env, u)
+    } else {
+      self.scoped(let.Body, env, u)
+    }
+    return
+  }
+  self.walk(let.Exprs[i], env, u)
+  f := newFrame(env)
+  self.bind(f, let.Patterns[i])
+  exposeFrame(f, append(append([]ast.Node{}, let.Exprs[i+1:]...), let.Body)...)
+  inner := newUses()
+  self.walkLetStar(let, i+1, f, inner)
+  self.merge(inner, f, u)
+}
+
+// like walkLetStar, each binding has the names of its formals
+func (self *converter) walkLetStarValues(let *ast.LetValues, i int, env *frame, u *uses) {
+  if i == len(let.Formals) {
+    if i == 0 {
+      self.walk(let.Body, env, u)
+    } else {
+      self.scoped(let.Body, env, u)
+    }
+    return
+  }
+  self.walk(let.Exprs[i], env, u)
+  f := newFrame(env)
+  for _, name := range ast.FormalNames(let.Formals[i]) {
+    self.bind(f, name)
+  }
+  exposeFrame(f, append(append([]ast.Node{}, let.Exprs[i+1:]...), let.Body)...)
+  inner := newUses()
+  self.walkLetStarValues(let, i+1, f, inner)
+  self.merge(inner, f, u)
+}
+
+// no variable of f can be captured when (the-environment) is evaluated
+// within its scope, nested scopes included: the environment reaches
+// every binding of f, which alist->environment! may change
+func exposeFrame(f *frame, scope ...ast.Node) {
+  for _, node := range scope {
+    if exposesScope(node) {
+      for name := range f.vars {
+        f.vars[name] = false
+      }
+      return
+    }
+  }
+}
+
+func exposesScope(node ast.Node) bool {
+  if _, ok := node.(*ast.TheEnvironment); ok {
+    return true
+  }
+  for _, child := range ast.Children(node) {
+    if exposesScope(child) {
+      return true
+    }
+  }
+  return false
+}
+
+// walk a body whose definitions are bound in f,
+// which has already been created for its binding form
+func (self *converter) scoped(body ast.Node, f *frame, u *uses) {
+  for _, name := range definitions(body) {
+    f.vars[name] = false
+  }
+  inner := newUses()
+  self.walk(body, f, inner)
+  self.merge(inner, f, u)
+}
+
+// add the names of inner not bound in f to u
+func (self *converter) merge(inner *uses, f *frame, u *uses) {
+  for name := range inner.names {
+    if _, bound := f.vars[name]; !bound {
+      u.names[name] = true
+    }
+  }
+  u.opaque = u.opaque || inner.opaque
+}
+
+func (self *converter) bindParams(lambda *ast.Lambda, f *frame) {
+  params := lambda.Params
+  for {
+    if pair, ok := params.(*ast.Pair); ok {
+      if name, ok := pair.First.(*ast.Name); ok {
+        self.bind(f, name)
+      }
+      params = pair.Second
+    } else {
+      if name, ok := params.(*ast.Name); ok {
+        self.bind(f, name)
+      }
+      break
+    }
+  }
+}
+
+func (self *converter) convert(lambda *ast.Lambda, env *frame, u *uses) {
+  f := newFrame(env)
+  self.bindParams(lambda, f)
+  exposeFrame(f, lambda.Body)
+  inner := newUses()
+  self.scoped(lambda.Body, f, inner)
+
+  flat := !inner.opaque
+  var captures []string
+  for name := range inner.names {
+    if capturable, found := env.lookup(name); found {
+      if capturable {
+        captures = append(captures, name)
+      } else {
+        flat = false
+      }
+    }
+  }
+  if flat && !self.dry {
+    sort.Strings(captures)
+    lambda.Flat, lambda.Captures = true, captures
+  }
+  for name := range inner.names {
+    u.names[name] = true
+  }
+  u.opaque = u.opaque || inner.opaque
+}
+
+// names defined by a body, including those in begin forms
+// and in the body of a let* without bindings, which are
+// all evaluated in the scope of the body
+func definitions(body ast.Node) []string {
+  var names []string
+  switch body.(type) {
+  case *ast.Define:
+    names = append(names, body.(*ast.Define).Pattern.Identifier)
+  case *ast.DefineContract:
+    names = append(names, body.(*ast.DefineContract).Define.Pattern.Identifier)
+  case *ast.DefineEnum:
+    names = body.(*ast.DefineEnum).Names()
+  case *ast.Block:
+    for _, expr := range body.(*ast.Block).Exprs {
+      names = append(names, definitions(expr)...)
+    }
+  case *ast.Begin:
+    names = definitions(body.(*ast.Begin).Body)
+  case *ast.LetStar:
+    if let := body.(*ast.LetStar); len(let.Patterns) == 0 {
+      names = definitions(let.Body)
+    }
+  }
+  return names
+}
+
+// subexpressions of a node, ok is false for nodes
+// which aren't known to evaluate only those
+func children(node ast.Node) (nodes []ast.Node, ok bool) {
+  switch node.(type) {
+  case *ast.Int, *ast.Float, *ast.Rational, *ast.String, *ast.Char, *ast.Name, *ast.Quote,
+    *ast.EmptyPair, *ast.Annotation, *ast.DefineSyntax, *ast.DefineEnum:
+    return nil, true
+  case *ast.Apply:
+    apply := node.(*ast.Apply)
+    return append([]ast.Node{apply.Proc}, apply.Args...), true
+  case *ast.Begin:
+    return []ast.Node{node.(*ast.Begin).Body}, true
+  case *ast.Block:
+    return node.(*ast.Block).Exprs, true
+  case *ast.Call:
+    call := node.(*ast.Call)
+    return append([]ast.Node{call.Callee}, call.Args...), true
+  case *ast.Define:
+    return []ast.Node{node.(*ast.Define).Value}, true
+  case *ast.DefineContract:
+    contract := node.(*ast.DefineContract)
+    nodes = append([]ast.Node{contract.Define.Value}, contract.Domain...)
+    return append(nodes, contract.Range), true
+  case *ast.Delay:
+    return []ast.Node{node.(*ast.Delay).Expr}, true
+  case *ast.Force:
+    return []ast.Node{node.(*ast.Force).Promise}, true
+  case *ast.Function:
+    return []ast.Node{node.(*ast.Function).Body}, true
+  case *ast.Go:
+    return []ast.Node{node.(*ast.Go).Expr}, true
+  case *ast.Nursery:
+    return []ast.Node{node.(*ast.Nursery).Body}, true
+  case *ast.If:
+    expr := node.(*ast.If)
+    nodes = []ast.Node{expr.Test, expr.Then}
+    if expr.Else != nil {
+      nodes = append(nodes, expr.Else)
+    }
+    return nodes, true
+  case *ast.Lambda:
+    return []ast.Node{node.(*ast.Lambda).Body}, true
+  case *ast.Let:
+    let := node.(*ast.Let)
+    return append(append([]ast.Node{}, let.Exprs...), let.Body), true
+  case *ast.LetStar:
+    let := node.(*ast.LetStar)
+    return append(append([]ast.Node{}, let.Exprs...), let.Body), true
+  case *ast.LetRec:
+    let := node.(*ast.LetRec)
+    return append(append([]ast.Node{}, let.Exprs...), let.Body), true
+  case *ast.LetValues:
+    let := node.(*ast.LetValues)
+    return append(append([]ast.Node{}, let.Exprs...), let.Body), true
+  case *ast.Select:
+    for _, clause := range node.(*ast.Select).Clauses {
+      nodes = append(nodes, clause...)
+    }
+    return nodes, true
+  case *ast.Cond, *ast.Case, *ast.When, *ast.Logic:
+    return ast.Children(node), true
+  case *ast.Do:
+    loop := node.(*ast.Do)
+    nodes = append([]ast.Node{}, loop.Inits...)
+    for _, child := range append(append([]ast.Node{}, loop.Steps...), loop.Test, loop.Result, loop.Body) {
+      if child != nil {
+        nodes = append(nodes, child)
+      }
+    }
+    return nodes, true
+  case *ast.Set:
+    return []ast.Node{node.(*ast.Set).Value}, true
+  case *ast.Quasiquote:
+    return unquoted(node.(*ast.Quasiquote).Body), true
+  case *ast.Unquote:
+    return []ast.Node{node.(*ast.Unquote).Body}, true
+  case *ast.UnquoteSplicing:
+    return []ast.Node{node.(*ast.UnquoteSplicing).Body}, true
+  }
+  return nil, false
+}
+
+// expressions evaluated by a quasiquote template,
+// names within it are data
+func unquoted(node ast.Node) []ast.Node {
+  switch node.(type) {
+  case *ast.Unquote, *ast.UnquoteSplicing:
+    return []ast.Node{node}
+  case *ast.Pair:
+    pair := node.(*ast.Pair)
+    return append(unquoted(pair.First), unquoted(pair.Second)...)
+  case *ast.Tuple:
+    var nodes []ast.Node
+    for _, element := range node.(*ast.Tuple).Elements {
+      nodes = append(nodes, unquoted(element)...)
+    }
+    return nodes
+  case *ast.Quasiquote:
+    return unquoted(node.(*ast.Quasiquote).Body)
+  case *ast.VectorTemplate:
+    return unquoted(node.(*ast.VectorTemplate).List)
+  }
+  return nil
+}
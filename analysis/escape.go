@@ -0,0 +1,64 @@
+package analysis
+
+import (
+  "github.com/kedebug/LispEx/ast"
+)
+
+// MarkReusableScopes marks the lambdas and let forms whose scopes can be
+// recycled once they are evaluated, because no value created meanwhile
+// refers to them: closures converted to flat frames only refer to the
+// global scope, while other closures, promises, goroutines and
+// (the-environment) keep the scope they are created in.
+// it relies on the lambdas being converted by ConvertClosures first
+func MarkReusableScopes(nodes []ast.Node) {
+  for _, node := range nodes {
+    markReusable(node)
+  }
+}
+
+func markReusable(node ast.Node) {
+  switch node.(type) {
+  case *ast.Lambda:
+    lambda := node.(*ast.Lambda)
+    lambda.Reusable = !escapes(lambda.Body)
+  case *ast.Let, *ast.LetStar, *ast.LetRec:
+    nodes, _ := children(node)
+    reusable := true
+    for _, child := range nodes {
+      reusable = reusable && !escapes(child)
+    }
+    switch node.(type) {
+    case *ast.Let:
+      node.(*ast.Let).Reusable = reusable
+    case *ast.LetStar:
+      node.(*ast.LetStar).Reusable = reusable
+    case *ast.LetRec:
+      node.(*ast.LetRec).Reusable = reusable
+    }
+  }
+  nodes, _ := children(node)
+  for _, child := range nodes {
+    markReusable(child)
+  }
+}
+
+// whether evaluating node may create a value
+// referring to the scope it is evaluated in
+func escapes(node ast.Node) bool {
+  switch node.(type) {
+  case *ast.Lambda:
+    return !node.(*ast.Lambda).Flat
+  case *ast.TheEnvironment, *ast.Delay, *ast.Go:
+    return true
+  }
+  nodes, ok := children(node)
+  if !ok {
+    return true
+  }
+  for _, child := range nodes {
+    if escapes(child) {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,27 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// (: <variable> <type>)
+//  a type annotation, only read by the type checker
+type Annotation struct {
+  Pattern *Name
+  Type    Node
+}
+
+func NewAnnotation(pattern *Name, typ Node) *Annotation {
+  return &Annotation{Pattern: pattern, Type: typ}
+}
+
+func (self *Annotation) Eval(env *scope.Scope) value.Value {
+  return nil
+}
+
+func (self *Annotation) String() string {
+  return fmt.Sprintf("(%s %s %s)", constants.ANNOTATE, self.Pattern, self.Type)
+}
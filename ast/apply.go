@@ -10,6 +10,7 @@ import (
 type Apply struct {
   Proc Node
   Args []Node
+  Pos  string
 }
 
 func NewApply(proc Node, args []Node) *Apply {
@@ -18,7 +19,7 @@ func NewApply(proc Node, args []Node) *Apply {
 
 func (self *Apply) Eval(s *scope.Scope) Value {
   // (apply proc arg1 ... args)
-  // Proc must be a procedure and args must be a list.
+  // Proc must be a procedure and args must be a list, or multiple values.
   // Calls proc with the elements of the list
   // (append (list arg1 ...) args) as the actual arguments.
 
@@ -26,17 +27,8 @@ func (self *Apply) Eval(s *scope.Scope) Value {
   args := ExpandApplyArgs(EvalList(self.Args, s))
 
   switch proc.(type) {
-  case *Closure:
-    curry := proc.(*Closure)
-    lambda, ok := curry.Body.(*Lambda)
-    if !ok {
-      panic(fmt.Sprint("unexpected type: ", curry.Body))
-    }
-    env := curry.Env.(*scope.Scope)
-    BindArguments(env, lambda.Params, args)
-    return lambda.Body.Eval(env)
-  case PrimFunc:
-    return proc.(PrimFunc).Apply(converter.PairsToSlice(args))
+  case *Closure, *Contract, PrimFunc:
+    return ApplyProcedure(proc, converter.PairsToSlice(args), self.Pos)
   default:
     panic(fmt.Sprintf("apply: expected a procedure, given: %s", self.Proc))
   }
@@ -75,6 +67,13 @@ func ExpandApplyArgs(args []Value) Value {
       if i != len(args)-1 {
         panic(fmt.Sprint("apply: expected list, given: ", arg))
       }
+    case *MultipleValues:
+      // (apply f 1 (values 2 3)) => (f 1 2 3)
+      if i != len(args)-1 {
+        panic(fmt.Sprint("apply: expected list, given: ", arg))
+      }
+      prev.Second = converter.SliceToPairValues(arg.(*MultipleValues).Values)
+      expectlist = false
     default:
       expectlist = true
       curr.First = arg
@@ -2,14 +2,19 @@ package ast
 
 import (
   "fmt"
-  "github.com/kedebug/LispEx/converter"
   "github.com/kedebug/LispEx/scope"
   . "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "runtime"
 )
 
 type Call struct {
   Callee Node
   Args   []Node
+  // source position of the call, blamed by contracts
+  Pos string
+  // global binding of a callee name
+  cache scope.Cache
 }
 
 func NewCall(callee Node, args []Node) *Call {
@@ -17,26 +22,80 @@ func NewCall(callee Node, args []Node) *Call {
 }
 
 func (self *Call) Eval(s *scope.Scope) Value {
-  callee := self.Callee.Eval(s)
+  var callee Value
+  if name, ok := self.Callee.(*Name); ok {
+    callee = name.EvalCached(s, &self.cache)
+  } else {
+    callee = self.Callee.Eval(s)
+  }
+  if builtin, ok := callee.(*primitives.Builtin); ok {
+    s.CountBuiltin(builtin.Name)
+    if len(self.Args) == 2 && isFixnumOp(builtin) {
+      x, y := self.Args[0].Eval(s), self.Args[1].Eval(s)
+      if result, ok := fixnumOp(builtin, x, y); ok {
+        return result
+      }
+      return ApplyProcedure(callee, []Value{x, y}, self.Pos)
+    }
+  }
   // we will handle (+ . (1)) latter
   args := EvalList(self.Args, s)
 
   switch callee.(type) {
-  case *Closure:
-    curry := callee.(*Closure)
-    env := scope.NewScope(curry.Env.(*scope.Scope))
-    lambda, ok := curry.Body.(*Lambda)
-    if !ok {
-      panic(fmt.Sprint("unexpected type: ", curry.Body))
-    }
-    // bind call arguments to parameters
-    // these nodes should be in Lisp pair structure
-    BindArguments(env, lambda.Params, converter.SliceToPairValues(args))
-    return lambda.Body.Eval(env)
+  case *Closure, *Contract:
+    s.CheckInterrupted()
+    return ApplyProcedure(callee, args, self.Pos)
   case PrimFunc:
-    return callee.(PrimFunc).Apply(args)
+    s.Reserve(primitives.AllocationSize(callee, args))
+    result := ApplyProcedure(callee, args, self.Pos)
+    s.Allocated(result)
+    return result
   default:
-    panic(fmt.Sprintf("%s: not allowed in a call context, args: %s", callee, self.Args[0]))
+    if s.ApplicableData() {
+      if result, ok := primitives.ApplyData(callee, args, self.Pos); ok {
+        return result
+      }
+    }
+  }
+  panic(&TypeError{fmt.Sprintf("%s: not allowed in a call context, in: %s", callee, self), self.Pos})
+}
+
+// apply an evaluated procedure to evaluated arguments,
+// caller is the position of the call site
+func ApplyProcedure(proc Value, args []Value, caller string) Value {
+  if contract, ok := proc.(*Contract); ok {
+    return contract.Call(args, caller)
+  }
+  if builtin, ok := proc.(*primitives.Builtin); ok {
+    return builtin.ApplyAt(args, caller)
+  }
+  if closure, ok := proc.(*Closure); ok {
+    // checked before binding the arguments, which doesn't know the caller
+    if signature, ok := closure.Body.(Signature); ok {
+      required, variadic := signature.Arity()
+      if len(args) < required {
+        panic(&ArityError{"missing arguments", caller})
+      } else if len(args) > required && !variadic {
+        panic(&ArityError{"too many arguments", caller})
+      }
+    }
+    defer traceCall(closure, caller)
+  }
+  return Invoke(proc, args)
+}
+
+// errors raised by the call of closure at caller get its frame
+// in their backtrace. bugs of the interpreter are left alone
+func traceCall(closure *Closure, caller string) {
+  if e := recover(); e != nil {
+    if _, ok := e.(runtime.Error); ok {
+      panic(e)
+    }
+    var name string
+    if lambda, ok := closure.Body.(*Lambda); ok {
+      name = lambda.Name
+    }
+    panic(AddFrame(e, Frame{name, caller}))
   }
 }
 
@@ -48,19 +107,21 @@ func (self *Call) String() string {
   return fmt.Sprintf("(%s%s)", self.Callee, s)
 }
 
-func BindArguments(env *scope.Scope, params Node, args Value) {
-  if name, ok := params.(*Name); ok && args == NilPairValue {
-    // ((lambda x <body>) '())
-    env.Put(name.Identifier, args)
-    return
-  }
+// bind args to params like those of a lambda, the errors of the
+// wrong number of arguments are raised as those of procedure at pos
+func BindArguments(env *scope.Scope, params Node, args Value, procedure, pos string) {
   for {
+    if name, ok := params.(*Name); ok && args == NilPairValue {
+      // ((lambda x <body>) '()) or ((lambda (x . y) <body>) 1)
+      env.Put(name.Identifier, args)
+      return
+    }
     if params == NilPair && args == NilPairValue {
       return
     } else if params == NilPair && args != NilPairValue {
-      panic(fmt.Sprint("too many arguments"))
+      panic(&ArityError{procedure + ": too many arguments", pos})
     } else if params != NilPair && args == NilPairValue {
-      panic(fmt.Sprint("missing arguments"))
+      panic(&ArityError{procedure + ": missing arguments", pos})
     }
     switch params.(type) {
     case *Pair:
@@ -68,7 +129,7 @@ func BindArguments(env *scope.Scope, params Node, args Value) {
       name, _ := params.(*Pair).First.(*Name)
       pair, ok := args.(*PairValue)
       if !ok {
-        panic(fmt.Sprint("arguments does not match given number"))
+        panic(&ArityError{procedure + ": arguments does not match given number", pos})
       }
       env.Put(name.Identifier, pair.First)
       params = params.(*Pair).Second
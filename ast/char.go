@@ -0,0 +1,28 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+type Char struct {
+  Value rune
+}
+
+func NewChar(text string) *Char {
+  r, err := lexer.ParseChar(text)
+  if err != nil {
+    panic(fmt.Sprint("read: ", err))
+  }
+  return &Char{Value: r}
+}
+
+func (self *Char) Eval(env *scope.Scope) value.Value {
+  return value.NewCharValue(self.Value)
+}
+
+func (self *Char) String() string {
+  return value.NewCharValue(self.Value).String()
+}
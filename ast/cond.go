@@ -0,0 +1,194 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/scope"
+  . "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+)
+
+// (test body...), (test => receiver), (test) or (else body...).
+// the test of an else clause is nil, the body of (test) too
+type CondClause struct {
+  Test  Node
+  Body  Node
+  Arrow bool
+}
+
+// the body of the first clause whose test isn't #f, evaluated
+// after the test: (test) gives the value of the test, and with
+// => the receiver is called with it. the value of no clause is unspecified
+type Cond struct {
+  Clauses []*CondClause
+  Pos     string
+}
+
+func NewCond(clauses []*CondClause) *Cond {
+  return &Cond{Clauses: clauses}
+}
+
+func (self *Cond) Eval(env *scope.Scope) Value {
+  for _, clause := range self.Clauses {
+    if clause.Test == nil {
+      return clause.Body.Eval(env)
+    }
+    test := clause.Test.Eval(env)
+    if isFalse(test) {
+      continue
+    }
+    if clause.Body == nil {
+      return test
+    }
+    if clause.Arrow {
+      return ApplyProcedure(clause.Body.Eval(env), []Value{test}, self.Pos)
+    }
+    return clause.Body.Eval(env)
+  }
+  return nil
+}
+
+func (self *Cond) String() string {
+  var result string
+  for _, clause := range self.Clauses {
+    result += " " + clause.String()
+  }
+  return fmt.Sprintf("(%s%s)", constants.COND, result)
+}
+
+func (self *CondClause) String() string {
+  switch {
+  case self.Test == nil:
+    return fmt.Sprintf("(%s %s)", constants.ELSE, self.Body)
+  case self.Body == nil:
+    return fmt.Sprintf("(%s)", self.Test)
+  case self.Arrow:
+    return fmt.Sprintf("(%s %s %s)", self.Test, constants.ARROW, self.Body)
+  }
+  return fmt.Sprintf("(%s %s)", self.Test, self.Body)
+}
+
+// ((datum...) body...), ((datum...) => receiver) or else in their
+// place, the data of an else clause being nil
+type CaseClause struct {
+  Data  []Value
+  Body  Node
+  Arrow bool
+}
+
+// the body of the first clause with a datum eqv? to the key,
+// with => the receiver is called with the key
+type Case struct {
+  Key     Node
+  Clauses []*CaseClause
+  Pos     string
+}
+
+func NewCase(key Node, clauses []*CaseClause) *Case {
+  return &Case{Key: key, Clauses: clauses}
+}
+
+func (self *Case) Eval(env *scope.Scope) Value {
+  key := self.Key.Eval(env)
+  for _, clause := range self.Clauses {
+    if clause.Data != nil && !clause.holds(key) {
+      continue
+    }
+    if clause.Arrow {
+      return ApplyProcedure(clause.Body.Eval(env), []Value{key}, self.Pos)
+    }
+    return clause.Body.Eval(env)
+  }
+  return nil
+}
+
+func (self *CaseClause) holds(key Value) bool {
+  for _, datum := range self.Data {
+    if primitives.Eqv(datum, key) {
+      return true
+    }
+  }
+  return false
+}
+
+func (self *Case) String() string {
+  result := fmt.Sprintf("(%s %s", constants.CASE, self.Key)
+  for _, clause := range self.Clauses {
+    result += " " + clause.String()
+  }
+  return result + ")"
+}
+
+func (self *CaseClause) String() string {
+  data := constants.ELSE
+  if self.Data != nil {
+    data = fmt.Sprint(converter.SliceToPairValues(self.Data))
+  }
+  if self.Arrow {
+    return fmt.Sprintf("(%s %s %s)", data, constants.ARROW, self.Body)
+  }
+  return fmt.Sprintf("(%s %s)", data, self.Body)
+}
+
+// (when test body...) evaluates body when test isn't #f,
+// (unless test body...) when it is. the value is otherwise unspecified
+type When struct {
+  Test   Node
+  Body   Node
+  Unless bool
+}
+
+func NewWhen(test, body Node, unless bool) *When {
+  return &When{Test: test, Body: body, Unless: unless}
+}
+
+func (self *When) Eval(env *scope.Scope) Value {
+  if isFalse(self.Test.Eval(env)) != self.Unless {
+    return nil
+  }
+  return self.Body.Eval(env)
+}
+
+func (self *When) String() string {
+  keyword := constants.WHEN
+  if self.Unless {
+    keyword = constants.UNLESS
+  }
+  return fmt.Sprintf("(%s %s %s)", keyword, self.Test, self.Body)
+}
+
+// (and expr...) is the value of the first expression evaluating to #f,
+// the next ones aren't evaluated, else of the last one or #t if none.
+// (or expr...) likewise stops at the first value which isn't #f
+type Logic struct {
+  Exprs []Node
+  Or    bool
+}
+
+func NewLogic(exprs []Node, or bool) *Logic {
+  return &Logic{Exprs: exprs, Or: or}
+}
+
+func (self *Logic) Eval(env *scope.Scope) Value {
+  var result Value = NewBoolValue(!self.Or)
+  for _, expr := range self.Exprs {
+    result = expr.Eval(env)
+    if isFalse(result) != self.Or {
+      return result
+    }
+  }
+  return result
+}
+
+func (self *Logic) String() string {
+  keyword := constants.AND
+  if self.Or {
+    keyword = constants.OR
+  }
+  var result string
+  for _, expr := range self.Exprs {
+    result += fmt.Sprintf(" %s", expr)
+  }
+  return fmt.Sprintf("(%s%s)", keyword, result)
+}
@@ -0,0 +1,298 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// the s-expression node was parsed from, so that lisp code can work on
+// programs as data: (define (f x) (* x 2)) gives the list of the same
+// symbols and numbers back. parser.FromDatum is the reverse
+func ToDatum(node Node) Value {
+  switch node.(type) {
+  case *Int:
+    return NewIntValue(node.(*Int).Value)
+  case *Float:
+    return NewFloatValue(node.(*Float).Value)
+  case *Rational:
+    return node.(*Rational).Eval(nil)
+  case *String:
+    return NewStringValue(node.(*String).Value)
+  case *Char:
+    return NewCharValue(node.(*Char).Value)
+  case *Name:
+    return node.(*Name).Datum()
+  case *EmptyPair:
+    return NilPairValue
+  case *Pair:
+    pair := node.(*Pair)
+    return NewPairValue(ToDatum(pair.First), ToDatum(pair.Second))
+  case *Tuple:
+    return listDatum(node.(*Tuple).Elements)
+  case *Vector:
+    return node.(*Vector).Eval(nil)
+  case *VectorTemplate:
+    items := []Value{}
+    for list := ToDatum(node.(*VectorTemplate).List); list != NilPairValue; {
+      pair := list.(*PairValue)
+      items = append(items, pair.First)
+      list = pair.Second
+    }
+    return NewVectorValue(items)
+  case *Quote:
+    return form(constants.QUOTE, node.(*Quote).Body)
+  case *Quasiquote:
+    return form(constants.QUASIQUOTE, node.(*Quasiquote).Body)
+  case *Unquote:
+    return form(constants.UNQUOTE, node.(*Unquote).Body)
+  case *UnquoteSplicing:
+    return form(constants.UNQUOTE_SPLICING, node.(*UnquoteSplicing).Body)
+  case *Block:
+    return form(constants.BEGIN, node.(*Block).Exprs...)
+  case *Begin:
+    return form(constants.BEGIN, body(node.(*Begin).Body)...)
+  case *Call:
+    call := node.(*Call)
+    return form("", append([]Node{call.Callee}, call.Args...)...)
+  case *Apply:
+    apply := node.(*Apply)
+    return form(constants.APPLY, append([]Node{apply.Proc}, apply.Args...)...)
+  case *Define:
+    define := node.(*Define)
+    keyword := constants.DEFINE
+    if define.Constant {
+      keyword = constants.DEFINE_CONSTANT
+    }
+    return defineDatum(keyword, define, nil)
+  case *DefineContract:
+    contract := node.(*DefineContract)
+    arrow := form("->", append(append([]Node{}, contract.Domain...), contract.Range)...)
+    return defineDatum(constants.DEFINE_CONTRACT, contract.Define, arrow)
+  case *DefineSyntax:
+    define := node.(*DefineSyntax)
+    return form(constants.DEFINE_SYNTAX, NewName(define.Name), define.Rules)
+  case *DefineEnum:
+    define := node.(*DefineEnum)
+    parts := []Node{define.Name}
+    for _, member := range define.Members {
+      parts = append(parts, member)
+    }
+    return form(constants.DEFINE_ENUM, parts...)
+  case *Function:
+    return ToDatum(node.(*Function).Body)
+  case *Lambda:
+    lambda := node.(*Lambda)
+    return NewPairValue(NewSymbol(constants.LAMBDA), NewPairValue(ToDatum(lambda.Params), form("", body(lambda.Body)...)))
+  case *Let:
+    let := node.(*Let)
+    return letDatum(constants.LET, let.Patterns, let.Exprs, let.Body)
+  case *LetStar:
+    let := node.(*LetStar)
+    return letDatum(constants.LET_STAR, let.Patterns, let.Exprs, let.Body)
+  case *LetRec:
+    let := node.(*LetRec)
+    return letDatum(constants.LET_REC, let.Patterns, let.Exprs, let.Body)
+  case *LetValues:
+    let := node.(*LetValues)
+    bindings := make([]Value, len(let.Formals))
+    for i, formals := range let.Formals {
+      bindings[i] = form("", formals, let.Exprs[i])
+    }
+    tail := NewPairValue(converter.SliceToPairValues(bindings), form("", body(let.Body)...))
+    return NewPairValue(NewSymbol(let.keyword()), tail)
+  case *If:
+    expr := node.(*If)
+    if expr.Else == nil {
+      return form(constants.IF, expr.Test, expr.Then)
+    }
+    return form(constants.IF, expr.Test, expr.Then, expr.Else)
+  case *Set:
+    set := node.(*Set)
+    return form(constants.SET, set.Pattern, set.Value)
+  case *Delay:
+    return form(constants.DELAY, node.(*Delay).Expr)
+  case *Force:
+    return form(constants.FORCE, node.(*Force).Promise)
+  case *Go:
+    return form(constants.GO, node.(*Go).Expr)
+  case *Nursery:
+    return form(constants.NURSERY, body(node.(*Nursery).Body)...)
+  case *Cond:
+    clauses := []Value{NewSymbol(constants.COND)}
+    for _, clause := range node.(*Cond).Clauses {
+      var test Value = NewSymbol(constants.ELSE)
+      if clause.Test != nil {
+        test = ToDatum(clause.Test)
+      }
+      clauses = append(clauses, clauseDatum(test, clause.Body, clause.Arrow))
+    }
+    return converter.SliceToPairValues(clauses)
+  case *Case:
+    expr := node.(*Case)
+    clauses := []Value{NewSymbol(constants.CASE), ToDatum(expr.Key)}
+    for _, clause := range expr.Clauses {
+      var data Value = NewSymbol(constants.ELSE)
+      if clause.Data != nil {
+        data = converter.SliceToPairValues(clause.Data)
+      }
+      clauses = append(clauses, clauseDatum(data, clause.Body, clause.Arrow))
+    }
+    return converter.SliceToPairValues(clauses)
+  case *Do:
+    loop := node.(*Do)
+    specs := make([]Value, len(loop.Vars))
+    for i, name := range loop.Vars {
+      if loop.Steps[i] == nil {
+        specs[i] = form("", name, loop.Inits[i])
+      } else {
+        specs[i] = form("", name, loop.Inits[i], loop.Steps[i])
+      }
+    }
+    exit := []Node{loop.Test}
+    if loop.Result != nil {
+      exit = append(exit, body(loop.Result)...)
+    }
+    var commands []Value
+    if loop.Body != nil {
+      for _, command := range body(loop.Body) {
+        commands = append(commands, ToDatum(command))
+      }
+    }
+    items := append([]Value{NewSymbol(constants.DO), converter.SliceToPairValues(specs), form("", exit...)}, commands...)
+    return converter.SliceToPairValues(items)
+  case *When:
+    expr := node.(*When)
+    keyword := constants.WHEN
+    if expr.Unless {
+      keyword = constants.UNLESS
+    }
+    return form(keyword, append([]Node{expr.Test}, body(expr.Body)...)...)
+  case *Logic:
+    expr := node.(*Logic)
+    keyword := constants.AND
+    if expr.Or {
+      keyword = constants.OR
+    }
+    return form(keyword, expr.Exprs...)
+  case *Select:
+    selection := node.(*Select)
+    keyword := constants.SELECT
+    if selection.Priority {
+      keyword = constants.PRIORITY_SELECT
+    }
+    clauses := []Value{NewSymbol(keyword)}
+    for i, clause := range selection.Clauses {
+      if receiver := selection.Receiver(i); receiver != nil {
+        if lambda, ok := clause[1].(*Lambda); ok {
+          test := form("", receiver, clause[0])
+          clauses = append(clauses, NewPairValue(test, form("", body(lambda.Body)...)))
+          continue
+        }
+      }
+      clauses = append(clauses, form("", clause...))
+    }
+    return converter.SliceToPairValues(clauses)
+  case *TheEnvironment:
+    return form(constants.THE_ENVIRONMENT)
+  case *Annotation:
+    annotation := node.(*Annotation)
+    return form(constants.ANNOTATE, annotation.Pattern, annotation.Type)
+  }
+  return node
+}
+
+// the list (keyword nodes...), without keyword if it is empty
+func form(keyword string, nodes ...Node) Value {
+  var items []Value
+  if keyword != "" {
+    items = append(items, NewSymbol(keyword))
+  }
+  for _, node := range nodes {
+    items = append(items, ToDatum(node))
+  }
+  return converter.SliceToPairValues(items)
+}
+
+// the list of elements as read, improper when they end with . and a tail
+func listDatum(elements []Node) Value {
+  n := len(elements)
+  dotted := false
+  for i, element := range elements {
+    if dot, ok := element.(*Name); ok && dot.Identifier == constants.DOT {
+      // only (a ... . b) is a dotted list, like the parser reads it
+      if i == 0 || i != n-2 {
+        panic(fmt.Sprint("illegal use of `.'"))
+      }
+      dotted = true
+    }
+  }
+  if !dotted {
+    return form("", elements...)
+  }
+  tail := ToDatum(elements[n-1])
+  for i := n - 3; i >= 0; i-- {
+    tail = NewPairValue(ToDatum(elements[i]), tail)
+  }
+  return tail
+}
+
+// the clause (head body...) of cond or case, (head => receiver) with an
+// arrow and (head) without body
+func clauseDatum(head Value, node Node, arrow bool) Value {
+  switch {
+  case node == nil:
+    return NewPairValue(head, NilPairValue)
+  case arrow:
+    return NewPairValue(head, NewPairValue(NewSymbol(constants.ARROW), form("", node)))
+  }
+  return NewPairValue(head, form("", body(node)...))
+}
+
+// the expressions of a body, which the parser wraps in a block
+func body(node Node) []Node {
+  if block, ok := node.(*Block); ok {
+    return block.Exprs
+  }
+  return []Node{node}
+}
+
+// (define (f x) body...) for procedures, curried ones included,
+// (define name value) for anything else. a contract goes after
+// the name and its formals
+func defineDatum(keyword string, define *Define, contract Value) Value {
+  function, ok := define.Value.(*Function)
+  if !ok {
+    items := []Value{NewSymbol(keyword), ToDatum(define.Pattern)}
+    if contract != nil {
+      items = append(items, contract)
+    }
+    return converter.SliceToPairValues(append(items, ToDatum(define.Value)))
+  }
+  // ((f x) y) is a lambda of x returning a lambda of y
+  var head Value = ToDatum(function.Caller)
+  lambda := function.Body.(*Lambda)
+  for {
+    head = NewPairValue(head, ToDatum(lambda.Params))
+    inner, ok := lambda.Body.(*Lambda)
+    if !ok {
+      break
+    }
+    lambda = inner
+  }
+  tail := form("", body(lambda.Body)...)
+  if contract != nil {
+    tail = NewPairValue(contract, tail)
+  }
+  return NewPairValue(NewSymbol(keyword), NewPairValue(head, tail))
+}
+
+func letDatum(keyword string, patterns []*Name, exprs []Node, node Node) Value {
+  bindings := make([]Value, len(patterns))
+  for i, pattern := range patterns {
+    bindings[i] = form("", pattern, exprs[i])
+  }
+  tail := NewPairValue(converter.SliceToPairValues(bindings), form("", body(node)...))
+  return NewPairValue(NewSymbol(keyword), tail)
+}
@@ -3,13 +3,17 @@ package ast
 import (
   "fmt"
   "github.com/kedebug/LispEx/binder"
+  "github.com/kedebug/LispEx/constants"
   "github.com/kedebug/LispEx/scope"
   "github.com/kedebug/LispEx/value"
 )
 
 type Define struct {
-  Pattern *Name
-  Value   Node
+  Pattern  *Name
+  Value    Node
+  Constant bool
+  // source position of the form
+  Pos string
 }
 
 func NewDefine(pattern *Name, val Node) *Define {
@@ -17,10 +21,26 @@ func NewDefine(pattern *Name, val Node) *Define {
 }
 
 func (self *Define) Eval(env *scope.Scope) value.Value {
-  binder.Define(env, self.Pattern.Identifier, self.Value.Eval(env))
+  if self.Constant {
+    binder.DefineConstant(env, self.Pattern.Identifier, self.Value.Eval(env), self.Pos)
+  } else {
+    binder.Define(env, self.Pattern.Identifier, self.eval(env), self.Pos)
+  }
   return nil
 }
 
+// the value of a procedure declared by Declare may have been
+// evaluated already, it keeps its identity
+func (self *Define) eval(env *scope.Scope) value.Value {
+  if pending, ok := env.LookupLocal(self.Pattern.Identifier).(*Pending); ok && pending.define == self {
+    return pending.Force()
+  }
+  return self.Value.Eval(env)
+}
+
 func (self *Define) String() string {
+  if self.Constant {
+    return fmt.Sprintf("(%s %s %s)", constants.DEFINE_CONSTANT, self.Pattern, self.Value)
+  }
   return fmt.Sprintf("(define %s %s)", self.Pattern, self.Value)
 }
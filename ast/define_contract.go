@@ -0,0 +1,66 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/binder"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/scope"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+)
+
+// (define/contract (f x) (-> integer? integer?) <body>)
+//  binds f to a procedure checking its arguments and result,
+//  a bad argument blames the caller, a bad result blames f
+type DefineContract struct {
+  Define *Define
+  Domain []Node
+  Range  Node
+  Pos    string
+}
+
+func NewDefineContract(define *Define, domain []Node, rang Node, pos string) *DefineContract {
+  return &DefineContract{Define: define, Domain: domain, Range: rang, Pos: pos}
+}
+
+func (self *DefineContract) Eval(env *scope.Scope) Value {
+  name := self.Define.Pattern.Identifier
+  proc := self.Define.Value.Eval(env)
+  checks := env.Contracts()
+  if !checks.On() {
+    binder.Define(env, name, proc, self.Pos)
+    return nil
+  }
+  switch proc.(type) {
+  case *Closure, *Contract, PrimFunc:
+  default:
+    panic(fmt.Sprintf("%s: %s: expected a procedure, given: %s", constants.DEFINE_CONTRACT, name, proc))
+  }
+  if closure, ok := proc.(*Closure); ok {
+    if signature, ok := closure.Body.(Signature); ok {
+      required, variadic := signature.Arity()
+      if len(self.Domain) < required || (!variadic && len(self.Domain) > required) {
+        panic(fmt.Sprintf("%s: %s: contract expects %d arguments, procedure takes %d",
+          constants.DEFINE_CONTRACT, name, len(self.Domain), required))
+      }
+    }
+  }
+
+  domain := EvalList(self.Domain, env)
+  names := make([]string, len(self.Domain))
+  for i, node := range self.Domain {
+    names[i] = node.String()
+  }
+  rang := self.Range.Eval(env)
+  binder.Define(env, name, NewContract(name, proc, domain, rang, names, self.Range.String(), self.Pos, checks), self.Pos)
+  return nil
+}
+
+func (self *DefineContract) String() string {
+  var domain []string
+  for _, node := range self.Domain {
+    domain = append(domain, node.String())
+  }
+  domain = append(domain, self.Range.String())
+  return fmt.Sprintf("(%s %s (-> %s) %s)", constants.DEFINE_CONTRACT, self.Define.Pattern, strings.Join(domain, " "), self.Define.Value)
+}
@@ -0,0 +1,57 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/binder"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+)
+
+// (define-enum color red green blue) defines red, green and blue as
+// the values of a new type, with color?, color->symbol and
+// symbol->color. evaluating it again, e.g. when the file is reloaded,
+// makes another type
+type DefineEnum struct {
+  Name    *Name
+  Members []*Name
+  Pos     string
+}
+
+func NewDefineEnum(name *Name, members []*Name) *DefineEnum {
+  return &DefineEnum{Name: name, Members: members}
+}
+
+func (self *DefineEnum) Eval(env *scope.Scope) value.Value {
+  names := make([]string, len(self.Members))
+  for i, member := range self.Members {
+    names[i] = member.Identifier
+  }
+  et := value.NewEnumType(self.Name.Identifier, names)
+  for _, member := range et.Members {
+    binder.Define(env, member.Name, member, self.Pos)
+  }
+  for _, builtin := range primitives.EnumBuiltins(et) {
+    binder.Define(env, builtin.Name, builtin, self.Pos)
+  }
+  return nil
+}
+
+// the names the form defines, the members first
+func (self *DefineEnum) Names() []string {
+  var names []string
+  for _, member := range self.Members {
+    names = append(names, member.Identifier)
+  }
+  name := self.Name.Identifier
+  return append(names, name+"?", name+"->symbol", "symbol->"+name)
+}
+
+func (self *DefineEnum) String() string {
+  s := fmt.Sprintf("(%s %s", constants.DEFINE_ENUM, self.Name)
+  for _, member := range self.Members {
+    s += fmt.Sprintf(" %s", member)
+  }
+  return s + ")"
+}
@@ -0,0 +1,28 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// (define-syntax name (syntax-rules ...)) defines its macro when it is
+// parsed, evaluating it does nothing. Rules is the syntax-rules form
+type DefineSyntax struct {
+  Name  string
+  Rules *Tuple
+  Pos   string
+}
+
+func NewDefineSyntax(name string, rules *Tuple) *DefineSyntax {
+  return &DefineSyntax{Name: name, Rules: rules}
+}
+
+func (self *DefineSyntax) Eval(env *scope.Scope) value.Value {
+  return nil
+}
+
+func (self *DefineSyntax) String() string {
+  return fmt.Sprintf("(%s %s %s)", constants.DEFINE_SYNTAX, self.Name, self.Rules)
+}
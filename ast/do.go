@@ -0,0 +1,77 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/binder"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// (do ((var init step) ...) (test result ...) command ...) binds each
+// var to its init, then until test isn't #f evaluates the commands and
+// binds the vars afresh to their steps, a var without step keeping its
+// value. it loops without growing the stack, unlike a named let
+type Do struct {
+  Vars  []*Name
+  Inits []Node
+  // nil for a var without step
+  Steps []Node
+  Test  Node
+  // the result and the commands, nil when there are none
+  Result Node
+  Body   Node
+}
+
+func NewDo(vars []*Name, inits, steps []Node, test, result, body Node) *Do {
+  return &Do{Vars: vars, Inits: inits, Steps: steps, Test: test, Result: result, Body: body}
+}
+
+func (self *Do) Eval(env *scope.Scope) value.Value {
+  values := EvalList(self.Inits, env)
+  for {
+    // each iteration has bindings of its own, which closures may keep
+    loop := scope.NewLocalScope(env)
+    for i, name := range self.Vars {
+      binder.Define(loop, name.Identifier, values[i], "")
+    }
+    if !isFalse(self.Test.Eval(loop)) {
+      if self.Result == nil {
+        return nil
+      }
+      return self.Result.Eval(loop)
+    }
+    if self.Body != nil {
+      self.Body.Eval(loop)
+    }
+    values = append([]value.Value(nil), values...)
+    for i, step := range self.Steps {
+      if step != nil {
+        values[i] = step.Eval(loop)
+      }
+    }
+    env.CheckInterrupted()
+  }
+}
+
+func (self *Do) String() string {
+  var specs string
+  for i, name := range self.Vars {
+    if i > 0 {
+      specs += " "
+    }
+    if self.Steps[i] == nil {
+      specs += fmt.Sprintf("(%s %s)", name, self.Inits[i])
+    } else {
+      specs += fmt.Sprintf("(%s %s %s)", name, self.Inits[i], self.Steps[i])
+    }
+  }
+  exit := fmt.Sprint(self.Test)
+  if self.Result != nil {
+    exit += fmt.Sprintf(" %s", self.Result)
+  }
+  if self.Body == nil {
+    return fmt.Sprintf("(%s (%s) (%s))", constants.DO, specs, exit)
+  }
+  return fmt.Sprintf("(%s (%s) (%s) %s)", constants.DO, specs, exit, self.Body)
+}
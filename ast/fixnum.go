@@ -0,0 +1,51 @@
+package ast
+
+import (
+  . "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+)
+
+// calls of + - * < = with two small integers are evaluated directly,
+// without building the argument slice and checking it in the builtin.
+// operands are bounded so that the results are the same as the builtins'
+const fixnumLimit = 1 << 31
+
+func isFixnumOp(builtin *primitives.Builtin) bool {
+  switch builtin.Proc.(type) {
+  case *primitives.Add, *primitives.Sub, *primitives.Mult, *primitives.Lt, *primitives.Eq:
+    return true
+  }
+  return false
+}
+
+func fixnum(val Value) (int64, bool) {
+  if n, ok := val.(*IntValue); ok && n.Value > -fixnumLimit && n.Value < fixnumLimit {
+    return n.Value, true
+  }
+  return 0, false
+}
+
+// ok is false unless both arguments are small integers
+func fixnumOp(builtin *primitives.Builtin, x, y Value) (Value, bool) {
+  a, ok := fixnum(x)
+  if !ok {
+    return nil, false
+  }
+  b, ok := fixnum(y)
+  if !ok {
+    return nil, false
+  }
+  switch builtin.Proc.(type) {
+  case *primitives.Add:
+    return NewIntValue(a + b), true
+  case *primitives.Sub:
+    return NewIntValue(a - b), true
+  case *primitives.Mult:
+    return NewIntValue(a * b), true
+  case *primitives.Lt:
+    return NewBoolValue(a < b), true
+  case *primitives.Eq:
+    return NewBoolValue(a == b), true
+  }
+  return nil, false
+}
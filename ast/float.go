@@ -4,7 +4,6 @@ import (
   "fmt"
   "github.com/kedebug/LispEx/scope"
   "github.com/kedebug/LispEx/value"
-  "strconv"
 )
 
 type Float struct {
@@ -12,7 +11,7 @@ type Float struct {
 }
 
 func NewFloat(s string) *Float {
-  val, err := strconv.ParseFloat(s, 64)
+  val, err := value.ParseFloat(s)
   if err != nil {
     panic(fmt.Sprintf("%s is not float format", s))
   }
@@ -24,5 +23,5 @@ func (self *Float) Eval(s *scope.Scope) value.Value {
 }
 
 func (self *Float) String() string {
-  return strconv.FormatFloat(self.Value, 'f', -1, 64)
+  return value.FormatFloat(self.Value)
 }
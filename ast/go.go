@@ -1,6 +1,8 @@
 package ast
 
 import (
+  "context"
+  "errors"
   "fmt"
   "github.com/kedebug/LispEx/scope"
   . "github.com/kedebug/LispEx/value"
@@ -8,6 +10,7 @@ import (
 
 type Go struct {
   Expr Node
+  Pos  string
 }
 
 func NewGo(expr Node) *Go {
@@ -15,18 +18,41 @@ func NewGo(expr Node) *Go {
 }
 
 func (self *Go) Eval(env *scope.Scope) Value {
+  if group := currentNursery(); group != nil {
+    group.join()
+    Spawn(self.Pos, func() {
+      group.run(func() Value { return self.Expr.Eval(scope.NewLocalScope(env)) })
+    })
+    return nil
+  }
   // We need to recover the panic message of goroutine
-  go func() {
+  Spawn(self.Pos, func() {
     defer func() {
       if err := recover(); err != nil {
-        fmt.Println(err)
+        // the goroutine evaluating the program reports deadlocks,
+        // and those of a program which was stopped stop quietly
+        if e, ok := err.(error); !ok || !deadlocked(e) && !stopped(e) {
+          fmt.Println(err)
+        }
       }
     }()
-    self.Expr.Eval(scope.NewScope(env))
-  }()
+    self.Expr.Eval(scope.NewLocalScope(env))
+  })
   return nil
 }
 
+func deadlocked(err error) bool {
+  var deadlock *DeadlockError
+  return errors.As(err, &deadlock)
+}
+
+// interrupted, or its sleep or I/O canceled, since
+// the context of the interpreter is done
+func stopped(err error) bool {
+  var interrupted *Interrupted
+  return errors.As(err, &interrupted) || errors.Is(err, context.Canceled)
+}
+
 func (self *Go) String() string {
   return fmt.Sprintf("(go %s)", self.Expr)
 }
@@ -34,3 +34,9 @@ func (self *If) Eval(env *scope.Scope) value.Value {
 func (self *If) String() string {
   return fmt.Sprintf("(%s %s %s %s)", constants.IF, self.Test, self.Then, self.Else)
 }
+
+// only #f is false, to if and the other conditionals
+func isFalse(val value.Value) bool {
+  b, ok := val.(*value.BoolValue)
+  return ok && !b.Value
+}
@@ -2,6 +2,7 @@ package ast
 
 import (
   "fmt"
+  "github.com/kedebug/LispEx/lexer"
   "github.com/kedebug/LispEx/scope"
   "github.com/kedebug/LispEx/value"
   "strconv"
@@ -14,21 +15,13 @@ type Int struct {
 }
 
 func NewInt(s string) *Int {
-  var val int64
-  var base int
-  var err error
-  var missed string
-
-  if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
-    missed = s[:2]
-    s = s[2:]
-    base = 16
-  } else {
-    base = 10
-  }
-  val, err = strconv.ParseInt(s, base, 64)
+  val, err := lexer.ParseInt(s)
   if err != nil {
-    panic(fmt.Sprintf("%s is not integer format", missed+s))
+    panic(fmt.Sprintf("%s is not integer format", s))
+  }
+  base := 10
+  if strings.ContainsAny(s, "xX") {
+    base = 16
   }
   return &Int{Value: val, Base: base}
 }
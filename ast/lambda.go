@@ -2,6 +2,8 @@ package ast
 
 import (
   "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/converter"
   "github.com/kedebug/LispEx/scope"
   "github.com/kedebug/LispEx/value"
 )
@@ -12,6 +14,18 @@ import (
 type Lambda struct {
   Params Node
   Body   Node
+  // the name it is defined with, for backtraces
+  Name string
+  // of the form defining it, file:line:column, for describe
+  Pos string
+  // set by closure conversion: the closure keeps only the Captures,
+  // copies of the local variables it refers to, and its global scope.
+  // variables of a scope (the-environment) exposes are never copied
+  Flat     bool
+  Captures []string
+  // set by escape analysis: no closure, promise or environment
+  // refers to the scope of a call once it returns
+  Reusable bool
 }
 
 func NewLambda(params Node, body Node) *Lambda {
@@ -22,9 +36,77 @@ func NewLambda(params Node, body Node) *Lambda {
 }
 
 func (self *Lambda) Eval(env *scope.Scope) value.Value {
-  return value.NewClosure(env, self)
+  if !self.Flat {
+    return value.NewClosure(env, self)
+  }
+  if len(self.Captures) == 0 {
+    return value.NewClosure(env.Global(), self)
+  }
+  frame := scope.NewLocalScope(env.Global())
+  for _, name := range self.Captures {
+    if val := env.Lookup(name); val != nil {
+      frame.Put(name, val)
+    }
+  }
+  return value.NewClosure(frame, self)
 }
 
 func (self *Lambda) String() string {
   return fmt.Sprintf("(lambda %s %s)", self.Params, self.Body)
 }
+
+// bind call arguments to parameters in a new scope
+// below env and evaluate the body there
+func (self *Lambda) Call(env interface{}, args []value.Value) value.Value {
+  if !self.Reusable {
+    local := scope.NewLocalScope(env.(*scope.Scope))
+    // these nodes should be in Lisp pair structure
+    BindArguments(local, self.Params, converter.SliceToPairValues(args), self.procedure(), self.Pos)
+    return self.Body.Eval(local)
+  }
+  local := scope.AcquireLocalScope(env.(*scope.Scope))
+  BindArguments(local, self.Params, converter.SliceToPairValues(args), self.procedure(), self.Pos)
+  result := self.Body.Eval(local)
+  local.Release()
+  return result
+}
+
+// the name of the procedure in errors
+func (self *Lambda) procedure() string {
+  if self.Name == "" {
+    return constants.LAMBDA
+  }
+  return self.Name
+}
+
+// number of required parameters and whether more are accepted
+func (self *Lambda) Arity() (int, bool) {
+  count := 0
+  params := self.Params
+  for {
+    switch params.(type) {
+    case *Pair:
+      count++
+      params = params.(*Pair).Second
+    case *Name:
+      return count, true
+    default:
+      return count, false
+    }
+  }
+}
+
+// a string literal followed by more expressions
+// at the start of the body is a doc string
+func (self *Lambda) Doc() string {
+  if block, ok := self.Body.(*Block); ok && len(block.Exprs) > 1 {
+    if doc, ok := block.Exprs[0].(*String); ok {
+      return doc.Value
+    }
+  }
+  return ""
+}
+
+func (self *Lambda) Location() string {
+  return self.Pos
+}
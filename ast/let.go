@@ -11,6 +11,8 @@ type Let struct {
   Patterns []*Name
   Exprs    []Node
   Body     Node
+  // set by escape analysis, see Lambda
+  Reusable bool
 }
 
 func NewLet(patterns []*Name, exprs []Node, body Node) *Let {
@@ -24,12 +26,21 @@ func (self *Let) Eval(s *scope.Scope) value.Value {
   // evaluated in the extended environment, and the value(s)
   // of the last expression of <body> is(are) returned.
 
-  env := scope.NewScope(s)
-  extended := scope.NewScope(s)
+  newScope := scope.NewLocalScope
+  if self.Reusable {
+    newScope = scope.AcquireLocalScope
+  }
+  env := newScope(s)
+  extended := newScope(s)
   for i := 0; i < len(self.Patterns); i++ {
-    binder.Define(extended, self.Patterns[i].Identifier, self.Exprs[i].Eval(env))
+    binder.Define(extended, self.Patterns[i].Identifier, self.Exprs[i].Eval(env), "")
+  }
+  result := self.Body.Eval(extended)
+  if self.Reusable {
+    env.Release()
+    extended.Release()
   }
-  return self.Body.Eval(extended)
+  return result
 }
 
 func (self *Let) String() string {
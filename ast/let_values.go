@@ -0,0 +1,92 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// (let-values (((a b . rest) init) ...) body...) binds the formals of
+// each binding to the values returned by its init, as the parameters of
+// a lambda are bound to arguments. the inits of let*-values see the
+// bindings before them, those of let-values are evaluated outside
+type LetValues struct {
+  // each like the Params of a Lambda
+  Formals    []Node
+  Exprs      []Node
+  Body       Node
+  Sequential bool
+  Pos        string
+}
+
+func NewLetValues(formals []Node, exprs []Node, body Node, sequential bool) *LetValues {
+  return &LetValues{Formals: formals, Exprs: exprs, Body: body, Sequential: sequential}
+}
+
+func (self *LetValues) Eval(env *scope.Scope) value.Value {
+  if self.Sequential {
+    for i, formals := range self.Formals {
+      values := self.Exprs[i].Eval(env)
+      env = scope.NewLocalScope(env)
+      self.bind(env, formals, values)
+    }
+    return self.Body.Eval(env)
+  }
+  values := EvalList(self.Exprs, env)
+  local := scope.NewLocalScope(env)
+  for i, formals := range self.Formals {
+    self.bind(local, formals, values[i])
+  }
+  return self.Body.Eval(local)
+}
+
+func (self *LetValues) bind(env *scope.Scope, formals Node, values value.Value) {
+  defer func() {
+    if err := recover(); err != nil {
+      if _, ok := err.(*value.ArityError); ok {
+        message := fmt.Sprintf("%s: %d values given for the formals %s", self.keyword(), len(value.SpreadValues(values)), formals)
+        panic(&value.ArityError{message, self.Pos})
+      }
+      panic(err)
+    }
+  }()
+  BindArguments(env, formals, converter.SliceToPairValues(value.SpreadValues(values)), self.keyword(), self.Pos)
+}
+
+func (self *LetValues) keyword() string {
+  if self.Sequential {
+    return constants.LET_STAR_VALUES
+  }
+  return constants.LET_VALUES
+}
+
+func (self *LetValues) String() string {
+  var bindings string
+  for i, formals := range self.Formals {
+    if i > 0 {
+      bindings += " "
+    }
+    bindings += fmt.Sprintf("(%s %s)", formals, self.Exprs[i])
+  }
+  return fmt.Sprintf("(%s (%s) %s)", self.keyword(), bindings, self.Body)
+}
+
+// the names bound by formals like those of a lambda, the rest last
+func FormalNames(formals Node) []*Name {
+  var names []*Name
+  for {
+    switch formals.(type) {
+    case *Pair:
+      if name, ok := formals.(*Pair).First.(*Name); ok {
+        names = append(names, name)
+      }
+      formals = formals.(*Pair).Second
+    case *Name:
+      return append(names, formals.(*Name))
+    default:
+      return names
+    }
+  }
+}
@@ -11,6 +11,8 @@ type LetRec struct {
   Patterns []*Name
   Exprs    []Node
   Body     Node
+  // set by escape analysis, see Lambda
+  Reusable bool
 }
 
 func NewLetRec(patterns []*Name, exprs []Node, body Node) *LetRec {
@@ -26,16 +28,27 @@ func (self *LetRec) Eval(s *scope.Scope) value.Value {
   // returned. Each binding of a <variable> has the entire letrec expression
   // as its region, making it possible to define mutually recursive procedures.
 
-  env := scope.NewScope(s)
+  newScope := scope.NewLocalScope
+  if self.Reusable {
+    newScope = scope.AcquireLocalScope
+  }
+  env := newScope(s)
   extended := make([]*scope.Scope, len(self.Patterns))
   for i := 0; i < len(self.Patterns); i++ {
-    extended[i] = scope.NewScope(env)
-    binder.Define(extended[i], self.Patterns[i].Identifier, self.Exprs[i].Eval(env))
+    extended[i] = newScope(env)
+    binder.Define(extended[i], self.Patterns[i].Identifier, self.Exprs[i].Eval(env), "")
   }
   for i := 0; i < len(extended); i++ {
     env.PutAll(extended[i])
   }
-  return self.Body.Eval(env)
+  result := self.Body.Eval(env)
+  if self.Reusable {
+    for i := 0; i < len(extended); i++ {
+      extended[i].Release()
+    }
+    env.Release()
+  }
+  return result
 }
 
 func (self *LetRec) String() string {
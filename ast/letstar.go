@@ -11,6 +11,8 @@ type LetStar struct {
   Patterns []*Name
   Exprs    []Node
   Body     Node
+  // set by escape analysis, see Lambda
+  Reusable bool
 }
 
 func NewLetStar(patterns []*Name, exprs []Node, body Node) *LetStar {
@@ -24,11 +26,24 @@ func (self *LetStar) Eval(env *scope.Scope) value.Value {
   // of the binding. Thus the second binding is done in an environment in
   // which the first binding is visible, and so on.
 
+  newScope := scope.NewLocalScope
+  if self.Reusable {
+    newScope = scope.AcquireLocalScope
+  }
+  outer := env
   for i := 0; i < len(self.Patterns); i++ {
-    env = scope.NewScope(env)
-    binder.Define(env, self.Patterns[i].Identifier, self.Exprs[i].Eval(env))
+    env = newScope(env)
+    binder.Define(env, self.Patterns[i].Identifier, self.Exprs[i].Eval(env), "")
+  }
+  result := self.Body.Eval(env)
+  if self.Reusable {
+    for env != outer {
+      parent := env.Parent()
+      env.Release()
+      env = parent
+    }
   }
-  return self.Body.Eval(env)
+  return result
 }
 
 func (self *LetStar) String() string {
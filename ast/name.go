@@ -1,13 +1,16 @@
 package ast
 
 import (
-  "fmt"
   "github.com/kedebug/LispEx/scope"
   . "github.com/kedebug/LispEx/value"
 )
 
 type Name struct {
   Identifier string
+  // source position of the name, blamed when it is unbound
+  Pos string
+  // written between bars: |#t| quotes a symbol, not a boolean
+  Barred bool
 }
 
 func NewName(identifier string) *Name {
@@ -16,12 +19,41 @@ func NewName(identifier string) *Name {
 
 func (self *Name) Eval(env *scope.Scope) Value {
   if val := env.Lookup(self.Identifier); val != nil {
+    if pending, ok := val.(*Pending); ok {
+      return pending.Force()
+    }
     return val.(Value)
   } else {
-    panic(fmt.Sprintf("%s: undefined identifier", self.Identifier))
+    panic(&UnboundVariable{self.Identifier, self.Pos})
+  }
+}
+
+// like Eval, with the global binding cached for the next evaluation
+func (self *Name) EvalCached(env *scope.Scope, cache *scope.Cache) Value {
+  if val := env.LookupCached(self.Identifier, cache); val != nil {
+    if pending, ok := val.(*Pending); ok {
+      return pending.Force()
+    }
+    return val.(Value)
+  } else {
+    panic(&UnboundVariable{self.Identifier, self.Pos})
   }
 }
 
 func (self *Name) String() string {
   return self.Identifier
 }
+
+// value of a quoted name: booleans are literals, other names are symbols
+func (self *Name) Datum() Value {
+  if self.Barred {
+    return NewSymbol(self.Identifier)
+  }
+  switch self.Identifier {
+  case "#t":
+    return NewBoolValue(true)
+  case "#f":
+    return NewBoolValue(false)
+  }
+  return NewSymbol(self.Identifier)
+}
@@ -0,0 +1,121 @@
+package ast
+
+import (
+  "context"
+  "fmt"
+  "github.com/kedebug/LispEx/scope"
+  . "github.com/kedebug/LispEx/value"
+  "reflect"
+  "sync"
+)
+
+// (nursery body...) scopes the goroutines started by the `go' forms
+// evaluated while its body runs, those of the procedures it calls and
+// nested ones included: it returns the value of the body once all of
+// them have returned, and raises the first error raised by the body or
+// one of them instead. the first error cancels the I/O and sleeps of
+// the others, and the goroutines still running are waited for too
+type Nursery struct {
+  Body Node
+  Pos  string
+}
+
+func NewNursery(body Node) *Nursery {
+  return &Nursery{Body: body}
+}
+
+// the goroutines of a nursery and its body, which counts as running
+// until it returns. done is closed once none is running anymore,
+// cancel cancels the context of the group on the first error
+type goroutineGroup struct {
+  running int
+  err     interface{}
+  done    *Channel
+  cancel  context.CancelFunc
+  mutex   sync.Mutex
+}
+
+func (self *Nursery) Eval(env *scope.Scope) Value {
+  done := NewChannel(0)
+  done.Local = true
+  ctx, cancel := context.WithCancel(env.DynamicContext())
+  defer cancel()
+  group := &goroutineGroup{running: 1, done: done, cancel: cancel}
+  dynamic := CurrentDynamic()
+  dynamic.Nursery = group
+  dynamic.Context = ctx
+
+  result := WithDynamic(dynamic, func() Value {
+    return group.run(func() Value { return self.Body.Eval(scope.NewLocalScope(env)) })
+  })
+  site := "nursery"
+  if self.Pos != "" {
+    site += " at " + self.Pos
+  }
+  group.wait(site)
+  if group.err != nil {
+    panic(group.err)
+  }
+  return result
+}
+
+// the group of the innermost nursery being evaluated by the
+// calling goroutine, or by the one which started it, if any
+func currentNursery() *goroutineGroup {
+  group, _ := CurrentDynamic().Nursery.(*goroutineGroup)
+  return group
+}
+
+// waiting counts as blocked for the detection of deadlocks. the
+// goroutines may wait for a body which failed, the deadlock is
+// then left out for the error of the body
+func (self *goroutineGroup) wait(site string) {
+  defer func() {
+    if err := recover(); err != nil {
+      self.mutex.Lock()
+      failed := self.err != nil
+      self.mutex.Unlock()
+      if !failed {
+        panic(err)
+      }
+    }
+  }()
+  wait := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(self.done.Value)}
+  BlockingSelect([]reflect.SelectCase{wait}, true, site)
+}
+
+func (self *goroutineGroup) join() {
+  self.mutex.Lock()
+  self.running++
+  self.mutex.Unlock()
+}
+
+// evaluate the body or a goroutine of the group, keeping the first
+// error raised by any of them. a goroutine of a pool starting after
+// the group failed isn't evaluated
+func (self *goroutineGroup) run(body func() Value) (result Value) {
+  defer func() {
+    err := recover()
+    self.mutex.Lock()
+    defer self.mutex.Unlock()
+    if err != nil && self.err == nil {
+      self.err = err
+      self.cancel()
+    }
+    self.running--
+    if self.running == 0 {
+      self.done.Close()
+    }
+  }()
+  self.mutex.Lock()
+  failed := self.err != nil
+  self.mutex.Unlock()
+  if failed {
+    return nil
+  }
+  return body()
+}
+
+func (self *Nursery) String() string {
+  return fmt.Sprintf("(nursery %s)", self.Body)
+}
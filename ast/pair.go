@@ -44,7 +44,7 @@ func (self *Pair) Eval(env *scope.Scope) value.Value {
   } else {
     switch self.Second.(type) {
     case *Name:
-      second = value.NewSymbol(self.Second.(*Name).Identifier)
+      second = self.Second.(*Name).Datum()
     default:
       second = self.Second.Eval(env)
     }
@@ -52,41 +52,45 @@ func (self *Pair) Eval(env *scope.Scope) value.Value {
 
   if name, ok := self.First.(*Name); ok {
     // treat Name as Symbol
-    first = value.NewSymbol(name.Identifier)
+    first = name.Datum()
   } else if _, ok := self.First.(*UnquoteSplicing); ok {
     // our parser garantees unquote-splicing only appears in quasiquote
     // and unquote-splicing will be evaluated to a list
-    first = self.First.Eval(env)
-    // () empty list must be handled
-    if first == value.NilPairValue {
-      return second
-    }
-    // seek for the last element
-    var last value.Value = first
-    for {
-      switch last.(type) {
-      case *value.PairValue:
-        pair := last.(*value.PairValue)
-        if pair.Second == value.NilPairValue {
-          pair.Second = second
-          return first
-        }
-        last = pair.Second
-      default:
-        if second == value.NilPairValue {
-          return first
-        } else {
-          // `(,@(cdr '(1 . 2) 3))
-          panic(fmt.Sprintf("unquote-splicing: expected list?, given: %s", first))
-        }
-      }
-    }
+    return splice(self.First.Eval(env), second)
   } else {
     first = self.First.Eval(env)
   }
   return value.NewPairValue(first, second)
 }
 
+// `(,@'(1 2) . rest) => (1 2 . rest)
+//  the spliced list is copied, linking its last pair to rest
+//  would change the list (a quoted constant, maybe) itself
+func splice(list, rest value.Value) value.Value {
+  var elements []value.Value
+  tail := list
+  for {
+    pair, ok := tail.(*value.PairValue)
+    if !ok {
+      break
+    }
+    elements = append(elements, pair.First)
+    tail = pair.Second
+  }
+  if tail != value.NilPairValue {
+    if rest != value.NilPairValue {
+      // `(,@(cdr '(1 . 2)) 3)
+      panic(fmt.Sprintf("unquote-splicing: expected list?, given: %s", list))
+    }
+    // `(1 ,@2) => (1 . 2)
+    rest = tail
+  }
+  for i := len(elements) - 1; i >= 0; i-- {
+    rest = value.NewPairValue(elements[i], rest)
+  }
+  return rest
+}
+
 func (self *Pair) String() string {
   if self.Second == NilPair {
     return fmt.Sprintf("(%s)", self.First)
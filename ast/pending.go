@@ -0,0 +1,72 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "sync"
+)
+
+// the procedure of a top-level define not evaluated yet, bound ahead
+// of it so that the forms above, and the goroutines they started, can
+// call it. it is evaluated once, by its first use or by the define
+type Pending struct {
+  define *Define
+  env    *scope.Scope
+  once   sync.Once
+  value  value.Value
+}
+
+func (self *Pending) Force() value.Value {
+  self.once.Do(func() {
+    self.value = self.define.Value.Eval(self.env)
+  })
+  return self.value
+}
+
+func (self *Pending) String() string {
+  return fmt.Sprintf("#<pending %s>", self.define.Pattern)
+}
+
+// declares the procedures defined by the top-level forms of a file,
+// as letrec would, and returns their names for Undeclare. names
+// already bound are left alone, their current value being used until
+// they are defined again
+func Declare(nodes []Node, env *scope.Scope) []string {
+  var names []string
+  for _, node := range nodes {
+    switch node.(type) {
+    case *Begin:
+      names = append(names, Declare([]Node{node.(*Begin).Body}, env)...)
+    case *Block:
+      names = append(names, Declare(node.(*Block).Exprs, env)...)
+    case *Define:
+      define := node.(*Define)
+      if define.Constant || !isProcedure(define.Value) {
+        continue
+      }
+      name := define.Pattern.Identifier
+      if env.Lookup(name) != nil {
+        continue
+      }
+      env.Declare(name, &Pending{define: define, env: env})
+      names = append(names, name)
+    }
+  }
+  return names
+}
+
+// removes the declarations whose define was never evaluated
+func Undeclare(names []string, env *scope.Scope) {
+  for _, name := range names {
+    env.Undeclare(name)
+  }
+}
+
+func isProcedure(node Node) bool {
+  switch node.(type) {
+  case *Lambda, *Function:
+    return true
+  }
+  return false
+}
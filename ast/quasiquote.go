@@ -16,9 +16,11 @@ func NewQuasiquote(body Node) *Quasiquote {
 
 func (self *Quasiquote) Eval(env *scope.Scope) value.Value {
   if name, ok := self.Body.(*Name); ok {
-    return value.NewSymbol(name.Identifier)
+    return name.Datum()
   } else {
-    return self.Body.Eval(env)
+    result := self.Body.Eval(env)
+    env.Allocated(result)
+    return result
   }
 }
 
@@ -20,7 +20,7 @@ func NewQuote(body Node) *Quote {
 
 func (self *Quote) Eval(env *scope.Scope) value.Value {
   if name, ok := self.Body.(*Name); ok {
-    return value.NewSymbol(name.Identifier)
+    return name.Datum()
   } else {
     return self.Body.Eval(env)
   }
@@ -0,0 +1,40 @@
+package ast
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/number"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "math/big"
+)
+
+// an exact number literal which doesn't fit in an Int:
+// an integer beyond the range of an int64, or a fraction
+type Rational struct {
+  Value *big.Rat
+}
+
+func NewRational(s string) *Rational {
+  val, ok := lexer.ParseRational(s)
+  if !ok {
+    panic(fmt.Sprintf("%s is not rational format", s))
+  }
+  return &Rational{Value: val}
+}
+
+// an Int when s fits in one
+func NewInteger(s string) Node {
+  if _, err := lexer.ParseInt(s); err == nil {
+    return NewInt(s)
+  }
+  return NewRational(s)
+}
+
+func (self *Rational) Eval(env *scope.Scope) value.Value {
+  return number.Rational(new(big.Rat).Set(self.Value))
+}
+
+func (self *Rational) String() string {
+  return self.Value.RatString()
+}
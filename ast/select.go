@@ -8,8 +8,19 @@ import (
   "reflect"
 )
 
+// like Go's select, when several clauses are ready one of them is
+// chosen at random, and the default clause only when none is ready.
+// priority-select instead takes the first clause ready, in order,
+// then waits for any of them if none is ready and there's no default.
+// a clause (var (<-chan ch)) binds var to the value received, or to
+// the eof object once ch is closed, its body is then a lambda of var.
+// a receive clause without body returns the same, while a clause
+// sending on a closed channel raises an error
 type Select struct {
-  Clauses [][]Node
+  Clauses   [][]Node
+  Receivers []*Name
+  Priority  bool
+  Pos       string
 }
 
 func NewSelect(clauses [][]Node) *Select {
@@ -18,6 +29,8 @@ func NewSelect(clauses [][]Node) *Select {
 
 func (self *Select) Eval(env *scope.Scope) Value {
   cases := make([]reflect.SelectCase, len(self.Clauses))
+  // a select may deadlock when it waits on local channels only
+  local, hasDefault := true, false
   for i, clause := range self.Clauses {
     // parser guarantee the test case is a Call or Name
     //   Call.Callee is either `<-chan' or `chan<-'
@@ -35,6 +48,7 @@ func (self *Select) Eval(env *scope.Scope) Value {
       _, ok := args[0].(*Channel)
       if ok {
         channel, _ = args[0].(*Channel)
+        local = local && channel.Local
       } else {
         panic(fmt.Sprintf("incorrect argument type for `%s', expected: channel, given: %s", name, args[0]))
       }
@@ -54,15 +68,25 @@ func (self *Select) Eval(env *scope.Scope) Value {
     } else if name.Identifier == constants.DEFAULT {
       // default
       cases[i].Dir = reflect.SelectDefault
+      hasDefault = true
     }
   }
 
-  chosen, recv, ok := reflect.Select(cases)
+  chosen, recv, ok := self.choose(cases, local, hasDefault)
   exprs := self.Clauses[chosen]
 
+  if self.Receiver(chosen) != nil {
+    var received Value = EOF
+    if ok {
+      received = recv.Interface().(Value)
+    }
+    return Invoke(exprs[1].Eval(env), []Value{received})
+  }
   if len(exprs) == 1 {
     if ok {
       return recv.Interface().(Value)
+    } else if cases[chosen].Dir == reflect.SelectRecv {
+      return EOF
     } else {
       return nil
     }
@@ -75,9 +99,69 @@ func (self *Select) Eval(env *scope.Scope) Value {
   }
 }
 
+// a clause sending on a closed channel raises an error
+func (self *Select) choose(cases []reflect.SelectCase, local, hasDefault bool) (int, reflect.Value, bool) {
+  defer CheckSendOnClosed(self.site())
+  if self.Priority {
+    return selectInOrder(cases, local, self.site())
+  } else if hasDefault {
+    return reflect.Select(cases)
+  }
+  return BlockingSelect(cases, local, self.site())
+}
+
+func selectInOrder(cases []reflect.SelectCase, local bool, site string) (int, reflect.Value, bool) {
+  fallback := -1
+  var waiting []reflect.SelectCase
+  var indexes []int
+  for i, c := range cases {
+    if c.Dir == reflect.SelectDefault {
+      fallback = i
+      continue
+    }
+    poll := []reflect.SelectCase{c, {Dir: reflect.SelectDefault}}
+    if chosen, recv, ok := reflect.Select(poll); chosen == 0 {
+      return i, recv, ok
+    }
+    waiting = append(waiting, c)
+    indexes = append(indexes, i)
+  }
+  if fallback >= 0 {
+    return fallback, reflect.Value{}, false
+  }
+  chosen, recv, ok := BlockingSelect(waiting, local, site)
+  return indexes[chosen], recv, ok
+}
+
+// the variable the i-th clause binds, nil unless it's a receive binding
+func (self *Select) Receiver(i int) *Name {
+  if self.Receivers == nil {
+    return nil
+  }
+  return self.Receivers[i]
+}
+
+// e.g. "select at ping.ss:8"
+func (self *Select) site() string {
+  keyword := constants.SELECT
+  if self.Priority {
+    keyword = constants.PRIORITY_SELECT
+  }
+  if self.Pos == "" {
+    return keyword
+  }
+  return keyword + " at " + self.Pos
+}
+
 func (self *Select) String() string {
   var result string
-  for _, clause := range self.Clauses {
+  for n, clause := range self.Clauses {
+    if receiver := self.Receiver(n); receiver != nil {
+      if lambda, ok := clause[1].(*Lambda); ok {
+        result += fmt.Sprintf(" ((%s %s) %s)", receiver, clause[0], lambda.Body)
+        continue
+      }
+    }
     var s string
     for i, expr := range clause {
       if i == 0 {
@@ -88,5 +172,8 @@ func (self *Select) String() string {
     }
     result += fmt.Sprintf(" (%s)", s)
   }
+  if self.Priority {
+    return fmt.Sprintf("(%s %s)", constants.PRIORITY_SELECT, result)
+  }
   return fmt.Sprintf("(select %s)", result)
 }
@@ -11,6 +11,8 @@ import (
 type Set struct {
   Pattern *Name
   Value   Node
+  // source position of the form
+  Pos string
 }
 
 func NewSet(pattern *Name, val Node) *Set {
@@ -19,7 +21,7 @@ func NewSet(pattern *Name, val Node) *Set {
 
 func (self *Set) Eval(env *scope.Scope) Value {
   val := self.Value.Eval(env)
-  binder.Assign(env, self.Pattern.Identifier, val)
+  binder.Assign(env, self.Pattern.Identifier, val, self.Pos)
   return nil
 }
 
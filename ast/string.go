@@ -1,9 +1,12 @@
 package ast
 
 import (
+  "bytes"
   "fmt"
   "github.com/kedebug/LispEx/scope"
   . "github.com/kedebug/LispEx/value"
+  "strconv"
+  "strings"
 )
 
 type String struct {
@@ -11,7 +14,7 @@ type String struct {
 }
 
 func NewString(val string) *String {
-  return &String{Value: val[1 : len(val)-1]}
+  return &String{Value: unescape(val[1 : len(val)-1])}
 }
 
 func (self *String) Eval(env *scope.Scope) Value {
@@ -19,5 +22,47 @@ func (self *String) Eval(env *scope.Scope) Value {
 }
 
 func (self *String) String() string {
-  return fmt.Sprintf("\"%s\"", self.Value)
+  return QuoteString(self.Value)
+}
+
+// R7RS escape sequences in string literals:
+//  \a \b \t \n \r \" \\ \| and \x<hex>;
+func unescape(s string) string {
+  if strings.IndexByte(s, '\\') < 0 {
+    return s
+  }
+  var buf bytes.Buffer
+  for i := 0; i < len(s); i++ {
+    if s[i] != '\\' || i+1 == len(s) {
+      buf.WriteByte(s[i])
+      continue
+    }
+    i++
+    switch s[i] {
+    case 'a':
+      buf.WriteByte('\a')
+    case 'b':
+      buf.WriteByte('\b')
+    case 't':
+      buf.WriteByte('\t')
+    case 'n':
+      buf.WriteByte('\n')
+    case 'r':
+      buf.WriteByte('\r')
+    case 'x', 'X':
+      end := strings.IndexByte(s[i:], ';')
+      if end < 0 {
+        panic(fmt.Sprint("read: missing `;' in string escape: ", s[i-1:]))
+      }
+      code, err := strconv.ParseUint(s[i+1:i+end], 16, 32)
+      if err != nil {
+        panic(fmt.Sprint("read: invalid string escape: ", s[i-1:i+end+1]))
+      }
+      buf.WriteRune(rune(code))
+      i += end
+    default:
+      buf.WriteByte(s[i])
+    }
+  }
+  return buf.String()
 }
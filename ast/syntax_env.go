@@ -0,0 +1,52 @@
+package ast
+
+import (
+  "github.com/kedebug/LispEx/scope"
+)
+
+// the syntactic environment of a form: the variables bound around it
+// where it was written, which the parser consults to tell them from
+// special forms and macros of the same name. it is apart from the
+// runtime scope, which holds their values. the environment without
+// parent is the top level, nil is one without macros
+type SyntaxEnv struct {
+  Parent    *SyntaxEnv
+  Variables map[string]bool
+  // of the top level only: the macros of the interpreter reading
+  // the form and whether it lets vectors and strings be called
+  Macros         *scope.Macros
+  ApplicableData bool
+}
+
+func NewSyntaxEnv(parent *SyntaxEnv, names []string) *SyntaxEnv {
+  variables := make(map[string]bool)
+  for _, name := range names {
+    variables[name] = true
+  }
+  return &SyntaxEnv{Parent: parent, Variables: variables}
+}
+
+// the top level of the interpreter of env, see scope.Macros
+// and scope.SetApplicableData
+func NewTopSyntaxEnv(env *scope.Scope) *SyntaxEnv {
+  return &SyntaxEnv{Macros: env.Macros(), ApplicableData: env.ApplicableData()}
+}
+
+// whether name is bound to a variable in the environment
+func (self *SyntaxEnv) IsVariable(name string) bool {
+  for env := self; env != nil; env = env.Parent {
+    if env.Variables[name] {
+      return true
+    }
+  }
+  return false
+}
+
+// the top level the environment is in, nil for nil
+func (self *SyntaxEnv) Top() *SyntaxEnv {
+  env := self
+  for env != nil && env.Parent != nil {
+    env = env.Parent
+  }
+  return env
+}
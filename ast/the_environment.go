@@ -0,0 +1,22 @@
+package ast
+
+import (
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+type TheEnvironment struct {
+}
+
+func NewTheEnvironment() *TheEnvironment {
+  return &TheEnvironment{}
+}
+
+func (self *TheEnvironment) Eval(env *scope.Scope) value.Value {
+  return value.NewEnvironment(env)
+}
+
+func (self *TheEnvironment) String() string {
+  return "(" + constants.THE_ENVIRONMENT + ")"
+}
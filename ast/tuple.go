@@ -8,6 +8,14 @@ import (
 
 type Tuple struct {
   Elements []Node
+  // source position as "file:line", empty for generated tuples
+  Pos string
+  // how many macro expansions made the tuple, see macro.SyntaxRules
+  Expansions int
+  // where the tuple was written, nil at the top level and for tuples
+  // made by macro templates, so that a variable shadowing a special
+  // form or macro of the same name makes the tuple a call
+  Syntax *SyntaxEnv
 }
 
 func NewTuple(elements []Node) *Tuple {
@@ -0,0 +1,57 @@
+package ast
+
+import (
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// #(<datum> ...), the elements are data as in a quote
+type Vector struct {
+  Elements []Node
+}
+
+func NewVector(elements []Node) *Vector {
+  return &Vector{Elements: elements}
+}
+
+// a fresh vector each time, so a literal in a procedure
+// filled by vector-set! is not shared across calls
+func (self *Vector) Eval(env *scope.Scope) value.Value {
+  items := make([]value.Value, len(self.Elements))
+  for i, element := range self.Elements {
+    items[i] = ToDatum(element)
+  }
+  return value.NewVectorValue(items)
+}
+
+func (self *Vector) String() string {
+  return "#" + NewTuple(self.Elements).String()
+}
+
+// #(<qq template> ...) within a quasiquote, List is the template of
+// the elements as that of a list, unquote-splicing included
+type VectorTemplate struct {
+  List Node
+}
+
+func NewVectorTemplate(list Node) *VectorTemplate {
+  return &VectorTemplate{List: list}
+}
+
+func (self *VectorTemplate) Eval(env *scope.Scope) value.Value {
+  var items []value.Value
+  list := self.List.Eval(env)
+  for {
+    pair, ok := list.(*value.PairValue)
+    if !ok {
+      break
+    }
+    items = append(items, pair.First)
+    list = pair.Second
+  }
+  return value.NewVectorValue(items)
+}
+
+func (self *VectorTemplate) String() string {
+  return "#" + self.List.String()
+}
@@ -0,0 +1,327 @@
+package ast
+
+import (
+  "fmt"
+)
+
+// the nodes node is made of, in source order: its subexpressions but
+// also the names a form binds, quoted data and type annotations.
+// literals, names and (the-environment) have none
+func Children(node Node) []Node {
+  var nodes []Node
+  switch node.(type) {
+  case *Annotation:
+    annotation := node.(*Annotation)
+    nodes = []Node{annotation.Pattern, annotation.Type}
+  case *Apply:
+    apply := node.(*Apply)
+    nodes = append([]Node{apply.Proc}, apply.Args...)
+  case *Begin:
+    nodes = []Node{node.(*Begin).Body}
+  case *Block:
+    nodes = append(nodes, node.(*Block).Exprs...)
+  case *Call:
+    call := node.(*Call)
+    nodes = append([]Node{call.Callee}, call.Args...)
+  case *Case:
+    expr := node.(*Case)
+    nodes = []Node{expr.Key}
+    for _, clause := range expr.Clauses {
+      nodes = append(nodes, clause.Body)
+    }
+  case *Cond:
+    for _, clause := range node.(*Cond).Clauses {
+      nodes = append(nodes, clause.Test, clause.Body)
+    }
+  case *Define:
+    define := node.(*Define)
+    nodes = []Node{define.Pattern, define.Value}
+  case *DefineEnum:
+    define := node.(*DefineEnum)
+    nodes = []Node{define.Name}
+    for _, member := range define.Members {
+      nodes = append(nodes, member)
+    }
+  case *DefineContract:
+    contract := node.(*DefineContract)
+    nodes = append([]Node{contract.Define}, contract.Domain...)
+    nodes = append(nodes, contract.Range)
+  case *Delay:
+    nodes = []Node{node.(*Delay).Expr}
+  case *Do:
+    loop := node.(*Do)
+    nodes = bindings(loop.Vars, loop.Inits, nil)
+    nodes = append(append(nodes, loop.Steps...), loop.Test, loop.Result, loop.Body)
+  case *Force:
+    nodes = []Node{node.(*Force).Promise}
+  case *Function:
+    function := node.(*Function)
+    nodes = []Node{function.Caller, function.Body}
+  case *Go:
+    nodes = []Node{node.(*Go).Expr}
+  case *If:
+    expr := node.(*If)
+    nodes = []Node{expr.Test, expr.Then, expr.Else}
+  case *Lambda:
+    lambda := node.(*Lambda)
+    nodes = []Node{lambda.Params, lambda.Body}
+  case *Let:
+    let := node.(*Let)
+    nodes = bindings(let.Patterns, let.Exprs, let.Body)
+  case *LetStar:
+    let := node.(*LetStar)
+    nodes = bindings(let.Patterns, let.Exprs, let.Body)
+  case *LetRec:
+    let := node.(*LetRec)
+    nodes = bindings(let.Patterns, let.Exprs, let.Body)
+  case *LetValues:
+    let := node.(*LetValues)
+    for i, formals := range let.Formals {
+      nodes = append(nodes, formals, let.Exprs[i])
+    }
+    nodes = append(nodes, let.Body)
+  case *Logic:
+    nodes = append(nodes, node.(*Logic).Exprs...)
+  case *Nursery:
+    nodes = []Node{node.(*Nursery).Body}
+  case *Pair:
+    pair := node.(*Pair)
+    nodes = []Node{pair.First, pair.Second}
+  case *Quasiquote:
+    nodes = []Node{node.(*Quasiquote).Body}
+  case *Quote:
+    nodes = []Node{node.(*Quote).Body}
+  case *Select:
+    for _, clause := range node.(*Select).Clauses {
+      nodes = append(nodes, clause...)
+    }
+  case *Set:
+    set := node.(*Set)
+    nodes = []Node{set.Pattern, set.Value}
+  case *Tuple:
+    nodes = append(nodes, node.(*Tuple).Elements...)
+  case *Unquote:
+    nodes = []Node{node.(*Unquote).Body}
+  case *VectorTemplate:
+    nodes = []Node{node.(*VectorTemplate).List}
+  case *UnquoteSplicing:
+    nodes = []Node{node.(*UnquoteSplicing).Body}
+  case *When:
+    expr := node.(*When)
+    nodes = []Node{expr.Test, expr.Body}
+  }
+  // e.g. an if without else
+  children := nodes[:0]
+  for _, child := range nodes {
+    if child != nil {
+      children = append(children, child)
+    }
+  }
+  return children
+}
+
+// each pattern followed by its expression, then the body
+func bindings(patterns []*Name, exprs []Node, body Node) []Node {
+  nodes := make([]Node, 0, 2*len(patterns)+1)
+  for i, pattern := range patterns {
+    nodes = append(nodes, pattern, exprs[i])
+  }
+  return append(nodes, body)
+}
+
+type Visitor interface {
+  Visit(node Node) (w Visitor)
+}
+
+// like go/ast.Walk, calls v.Visit(node) and unless it returns a nil
+// visitor w, walks each child of node with w, then calls w.Visit(nil)
+func Walk(v Visitor, node Node) {
+  if v = v.Visit(node); v == nil {
+    return
+  }
+  for _, child := range Children(node) {
+    Walk(v, child)
+  }
+  v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+  if f(node) {
+    return f
+  }
+  return nil
+}
+
+// calls f for node and its children in depth-first order,
+// the children of a node are skipped when f returns false
+func Inspect(node Node, f func(Node) bool) {
+  Walk(inspector(f), node)
+}
+
+// Rewrite replaces every node of the tree rooted at node by f(node),
+// children first, and returns the replacement of node. f returns its
+// argument to keep a node. the nodes are changed in place, so programs
+// are rewritten before they are evaluated. the names a form binds, like
+// the pattern of a define, can only be replaced by names
+func Rewrite(node Node, f func(Node) Node) Node {
+  if node == nil {
+    return nil
+  }
+  switch node.(type) {
+  case *Annotation:
+    annotation := node.(*Annotation)
+    annotation.Pattern = rewriteName(annotation.Pattern, f)
+    annotation.Type = Rewrite(annotation.Type, f)
+  case *Apply:
+    apply := node.(*Apply)
+    apply.Proc = Rewrite(apply.Proc, f)
+    rewriteAll(apply.Args, f)
+  case *Begin:
+    begin := node.(*Begin)
+    begin.Body = Rewrite(begin.Body, f)
+  case *Block:
+    rewriteAll(node.(*Block).Exprs, f)
+  case *Call:
+    call := node.(*Call)
+    call.Callee = Rewrite(call.Callee, f)
+    rewriteAll(call.Args, f)
+  case *Case:
+    expr := node.(*Case)
+    expr.Key = Rewrite(expr.Key, f)
+    for _, clause := range expr.Clauses {
+      clause.Body = Rewrite(clause.Body, f)
+    }
+  case *Cond:
+    for _, clause := range node.(*Cond).Clauses {
+      clause.Test = Rewrite(clause.Test, f)
+      clause.Body = Rewrite(clause.Body, f)
+    }
+  case *Define:
+    define := node.(*Define)
+    define.Pattern = rewriteName(define.Pattern, f)
+    define.Value = Rewrite(define.Value, f)
+  case *DefineEnum:
+    define := node.(*DefineEnum)
+    define.Name = rewriteName(define.Name, f)
+    for i, member := range define.Members {
+      define.Members[i] = rewriteName(member, f)
+    }
+  case *DefineContract:
+    contract := node.(*DefineContract)
+    replaced := Rewrite(contract.Define, f)
+    define, ok := replaced.(*Define)
+    if !ok {
+      panic(fmt.Sprint("rewrite: expected a define, given: ", replaced))
+    }
+    contract.Define = define
+    rewriteAll(contract.Domain, f)
+    contract.Range = Rewrite(contract.Range, f)
+  case *Delay:
+    delay := node.(*Delay)
+    delay.Expr = Rewrite(delay.Expr, f)
+  case *Do:
+    loop := node.(*Do)
+    rewriteBindings(loop.Vars, loop.Inits, nil, f)
+    rewriteAll(loop.Steps, f)
+    loop.Test = Rewrite(loop.Test, f)
+    loop.Result = Rewrite(loop.Result, f)
+    loop.Body = Rewrite(loop.Body, f)
+  case *Force:
+    force := node.(*Force)
+    force.Promise = Rewrite(force.Promise, f)
+  case *Function:
+    function := node.(*Function)
+    function.Caller = rewriteName(function.Caller, f)
+    function.Body = Rewrite(function.Body, f)
+  case *Go:
+    expr := node.(*Go)
+    expr.Expr = Rewrite(expr.Expr, f)
+  case *If:
+    expr := node.(*If)
+    expr.Test = Rewrite(expr.Test, f)
+    expr.Then = Rewrite(expr.Then, f)
+    expr.Else = Rewrite(expr.Else, f)
+  case *Lambda:
+    lambda := node.(*Lambda)
+    lambda.Params = Rewrite(lambda.Params, f)
+    lambda.Body = Rewrite(lambda.Body, f)
+  case *Let:
+    let := node.(*Let)
+    let.Body = rewriteBindings(let.Patterns, let.Exprs, let.Body, f)
+  case *LetStar:
+    let := node.(*LetStar)
+    let.Body = rewriteBindings(let.Patterns, let.Exprs, let.Body, f)
+  case *LetRec:
+    let := node.(*LetRec)
+    let.Body = rewriteBindings(let.Patterns, let.Exprs, let.Body, f)
+  case *LetValues:
+    let := node.(*LetValues)
+    rewriteAll(let.Formals, f)
+    rewriteAll(let.Exprs, f)
+    let.Body = Rewrite(let.Body, f)
+  case *Logic:
+    rewriteAll(node.(*Logic).Exprs, f)
+  case *Nursery:
+    nursery := node.(*Nursery)
+    nursery.Body = Rewrite(nursery.Body, f)
+  case *Pair:
+    pair := node.(*Pair)
+    pair.First = Rewrite(pair.First, f)
+    pair.Second = Rewrite(pair.Second, f)
+  case *Quasiquote:
+    quasiquote := node.(*Quasiquote)
+    quasiquote.Body = Rewrite(quasiquote.Body, f)
+  case *Quote:
+    quote := node.(*Quote)
+    quote.Body = Rewrite(quote.Body, f)
+  case *Select:
+    for _, clause := range node.(*Select).Clauses {
+      rewriteAll(clause, f)
+    }
+  case *Set:
+    set := node.(*Set)
+    set.Pattern = rewriteName(set.Pattern, f)
+    set.Value = Rewrite(set.Value, f)
+  case *Tuple:
+    rewriteAll(node.(*Tuple).Elements, f)
+  case *Unquote:
+    unquote := node.(*Unquote)
+    unquote.Body = Rewrite(unquote.Body, f)
+  case *UnquoteSplicing:
+    unquote := node.(*UnquoteSplicing)
+    unquote.Body = Rewrite(unquote.Body, f)
+  case *VectorTemplate:
+    template := node.(*VectorTemplate)
+    template.List = Rewrite(template.List, f)
+  case *When:
+    expr := node.(*When)
+    expr.Test = Rewrite(expr.Test, f)
+    expr.Body = Rewrite(expr.Body, f)
+  }
+  return f(node)
+}
+
+func rewriteAll(nodes []Node, f func(Node) Node) {
+  for i, node := range nodes {
+    nodes[i] = Rewrite(node, f)
+  }
+}
+
+func rewriteName(name *Name, f func(Node) Node) *Name {
+  node := Rewrite(name, f)
+  if name, ok := node.(*Name); ok {
+    return name
+  }
+  panic(fmt.Sprint("rewrite: expected a name, given: ", node))
+}
+
+// returns the rewritten body
+func rewriteBindings(patterns []*Name, exprs []Node, body Node, f func(Node) Node) Node {
+  for i := range patterns {
+    patterns[i] = rewriteName(patterns[i], f)
+    exprs[i] = Rewrite(exprs[i], f)
+  }
+  return Rewrite(body, f)
+}
@@ -5,12 +5,29 @@ import (
   "github.com/kedebug/LispEx/scope"
 )
 
-func Define(env *scope.Scope, pattern string, value interface{}) {
+// pos is the source position of the form, recorded by the audit log
+func Define(env *scope.Scope, pattern string, value interface{}, pos string) {
+  if env.IsConstant(pattern) {
+    panic(fmt.Sprintf("define: cannot change constant: %s", pattern))
+  }
+  env.Audit("define", pattern, value, pos)
   env.Put(pattern, value)
 }
 
-func Assign(s *scope.Scope, pattern string, value interface{}) {
+func DefineConstant(env *scope.Scope, pattern string, value interface{}, pos string) {
+  if env.IsConstant(pattern) {
+    panic(fmt.Sprintf("define-constant: cannot change constant: %s", pattern))
+  }
+  env.Audit("define-constant", pattern, value, pos)
+  env.PutConstant(pattern, value)
+}
+
+func Assign(s *scope.Scope, pattern string, value interface{}, pos string) {
   if env := s.FindScope(pattern); env != nil {
+    if env.IsConstant(pattern) {
+      panic(fmt.Sprintf("set!: cannot change constant: %s", pattern))
+    }
+    env.Audit("set!", pattern, value, pos)
     env.Put(pattern, value)
   } else {
     panic(fmt.Sprintf("%s was not defined", pattern))
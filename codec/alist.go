@@ -0,0 +1,52 @@
+package codec
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+type entry struct {
+  key   string
+  value Value
+}
+
+// elements of a proper list
+func ProperList(val Value) ([]Value, bool) {
+  var items []Value
+  for {
+    switch val.(type) {
+    case *EmptyPairValue:
+      return items, true
+    case *PairValue:
+      pair := val.(*PairValue)
+      items = append(items, pair.First)
+      val = pair.Second
+    default:
+      return nil, false
+    }
+  }
+}
+
+// entries of a non-empty list of pairs keyed by strings or symbols,
+// which is how mappings are represented
+func AssociationList(val Value) ([]entry, bool) {
+  items, ok := ProperList(val)
+  if !ok || len(items) == 0 {
+    return nil, false
+  }
+  entries := make([]entry, len(items))
+  for i, item := range items {
+    pair, ok := item.(*PairValue)
+    if !ok {
+      return nil, false
+    }
+    switch pair.First.(type) {
+    case *StringValue:
+      entries[i] = entry{pair.First.(*StringValue).Value, pair.Second}
+    case *Symbol:
+      entries[i] = entry{pair.First.(*Symbol).Value, pair.Second}
+    default:
+      return nil, false
+    }
+  }
+  return entries, true
+}
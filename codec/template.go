@@ -0,0 +1,175 @@
+package codec
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "html"
+  "strings"
+)
+
+// a mustache-like subset over association lists:
+//  {{name}}           value of `name', html escaped, dotted names descend
+//                     into nested alists. names bound nowhere render empty
+//  {{{name}}}         value of `name' as it is, for trusted markup,
+//                     likewise {{& name}}
+//  {{.}}              the current item inside a section
+//  {{#name}}..{{/name}} repeated for each element of a list,
+//                     rendered once for any other true value
+//  {{^name}}..{{/name}} rendered when `name' is missing, #f or '()
+func RenderTemplate(text string, data Value) string {
+  nodes, rest := parseTemplate(text, "")
+  if rest != "" {
+    panic(fmt.Sprint("template: unexpected ", rest))
+  }
+  var buffer strings.Builder
+  renderTemplate(&buffer, nodes, []Value{data})
+  return buffer.String()
+}
+
+type templateNode struct {
+  text     string
+  name     string
+  raw      bool
+  section  byte
+  children []templateNode
+}
+
+// parse until the closing tag of `section', returning the unparsed rest
+func parseTemplate(text, section string) ([]templateNode, string) {
+  var nodes []templateNode
+  for {
+    start := strings.Index(text, "{{")
+    if start < 0 {
+      if section != "" {
+        panic(fmt.Sprintf("template: unclosed section {{#%s}}", section))
+      }
+      return append(nodes, templateNode{text: text}), ""
+    }
+    nodes = append(nodes, templateNode{text: text[:start]})
+    open, close := "{{", "}}"
+    if strings.HasPrefix(text[start:], "{{{") {
+      open, close = "{{{", "}}}"
+    }
+    end := strings.Index(text[start:], close)
+    if end < 0 {
+      panic(fmt.Sprint("template: unterminated tag: ", text[start:]))
+    }
+    tag := strings.TrimSpace(text[start+len(open) : start+end])
+    text = text[start+end+len(close):]
+
+    if tag == "" {
+      panic(fmt.Sprint("template: empty tag"))
+    }
+    if open == "{{{" {
+      nodes = append(nodes, templateNode{name: tag, raw: true})
+      continue
+    }
+    switch tag[0] {
+    case '#', '^':
+      name := strings.TrimSpace(tag[1:])
+      children, rest := parseTemplate(text, name)
+      nodes = append(nodes, templateNode{name: name, section: tag[0], children: children})
+      text = rest
+    case '/':
+      name := strings.TrimSpace(tag[1:])
+      if name != section {
+        panic(fmt.Sprintf("template: unexpected {{/%s}}", name))
+      }
+      return nodes, text
+    case '&':
+      nodes = append(nodes, templateNode{name: strings.TrimSpace(tag[1:]), raw: true})
+    default:
+      nodes = append(nodes, templateNode{name: tag})
+    }
+  }
+}
+
+func renderTemplate(buffer *strings.Builder, nodes []templateNode, stack []Value) {
+  for _, node := range nodes {
+    if node.name == "" {
+      buffer.WriteString(node.text)
+      continue
+    }
+    val := lookupTemplate(node.name, stack)
+    switch node.section {
+    case '#':
+      if items, ok := ProperList(val); ok && !isTemplateMapping(val) {
+        for _, item := range items {
+          renderTemplate(buffer, node.children, append(stack, item))
+        }
+      } else if isTemplateTrue(val) {
+        renderTemplate(buffer, node.children, append(stack, val))
+      }
+    case '^':
+      if !isTemplateTrue(val) {
+        renderTemplate(buffer, node.children, stack)
+      }
+    default:
+      if val == nil {
+        continue
+      }
+      if node.raw {
+        buffer.WriteString(templateString(val))
+      } else {
+        buffer.WriteString(html.EscapeString(templateString(val)))
+      }
+    }
+  }
+}
+
+// innermost context first, nil if the name is bound nowhere
+func lookupTemplate(name string, stack []Value) Value {
+  if name == "." {
+    return stack[len(stack)-1]
+  }
+  path := strings.Split(name, ".")
+  for i := len(stack) - 1; i >= 0; i-- {
+    if val := lookupTemplateKey(stack[i], path[0]); val != nil {
+      for _, key := range path[1:] {
+        if val = lookupTemplateKey(val, key); val == nil {
+          return nil
+        }
+      }
+      return val
+    }
+  }
+  return nil
+}
+
+func lookupTemplateKey(context Value, key string) Value {
+  entries, ok := AssociationList(context)
+  if !ok {
+    return nil
+  }
+  for _, entry := range entries {
+    if entry.key == key {
+      return entry.value
+    }
+  }
+  return nil
+}
+
+func isTemplateMapping(val Value) bool {
+  _, ok := AssociationList(val)
+  return ok
+}
+
+func isTemplateTrue(val Value) bool {
+  switch val.(type) {
+  case nil, *EmptyPairValue:
+    return false
+  case *BoolValue:
+    return val.(*BoolValue).Value
+  }
+  return true
+}
+
+func templateString(val Value) string {
+  switch val.(type) {
+  case *StringValue:
+    return val.(*StringValue).Value
+  case *EmptyPairValue:
+    return ""
+  }
+  return val.String()
+}
@@ -0,0 +1,526 @@
+package codec
+
+import (
+  "bytes"
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "math"
+  "strconv"
+  "strings"
+)
+
+// TOML v1.0 documents without datetime semantics:
+// dates and times are read as strings.
+// Tables are represented as association lists with string keys,
+// arrays as lists.
+
+type tomlTable struct {
+  keys   []string
+  values map[string]interface{}
+}
+
+func newTOMLTable() *tomlTable {
+  return &tomlTable{values: make(map[string]interface{})}
+}
+
+func (t *tomlTable) set(key string, val interface{}) {
+  if _, ok := t.values[key]; !ok {
+    t.keys = append(t.keys, key)
+  }
+  t.values[key] = val
+}
+
+type tomlParser struct {
+  text string
+  pos  int
+  line int
+}
+
+func ReadTOML(input string) Value {
+  p := &tomlParser{text: input, line: 1}
+  root := newTOMLTable()
+  current := root
+  for {
+    p.skipBlank()
+    if p.pos == len(p.text) {
+      break
+    }
+    if strings.HasPrefix(p.text[p.pos:], "[[") {
+      p.pos += 2
+      keys := p.parseKey()
+      p.expect("]]")
+      parent := p.descend(root, keys[:len(keys)-1])
+      last := keys[len(keys)-1]
+      current = newTOMLTable()
+      array, _ := parent.values[last].([]interface{})
+      parent.set(last, append(array, current))
+    } else if p.text[p.pos] == '[' {
+      p.pos++
+      keys := p.parseKey()
+      p.expect("]")
+      current = p.descend(root, keys)
+    } else {
+      p.parseKeyValue(current)
+    }
+    p.endOfLine()
+  }
+  return tomlToValue(root)
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) {
+  panic(fmt.Sprintf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...)))
+}
+
+// walk (and create) the tables named by a dotted key
+func (p *tomlParser) descend(table *tomlTable, keys []string) *tomlTable {
+  for _, key := range keys {
+    switch table.values[key].(type) {
+    case nil:
+      child := newTOMLTable()
+      table.set(key, child)
+      table = child
+    case *tomlTable:
+      table = table.values[key].(*tomlTable)
+    case []interface{}:
+      // the last table of an array of tables
+      array := table.values[key].([]interface{})
+      child, ok := array[len(array)-1].(*tomlTable)
+      if !ok {
+        p.errorf("key `%s' is not a table", key)
+      }
+      table = child
+    default:
+      p.errorf("key `%s' is already defined", key)
+    }
+  }
+  return table
+}
+
+func (p *tomlParser) parseKeyValue(table *tomlTable) {
+  keys := p.parseKey()
+  p.skipSpaces()
+  p.expect("=")
+  val := p.parseValue()
+  table = p.descend(table, keys[:len(keys)-1])
+  last := keys[len(keys)-1]
+  if _, ok := table.values[last]; ok {
+    p.errorf("key `%s' is already defined", last)
+  }
+  table.set(last, val)
+}
+
+func (p *tomlParser) parseKey() []string {
+  var keys []string
+  for {
+    p.skipSpaces()
+    if p.pos == len(p.text) {
+      p.errorf("expected key")
+    }
+    switch p.text[p.pos] {
+    case '"', '\'':
+      keys = append(keys, p.parseString())
+    default:
+      start := p.pos
+      for p.pos < len(p.text) && isTOMLBareKey(p.text[p.pos]) {
+        p.pos++
+      }
+      if start == p.pos {
+        p.errorf("invalid key")
+      }
+      keys = append(keys, p.text[start:p.pos])
+    }
+    p.skipSpaces()
+    if p.pos < len(p.text) && p.text[p.pos] == '.' {
+      p.pos++
+      continue
+    }
+    return keys
+  }
+}
+
+func isTOMLBareKey(c byte) bool {
+  return c == '_' || c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func (p *tomlParser) parseValue() interface{} {
+  p.skipSpaces()
+  if p.pos == len(p.text) {
+    p.errorf("expected value")
+  }
+  switch p.text[p.pos] {
+  case '"', '\'':
+    return p.parseString()
+  case '[':
+    p.pos++
+    var array []interface{}
+    for {
+      p.skipBlank()
+      if p.pos < len(p.text) && p.text[p.pos] == ']' {
+        p.pos++
+        return array
+      }
+      array = append(array, p.parseValue())
+      p.skipBlank()
+      if p.pos < len(p.text) && p.text[p.pos] == ',' {
+        p.pos++
+      } else if p.pos == len(p.text) || p.text[p.pos] != ']' {
+        p.errorf("expected `,' or `]' in array")
+      }
+    }
+  case '{':
+    p.pos++
+    table := newTOMLTable()
+    p.skipSpaces()
+    if p.pos < len(p.text) && p.text[p.pos] == '}' {
+      p.pos++
+      return table
+    }
+    for {
+      p.parseKeyValue(table)
+      p.skipSpaces()
+      if p.pos < len(p.text) && p.text[p.pos] == ',' {
+        p.pos++
+      } else {
+        p.expect("}")
+        return table
+      }
+    }
+  }
+  start := p.pos
+  for p.pos < len(p.text) && !strings.ContainsRune(",]}#\r\n", rune(p.text[p.pos])) {
+    p.pos++
+  }
+  literal := strings.TrimSpace(p.text[start:p.pos])
+  switch literal {
+  case "true":
+    return true
+  case "false":
+    return false
+  case "inf", "+inf":
+    return math.Inf(1)
+  case "-inf":
+    return math.Inf(-1)
+  case "nan", "+nan", "-nan":
+    return math.NaN()
+  }
+  clean := strings.Replace(literal, "_", "", -1)
+  if isTOMLInteger(clean) {
+    val, err := strconv.ParseInt(clean, 0, 64)
+    if err != nil {
+      p.errorf("invalid integer: %s", literal)
+    }
+    return val
+  }
+  if val, err := strconv.ParseFloat(clean, 64); err == nil {
+    return val
+  }
+  if len(literal) > 0 && '0' <= literal[0] && literal[0] <= '9' {
+    // offset date-times, local dates and times
+    return literal
+  }
+  p.errorf("invalid value: %s", literal)
+  return nil
+}
+
+func isTOMLInteger(s string) bool {
+  s = strings.TrimLeft(s, "+-")
+  if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") || strings.HasPrefix(s, "0b") {
+    return true
+  }
+  for i := 0; i < len(s); i++ {
+    if s[i] < '0' || '9' < s[i] {
+      return false
+    }
+  }
+  return s != ""
+}
+
+func (p *tomlParser) parseString() string {
+  quote := p.text[p.pos]
+  multiline := strings.HasPrefix(p.text[p.pos:], strings.Repeat(string(quote), 3))
+  delimiter := string(quote)
+  if multiline {
+    delimiter = strings.Repeat(delimiter, 3)
+    p.pos += 3
+    // a newline immediately following the opening delimiter is trimmed
+    if strings.HasPrefix(p.text[p.pos:], "\r\n") {
+      p.pos += 2
+      p.line++
+    } else if strings.HasPrefix(p.text[p.pos:], "\n") {
+      p.pos++
+      p.line++
+    }
+  } else {
+    p.pos++
+  }
+
+  var buf bytes.Buffer
+  for {
+    if p.pos >= len(p.text) {
+      p.errorf("unterminated string")
+    }
+    if strings.HasPrefix(p.text[p.pos:], delimiter) {
+      p.pos += len(delimiter)
+      return buf.String()
+    }
+    c := p.text[p.pos]
+    if c == '\n' {
+      if !multiline {
+        p.errorf("unterminated string")
+      }
+      p.line++
+    }
+    if c == '\\' && quote == '"' {
+      p.pos++
+      p.parseEscape(&buf, multiline)
+      continue
+    }
+    buf.WriteByte(c)
+    p.pos++
+  }
+}
+
+func (p *tomlParser) parseEscape(buf *bytes.Buffer, multiline bool) {
+  if p.pos >= len(p.text) {
+    p.errorf("unterminated string")
+  }
+  c := p.text[p.pos]
+  p.pos++
+  switch c {
+  case 'b':
+    buf.WriteByte('\b')
+  case 't':
+    buf.WriteByte('\t')
+  case 'n':
+    buf.WriteByte('\n')
+  case 'f':
+    buf.WriteByte('\f')
+  case 'r':
+    buf.WriteByte('\r')
+  case 'e':
+    buf.WriteByte(0x1b)
+  case '"', '\\':
+    buf.WriteByte(c)
+  case 'u', 'U':
+    size := 4
+    if c == 'U' {
+      size = 8
+    }
+    if p.pos+size > len(p.text) {
+      p.errorf("invalid unicode escape")
+    }
+    code, err := strconv.ParseUint(p.text[p.pos:p.pos+size], 16, 32)
+    if err != nil {
+      p.errorf("invalid unicode escape")
+    }
+    buf.WriteRune(rune(code))
+    p.pos += size
+  default:
+    if multiline && (c == ' ' || c == '\t' || c == '\r' || c == '\n') {
+      // line ending backslash trims the following whitespace
+      for p.pos--; p.pos < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.pos])); p.pos++ {
+        if p.text[p.pos] == '\n' {
+          p.line++
+        }
+      }
+      return
+    }
+    p.errorf("invalid escape sequence: \\%c", c)
+  }
+}
+
+func (p *tomlParser) skipSpaces() {
+  for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+    p.pos++
+  }
+}
+
+// skip whitespace, newlines and comments
+func (p *tomlParser) skipBlank() {
+  for p.pos < len(p.text) {
+    switch p.text[p.pos] {
+    case ' ', '\t', '\r':
+      p.pos++
+    case '\n':
+      p.pos++
+      p.line++
+    case '#':
+      for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+        p.pos++
+      }
+    default:
+      return
+    }
+  }
+}
+
+func (p *tomlParser) endOfLine() {
+  p.skipSpaces()
+  if p.pos < len(p.text) && p.text[p.pos] == '#' {
+    for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+      p.pos++
+    }
+  }
+  if p.pos < len(p.text) && p.text[p.pos] == '\r' {
+    p.pos++
+  }
+  if p.pos < len(p.text) && p.text[p.pos] != '\n' {
+    p.errorf("expected end of line")
+  }
+}
+
+func (p *tomlParser) expect(s string) {
+  p.skipSpaces()
+  if !strings.HasPrefix(p.text[p.pos:], s) {
+    p.errorf("expected `%s'", s)
+  }
+  p.pos += len(s)
+}
+
+func tomlToValue(val interface{}) Value {
+  switch val.(type) {
+  case *tomlTable:
+    table := val.(*tomlTable)
+    entries := make([]Value, len(table.keys))
+    for i, key := range table.keys {
+      entries[i] = NewPairValue(NewStringValue(key), tomlToValue(table.values[key]))
+    }
+    return converter.SliceToPairValues(entries)
+  case []interface{}:
+    array := val.([]interface{})
+    items := make([]Value, len(array))
+    for i, item := range array {
+      items[i] = tomlToValue(item)
+    }
+    return converter.SliceToPairValues(items)
+  case string:
+    return NewStringValue(val.(string))
+  case int64:
+    return NewIntValue(val.(int64))
+  case float64:
+    return NewFloatValue(val.(float64))
+  case bool:
+    return NewBoolValue(val.(bool))
+  }
+  panic(fmt.Sprint("toml: unexpected value: ", val))
+}
+
+func WriteTOML(val Value) string {
+  entries, ok := AssociationList(val)
+  if !ok && val != NilPairValue {
+    panic(fmt.Sprint("toml-write: expected association list, given: ", val))
+  }
+  var buf bytes.Buffer
+  writeTOMLTable(&buf, entries, nil)
+  return buf.String()
+}
+
+func writeTOMLTable(buf *bytes.Buffer, entries []entry, path []string) {
+  // plain key/value pairs must precede sub tables
+  var tables []entry
+  for _, e := range entries {
+    if _, ok := AssociationList(e.value); ok {
+      tables = append(tables, e)
+    } else if isTOMLTableArray(e.value) {
+      tables = append(tables, e)
+    } else {
+      buf.WriteString(tomlKey(e.key) + " = " + tomlValue(e.value) + "\n")
+    }
+  }
+  for _, e := range tables {
+    name := append(append([]string{}, path...), e.key)
+    if children, ok := AssociationList(e.value); ok {
+      buf.WriteString("\n[" + tomlPath(name) + "]\n")
+      writeTOMLTable(buf, children, name)
+      continue
+    }
+    items, _ := ProperList(e.value)
+    for _, item := range items {
+      children, _ := AssociationList(item)
+      buf.WriteString("\n[[" + tomlPath(name) + "]]\n")
+      writeTOMLTable(buf, children, name)
+    }
+  }
+}
+
+func isTOMLTableArray(val Value) bool {
+  items, ok := ProperList(val)
+  if !ok || len(items) == 0 {
+    return false
+  }
+  for _, item := range items {
+    if _, ok := AssociationList(item); !ok {
+      return false
+    }
+  }
+  return true
+}
+
+func tomlPath(keys []string) string {
+  quoted := make([]string, len(keys))
+  for i, key := range keys {
+    quoted[i] = tomlKey(key)
+  }
+  return strings.Join(quoted, ".")
+}
+
+func tomlKey(key string) string {
+  for i := 0; i < len(key); i++ {
+    if !isTOMLBareKey(key[i]) {
+      return strconv.Quote(key)
+    }
+  }
+  if key == "" {
+    return `""`
+  }
+  return key
+}
+
+func tomlValue(val Value) string {
+  switch val.(type) {
+  case *StringValue:
+    return strconv.Quote(val.(*StringValue).Value)
+  case *Symbol:
+    return strconv.Quote(val.(*Symbol).Value)
+  case *BoolValue:
+    if val.(*BoolValue).Value {
+      return "true"
+    }
+    return "false"
+  case *IntValue:
+    return val.String()
+  case *FloatValue:
+    f := val.(*FloatValue).Value
+    switch {
+    case math.IsInf(f, 1):
+      return "inf"
+    case math.IsInf(f, -1):
+      return "-inf"
+    case math.IsNaN(f):
+      return "nan"
+    }
+    s := strconv.FormatFloat(f, 'g', -1, 64)
+    if !strings.ContainsAny(s, ".e") {
+      s += ".0"
+    }
+    return s
+  case *EmptyPairValue:
+    return "[]"
+  }
+  if entries, ok := AssociationList(val); ok {
+    pairs := make([]string, len(entries))
+    for i, e := range entries {
+      pairs[i] = tomlKey(e.key) + " = " + tomlValue(e.value)
+    }
+    return "{ " + strings.Join(pairs, ", ") + " }"
+  }
+  if items, ok := ProperList(val); ok {
+    values := make([]string, len(items))
+    for i, item := range items {
+      values[i] = tomlValue(item)
+    }
+    return "[" + strings.Join(values, ", ") + "]"
+  }
+  panic(fmt.Sprint("toml-write: value can not be written: ", val))
+}
@@ -0,0 +1,481 @@
+package codec
+
+import (
+  "bytes"
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "math"
+  "strconv"
+  "strings"
+)
+
+// A subset of YAML 1.2 covering the block structures found in
+// configuration files:
+//  block mappings and sequences, flow collections `[...]' `{...}',
+//  plain and quoted scalars, literal `|' and folded `>' block scalars.
+// Mappings are represented as association lists with string keys,
+// sequences as lists and null as the empty list.
+
+type yamlLine struct {
+  number int
+  indent int
+  text   string
+}
+
+type yamlParser struct {
+  lines []yamlLine
+  raw   []string
+  pos   int
+}
+
+func ReadYAML(input string) Value {
+  p := &yamlParser{raw: strings.Split(input, "\n")}
+  for i, raw := range p.raw {
+    text := strings.TrimRight(stripYAMLComment(raw), " \t\r")
+    trimmed := strings.TrimLeft(text, " ")
+    if trimmed == "" || trimmed == "---" || trimmed == "..." {
+      continue
+    }
+    if strings.HasPrefix(trimmed, "%") {
+      // directives
+      continue
+    }
+    indent := len(text) - len(trimmed)
+    p.lines = append(p.lines, yamlLine{number: i, indent: indent, text: trimmed})
+  }
+  if len(p.lines) == 0 {
+    return NilPairValue
+  }
+  val := p.parseNode()
+  if p.pos < len(p.lines) {
+    panic(fmt.Sprintf("yaml: unexpected content at line %d: %s", p.lines[p.pos].number+1, p.lines[p.pos].text))
+  }
+  return val
+}
+
+func (p *yamlParser) parseNode() Value {
+  line := p.lines[p.pos]
+  if isYAMLSequenceItem(line.text) {
+    return p.parseSequence(line.indent)
+  }
+  if _, _, ok := splitYAMLKey(line.text); ok {
+    return p.parseMapping(line.indent)
+  }
+  p.pos++
+  return parseYAMLScalar(line.text)
+}
+
+func (p *yamlParser) parseSequence(indent int) Value {
+  var items []Value
+  for p.pos < len(p.lines) {
+    line := &p.lines[p.pos]
+    if line.indent != indent || !isYAMLSequenceItem(line.text) {
+      break
+    }
+    rest := strings.TrimLeft(line.text[1:], " ")
+    if rest == "" {
+      p.pos++
+      items = append(items, p.parseChild(indent))
+      continue
+    }
+    // treat the item content as a line of its own so that
+    // `- key: value' continues with the following indented keys
+    line.indent += len(line.text) - len(rest)
+    line.text = rest
+    items = append(items, p.parseNode())
+  }
+  return converter.SliceToPairValues(items)
+}
+
+func (p *yamlParser) parseMapping(indent int) Value {
+  var entries []Value
+  for p.pos < len(p.lines) {
+    line := p.lines[p.pos]
+    if line.indent != indent {
+      break
+    }
+    key, rest, ok := splitYAMLKey(line.text)
+    if !ok {
+      break
+    }
+    p.pos++
+    var val Value
+    switch {
+    case rest == "":
+      if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text) {
+        // sequences may start at the indentation of their key
+        val = p.parseSequence(indent)
+      } else {
+        val = p.parseChild(indent)
+      }
+    case rest[0] == '|' || rest[0] == '>':
+      val = NewStringValue(p.parseBlockScalar(line, rest))
+    default:
+      val = parseYAMLScalar(rest)
+    }
+    entries = append(entries, NewPairValue(NewStringValue(key), val))
+  }
+  return converter.SliceToPairValues(entries)
+}
+
+// the value of a key or item is on the following, deeper indented lines
+func (p *yamlParser) parseChild(indent int) Value {
+  if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+    return p.parseNode()
+  }
+  return NilPairValue
+}
+
+func (p *yamlParser) parseBlockScalar(header yamlLine, indicator string) string {
+  folded := indicator[0] == '>'
+  chomp := "clip"
+  if strings.Contains(indicator, "-") {
+    chomp = "strip"
+  } else if strings.Contains(indicator, "+") {
+    chomp = "keep"
+  }
+
+  // collect the raw lines (blank lines included) deeper than the key
+  var body []string
+  last := header.number
+  for p.pos < len(p.lines) && p.lines[p.pos].indent > header.indent {
+    last = p.lines[p.pos].number
+    p.pos++
+  }
+  body = p.raw[header.number+1 : last+1]
+
+  indent := -1
+  for _, raw := range body {
+    if trimmed := strings.TrimLeft(raw, " "); trimmed != "" {
+      indent = len(raw) - len(trimmed)
+      break
+    }
+  }
+  var buf bytes.Buffer
+  for i, raw := range body {
+    text := ""
+    if len(raw) >= indent && indent >= 0 {
+      text = strings.TrimRight(raw[indent:], "\r")
+    }
+    if i > 0 {
+      if folded && text != "" && body[i-1] != "" {
+        buf.WriteString(" ")
+      } else {
+        buf.WriteString("\n")
+      }
+    }
+    buf.WriteString(text)
+  }
+  text := strings.TrimRight(buf.String(), "\n")
+  switch chomp {
+  case "clip":
+    text += "\n"
+  case "keep":
+    text = buf.String() + "\n"
+  }
+  return text
+}
+
+func isYAMLSequenceItem(text string) bool {
+  return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// split `key: value' outside of quotes and flow collections
+func splitYAMLKey(text string) (string, string, bool) {
+  if text[0] == '[' || text[0] == '{' {
+    return "", "", false
+  }
+  var quote byte
+  for i := 0; i < len(text); i++ {
+    c := text[i]
+    switch {
+    case quote != 0:
+      if c == quote {
+        quote = 0
+      }
+    case (c == '"' || c == '\'') && i == 0:
+      quote = c
+    case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+      key := strings.TrimSpace(text[:i])
+      if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') {
+        key = parseYAMLScalar(key).(*StringValue).Value
+      }
+      return key, strings.TrimSpace(text[i+1:]), true
+    }
+  }
+  return "", "", false
+}
+
+func stripYAMLComment(text string) string {
+  var quote byte
+  for i := 0; i < len(text); i++ {
+    c := text[i]
+    switch {
+    case quote != 0:
+      if c == quote {
+        quote = 0
+      } else if c == '\\' && quote == '"' {
+        i++
+      }
+    case c == '"' || c == '\'':
+      quote = c
+    case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+      return text[:i]
+    }
+  }
+  return text
+}
+
+func parseYAMLScalar(text string) Value {
+  flow := &yamlFlow{text: text}
+  val := flow.parse()
+  flow.skipSpaces()
+  if flow.pos != len(text) {
+    panic(fmt.Sprint("yaml: unexpected characters after value: ", text))
+  }
+  return val
+}
+
+// parser of flow style nodes: [a, b], {k: v} and scalars
+type yamlFlow struct {
+  text string
+  pos  int
+}
+
+func (f *yamlFlow) skipSpaces() {
+  for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+    f.pos++
+  }
+}
+
+func (f *yamlFlow) parse() Value {
+  f.skipSpaces()
+  if f.pos == len(f.text) {
+    return NilPairValue
+  }
+  switch f.text[f.pos] {
+  case '[':
+    f.pos++
+    var items []Value
+    for {
+      f.skipSpaces()
+      if f.pos < len(f.text) && f.text[f.pos] == ']' {
+        f.pos++
+        return converter.SliceToPairValues(items)
+      }
+      items = append(items, f.parse())
+      f.separator(']')
+    }
+  case '{':
+    f.pos++
+    var entries []Value
+    for {
+      f.skipSpaces()
+      if f.pos < len(f.text) && f.text[f.pos] == '}' {
+        f.pos++
+        return converter.SliceToPairValues(entries)
+      }
+      key := f.parse()
+      f.skipSpaces()
+      if f.pos == len(f.text) || f.text[f.pos] != ':' {
+        panic(fmt.Sprint("yaml: expected `:' in flow mapping: ", f.text))
+      }
+      f.pos++
+      if str, ok := key.(*StringValue); !ok {
+        key = NewStringValue(key.String())
+      } else {
+        key = str
+      }
+      entries = append(entries, NewPairValue(key, f.parse()))
+      f.separator('}')
+    }
+  case '"':
+    end := f.pos + 1
+    for ; end < len(f.text) && f.text[end] != '"'; end++ {
+      if f.text[end] == '\\' {
+        end++
+      }
+    }
+    if end >= len(f.text) {
+      panic(fmt.Sprint("yaml: unterminated string: ", f.text))
+    }
+    str, err := strconv.Unquote(f.text[f.pos : end+1])
+    if err != nil {
+      panic(fmt.Sprint("yaml: invalid string: ", f.text[f.pos:end+1]))
+    }
+    f.pos = end + 1
+    return NewStringValue(str)
+  case '\'':
+    var buf bytes.Buffer
+    for f.pos++; ; f.pos++ {
+      if f.pos >= len(f.text) {
+        panic(fmt.Sprint("yaml: unterminated string: ", f.text))
+      }
+      if f.text[f.pos] == '\'' {
+        if f.pos+1 < len(f.text) && f.text[f.pos+1] == '\'' {
+          f.pos++
+        } else {
+          f.pos++
+          return NewStringValue(buf.String())
+        }
+      }
+      buf.WriteByte(f.text[f.pos])
+    }
+  }
+  start := f.pos
+  for f.pos < len(f.text) && !strings.ContainsRune(",]}", rune(f.text[f.pos])) {
+    if f.text[f.pos] == ':' && (f.pos+1 == len(f.text) || f.text[f.pos+1] == ' ') {
+      break
+    }
+    f.pos++
+  }
+  if start == 0 && f.pos < len(f.text) {
+    // commas and brackets only delimit plain scalars inside flow collections
+    f.pos = len(f.text)
+  }
+  return plainYAMLScalar(strings.TrimSpace(f.text[start:f.pos]))
+}
+
+func (f *yamlFlow) separator(closing byte) {
+  f.skipSpaces()
+  if f.pos < len(f.text) {
+    if f.text[f.pos] == ',' {
+      f.pos++
+      return
+    }
+    if f.text[f.pos] == closing {
+      return
+    }
+  }
+  panic(fmt.Sprintf("yaml: expected `,' or `%c' in: %s", closing, f.text))
+}
+
+func plainYAMLScalar(text string) Value {
+  switch text {
+  case "", "~", "null", "Null", "NULL":
+    return NilPairValue
+  case "true", "True", "TRUE":
+    return NewBoolValue(true)
+  case "false", "False", "FALSE":
+    return NewBoolValue(false)
+  case ".inf", ".Inf", ".INF", "+.inf":
+    return NewFloatValue(math.Inf(1))
+  case "-.inf", "-.Inf", "-.INF":
+    return NewFloatValue(math.Inf(-1))
+  case ".nan", ".NaN", ".NAN":
+    return NewFloatValue(math.NaN())
+  }
+  if val, err := strconv.ParseInt(text, 10, 64); err == nil {
+    return NewIntValue(val)
+  }
+  if strings.HasPrefix(text, "0x") {
+    if val, err := strconv.ParseInt(text[2:], 16, 64); err == nil {
+      return NewIntValue(val)
+    }
+  }
+  if strings.HasPrefix(text, "0o") {
+    if val, err := strconv.ParseInt(text[2:], 8, 64); err == nil {
+      return NewIntValue(val)
+    }
+  }
+  if strings.ContainsAny(text, "0123456789") {
+    if val, err := strconv.ParseFloat(text, 64); err == nil {
+      return NewFloatValue(val)
+    }
+  }
+  return NewStringValue(text)
+}
+
+func WriteYAML(val Value) string {
+  var buf bytes.Buffer
+  writeYAMLNode(&buf, val, 0, false)
+  return buf.String()
+}
+
+func writeYAMLNode(buf *bytes.Buffer, val Value, indent int, inline bool) {
+  prefix := strings.Repeat(" ", indent)
+  if entries, ok := AssociationList(val); ok {
+    for i, entry := range entries {
+      if i > 0 || !inline {
+        buf.WriteString(prefix)
+      }
+      buf.WriteString(yamlString(entry.key) + ":")
+      writeYAMLValue(buf, entry.value, indent)
+    }
+    return
+  }
+  if items, ok := ProperList(val); ok && len(items) > 0 {
+    for i, item := range items {
+      if i > 0 || !inline {
+        buf.WriteString(prefix)
+      }
+      buf.WriteString("-")
+      if _, ok := AssociationList(item); ok {
+        buf.WriteString(" ")
+        writeYAMLNode(buf, item, indent+2, true)
+      } else {
+        writeYAMLValue(buf, item, indent)
+      }
+    }
+    return
+  }
+  buf.WriteString(yamlScalar(val) + "\n")
+}
+
+func writeYAMLValue(buf *bytes.Buffer, val Value, indent int) {
+  if _, ok := AssociationList(val); ok {
+    buf.WriteString("\n")
+    writeYAMLNode(buf, val, indent+2, false)
+  } else if items, ok := ProperList(val); ok && len(items) > 0 {
+    buf.WriteString("\n")
+    writeYAMLNode(buf, val, indent+2, false)
+  } else {
+    buf.WriteString(" " + yamlScalar(val) + "\n")
+  }
+}
+
+func yamlScalar(val Value) string {
+  switch val.(type) {
+  case *EmptyPairValue:
+    return "null"
+  case *BoolValue:
+    if val.(*BoolValue).Value {
+      return "true"
+    }
+    return "false"
+  case *FloatValue:
+    f := val.(*FloatValue).Value
+    switch {
+    case math.IsInf(f, 1):
+      return ".inf"
+    case math.IsInf(f, -1):
+      return "-.inf"
+    case math.IsNaN(f):
+      return ".nan"
+    }
+    s := strconv.FormatFloat(f, 'g', -1, 64)
+    if !strings.ContainsAny(s, ".e") {
+      s += ".0"
+    }
+    return s
+  case *StringValue:
+    return yamlString(val.(*StringValue).Value)
+  case *Symbol:
+    return yamlString(val.(*Symbol).Value)
+  case *PairValue:
+    panic(fmt.Sprint("yaml-write: improper list can not be written: ", val))
+  default:
+    return val.String()
+  }
+}
+
+// quote strings which would otherwise be read back as another type
+func yamlString(s string) string {
+  if _, ok := plainYAMLScalar(s).(*StringValue); !ok || s == "" ||
+    strings.ContainsAny(s, ":#[]{},\"'\n\t") ||
+    strings.ContainsAny(s[:1], "-?|>!&*%@` ") || strings.HasSuffix(s, " ") {
+    return strconv.Quote(s)
+  }
+  return s
+}
@@ -2,12 +2,24 @@ package constants
 
 const (
   DEFINE           = "define"
+  DEFINE_CONSTANT  = "define-constant"
+  DEFINE_CONTRACT  = "define/contract"
+  DEFINE_SYNTAX    = "define-syntax"
+  DEFINE_ENUM      = "define-enum"
+  SYNTAX_RULES     = "syntax-rules"
+  ELLIPSIS         = "..."
+  UNDERSCORE       = "_"
+  THE_ENVIRONMENT  = "the-environment"
+  ANNOTATE         = ":"
   BEGIN            = "begin"
   SET              = "set!"
   LAMBDA           = "lambda"
   LET              = "let"
   LET_STAR         = "let*"
   LET_REC          = "letrec"
+  LET_VALUES       = "let-values"
+  LET_STAR_VALUES  = "let*-values"
+  DO               = "do"
   OPEN_PARANT      = "("
   CLOSE_PARANT     = ")"
   ADD              = "+"
@@ -22,12 +34,21 @@ const (
   APPLY            = "apply"
   IF               = "if"
   COND             = "cond"
+  CASE             = "case"
+  WHEN             = "when"
+  UNLESS           = "unless"
+  AND              = "and"
+  OR               = "or"
+  ELSE             = "else"
+  ARROW            = "=>"
   DELAY            = "delay"
   FORCE            = "force"
   GO               = "go"
+  NURSERY          = "nursery"
   CHAN_SEND        = "chan<-"
   CHAN_RECV        = "<-chan"
   SELECT           = "select"
+  PRIORITY_SELECT  = "priority-select"
   DEFAULT          = "default"
   SLEEP            = "sleep"
   RANDOM           = "random"
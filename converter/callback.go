@@ -0,0 +1,107 @@
+package converter
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "reflect"
+  "sync"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func isProcedure(val Value) bool {
+  switch val.(type) {
+  case *Closure, *Contract, PrimFunc:
+    return true
+  }
+  return false
+}
+
+// go function of type t calling the lisp procedure proc, its arguments
+// converted with ToValue and its result with FromValue; a procedure
+// returns a list for functions with several results. when the last
+// result of t is an error, errors raised by the procedure are returned
+// there instead of panicking in the caller. serialized functions run
+// one call at a time, for procedures mutating shared state which hosts
+// call from several goroutines, like http handlers
+func ToFunc(proc Value, t reflect.Type, serialized bool) reflect.Value {
+  if !isProcedure(proc) || t.Kind() != reflect.Func {
+    panic(fmt.Sprintf("callback: expected a procedure for %s, given: %s", t, proc))
+  }
+  results := make([]reflect.Type, t.NumOut())
+  for i := range results {
+    results[i] = t.Out(i)
+  }
+  returnsError := len(results) > 0 && results[len(results)-1] == errorType
+  if returnsError {
+    results = results[:len(results)-1]
+  }
+  var mutex sync.Mutex
+
+  return reflect.MakeFunc(t, func(in []reflect.Value) (out []reflect.Value) {
+    defer Evaluating(CallbackEvaluator)()
+    if serialized {
+      mutex.Lock()
+      defer mutex.Unlock()
+    }
+    if returnsError {
+      defer func() {
+        if err := recover(); err != nil {
+          out = make([]reflect.Value, t.NumOut())
+          for i := range results {
+            out[i] = reflect.Zero(results[i])
+          }
+          e, ok := err.(error)
+          if !ok {
+            e = &Error{Message: fmt.Sprint(err)}
+          }
+          out[len(results)] = reflect.ValueOf(&e).Elem()
+        }
+      }()
+    }
+
+    var args []Value
+    for i, arg := range in {
+      if t.IsVariadic() && i == len(in)-1 {
+        // the variadic arguments are passed one by one
+        for j := 0; j < arg.Len(); j++ {
+          args = append(args, ToValue(arg.Index(j).Interface()))
+        }
+      } else {
+        args = append(args, ToValue(arg.Interface()))
+      }
+    }
+    result := Invoke(proc, args)
+
+    values := []Value{result}
+    if len(results) == 0 {
+      values = nil
+    } else if len(results) > 1 {
+      items, ok := listItems(result)
+      if !ok || len(items) != len(results) {
+        panic(fmt.Sprintf("callback: expected a list of %d results, given: %s", len(results), result))
+      }
+      values = items
+    }
+    for i, val := range values {
+      v, ok := FromValue(val, results[i])
+      if !ok {
+        panic(fmt.Sprintf("callback: expected %s, given: %s", results[i], val))
+      }
+      out = append(out, v)
+    }
+    if returnsError {
+      out = append(out, reflect.Zero(errorType))
+    }
+    return out
+  })
+}
+
+// set the function variable fnptr points to to a function calling proc
+func BindFunc(proc Value, fnptr interface{}, serialized bool) {
+  v := reflect.ValueOf(fnptr)
+  if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Func {
+    panic(fmt.Sprintf("callback: expected a pointer to a function, given: %T", fnptr))
+  }
+  v.Elem().Set(ToFunc(proc, v.Elem().Type(), serialized))
+}
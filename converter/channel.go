@@ -0,0 +1,101 @@
+package converter
+
+import (
+  . "github.com/kedebug/LispEx/value"
+  "reflect"
+)
+
+// lisp channel delivering the values sent on a host channel,
+// converted with ToValue on a separate goroutine. it is closed
+// when the host channel is, and stops forwarding once lisp closes
+// it, leaving the rest of the host channel unread. like every forwarded channel it holds
+// one value more than the host channel: a send completes once
+// the value is taken for forwarding, not when lisp receives it
+func FromGoChannel(ch <-chan interface{}) *Channel {
+  channel := NewChannel(0)
+  go func() {
+    for val := range ch {
+      if !channel.Forward(ToValue(val)) {
+        return
+      }
+    }
+    channel.Close()
+  }()
+  return channel
+}
+
+// host channel delivering the values sent on a lisp channel, converted
+// like FromValue converts to interface{}: numbers to int64 or float64,
+// lists to []interface{} and so on. it is closed when the lisp channel is
+func ToGoChannel(channel *Channel) <-chan interface{} {
+  ch := make(chan interface{})
+  go func() {
+    for val := range channel.Value {
+      ch <- naturalValue(val).Interface()
+    }
+    close(ch)
+  }()
+  return ch
+}
+
+// lisp channel for a host channel of any element type, as ToValue
+// converts it: the values received from a channel lisp may receive
+// from are forwarded to lisp, and what lisp sends on the lisp
+// channel is forwarded to a send-only one. forwarding to lisp stops
+// when lisp closes the channel
+func fromGoChan(ch reflect.Value) *Channel {
+  channel := NewChannel(0)
+  if ch.Type().ChanDir() == reflect.SendDir {
+    go forwardToGo(channel, ch)
+    return channel
+  }
+  go func() {
+    for {
+      val, ok := ch.Recv()
+      if !ok {
+        break
+      }
+      if !channel.Forward(ToValue(val.Interface())) {
+        return
+      }
+    }
+    channel.Close()
+  }()
+  return channel
+}
+
+// host channel of type t for a lisp channel, as FromValue converts it,
+// the reverse of fromGoChan. a value sent by lisp without counterpart
+// of the element type closes the host channel, a lisp channel closed
+// by lisp stops the forwarding of what the host sends
+func toGoChan(channel *Channel, t reflect.Type) reflect.Value {
+  ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, t.Elem()), 0)
+  if t.ChanDir() == reflect.SendDir {
+    go func() {
+      for {
+        val, ok := ch.Recv()
+        if !ok {
+          break
+        }
+        if !channel.Forward(ToValue(val.Interface())) {
+          return
+        }
+      }
+      channel.Close()
+    }()
+  } else {
+    go forwardToGo(channel, ch)
+  }
+  return ch.Convert(t)
+}
+
+func forwardToGo(channel *Channel, ch reflect.Value) {
+  defer ch.Close()
+  for val := range channel.Value {
+    v, ok := FromValue(val, ch.Type().Elem())
+    if !ok {
+      return
+    }
+    ch.Send(v)
+  }
+}
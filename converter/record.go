@@ -0,0 +1,106 @@
+package converter
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "reflect"
+  "strings"
+  "sync"
+  "unicode"
+)
+
+var records = struct {
+  sync.RWMutex
+  types map[reflect.Type]*RecordType
+}{types: make(map[reflect.Type]*RecordType)}
+
+// record type mirroring the exported fields of struct type t,
+// values of t are then converted to records and back.
+// MyConfig becomes my-config and its field ListenAddr listen-addr,
+// unless the field has a `lisp:"name"' tag; `lisp:"-"' skips it
+func RegisterStruct(t reflect.Type) *RecordType {
+  if t.Kind() != reflect.Struct {
+    panic(fmt.Sprint("register-struct: expected a struct, given: ", t))
+  }
+  records.Lock()
+  defer records.Unlock()
+  if rt, ok := records.types[t]; ok {
+    return rt
+  }
+  rt := &RecordType{Name: LispName(t.Name()), Struct: t}
+  for i := 0; i < t.NumField(); i++ {
+    field := t.Field(i)
+    name := field.Tag.Get("lisp")
+    if field.PkgPath != "" || name == "-" {
+      continue
+    }
+    if name == "" {
+      name = LispName(field.Name)
+    }
+    rt.Fields = append(rt.Fields, name)
+    rt.Index = append(rt.Index, i)
+  }
+  records.types[t] = rt
+  return rt
+}
+
+func registeredStruct(t reflect.Type) *RecordType {
+  records.RLock()
+  defer records.RUnlock()
+  return records.types[t]
+}
+
+// fill the struct target points to with the fields of a record of its type
+func RecordToStruct(val Value, target interface{}) {
+  v := reflect.ValueOf(target)
+  if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+    panic(fmt.Sprintf("record->struct: expected a pointer to a struct, given: %T", target))
+  }
+  s, ok := recordToStruct(val, v.Elem().Type())
+  if !ok {
+    panic(fmt.Sprintf("record->struct: expected %s record, given: %s", LispName(v.Elem().Type().Name()), val))
+  }
+  v.Elem().Set(s)
+}
+
+func structToRecord(v reflect.Value, rt *RecordType) *Record {
+  values := make([]Value, len(rt.Index))
+  for i, index := range rt.Index {
+    values[i] = ToValue(v.Field(index).Interface())
+  }
+  return NewRecord(rt, values)
+}
+
+// fields of the struct not mirrored by the record are left zero
+func recordToStruct(val Value, t reflect.Type) (reflect.Value, bool) {
+  record, ok := val.(*Record)
+  if !ok || record.Type.Struct != t {
+    return reflect.Value{}, false
+  }
+  s := reflect.New(t).Elem()
+  for i, index := range record.Type.Index {
+    field, ok := FromValue(record.Values[i], t.Field(index).Type)
+    if !ok {
+      return s, false
+    }
+    s.Field(index).Set(field)
+  }
+  return s, true
+}
+
+// lower case words separated by dashes, e.g. HTTPPort is http-port
+func LispName(name string) string {
+  runes := []rune(name)
+  var b strings.Builder
+  for i, r := range runes {
+    if i > 0 && unicode.IsUpper(r) {
+      prev := runes[i-1]
+      nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+      if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+        b.WriteRune('-')
+      }
+    }
+    b.WriteRune(unicode.ToLower(r))
+  }
+  return b.String()
+}
@@ -0,0 +1,221 @@
+package converter
+
+import (
+  . "github.com/kedebug/LispEx/value"
+  "math/big"
+  "reflect"
+  "sort"
+)
+
+// convert a golang value to lisp: booleans, numbers and strings to
+// their lisp counterparts, slices to lists, maps with string keys to
+// association lists, channels to lisp channels forwarding their values,
+// registered structs to records and nil to the empty list. values already lisp are kept, anything else is boxed
+// in an Opaque
+func ToValue(val interface{}) Value {
+  if val == nil {
+    return NilPairValue
+  }
+  if lisp, ok := val.(Value); ok {
+    return lisp
+  }
+  v := reflect.ValueOf(val)
+  switch v.Kind() {
+  case reflect.Bool:
+    return NewBoolValue(v.Bool())
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return NewIntValue(v.Int())
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    if n := v.Uint(); n <= 1<<63-1 {
+      return NewIntValue(int64(n))
+    }
+    return NewBigIntValue(new(big.Int).SetUint64(v.Uint()))
+  case reflect.Float32, reflect.Float64:
+    return NewFloatValue(v.Float())
+  case reflect.String:
+    return NewStringValue(v.String())
+  case reflect.Slice, reflect.Array:
+    if v.Kind() == reflect.Slice && v.IsNil() {
+      return NilPairValue
+    }
+    if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+      return NewStringValue(string(v.Bytes()))
+    }
+    items := make([]Value, v.Len())
+    for i := range items {
+      items[i] = ToValue(v.Index(i).Interface())
+    }
+    return SliceToPairValues(items)
+  case reflect.Struct:
+    if rt := registeredStruct(v.Type()); rt != nil {
+      return structToRecord(v, rt)
+    }
+  case reflect.Map:
+    if v.Type().Key().Kind() != reflect.String {
+      break
+    }
+    // go randomizes the order of maps, lisp code may print them
+    keys := make([]string, 0, v.Len())
+    for _, key := range v.MapKeys() {
+      keys = append(keys, key.String())
+    }
+    sort.Strings(keys)
+    entries := make([]Value, len(keys))
+    for i, key := range keys {
+      item := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+      entries[i] = NewPairValue(NewStringValue(key), ToValue(item.Interface()))
+    }
+    return SliceToPairValues(entries)
+  case reflect.Chan:
+    if !v.IsNil() {
+      return fromGoChan(v)
+    }
+  }
+  return NewOpaque(val)
+}
+
+// convert a lisp value to a golang value of type t, the reverse of
+// ToValue. an interface{} receives int64, float64, bool, string or
+// []interface{}, or the lisp value itself, and procedures become
+// functions with ToFunc. ok is false if val has no counterpart of type t
+func FromValue(val Value, t reflect.Type) (v reflect.Value, ok bool) {
+  if opaque, isOpaque := val.(*Opaque); isOpaque && opaque.Value != nil {
+    if reflect.TypeOf(opaque.Value).AssignableTo(t) {
+      return reflect.ValueOf(opaque.Value), true
+    }
+    return v, false
+  }
+  if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+    return naturalValue(val), true
+  }
+  if val != nil && reflect.TypeOf(val).AssignableTo(t) {
+    return reflect.ValueOf(val), true
+  }
+  switch t.Kind() {
+  case reflect.Struct:
+    return recordToStruct(val, t)
+  case reflect.Func:
+    if isProcedure(val) {
+      return ToFunc(val, t, false), true
+    }
+  case reflect.Bool:
+    if b, isBool := val.(*BoolValue); isBool {
+      return reflect.ValueOf(b.Value).Convert(t), true
+    }
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    if n, isInt := val.(*IntValue); isInt && !reflect.Zero(t).OverflowInt(n.Value) {
+      return reflect.ValueOf(n.Value).Convert(t), true
+    }
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    if n, isInt := val.(*IntValue); isInt && n.Value >= 0 && !reflect.Zero(t).OverflowUint(uint64(n.Value)) {
+      return reflect.ValueOf(n.Value).Convert(t), true
+    }
+  case reflect.Float32, reflect.Float64:
+    switch val.(type) {
+    case *IntValue:
+      return reflect.ValueOf(float64(val.(*IntValue).Value)).Convert(t), true
+    case *FloatValue:
+      return reflect.ValueOf(val.(*FloatValue).Value).Convert(t), true
+    case *BigIntValue:
+      f, _ := new(big.Float).SetInt(val.(*BigIntValue).Value).Float64()
+      return reflect.ValueOf(f).Convert(t), true
+    case *RatValue:
+      f, _ := val.(*RatValue).Value.Float64()
+      return reflect.ValueOf(f).Convert(t), true
+    }
+  case reflect.String:
+    switch val.(type) {
+    case *StringValue:
+      return reflect.ValueOf(val.(*StringValue).Value).Convert(t), true
+    case *Symbol:
+      return reflect.ValueOf(val.(*Symbol).Value).Convert(t), true
+    }
+  case reflect.Slice:
+    if s, isString := val.(*StringValue); isString && t.Elem().Kind() == reflect.Uint8 {
+      return reflect.ValueOf([]byte(s.Value)).Convert(t), true
+    }
+    items, isList := listItems(val)
+    if !isList {
+      break
+    }
+    v = reflect.MakeSlice(t, len(items), len(items))
+    for i, item := range items {
+      element, ok := FromValue(item, t.Elem())
+      if !ok {
+        return v, false
+      }
+      v.Index(i).Set(element)
+    }
+    return v, true
+  case reflect.Map:
+    items, isList := listItems(val)
+    if !isList || t.Key().Kind() != reflect.String {
+      break
+    }
+    v = reflect.MakeMapWithSize(t, len(items))
+    for _, item := range items {
+      pair, isPair := item.(*PairValue)
+      if !isPair {
+        return v, false
+      }
+      key, ok := FromValue(pair.First, t.Key())
+      if !ok {
+        return v, false
+      }
+      element, ok := FromValue(pair.Second, t.Elem())
+      if !ok {
+        return v, false
+      }
+      v.SetMapIndex(key, element)
+    }
+    return v, true
+  case reflect.Chan:
+    if channel, isChannel := val.(*Channel); isChannel {
+      return toGoChan(channel, t), true
+    }
+  }
+  return v, false
+}
+
+func naturalValue(val Value) reflect.Value {
+  var natural interface{} = val
+  switch val.(type) {
+  case *IntValue:
+    natural = val.(*IntValue).Value
+  case *FloatValue:
+    natural = val.(*FloatValue).Value
+  case *BigIntValue:
+    natural = val.(*BigIntValue).Value
+  case *RatValue:
+    natural = val.(*RatValue).Value
+  case *BoolValue:
+    natural = val.(*BoolValue).Value
+  case *StringValue:
+    natural = val.(*StringValue).Value
+  case *EmptyPairValue, *PairValue:
+    if items, isList := listItems(val); isList {
+      slice := make([]interface{}, len(items))
+      for i, item := range items {
+        slice[i] = naturalValue(item).Interface()
+      }
+      natural = slice
+    }
+  }
+  return reflect.ValueOf(&natural).Elem()
+}
+
+func listItems(val Value) ([]Value, bool) {
+  var items []Value
+  for {
+    switch val.(type) {
+    case *EmptyPairValue:
+      return items, true
+    case *PairValue:
+      pair := val.(*PairValue)
+      items = append(items, pair.First)
+      val = pair.Second
+    default:
+      return nil, false
+    }
+  }
+}
@@ -0,0 +1,155 @@
+package doc
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/parser"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "html"
+  "strconv"
+  "strings"
+)
+
+// the reference of a builtin or of a definition of the standard library
+type Entry struct {
+  Name      string
+  Signature string
+  Doc       string
+  Builtin   bool
+}
+
+// the builtins in the order of their registration table
+func Builtins() []*Entry {
+  var entries []*Entry
+  for _, builtin := range primitives.Builtins {
+    entries = append(entries, &Entry{builtin.Name, builtin.Signature(), builtin.Doc, true})
+  }
+  return entries
+}
+
+// the top-level definitions and macros of the standard library source, in order.
+// the doc of a procedure is its doc string, else the comment lines
+// right above its definition
+func Stdlib(source string) ([]*Entry, error) {
+  nodes, err := parser.ParseFromString("stdlib.ss", source)
+  if err != nil {
+    return nil, err
+  }
+  lines := strings.Split(source, "\n")
+  var entries []*Entry
+  for _, node := range nodes {
+    var define *ast.Define
+    switch node.(type) {
+    case *ast.DefineSyntax:
+      syntax := node.(*ast.DefineSyntax)
+      entries = append(entries, &Entry{Name: syntax.Name, Signature: syntax.Name, Doc: commentAbove(lines, syntax.Pos)})
+      continue
+    case *ast.Define:
+      define = node.(*ast.Define)
+    case *ast.DefineContract:
+      define = node.(*ast.DefineContract).Define
+    default:
+      continue
+    }
+    entry := &Entry{Name: define.Pattern.Identifier, Signature: define.Pattern.Identifier}
+    if function, ok := define.Value.(*ast.Function); ok {
+      // (define (f x) ...) => (f x)
+      datum := ast.ToDatum(define).(*value.PairValue)
+      entry.Signature = datum.Second.(*value.PairValue).First.String()
+      entry.Doc = function.Body.(*ast.Lambda).Doc()
+    }
+    if entry.Doc == "" {
+      entry.Doc = commentAbove(lines, define.Pos)
+    }
+    entries = append(entries, entry)
+  }
+  return entries, nil
+}
+
+// the text of the comment lines right above the line of pos
+func commentAbove(lines []string, pos string) string {
+  // pos is file:line:column
+  parts := strings.Split(pos, ":")
+  if len(parts) < 3 {
+    return ""
+  }
+  line, err := strconv.Atoi(parts[len(parts)-2])
+  if err != nil {
+    return ""
+  }
+  var comment []string
+  for i := line - 2; i >= 0; i-- {
+    text := strings.TrimSpace(lines[i])
+    if !strings.HasPrefix(text, ";") {
+      break
+    }
+    comment = append([]string{strings.TrimSpace(strings.TrimLeft(text, ";"))}, comment...)
+  }
+  return strings.Join(comment, " ")
+}
+
+// the entry named name, nil if there is none
+func Lookup(entries []*Entry, name string) *Entry {
+  for _, entry := range entries {
+    if entry.Name == name {
+      return entry
+    }
+  }
+  return nil
+}
+
+// e.g.
+//   (list-tail x k)
+//     standard library, from tinyscheme
+func (self *Entry) String() string {
+  kind := "standard library"
+  if self.Builtin {
+    kind = "builtin"
+  }
+  if self.Doc == "" {
+    return fmt.Sprintf("%s\n  %s\n", self.Signature, kind)
+  }
+  return fmt.Sprintf("%s\n  %s, %s\n", self.Signature, kind, self.Doc)
+}
+
+// the reference of the builtins and the standard library in Markdown
+func Markdown(builtins, stdlib []*Entry) string {
+  text := "# LispEx reference\n"
+  for _, section := range sections(builtins, stdlib) {
+    text += fmt.Sprintf("\n## %s\n\n", section.title)
+    for _, entry := range section.entries {
+      if entry.Doc == "" {
+        text += fmt.Sprintf("- `%s`\n", entry.Signature)
+      } else {
+        text += fmt.Sprintf("- `%s`: %s\n", entry.Signature, entry.Doc)
+      }
+    }
+  }
+  return text
+}
+
+// the same reference as a standalone HTML page
+func HTML(builtins, stdlib []*Entry) string {
+  text := "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>LispEx reference</title></head>\n<body>\n<h1>LispEx reference</h1>\n"
+  for _, section := range sections(builtins, stdlib) {
+    text += fmt.Sprintf("<h2>%s</h2>\n<dl>\n", section.title)
+    for _, entry := range section.entries {
+      text += fmt.Sprintf("<dt id=\"%s\"><code>%s</code></dt>\n", html.EscapeString(entry.Name), html.EscapeString(entry.Signature))
+      if entry.Doc != "" {
+        text += fmt.Sprintf("<dd>%s</dd>\n", html.EscapeString(entry.Doc))
+      }
+    }
+    text += "</dl>\n"
+  }
+  return text + "</body>\n</html>\n"
+}
+
+type section struct {
+  title   string
+  entries []*Entry
+}
+
+func sections(builtins, stdlib []*Entry) []section {
+  return []section{{"Builtins", builtins}, {"Standard library", stdlib}}
+}
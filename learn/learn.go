@@ -0,0 +1,159 @@
+package learn
+
+import (
+  "bufio"
+  "fmt"
+  "github.com/kedebug/LispEx/repl"
+  "github.com/kedebug/LispEx/scope"
+  "io"
+  "strings"
+)
+
+// a lesson is passed when evaluating the answer
+// prints the same result as the expected string
+type Lesson struct {
+  Title    string
+  Text     string
+  Task     string
+  Expected string
+  Solution string
+}
+
+var Lessons = []Lesson{
+  {
+    Title:    "Expressions",
+    Text:     "Everything is written in prefix form: (+ 1 2) adds 1 and 2.\nForms nest, so (* 2 (+ 1 2)) is 6.",
+    Task:     "Compute 3 times the sum of 4 and 5.",
+    Expected: "27",
+    Solution: "(* 3 (+ 4 5))",
+  },
+  {
+    Title:    "Definitions",
+    Text:     "(define name value) binds a name, (define (f x) body) defines a procedure.",
+    Task:     "Define a procedure `square' and use it to compute the square of 12.",
+    Expected: "144",
+    Solution: "(define (square x) (* x x)) (square 12)",
+  },
+  {
+    Title:    "Lists",
+    Text:     "'(1 2 3) is a quoted list. car takes the first element, cdr the rest,\ncons builds a new pair, and map applies a procedure to every element.",
+    Task:     "Double every element of '(1 2 3) using map and a lambda.",
+    Expected: "(2 4 6)",
+    Solution: "(map (lambda (x) (* 2 x)) '(1 2 3))",
+  },
+  {
+    Title:    "Recursion",
+    Text:     "Loops are written as recursive procedures; (if test then else) picks a branch.",
+    Task:     "Define `fact' computing the factorial and evaluate (fact 10).",
+    Expected: "3628800",
+    Solution: "(define (fact n) (if (= n 0) 1 (* n (fact (- n 1))))) (fact 10)",
+  },
+  {
+    Title:    "Quasiquote",
+    Text:     "`(a ,x) builds a list like quote, but ,x inserts the value of x\nand ,@xs splices the elements of the list xs.",
+    Task:     "With (define xs '(2 3)), build the list (1 2 3 4) using quasiquote.",
+    Expected: "(1 2 3 4)",
+    Solution: "(define xs '(2 3)) `(1 ,@xs 4)",
+  },
+  {
+    Title:    "Goroutines and channels",
+    Text:     "(go expr) evaluates expr concurrently. (make-chan) creates a channel,\n(chan<- c v) sends v and (<-chan c) receives from it.",
+    Task:     "Send 'hello to a channel from a goroutine and receive it.",
+    Expected: "hello",
+    Solution: "(define c (make-chan)) (go (chan<- c 'hello)) (<-chan c)",
+  },
+  {
+    Title:    "Select",
+    Text:     "(select ((<-chan c) expr) (default expr)) waits on several channels,\nfalling back to the default clause when none is ready.",
+    Task:     "Make a channel nobody sends to and select on it with a default clause returning 'idle.",
+    Expected: "idle",
+    Solution: "(define c (make-chan)) (select ((<-chan c)) (default 'idle))",
+  },
+}
+
+// every exercise runs in a fresh child of a scope holding the stdlib,
+// so definitions made while answering one lesson don't leak into the next.
+// the scope is restricted, answers can't reach files or the network
+type Sandbox struct {
+  base *scope.Scope
+}
+
+func NewSandbox(stdlib string) *Sandbox {
+  base := scope.NewRestrictedRootScope()
+  base.SetPrelude("stdlib.ss", stdlib)
+  repl.REPL(stdlib, base)
+  base.Freeze()
+  return &Sandbox{base: base}
+}
+
+func (self *Sandbox) Check(lesson Lesson, answer string) (result string, ok bool) {
+  defer func() {
+    if err := recover(); err != nil {
+      result, ok = fmt.Sprint("error: ", err), false
+    }
+  }()
+  result = repl.REPL(answer, scope.NewScope(self.base))
+  if index := strings.LastIndex(result, "\n"); index >= 0 {
+    result = result[index+1:]
+  }
+  return result, result == lesson.Expected
+}
+
+func Run(stdlib string, in io.Reader, out io.Writer) {
+  sandbox := NewSandbox(stdlib)
+  reader := bufio.NewReader(in)
+
+  fmt.Fprintln(out, "Welcome to LispEx! Type your answer after the prompt,")
+  fmt.Fprintln(out, ":hint shows a solution, :skip moves on and :quit leaves.")
+  for i, lesson := range Lessons {
+    fmt.Fprintf(out, "\nLesson %d/%d: %s\n\n%s\n\n%s\n", i+1, len(Lessons), lesson.Title, lesson.Text, lesson.Task)
+    for {
+      answer, err := readForm(reader, out)
+      if err != nil {
+        return
+      }
+      switch strings.TrimSpace(answer) {
+      case "":
+        continue
+      case ":quit":
+        return
+      case ":hint":
+        fmt.Fprintln(out, lesson.Solution)
+        continue
+      }
+      if strings.TrimSpace(answer) == ":skip" {
+        break
+      }
+      result, ok := sandbox.Check(lesson, answer)
+      if ok {
+        fmt.Fprintf(out, "%s\nCorrect!\n", result)
+        break
+      }
+      fmt.Fprintf(out, "%s\nNot quite, expected %s. Try again.\n", result, lesson.Expected)
+    }
+  }
+  fmt.Fprintln(out, "\nYou have finished all lessons.")
+}
+
+// read lines until the parentheses are balanced, the way the REPL
+// does, so parentheses in strings and comments are not counted
+func readForm(reader *bufio.Reader, out io.Writer) (string, error) {
+  input := &repl.Input{}
+  fmt.Fprint(out, ">>> ")
+  for {
+    line, err := reader.ReadString('\n')
+    if err != nil {
+      if line != "" {
+        input.Add(line)
+      }
+      if strings.TrimSpace(input.Text()) != "" {
+        return input.Text(), nil
+      }
+      return "", err
+    }
+    if input.Add(strings.TrimRight(line, "\r\n")) {
+      return input.Text(), nil
+    }
+    fmt.Fprint(out, "... ")
+  }
+}
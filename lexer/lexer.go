@@ -2,6 +2,8 @@ package lexer
 
 import (
   "fmt"
+  "math/big"
+  "strconv"
   "strings"
   "unicode"
   "unicode/utf8"
@@ -16,26 +18,46 @@ const (
   TokenEOF
 
   TokenIdentifier
+  // written between bars, e.g. |odd name|
+  TokenBarIdentifier
 
   TokenStringLiteral
+  TokenCharLiteral
   TokenIntegerLiteral
   TokenFloatLiteral
+  TokenRationalLiteral
   TokenBooleanLiteral
 
   TokenQuote
   TokenQuasiquote
   TokenUnquote
   TokenUnquoteSplicing
+  TokenDatumComment
 
   TokenOpenParen
+  TokenOpenVector
   TokenCloseParen
   TokenOpenSquare
   TokenCloseSquare
 )
 
 type Token struct {
-  Type  TokenType
-  Value string
+  Type   TokenType
+  Value  string
+  Line   int
+  Column int
+}
+
+// a lexical error, reported with the position where the bad token starts
+type Error struct {
+  Name    string
+  Line    int
+  Column  int
+  Message string
+}
+
+func (e *Error) Error() string {
+  return fmt.Sprintf("%s:%d:%d: %s", e.Name, e.Line, e.Column, e.Message)
 }
 
 type stateFn func(*Lexer) stateFn
@@ -47,23 +69,46 @@ type Lexer struct {
   start  int
   pos    int
   width  int
+  line   int
+  lined  int
   tokens chan Token
+  // set by #!fold-case, cleared by #!no-fold-case
+  fold bool
 }
 
 func NewLexer(name, input string) *Lexer {
+  return NewFoldingLexer(name, input, false)
+}
+
+// like NewLexer, fold makes it read Foo as foo like older R5RS
+// implementations, as if the input started with #!fold-case
+func NewFoldingLexer(name, input string, fold bool) *Lexer {
   l := &Lexer{
     name:   name,
     input:  input,
+    line:   1,
     tokens: make(chan Token),
+    fold:   fold,
   }
   go l.run()
   return l
 }
 
+func (l *Lexer) Name() string {
+  return l.name
+}
+
 func (l *Lexer) NextToken() Token {
   return <-l.tokens
 }
 
+// consume the remaining tokens, letting the lexing
+// goroutine finish when parsing stopped early
+func (l *Lexer) Drain() {
+  for range l.tokens {
+  }
+}
+
 func (l *Lexer) run() {
   for l.state = lexWhiteSpace; l.state != nil; {
     l.state = l.state(l)
@@ -72,10 +117,33 @@ func (l *Lexer) run() {
 }
 
 func (l *Lexer) emit(t TokenType) {
-  l.tokens <- Token{t, l.input[l.start:l.pos]}
+  l.emitValue(t, l.input[l.start:l.pos])
+}
+
+func (l *Lexer) emitValue(t TokenType, value string) {
+  line, column := l.position()
+  l.tokens <- Token{t, value, line, column}
   l.start = l.pos
 }
 
+// simple case folding as char-foldcase does, identifiers
+// and character names are folded after #!fold-case
+func (l *Lexer) folded() string {
+  text := l.input[l.start:l.pos]
+  if !l.fold {
+    return text
+  }
+  return strings.Map(func(r rune) rune { return unicode.ToLower(unicode.ToUpper(r)) }, text)
+}
+
+// line and column of the current token, newlines are
+// counted up to the token start since the previous call
+func (l *Lexer) position() (int, int) {
+  l.line += strings.Count(l.input[l.lined:l.start], "\n")
+  l.lined = l.start
+  return l.line, l.start - strings.LastIndex(l.input[:l.start], "\n")
+}
+
 func (l *Lexer) next() rune {
   if len(l.input) <= l.pos {
     l.width = 0
@@ -118,7 +186,8 @@ func (l *Lexer) acceptRun(valid string) {
 }
 
 func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
-  l.tokens <- Token{TokenError, fmt.Sprintf(format, args...)}
+  line, column := l.position()
+  l.tokens <- Token{TokenError, fmt.Sprintf(format, args...), line, column}
   return nil
 }
 
@@ -133,18 +202,28 @@ func lexWhiteSpace(l *Lexer) stateFn {
     return lexEOF
   case r == ';':
     return lexComment
+  case r == '#' && l.peek() == ';':
+    return lexDatumComment
+  case r == '#' && l.peek() == '!':
+    return lexDirective
   case r == '(':
     return lexOpenParen
+  case r == '#' && l.peek() == '(':
+    return lexOpenVector
   case r == ')':
     return lexCloseParen
   case r == '"':
     return lexString
+  case r == '#' && l.peek() == '\\':
+    return lexChar
   case r == '\'':
     return lexQuote
   case r == '`':
     return lexQuasiquote
   case r == ',':
     return lexUnquote
+  case r == '|':
+    return lexBarSymbol
   case r == '+' || r == '-' || ('0' <= r && r <= '9'):
     l.backup()
     return lexNumber
@@ -152,7 +231,7 @@ func lexWhiteSpace(l *Lexer) stateFn {
     // begin with non-numberic character
     return lexIdentifier
   default:
-    return l.errorf("Unexpected character: %q", r)
+    return l.errorf("invalid character %s", charName(r))
   }
 }
 
@@ -186,24 +265,80 @@ func lexUnquoteSplicing(l *Lexer) stateFn {
   return lexWhiteSpace
 }
 
+// #; comments out the datum following it
+func lexDatumComment(l *Lexer) stateFn {
+  l.next()
+  l.emit(TokenDatumComment)
+  return lexWhiteSpace
+}
+
+// #!fold-case and #!no-fold-case, which apply to the rest of the input
+func lexDirective(l *Lexer) stateFn {
+  for r := l.next(); isAlphaNumeric(r); r = l.next() {
+  }
+  l.backup()
+  switch directive := l.input[l.start:l.pos]; directive {
+  case "#!fold-case":
+    l.fold = true
+  case "#!no-fold-case":
+    l.fold = false
+  default:
+    return l.errorf("unknown directive %s", directive)
+  }
+  l.ignore()
+  return lexWhiteSpace
+}
+
 func lexString(l *Lexer) stateFn {
   for r := l.next(); r != '"'; r = l.next() {
     if r == '\\' {
       r = l.next()
     }
     if r == EOF {
-      return l.errorf("read: expected a closing `\"'")
+      line, _ := l.position()
+      return l.errorf("unterminated string starting at line %d", line)
     }
   }
   l.emit(TokenStringLiteral)
   return lexWhiteSpace
 }
 
+// #\a, #\( or #\space: a letter may be followed by more letters
+// naming the character, any other character stands for itself
+func lexChar(l *Lexer) stateFn {
+  l.next()
+  r := l.next()
+  if r == EOF {
+    return l.errorf("bad character syntax: %q", l.input[l.start:l.pos])
+  }
+  if unicode.IsLetter(r) {
+    for next := l.next(); isAlphaNumeric(next); next = l.next() {
+    }
+    l.backup()
+  }
+  text := l.input[l.start:l.pos]
+  if len(text) > len("#\\")+utf8.RuneLen(r) {
+    // a name like #\Space, not a letter like #\A
+    text = l.folded()
+  }
+  if _, err := ParseChar(text); err != nil {
+    return l.errorf("%s", err)
+  }
+  l.emitValue(TokenCharLiteral, text)
+  return lexWhiteSpace
+}
+
 func lexOpenParen(l *Lexer) stateFn {
   l.emit(TokenOpenParen)
   return lexWhiteSpace
 }
 
+func lexOpenVector(l *Lexer) stateFn {
+  l.next()
+  l.emit(TokenOpenVector)
+  return lexWhiteSpace
+}
+
 func lexCloseParen(l *Lexer) stateFn {
   l.emit(TokenCloseParen)
   return lexWhiteSpace
@@ -214,12 +349,30 @@ func lexIdentifier(l *Lexer) stateFn {
   }
   l.backup()
 
-  l.emit(TokenIdentifier)
+  l.emitValue(TokenIdentifier, l.folded())
+  return lexWhiteSpace
+}
+
+// |odd name| is the symbol of the characters between the bars, which
+// may be whitespace or delimiters, \| and \\ stand for a bar and a backslash
+func lexBarSymbol(l *Lexer) stateFn {
+  var name strings.Builder
+  for r := l.next(); r != '|'; r = l.next() {
+    if r == '\\' {
+      r = l.next()
+    }
+    if r == EOF {
+      line, _ := l.position()
+      return l.errorf("unterminated symbol starting at line %d", line)
+    }
+    name.WriteRune(r)
+  }
+  l.emitValue(TokenBarIdentifier, name.String())
   return lexWhiteSpace
 }
 
 func lexComment(l *Lexer) stateFn {
-  for r := l.next(); r != '\n'; r = l.next() {
+  for r := l.next(); r != '\n' && r != EOF; r = l.next() {
   }
   return lexWhiteSpace
 }
@@ -227,19 +380,35 @@ func lexComment(l *Lexer) stateFn {
 func lexNumber(l *Lexer) stateFn {
   isFloat := false
 
-  hasFlag := l.accept("+-")
-  digits := "0123456789"
+  // a lone sign, or a sign followed by a non-digit like `->'
+  if l.accept("+-") && !strings.ContainsRune("0123456789.", l.peek()) {
+    return lexSpecialFloat
+  }
+  digits, hex := "0123456789", false
   if l.accept("0") && l.accept("xX") {
-    digits = "0123456789abcdefABCDEF"
+    digits, hex = "0123456789abcdefABCDEF", true
   }
   l.acceptRun(digits)
 
-  if l.accept(".") {
+  // a fraction like 1/3
+  isRational := false
+  if !hex && l.peek() == '/' {
+    l.next()
+    isRational = true
+    if !l.accept(digits) {
+      return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
+    }
+    l.acceptRun(digits)
+  }
+
+  if !isRational && l.accept(".") {
     isFloat = true
     l.acceptRun(digits)
   }
 
-  if l.accept("eE") {
+  // hexadecimal digits already took any `e'
+  if !isRational && l.accept("eE") {
+    isFloat = true
     l.accept("+-")
     l.acceptRun("0123456789")
   }
@@ -249,20 +418,121 @@ func lexNumber(l *Lexer) stateFn {
     return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
   }
 
-  if hasFlag && l.start+1 == l.pos {
-    return lexIdentifier
-  }
-
-  if isFloat {
+  text := l.input[l.start:l.pos]
+  switch {
+  case isFloat:
+    if _, err := strconv.ParseFloat(text, 64); err != nil {
+      return l.numberError(text, err)
+    }
     l.emit(TokenFloatLiteral)
-  } else {
+  case isRational:
+    if _, ok := ParseRational(text); !ok {
+      return l.errorf("division by zero in number literal: %s", text)
+    }
+    l.emit(TokenRationalLiteral)
+  default:
+    // integers out of the range of an int64 are bignums
+    if _, ok := ParseRational(text); !ok {
+      return l.errorf("bad number syntax: %q", text)
+    }
     l.emit(TokenIntegerLiteral)
   }
   return lexWhiteSpace
 }
 
+// +inf.0, -inf.0 and +nan.0 after their sign, other
+// names starting with a sign are identifiers
+func lexSpecialFloat(l *Lexer) stateFn {
+  for _, name := range []string{"inf.0", "nan.0"} {
+    if !strings.HasPrefix(l.input[l.pos:], name) {
+      continue
+    }
+    if next, _ := utf8.DecodeRuneInString(l.input[l.pos+len(name):]); !isAlphaNumeric(next) {
+      l.pos += len(name)
+      l.emit(TokenFloatLiteral)
+      return lexWhiteSpace
+    }
+  }
+  return lexIdentifier
+}
+
+func (l *Lexer) numberError(text string, err error) stateFn {
+  if err.(*strconv.NumError).Err == strconv.ErrRange {
+    return l.errorf("number literal out of range: %s", text)
+  }
+  return l.errorf("bad number syntax: %q", text)
+}
+
+// integer literals are decimal or, prefixed by 0x, hexadecimal,
+// both with an optional sign
+func ParseInt(text string) (int64, error) {
+  sign, digits := "", text
+  if strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+    sign, digits = digits[:1], digits[1:]
+  }
+  if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+    return strconv.ParseInt(sign+digits[2:], 16, 64)
+  }
+  return strconv.ParseInt(sign+digits, 10, 64)
+}
+
+// integer literals of any size, or fractions of decimal integers
+func ParseRational(text string) (*big.Rat, bool) {
+  sign, digits := "", text
+  if strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+    sign, digits = digits[:1], digits[1:]
+  }
+  if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+    n, ok := new(big.Int).SetString(sign+digits[2:], 16)
+    if !ok {
+      return nil, false
+    }
+    return new(big.Rat).SetInt(n), true
+  }
+  return new(big.Rat).SetString(sign + digits)
+}
+
+// names of characters besides #\<char> and #\x<hex>
+var charNames = map[string]rune{
+  "alarm":     '\a',
+  "backspace": '\b',
+  "delete":    0x7f,
+  "escape":    0x1b,
+  "newline":   '\n',
+  "null":      0,
+  "return":    '\r',
+  "space":     ' ',
+  "tab":       '\t',
+}
+
+// character of a literal like #\a, #\space or #\x41
+func ParseChar(text string) (rune, error) {
+  name := strings.TrimPrefix(text, "#\\")
+  if utf8.RuneCountInString(name) == 1 {
+    r, _ := utf8.DecodeRuneInString(name)
+    return r, nil
+  }
+  if r, ok := charNames[name]; ok {
+    return r, nil
+  }
+  if len(name) > 1 && (name[0] == 'x' || name[0] == 'X') {
+    if code, err := strconv.ParseUint(name[1:], 16, 32); err == nil && utf8.ValidRune(rune(code)) {
+      return rune(code), nil
+    }
+  }
+  return 0, fmt.Errorf("unknown character name: %s", text)
+}
+
+// characters are named as in #\a, unprintable ones by their code
+func charName(r rune) string {
+  if unicode.IsPrint(r) && r != ' ' {
+    return fmt.Sprintf("#\\%c", r)
+  }
+  return fmt.Sprintf("#\\x%x", r)
+}
+
 func isAlphaNumeric(r rune) bool {
-  if strings.IndexRune("!#$%&|*+-/:<=>?@^_~", r) >= 0 {
+  if strings.IndexRune("!#$%&*+-/:<=>?@^_~", r) >= 0 {
     return true
   }
   return r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
@@ -0,0 +1,113 @@
+package lispex
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/repl"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/stdlib"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "io/ioutil"
+  "reflect"
+)
+
+// an interpreter for Go programs using LispEx as a scripting
+// engine. each has its own scopes: what the scripts of one define
+// is unknown to the others, and so are their macros and settings
+type Interp struct {
+  root  *scope.Scope
+  env   *scope.Scope
+  usage *scope.Usage
+  hook  func(forms, builtins map[string]int64)
+}
+
+// a new interpreter with the builtins and the standard library
+func NewInterp() (*Interp, error) {
+  root := scope.NewRootScope()
+  root.SetPrelude("stdlib.ss", stdlib.Source)
+  if _, err := repl.Run("stdlib.ss", stdlib.Source, root); err != nil {
+    return nil, err
+  }
+  root.Freeze()
+  return &Interp{root: root, env: repl.NewTopLevel(root)}, nil
+}
+
+// the value of the last form of source, nil if it has none. errors
+// are those of repl.Run
+func (self *Interp) Eval(source string) (value.Value, error) {
+  return self.run("<eval>", source)
+}
+
+// like Eval, source positions of errors refer to filename
+func (self *Interp) EvalFile(filename string) (value.Value, error) {
+  source, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return nil, err
+  }
+  return self.run(filename, string(source))
+}
+
+func (self *Interp) run(name, source string) (value.Value, error) {
+  if self.hook != nil {
+    defer self.reportUsage()
+  }
+  values, err := repl.Run(name, source, self.env)
+  if err != nil || len(values) == 0 {
+    return nil, err
+  }
+  return values[len(values)-1], nil
+}
+
+// hook is called after each Eval and EvalFile with how many times the
+// script evaluated wrote each special form and macro, and called each
+// builtin, e.g. forms["cond"] and builtins["string-append"]. nothing
+// is counted without a hook, nil removes it. the counts go nowhere else
+func (self *Interp) SetUsageHook(hook func(forms, builtins map[string]int64)) {
+  self.hook = hook
+  if hook == nil {
+    self.usage = nil
+  } else if self.usage == nil {
+    self.usage = scope.NewUsage()
+  }
+  self.root.SetUsage(self.usage)
+}
+
+func (self *Interp) reportUsage() {
+  forms, builtins := self.usage.Forms(), self.usage.Builtins()
+  self.usage.Reset()
+  self.hook(forms, builtins)
+}
+
+// bind name to v for the scripts: go functions are wrapped with
+// primitives.WrapGo, other values converted by converter.ToValue
+func (self *Interp) Define(name string, v interface{}) {
+  if v != nil && reflect.TypeOf(v).Kind() == reflect.Func {
+    self.env.Put(name, primitives.WrapGo(name, "", v))
+    return
+  }
+  self.env.Put(name, converter.ToValue(v))
+}
+
+// bind name to the go function fn, called by the scripts like a builtin:
+// its arguments and results are converted as primitives.WrapGo does,
+// numbers, strings, slices, maps and channels included
+func (self *Interp) Register(name string, fn interface{}) error {
+  if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+    return fmt.Errorf("%s: expected a function, given: %T", name, fn)
+  }
+  self.env.Put(name, primitives.WrapGo(name, "", fn))
+  return nil
+}
+
+// the value name is bound to, ok is false when it is unbound
+func (self *Interp) Lookup(name string) (val value.Value, ok bool) {
+  val, ok = self.env.Lookup(name).(value.Value)
+  return
+}
+
+// the top-level scope of the scripts, for the functions of the
+// other packages taking one, like repl.RegisterStruct
+func (self *Interp) Scope() *scope.Scope {
+  return self.env
+}
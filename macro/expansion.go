@@ -0,0 +1,172 @@
+package macro
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/constants"
+  "sync/atomic"
+)
+
+// numbers the variables renamed by expansions
+var renamed int64
+
+// a template being instantiated for the use of a macro at pos
+type expansion struct {
+  rules      *SyntaxRules
+  pos        string
+  expansions int
+  // the names put in by the template, as opposed to
+  // those coming from the forms of the use
+  introduced map[*ast.Name]bool
+}
+
+func (self *expansion) instantiate(template ast.Node, b bindings) ast.Node {
+  switch template.(type) {
+  case *ast.Name:
+    name := template.(*ast.Name)
+    if bound, ok := b[name.Identifier]; ok {
+      if bound.form == nil {
+        panic(fmt.Sprintf("%s: %s is followed by %s in the pattern but not in the template", self.rules.Keyword, name, self.rules.Ellipsis))
+      }
+      return bound.form
+    }
+    introduced := ast.NewName(name.Identifier)
+    introduced.Pos = self.pos
+    self.introduced[introduced] = true
+    return introduced
+  case *ast.Tuple:
+    elements := template.(*ast.Tuple).Elements
+    var expanded []ast.Node
+    for i := 0; i < len(elements); i++ {
+      if i+1 < len(elements) && self.rules.isEllipsis(elements[i+1]) {
+        expanded = append(expanded, self.repeat(elements[i], b)...)
+        i++
+        continue
+      }
+      expanded = append(expanded, self.instantiate(elements[i], b))
+    }
+    tuple := ast.NewTuple(spliceDot(expanded))
+    tuple.Pos = self.pos
+    tuple.Expansions = self.expansions
+    return tuple
+  }
+  return template
+}
+
+// the instances of a template followed by an ellipsis, one for each
+// repetition of the pattern variables it uses
+func (self *expansion) repeat(template ast.Node, b bindings) []ast.Node {
+  var names []string
+  n := -1
+  for _, name := range self.rules.variables(template) {
+    bound, ok := b[name]
+    if !ok || bound.form != nil {
+      continue
+    }
+    if n >= 0 && len(bound.repeats) != n {
+      panic(fmt.Sprintf("%s: the variables before %s in %s repeat different numbers of times", self.rules.Keyword, self.rules.Ellipsis, template))
+    }
+    n = len(bound.repeats)
+    names = append(names, name)
+  }
+  if n < 0 {
+    panic(fmt.Sprintf("%s: no pattern variable to repeat before %s in %s", self.rules.Keyword, self.rules.Ellipsis, template))
+  }
+  instances := make([]ast.Node, n)
+  for i := range instances {
+    inner := make(bindings, len(b))
+    for name, bound := range b {
+      inner[name] = bound
+    }
+    for _, name := range names {
+      inner[name] = b[name].repeats[i]
+    }
+    instances[i] = self.instantiate(template, inner)
+  }
+  return instances
+}
+
+// the variables bound by lambda and let forms of the template are
+// renamed, along with the names of the template referring to them,
+// so that they can't capture the variables of the forms of the use:
+// the tmp of (swap! tmp x) isn't the tmp the template of swap! binds
+func (self *expansion) rename(node ast.Node) ast.Node {
+  renames := make(map[string]string)
+  self.binders(node, renames)
+  if len(renames) == 0 {
+    return node
+  }
+  return self.replace(node, renames)
+}
+
+func (self *expansion) binders(node ast.Node, renames map[string]string) {
+  tuple, ok := node.(*ast.Tuple)
+  if !ok || len(tuple.Elements) == 0 {
+    return
+  }
+  elements := tuple.Elements
+  if head, ok := elements[0].(*ast.Name); ok && len(elements) > 1 {
+    switch head.Identifier {
+    case constants.QUOTE:
+      return
+    case constants.LAMBDA:
+      self.bind(elements[1], renames)
+    case constants.DEFINE:
+      // the formals of (define (f x) ...), f may be
+      // the name the use asks to define
+      if formals, ok := elements[1].(*ast.Tuple); ok && len(formals.Elements) > 0 {
+        self.bind(ast.NewTuple(formals.Elements[1:]), renames)
+      }
+    case constants.LET, constants.LET_STAR, constants.LET_REC:
+      if bindings, ok := elements[1].(*ast.Tuple); ok {
+        for _, binding := range bindings.Elements {
+          if pair, ok := binding.(*ast.Tuple); ok && len(pair.Elements) > 0 {
+            self.bind(pair.Elements[0], renames)
+          }
+        }
+      }
+    }
+  }
+  for _, element := range elements {
+    self.binders(element, renames)
+  }
+}
+
+// rename the names of formals, a name or a list of names,
+// which the template introduced
+func (self *expansion) bind(formals ast.Node, renames map[string]string) {
+  switch formals.(type) {
+  case *ast.Name:
+    name := formals.(*ast.Name)
+    if _, ok := renames[name.Identifier]; !ok && self.introduced[name] && name.Identifier != constants.DOT {
+      renames[name.Identifier] = fmt.Sprintf("%s.%d", name.Identifier, atomic.AddInt64(&renamed, 1))
+    }
+  case *ast.Tuple:
+    for _, formal := range formals.(*ast.Tuple).Elements {
+      self.bind(formal, renames)
+    }
+  }
+}
+
+func (self *expansion) replace(node ast.Node, renames map[string]string) ast.Node {
+  switch node.(type) {
+  case *ast.Name:
+    name := node.(*ast.Name)
+    if renamed, ok := renames[name.Identifier]; ok && self.introduced[name] {
+      replaced := ast.NewName(renamed)
+      replaced.Pos = name.Pos
+      return replaced
+    }
+  case *ast.Tuple:
+    elements := node.(*ast.Tuple).Elements
+    if len(elements) > 0 && isName(elements[0], constants.QUOTE) {
+      return node
+    }
+    for i, element := range elements {
+      if replaced := self.replace(element, renames); replaced != element {
+        elements[i] = replaced
+      }
+    }
+  }
+  return node
+}
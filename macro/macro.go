@@ -0,0 +1,16 @@
+package macro
+
+import (
+  "github.com/kedebug/LispEx/scope"
+)
+
+// macros are defined in the table of an interpreter, see scope.Macros
+func Define(macros *scope.Macros, rules *SyntaxRules) {
+  macros.Define(rules.Keyword, rules)
+}
+
+// the macro named keyword, nil if there is none
+func Lookup(macros *scope.Macros, keyword string) *SyntaxRules {
+  rules, _ := macros.Lookup(keyword).(*SyntaxRules)
+  return rules
+}
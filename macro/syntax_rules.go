@@ -0,0 +1,207 @@
+package macro
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/constants"
+)
+
+// a template expanding to a use of a macro counts as one more
+// expansion, past this many the macro is taken to expand forever
+const maxExpansions = 10000
+
+// (syntax-rules (literal ...) (pattern template) ...) as in R7RS. an
+// identifier before the literals stands for the ellipsis instead of ...
+type SyntaxRules struct {
+  Keyword  string
+  Ellipsis string
+  Literals map[string]bool
+  Rules    []*Rule
+}
+
+// the keyword at the start of the pattern is not matched
+type Rule struct {
+  Pattern  *ast.Tuple
+  Template ast.Node
+}
+
+func NewSyntaxRules(keyword string, spec ast.Node) *SyntaxRules {
+  tuple, ok := spec.(*ast.Tuple)
+  if !ok || len(tuple.Elements) < 2 || !isName(tuple.Elements[0], constants.SYNTAX_RULES) {
+    panic(fmt.Sprintf("%s: expected (syntax-rules (literal ...) (pattern template) ...), given: %s", keyword, spec))
+  }
+  self := &SyntaxRules{Keyword: keyword, Ellipsis: constants.ELLIPSIS, Literals: make(map[string]bool)}
+  elements := tuple.Elements[1:]
+  if name, ok := elements[0].(*ast.Name); ok {
+    self.Ellipsis = name.Identifier
+    elements = elements[1:]
+  }
+  if len(elements) == 0 {
+    panic(fmt.Sprintf("%s: syntax-rules: missing the list of literals", keyword))
+  }
+  literals, ok := elements[0].(*ast.Tuple)
+  if !ok {
+    panic(fmt.Sprintf("%s: syntax-rules: expected a list of literals, given: %s", keyword, elements[0]))
+  }
+  for _, literal := range literals.Elements {
+    name, ok := literal.(*ast.Name)
+    if !ok {
+      panic(fmt.Sprintf("%s: syntax-rules: literal is not an identifier: %s", keyword, literal))
+    }
+    self.Literals[name.Identifier] = true
+  }
+  // a literal ellipsis is matched like any other literal
+  if self.Literals[self.Ellipsis] {
+    self.Ellipsis = ""
+  }
+  for _, element := range elements[1:] {
+    clause, ok := element.(*ast.Tuple)
+    if !ok || len(clause.Elements) != 2 {
+      panic(fmt.Sprintf("%s: syntax-rules: expected (pattern template), given: %s", keyword, element))
+    }
+    pattern, ok := clause.Elements[0].(*ast.Tuple)
+    if !ok || len(pattern.Elements) == 0 {
+      panic(fmt.Sprintf("%s: syntax-rules: expected a pattern starting with the keyword, given: %s", keyword, clause.Elements[0]))
+    }
+    self.Rules = append(self.Rules, &Rule{pattern, clause.Elements[1]})
+  }
+  return self
+}
+
+// the form a use of the macro expands to, the template of the first rule
+// whose pattern matches the use with the pattern variables replaced by
+// what they matched
+func (self *SyntaxRules) Expand(form *ast.Tuple) ast.Node {
+  if form.Expansions >= maxExpansions {
+    panic(fmt.Sprintf("%s: expanded %d times, the macro may expand to itself forever", self.Keyword, form.Expansions))
+  }
+  for _, rule := range self.Rules {
+    b := make(bindings)
+    if self.matchList(rule.Pattern.Elements[1:], form.Elements[1:], b) {
+      e := &expansion{rules: self, pos: form.Pos, expansions: form.Expansions + 1, introduced: make(map[*ast.Name]bool)}
+      return e.rename(e.instantiate(rule.Template, b))
+    }
+  }
+  panic(fmt.Sprintf("%s: bad syntax, no rule matches %s", self.Keyword, form))
+}
+
+// a pattern variable is bound to the form it matched or, when the
+// pattern repeats it with an ellipsis, to a binding per repetition
+type binding struct {
+  form    ast.Node
+  repeats []*binding
+}
+
+type bindings map[string]*binding
+
+func (self *SyntaxRules) match(pattern, form ast.Node, b bindings) bool {
+  switch pattern.(type) {
+  case *ast.Name:
+    name := pattern.(*ast.Name).Identifier
+    switch {
+    case name == constants.UNDERSCORE:
+      return true
+    case self.Literals[name]:
+      return isName(form, name)
+    }
+    b[name] = &binding{form: form}
+    return true
+  case *ast.Tuple:
+    tuple, ok := form.(*ast.Tuple)
+    return ok && self.matchList(pattern.(*ast.Tuple).Elements, tuple.Elements, b)
+  }
+  // other data match equal data
+  return fmt.Sprintf("%T %s", pattern, pattern) == fmt.Sprintf("%T %s", form, form)
+}
+
+func (self *SyntaxRules) matchList(patterns, forms []ast.Node, b bindings) bool {
+  patterns, tail := splitDot(patterns)
+  forms, formTail := splitDot(forms)
+  for len(patterns) > 0 {
+    if len(patterns) > 1 && self.isEllipsis(patterns[1]) {
+      // the repetitions leave a form for each pattern after the ellipsis
+      n := len(forms) - (len(patterns) - 2)
+      if n < 0 || !self.matchRepeats(patterns[0], forms[:n], b) {
+        return false
+      }
+      patterns, forms = patterns[2:], forms[n:]
+      continue
+    }
+    if len(forms) == 0 || !self.match(patterns[0], forms[0], b) {
+      return false
+    }
+    patterns, forms = patterns[1:], forms[1:]
+  }
+  if tail == nil {
+    return len(forms) == 0 && formTail == nil
+  }
+  // (a . rest) binds rest to the forms left
+  if len(forms) == 0 && formTail != nil {
+    return self.match(tail, formTail, b)
+  }
+  if formTail != nil {
+    forms = append(append(forms[:len(forms):len(forms)], ast.NewName(constants.DOT)), formTail)
+  }
+  return self.match(tail, ast.NewTuple(forms), b)
+}
+
+func (self *SyntaxRules) matchRepeats(pattern ast.Node, forms []ast.Node, b bindings) bool {
+  repeats := make([]bindings, len(forms))
+  for i, form := range forms {
+    repeats[i] = make(bindings)
+    if !self.match(pattern, form, repeats[i]) {
+      return false
+    }
+  }
+  for _, name := range self.variables(pattern) {
+    bound := &binding{repeats: make([]*binding, len(forms))}
+    for i := range forms {
+      bound.repeats[i] = repeats[i][name]
+    }
+    b[name] = bound
+  }
+  return true
+}
+
+// the names in a pattern which may be pattern variables
+func (self *SyntaxRules) variables(pattern ast.Node) []string {
+  var names []string
+  switch pattern.(type) {
+  case *ast.Name:
+    name := pattern.(*ast.Name).Identifier
+    if name != constants.UNDERSCORE && name != constants.DOT && name != self.Ellipsis && !self.Literals[name] {
+      names = append(names, name)
+    }
+  case *ast.Tuple:
+    for _, element := range pattern.(*ast.Tuple).Elements {
+      names = append(names, self.variables(element)...)
+    }
+  }
+  return names
+}
+
+func (self *SyntaxRules) isEllipsis(node ast.Node) bool {
+  return self.Ellipsis != "" && isName(node, self.Ellipsis)
+}
+
+func isName(node ast.Node, identifier string) bool {
+  name, ok := node.(*ast.Name)
+  return ok && name.Identifier == identifier
+}
+
+// (a b . c) is split into (a b) and c
+func splitDot(nodes []ast.Node) ([]ast.Node, ast.Node) {
+  if n := len(nodes); n >= 2 && isName(nodes[n-2], constants.DOT) {
+    return nodes[:n-2], nodes[n-1]
+  }
+  return nodes, nil
+}
+
+// (a . (b c)) is written (a b c) and (a . ()) is (a)
+func spliceDot(nodes []ast.Node) []ast.Node {
+  elements, tail := splitDot(nodes)
+  if tuple, ok := tail.(*ast.Tuple); ok {
+    return append(elements, tuple.Elements...)
+  }
+  return nodes
+}
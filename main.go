@@ -2,37 +2,184 @@ package main
 
 import (
   "bufio"
+  "context"
+  "flag"
   "fmt"
+  "github.com/kedebug/LispEx/doc"
+  "github.com/kedebug/LispEx/learn"
+  "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/parser"
   "github.com/kedebug/LispEx/repl"
   "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/stdlib"
+  "github.com/kedebug/LispEx/typecheck"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "io"
   "io/ioutil"
   "os"
+  "strings"
   "time"
 )
 
 const version = "LispEx 0.1.0"
 
+// the standard library built into the binary,
+// or the file given with -stdlib
 func LoadStdlib() (string, error) {
-  lib, err := ioutil.ReadFile("stdlib.ss")
+  if *stdlibPath == "" {
+    return stdlib.Source, nil
+  }
+  lib, err := ioutil.ReadFile(*stdlibPath)
   if err != nil {
     return "", err
   }
   return string(lib), nil
 }
 
-func EvalFile(filename string) error {
+// a root scope with the builtins, bind adds to them before the
+// standard library is evaluated in it on its own, its errors
+// referring to its file rather than to the program's
+func NewRootScope(bind func(root *scope.Scope)) (*scope.Scope, error) {
   lib, err := LoadStdlib()
+  if err != nil {
+    return nil, err
+  }
+  root := scope.NewRootScope()
+  root.Contracts().Set(!*noContracts)
+  root.SetRemoteEnabled(*allowURLs)
+  root.SetFoldCase(*foldCase)
+  root.SetApplicableData(*applicableData)
+  if bind != nil {
+    bind(root)
+  }
+  name := "stdlib.ss"
+  if *stdlibPath != "" {
+    name = *stdlibPath
+  }
+  root.SetPrelude(name, lib)
+  if _, err := repl.Run(name, lib, root); err != nil {
+    return nil, err
+  }
+  root.Freeze()
+  return root, nil
+}
+
+// the program is evaluated after the standard library, in a scope
+// of its own, so its positions are those of filename and only the
+// values of its forms are printed with -print-toplevel. the program
+// and its goroutines stop at their next procedure call once ctx is done
+func EvalFile(ctx context.Context, filename string, args []string) error {
+  exprs, err := repl.ReadSource(filename, *allowURLs)
   if err != nil {
     return err
   }
-  exprs, err := ioutil.ReadFile(filename)
+  args = append([]string{filename}, args...)
+  root, err := NewRootScope(func(root *scope.Scope) {
+    root.Put("command-line", primitives.LookupBuiltin("command-line").With(primitives.NewCommandLine(args)))
+  })
   if err != nil {
     return err
   }
-  fmt.Println(repl.REPL(string(lib)+string(exprs), scope.NewRootScope()))
+  root.SetDisplayResults(*printToplevel)
+  // a context which is never done leaves the calls unchecked
+  if ctx.Done() != nil {
+    root.SetContext(ctx)
+    root.SetInterruptible(true)
+  }
+  return repl.RunPrinting(filename, string(exprs), repl.NewTopLevel(root), os.Stdout)
+}
+
+// print the reference of the builtins and the
+// standard library in Markdown, or HTML with --html
+func Doc(args []string) error {
+  flags := flag.NewFlagSet("doc", flag.ExitOnError)
+  html := flags.Bool("html", false, "write an HTML page instead of Markdown")
+  flags.Parse(args)
+  builtins, stdlib, err := reference()
+  if err != nil {
+    return err
+  }
+  if *html {
+    fmt.Print(doc.HTML(builtins, stdlib))
+  } else {
+    fmt.Print(doc.Markdown(builtins, stdlib))
+  }
   return nil
 }
 
+func reference() (builtins, stdlib []*doc.Entry, err error) {
+  lib, err := LoadStdlib()
+  if err != nil {
+    return nil, nil, err
+  }
+  stdlib, err = doc.Stdlib(lib)
+  return doc.Builtins(), stdlib, err
+}
+
+// run the tests of the files, each file in a fresh scope,
+// returns whether they all passed
+func Test(args []string) bool {
+  flags := flag.NewFlagSet("test", flag.ExitOnError)
+  timeout := flags.Duration("timeout", time.Second, "deadline of the tests without a #:timeout of their own")
+  flags.Parse(args)
+  passed, failed := 0, 0
+  for _, filename := range flags.Args() {
+    exprs, err := repl.ReadSource(filename, *allowURLs)
+    if err != nil {
+      fmt.Println(err)
+      return false
+    }
+    root, err := NewRootScope(nil)
+    if err != nil {
+      fmt.Println(repl.FormatError(err))
+      return false
+    }
+    results, err := repl.RunTests(filename, string(exprs), repl.NewTopLevel(root), *timeout)
+    for _, result := range results {
+      fmt.Println(result)
+      if result.Err == nil {
+        passed++
+      } else {
+        failed++
+      }
+    }
+    if err != nil {
+      fmt.Println(repl.FormatError(err))
+      failed++
+    }
+  }
+  fmt.Printf("%d passed, %d failed\n", passed, failed)
+  return failed == 0
+}
+
+// report type errors of an annotated program,
+// returns whether the program checks
+func Typecheck(filename string) bool {
+  exprs, err := ioutil.ReadFile(filename)
+  if err != nil {
+    fmt.Println(err)
+    return false
+  }
+  ok := false
+  try(
+    func() {
+      nodes, err := parser.Parse(lexer.NewFoldingLexer(filename, string(exprs), *foldCase))
+      if err != nil {
+        fmt.Println(err)
+        return
+      }
+      errors := typecheck.Check(nodes)
+      for _, err := range errors {
+        fmt.Printf("%s: %s\n", filename, err)
+      }
+      ok = len(errors) == 0
+    },
+    func(e interface{}) { fmt.Println(e) },
+  )
+  return ok
+}
+
 func try(body func(), handler func(interface{})) {
   defer func() {
     if err := recover(); err != nil {
@@ -42,36 +189,192 @@ func try(body func(), handler func(interface{})) {
   body()
 }
 
+var watch = flag.Bool("watch", false, "re-evaluate the file in a fresh scope whenever it changes")
+var printToplevel = flag.Bool("print-toplevel", false, "print the value of each top-level form of the file")
+var foldCase = flag.Bool("fold-case", false, "read identifiers case-insensitively, as if files started with #!fold-case")
+var echo = flag.Bool("echo", false, "print each form typed in the REPL again before its value")
+var waitGoroutines = flag.Bool("wait-goroutines", false, "wait for the goroutines started by the file to return before exiting")
+var stdlibPath = flag.String("stdlib", "", "load the standard library from the file instead of the one built in")
+var allowURLs = flag.Bool("allow-urls", false, "read the program and the files it loads from http and https URLs")
+var noContracts = flag.Bool("no-contracts", false, "skip the checks of define/contract")
+var applicableData = flag.Bool("applicable-data", false, "let (v i) index a vector and (s i) a string")
+
 func main() {
-  if len(os.Args) > 1 {
-    if err := EvalFile(os.Args[1]); err != nil {
+  flag.Parse()
+  args := flag.Args()
+
+  if len(args) > 0 && args[0] == "learn" {
+    lib, err := LoadStdlib()
+    if err != nil {
       fmt.Println(err)
+      return
     }
+    learn.Run(lib, os.Stdin, os.Stdout)
+    return
+  }
+  if len(args) > 0 && args[0] == "doc" {
+    if err := Doc(args[1:]); err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    return
+  }
+  if len(args) > 1 && args[0] == "test" {
+    if !Test(args[1:]) {
+      os.Exit(1)
+    }
+    return
+  }
+  if len(args) > 1 && args[0] == "typecheck" {
+    if !Typecheck(args[1]) {
+      os.Exit(1)
+    }
+    return
+  }
+  if len(args) > 0 && *watch {
+    eval := func(ctx context.Context) error { return EvalFile(ctx, args[0], args[1:]) }
+    repl.Watch(context.Background(), args[0], 500*time.Millisecond, eval, os.Stdout)
+    return
+  }
+  if len(args) > 0 {
+    // errors of the program, like deadlocks, are reported
+    // without the stack of the interpreter
+    try(
+      func() {
+        if err := EvalFile(context.Background(), args[0], args[1:]); err != nil {
+          fmt.Println(repl.FormatError(err))
+          os.Exit(1)
+        }
+      },
+      func(e interface{}) {
+        fmt.Println(e)
+        os.Exit(1)
+      },
+    )
+    if *waitGoroutines {
+      value.WaitGoroutines()
+    }
+    // goroutines left behind are stopped when the process exits
+    fmt.Fprint(os.Stderr, repl.GoroutineReport())
     return
   }
 
-  lib, err := LoadStdlib()
+  root, err := NewRootScope(nil)
   if err != nil {
-    fmt.Println(err)
+    fmt.Println(repl.FormatError(err))
     return
   }
-  env := scope.NewRootScope()
-  repl.REPL(lib, env)
+  env := repl.NewTopLevel(root)
   reader := bufio.NewReader(os.Stdin)
+  history := repl.NewHistory(env, 10)
+  undo := repl.NewUndo(env, 100)
+  input := &repl.Input{}
+  // values, errors and what the program prints
+  // also go to the transcript while recording
+  out := primitives.Output()
+  var transcript *repl.Transcript
+  // the inspector reads the lines typed while it runs
+  env.Put("inspect", primitives.LookupBuiltin("inspect").With(primitives.NewInspect(reader, out)))
 
   fmt.Printf("%s (%v)\n", version, time.Now().Format(time.RFC850))
 
   for {
-    fmt.Print(">>> ")
-    line, _, _ := reader.ReadLine()
+    if input.Empty() {
+      fmt.Print(">>> ")
+    } else {
+      fmt.Print("... " + strings.Repeat(" ", input.Indent()))
+    }
+    line, _, err := reader.ReadLine()
+    if err == io.EOF {
+      fmt.Println()
+      if transcript != nil {
+        transcript.Close()
+      }
+      return
+    }
+    command := strings.Fields(string(line))
+    if input.Empty() && len(command) == 2 && command[0] == ":doc" {
+      if transcript != nil {
+        transcript.Input(string(line))
+      }
+      builtins, stdlib, err := reference()
+      if entry := doc.Lookup(append(builtins, stdlib...), command[1]); err == nil && entry != nil {
+        fmt.Fprint(out, entry)
+      } else if val, ok := env.Lookup(command[1]).(value.Value); ok {
+        fmt.Fprint(out, primitives.DescribeValue(val))
+      } else {
+        fmt.Fprintln(out, "no documentation for", command[1])
+      }
+      continue
+    }
+    if input.Empty() && len(command) > 0 && (command[0] == ":undo" || command[0] == ":transcript") {
+      if transcript != nil {
+        transcript.Input(string(line))
+      }
+      switch {
+      case command[0] == ":undo":
+        changes := undo.Undo()
+        if len(changes) == 0 {
+          fmt.Fprintln(out, "nothing to undo")
+        }
+        for _, change := range changes {
+          fmt.Fprintln(out, "undone", change)
+        }
+      case transcript != nil:
+        // a new transcript or none, :transcript alone stops recording
+        primitives.SetOutput(nil)
+        transcript.Close()
+        fmt.Println("transcript written to", transcript.Filename)
+        transcript = nil
+      case len(command) == 1:
+        fmt.Println("not recording a transcript")
+      }
+      if command[0] == ":transcript" && len(command) > 1 {
+        t, err := repl.NewTranscript(command[1])
+        if err != nil {
+          fmt.Println(err)
+          continue
+        }
+        transcript = t
+        primitives.SetOutput(io.MultiWriter(os.Stdout, transcript))
+        fmt.Println("recording to", transcript.Filename)
+      }
+      continue
+    }
+    if !input.Add(string(line)) {
+      continue
+    }
+    source := input.Text()
+    input.Reset()
+    if transcript != nil && strings.TrimSpace(source) != "" {
+      transcript.Input(source)
+    }
+    if *echo {
+      fmt.Println(source)
+    }
+    // errors of the program are returned by Run,
+    // bugs of the interpreter still panic
     try(
       func() {
-        r := repl.REPL(string(line), env)
+        undo.Begin()
+        values, err := repl.Run("<REPL>", source, env)
+        if err != nil {
+          history.RecordError(err)
+          fmt.Fprintln(out, repl.FormatError(err))
+          return
+        }
+        for _, val := range values {
+          history.Record(val)
+        }
+        r := repl.Print(values)
         if len(r) > 0 {
-          fmt.Println(r)
+          fmt.Fprintln(out, r)
         }
       },
-      func(e interface{}) { fmt.Println(e) },
+      func(e interface{}) {
+        history.RecordError(e)
+        fmt.Fprintln(out, e)
+      },
     )
   }
 }
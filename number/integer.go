@@ -0,0 +1,169 @@
+package number
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "math"
+  "math/big"
+)
+
+// an operation on integers, exact unless one of the arguments is an
+// integral float. small is used when both are fixnums, ok being false
+// when the result overflows
+func integerOp(name string, x, y Value, small func(a, b int64) (int64, bool), large func(a, b *big.Int) *big.Int) Value {
+  checkIntegers(name, x, y)
+  if a, ok := x.(*IntValue); ok {
+    if b, ok := y.(*IntValue); ok {
+      if n, ok := small(a.Value, b.Value); ok {
+        return NewIntValue(n)
+      }
+    }
+  }
+  n := large(toBig(x), toBig(y))
+  if IsExact(x) && IsExact(y) {
+    return Integer(n)
+  }
+  f, _ := new(big.Float).SetInt(n).Float64()
+  return NewFloatValue(f)
+}
+
+func checkIntegers(name string, vals ...Value) {
+  for _, val := range vals {
+    if !IsInteger(val) {
+      panic(fmt.Sprintf("%s: expected integer, given: %s", name, val))
+    }
+  }
+}
+
+// quotient, remainder and modulo of R5RS: the quotient is truncated,
+// the remainder has the sign of the dividend and the modulo the sign
+// of the divisor
+func divide(name string, x, y Value, small func(a, b int64) int64, large func(q, r, b *big.Int) *big.Int) Value {
+  checkIntegers(name, x, y)
+  if isZero(y) {
+    panic(fmt.Sprintf("%s: undefined for 0", name))
+  }
+  return integerOp(name, x, y, func(a, b int64) (int64, bool) {
+    // the quotient of math.MinInt64 by -1 overflows
+    return small(a, b), b != -1
+  }, func(a, b *big.Int) *big.Int {
+    q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+    return large(q, r, b)
+  })
+}
+
+func Quotient(x, y Value) Value {
+  return divide("quotient", x, y, func(a, b int64) int64 { return a / b }, func(q, r, b *big.Int) *big.Int { return q })
+}
+
+func Remainder(x, y Value) Value {
+  return divide("remainder", x, y, func(a, b int64) int64 { return a % b }, func(q, r, b *big.Int) *big.Int { return r })
+}
+
+func Modulo(x, y Value) Value {
+  return divide("modulo", x, y, func(a, b int64) int64 {
+    m := a % b
+    if m != 0 && (m < 0) != (b < 0) {
+      m += b
+    }
+    return m
+  }, func(q, r, b *big.Int) *big.Int {
+    if r.Sign() != 0 && r.Sign() != b.Sign() {
+      r.Add(r, b)
+    }
+    return r
+  })
+}
+
+// never negative, (gcd 0 0) is 0
+func Gcd(x, y Value) Value {
+  return integerOp("gcd", x, y, func(a, b int64) (int64, bool) {
+    if a == math.MinInt64 || b == math.MinInt64 {
+      return 0, false
+    }
+    if a < 0 {
+      a = -a
+    }
+    if b < 0 {
+      b = -b
+    }
+    for b != 0 {
+      a, b = b, a%b
+    }
+    return a, true
+  }, func(a, b *big.Int) *big.Int {
+    return new(big.Int).GCD(nil, nil, a, b)
+  })
+}
+
+// never negative, 0 when one of them is
+func Lcm(x, y Value) Value {
+  return integerOp("lcm", x, y, func(a, b int64) (int64, bool) {
+    return 0, false
+  }, func(a, b *big.Int) *big.Int {
+    if a.Sign() == 0 || b.Sign() == 0 {
+      return new(big.Int)
+    }
+    n := new(big.Int).Mul(a, b)
+    n.Quo(n, new(big.Int).GCD(nil, nil, a, b))
+    return n.Abs(n)
+  })
+}
+
+// exact when base is exact and exponent an exact integer, (expt 2 100)
+// is 1267650600228229401496703205376 and (expt 2 -2) 1/4
+func Expt(base, exponent Value) Value {
+  if !IsExact(base) || !IsExact(exponent) || !IsInteger(exponent) {
+    return NewFloatValue(math.Pow(ToFloat(base), ToFloat(exponent)))
+  }
+  e, ok := exponent.(*IntValue)
+  if !ok {
+    panic(fmt.Sprint("expt: exponent too large: ", exponent))
+  }
+  if e.Value < 0 && isZero(base) {
+    panic(fmt.Sprint("expt: division by zero"))
+  }
+  n := new(big.Int).Abs(big.NewInt(e.Value))
+  r := toRat(base)
+  num, denom := new(big.Int).Exp(r.Num(), n, nil), new(big.Int).Exp(r.Denom(), n, nil)
+  if e.Value < 0 {
+    num, denom = denom, num
+  }
+  return Rational(new(big.Rat).SetFrac(num, denom))
+}
+
+func ExactToInexact(val Value) Value {
+  if IsExact(val) {
+    return NewFloatValue(ToFloat(val))
+  }
+  return val
+}
+
+// floats are exactly binary fractions: (inexact->exact 0.5) is 1/2
+func InexactToExact(val Value) Value {
+  f, ok := val.(*FloatValue)
+  if !ok {
+    return val
+  }
+  if math.IsInf(f.Value, 0) || math.IsNaN(f.Value) {
+    panic(fmt.Sprint("inexact->exact: no exact number for ", val))
+  }
+  return Rational(new(big.Rat).SetFloat64(f.Value))
+}
+
+// of the number in lowest terms, inexact for floats
+func Numerator(val Value) Value {
+  return fraction(val, (*big.Rat).Num)
+}
+
+func Denominator(val Value) Value {
+  return fraction(val, (*big.Rat).Denom)
+}
+
+func fraction(val Value, part func(*big.Rat) *big.Int) Value {
+  n := Integer(new(big.Int).Set(part(toRat(InexactToExact(val)))))
+  if IsExact(val) {
+    return n
+  }
+  return ExactToInexact(n)
+}
@@ -0,0 +1,270 @@
+package number
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "math"
+  "math/big"
+)
+
+// the numeric tower of R5RS: exact integers are IntValues while they
+// fit in an int64 and BigIntValues beyond, other exact numbers are
+// RatValues and inexact ones FloatValues. operations promote their
+// arguments to the type of the highest one and return the lowest type
+// holding the result: an integral RatValue, or a BigIntValue which
+// fits in an int64, never comes out of them
+const (
+  fixnum = iota
+  bignum
+  ratnum
+  flonum
+  notNumber
+)
+
+func rank(val Value) int {
+  switch val.(type) {
+  case *IntValue:
+    return fixnum
+  case *BigIntValue:
+    return bignum
+  case *RatValue:
+    return ratnum
+  case *FloatValue:
+    return flonum
+  }
+  return notNumber
+}
+
+// the higher rank of x and y
+func common(x, y Value) int {
+  if r := rank(y); r > rank(x) {
+    return r
+  }
+  return rank(x)
+}
+
+func IsNumber(val Value) bool {
+  return rank(val) != notNumber
+}
+
+func IsExact(val Value) bool {
+  return rank(val) < flonum
+}
+
+// integral floats such as 2.0 are integers as well
+func IsInteger(val Value) bool {
+  switch rank(val) {
+  case fixnum, bignum:
+    return true
+  case flonum:
+    f := val.(*FloatValue).Value
+    return !math.IsInf(f, 0) && f == math.Trunc(f)
+  }
+  return false
+}
+
+// every number but the infinities and NaN
+func IsRational(val Value) bool {
+  if f, ok := val.(*FloatValue); ok {
+    return !math.IsInf(f.Value, 0) && !math.IsNaN(f.Value)
+  }
+  return IsNumber(val)
+}
+
+// n as an IntValue when it fits in one
+func Integer(n *big.Int) Value {
+  if n.IsInt64() {
+    return NewIntValue(n.Int64())
+  }
+  return NewBigIntValue(n)
+}
+
+// r as an integer when its denominator is 1
+func Rational(r *big.Rat) Value {
+  if r.IsInt() {
+    return Integer(new(big.Int).Set(r.Num()))
+  }
+  return NewRatValue(r)
+}
+
+// the value of an exact integer or of an integral float, not
+// to be modified: it may be the one of a BigIntValue
+func toBig(val Value) *big.Int {
+  switch val.(type) {
+  case *IntValue:
+    return big.NewInt(val.(*IntValue).Value)
+  case *BigIntValue:
+    return val.(*BigIntValue).Value
+  }
+  n, _ := new(big.Float).SetFloat64(val.(*FloatValue).Value).Int(nil)
+  return n
+}
+
+// the value of an exact number or of a finite float, not to be
+// modified either
+func toRat(val Value) *big.Rat {
+  switch val.(type) {
+  case *IntValue:
+    return new(big.Rat).SetInt64(val.(*IntValue).Value)
+  case *BigIntValue:
+    return new(big.Rat).SetInt(val.(*BigIntValue).Value)
+  case *RatValue:
+    return val.(*RatValue).Value
+  }
+  return new(big.Rat).SetFloat64(val.(*FloatValue).Value)
+}
+
+// the float nearest to a number
+func ToFloat(val Value) float64 {
+  switch val.(type) {
+  case *IntValue:
+    return float64(val.(*IntValue).Value)
+  case *BigIntValue:
+    f, _ := new(big.Float).SetInt(val.(*BigIntValue).Value).Float64()
+    return f
+  case *RatValue:
+    f, _ := val.(*RatValue).Value.Float64()
+    return f
+  }
+  return val.(*FloatValue).Value
+}
+
+func isZero(val Value) bool {
+  switch val.(type) {
+  case *IntValue:
+    return val.(*IntValue).Value == 0
+  case *FloatValue:
+    return val.(*FloatValue).Value == 0
+  }
+  return false
+}
+
+// an operation at each level of the tower, fixnum being false when
+// the int64 result overflows
+type operation struct {
+  fixnum func(a, b int64) (int64, bool)
+  bignum func(z, a, b *big.Int) *big.Int
+  ratnum func(z, a, b *big.Rat) *big.Rat
+  flonum func(a, b float64) float64
+}
+
+func (self *operation) apply(x, y Value) Value {
+  switch common(x, y) {
+  case fixnum:
+    if n, ok := self.fixnum(x.(*IntValue).Value, y.(*IntValue).Value); ok {
+      return NewIntValue(n)
+    }
+    fallthrough
+  case bignum:
+    return Integer(self.bignum(new(big.Int), toBig(x), toBig(y)))
+  case ratnum:
+    return Rational(self.ratnum(new(big.Rat), toRat(x), toRat(y)))
+  }
+  return NewFloatValue(self.flonum(ToFloat(x), ToFloat(y)))
+}
+
+var addition = &operation{
+  func(a, b int64) (int64, bool) {
+    c := a + b
+    return c, (c > a) == (b > 0)
+  },
+  (*big.Int).Add,
+  (*big.Rat).Add,
+  func(a, b float64) float64 { return a + b },
+}
+
+var subtraction = &operation{
+  func(a, b int64) (int64, bool) {
+    c := a - b
+    return c, (c < a) == (b > 0)
+  },
+  (*big.Int).Sub,
+  (*big.Rat).Sub,
+  func(a, b float64) float64 { return a - b },
+}
+
+var multiplication = &operation{
+  func(a, b int64) (int64, bool) {
+    if a == 0 || b == 0 {
+      return 0, true
+    }
+    c := a * b
+    return c, c/b == a && !(a == math.MinInt64 && b == -1)
+  },
+  (*big.Int).Mul,
+  (*big.Rat).Mul,
+  func(a, b float64) float64 { return a * b },
+}
+
+func Add(x, y Value) Value {
+  return addition.apply(x, y)
+}
+
+func Sub(x, y Value) Value {
+  return subtraction.apply(x, y)
+}
+
+func Mul(x, y Value) Value {
+  return multiplication.apply(x, y)
+}
+
+// the quotient of exact numbers is exact, 1/3 rather than 0.333...
+// only exact division by zero raises an error, that of floats is
+// +inf.0, -inf.0 or +nan.0 as in IEEE 754
+func Div(x, y Value) Value {
+  kind := common(x, y)
+  if kind != flonum && isZero(y) {
+    panic(fmt.Sprint("`/' division by zero"))
+  }
+  switch kind {
+  case fixnum:
+    // the quotient of math.MinInt64 by -1 overflows
+    if a, b := x.(*IntValue).Value, y.(*IntValue).Value; b != -1 && a%b == 0 {
+      return NewIntValue(a / b)
+    }
+    fallthrough
+  case bignum, ratnum:
+    return Rational(new(big.Rat).Quo(toRat(x), toRat(y)))
+  }
+  return NewFloatValue(ToFloat(x) / ToFloat(y))
+}
+
+// -1, 0 or 1 as x is less than, equal to or greater than y. ok is
+// false when one of them is NaN, which compares to nothing. exact
+// numbers and finite floats are compared exactly
+func Compare(x, y Value) (result int, ok bool) {
+  if a, ok := x.(*IntValue); ok {
+    if b, ok := y.(*IntValue); ok {
+      switch {
+      case a.Value < b.Value:
+        return -1, true
+      case a.Value > b.Value:
+        return 1, true
+      }
+      return 0, true
+    }
+  }
+  fx, xfloat := x.(*FloatValue)
+  fy, yfloat := y.(*FloatValue)
+  switch {
+  case xfloat && yfloat:
+    return compareFloats(fx.Value, fy.Value)
+  case xfloat && (math.IsInf(fx.Value, 0) || math.IsNaN(fx.Value)):
+    return compareFloats(fx.Value, 0)
+  case yfloat && (math.IsInf(fy.Value, 0) || math.IsNaN(fy.Value)):
+    return compareFloats(0, fy.Value)
+  }
+  return toRat(x).Cmp(toRat(y)), true
+}
+
+func compareFloats(a, b float64) (int, bool) {
+  switch {
+  case a < b:
+    return -1, true
+  case a > b:
+    return 1, true
+  case a == b:
+    return 0, true
+  }
+  return 0, false
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/value"
+  "math/big"
+  "runtime"
+)
+
+// the node of the form a datum stands for, as if its printed form was
+// read, so that programs built by lisp code can be evaluated: the reverse
+// of ast.ToDatum. the datum may be a definition. returns an *Error when
+// the form is malformed or holds values without syntax, like procedures
+func FromDatum(datum value.Value) (node ast.Node, err error) {
+  defer func() {
+    if e := recover(); e != nil {
+      switch e.(type) {
+      case *Error:
+        node, err = nil, e.(error)
+      case runtime.Error:
+        panic(e)
+      default:
+        node, err = nil, &Error{Pos: "<datum>", Message: fmt.Sprint(e)}
+      }
+    }
+  }()
+  return ParseDefinition(elementOf(datum)), nil
+}
+
+// the element the reader would produce for the printed datum
+func elementOf(datum value.Value) ast.Node {
+  switch datum.(type) {
+  case *value.Symbol:
+    return ast.NewName(datum.(*value.Symbol).Value)
+  case *value.BoolValue:
+    if datum.(*value.BoolValue).Value {
+      return ast.NewName("#t")
+    }
+    return ast.NewName("#f")
+  case *value.IntValue:
+    return &ast.Int{Value: datum.(*value.IntValue).Value, Base: 10}
+  case *value.FloatValue:
+    return &ast.Float{Value: datum.(*value.FloatValue).Value}
+  case *value.BigIntValue:
+    return &ast.Rational{Value: new(big.Rat).SetInt(datum.(*value.BigIntValue).Value)}
+  case *value.RatValue:
+    return &ast.Rational{Value: datum.(*value.RatValue).Value}
+  case *value.StringValue:
+    return &ast.String{Value: datum.(*value.StringValue).Value}
+  case *value.CharValue:
+    return &ast.Char{Value: datum.(*value.CharValue).Value}
+  case *value.EmptyPairValue:
+    return ast.NewTuple(nil)
+  case *value.PairValue:
+    var elements []ast.Node
+    for {
+      pair, ok := datum.(*value.PairValue)
+      if !ok {
+        break
+      }
+      elements = append(elements, elementOf(pair.First))
+      datum = pair.Second
+    }
+    if datum != value.NilPairValue {
+      // (a . b)
+      elements = append(elements, ast.NewName(constants.DOT), elementOf(datum))
+    }
+    return ast.NewTuple(elements)
+  case *value.VectorValue:
+    items := datum.(*value.VectorValue).Value
+    elements := make([]ast.Node, len(items))
+    for i, item := range items {
+      elements[i] = elementOf(item)
+    }
+    return ast.NewVector(elements)
+  }
+  panic(fmt.Sprint("no syntax for ", datum))
+}
@@ -88,6 +88,10 @@ func ExpandList(nodes []ast.Node) ast.Node {
     }
     if !isdot {
       if dotted {
+        if _, ok := expanded.(*ast.UnquoteSplicing); ok {
+          // `(1 . ,@(2 3))
+          panic(fmt.Sprint("unquote-splicing: invalid context within quasiquote"))
+        }
         prev.Second = expanded
       } else {
         prev.Second = curr
@@ -0,0 +1,65 @@
+package parser
+
+import (
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/constants"
+)
+
+// the names of formals like x, (x y . z) or, for curried
+// definitions, ((f x) y)
+func formalNames(formals ast.Node) []string {
+  var names []string
+  switch formals.(type) {
+  case *ast.Name:
+    if name := formals.(*ast.Name).Identifier; name != constants.DOT {
+      names = append(names, name)
+    }
+  case *ast.Tuple:
+    for _, formal := range formals.(*ast.Tuple).Elements {
+      names = append(names, formalNames(formal)...)
+    }
+  }
+  return names
+}
+
+// the names the definitions among nodes define
+func definedNames(nodes []ast.Node) []string {
+  var names []string
+  for _, node := range nodes {
+    tuple, ok := node.(*ast.Tuple)
+    if !ok || len(tuple.Elements) < 2 {
+      continue
+    }
+    name, ok := tuple.Elements[0].(*ast.Name)
+    if !ok {
+      continue
+    }
+    switch name.Identifier {
+    case constants.DEFINE, constants.DEFINE_CONSTANT, constants.DEFINE_CONTRACT:
+      pattern := tuple.Elements[1]
+      // (define ((f x) y) ...) defines f
+      for {
+        signature, ok := pattern.(*ast.Tuple)
+        if !ok || len(signature.Elements) == 0 {
+          break
+        }
+        pattern = signature.Elements[0]
+      }
+      if name, ok := pattern.(*ast.Name); ok {
+        names = append(names, name.Identifier)
+      }
+    case constants.DEFINE_ENUM:
+      // its errors are raised when it is parsed
+      if name, ok := tuple.Elements[1].(*ast.Name); ok {
+        var members []*ast.Name
+        for _, element := range tuple.Elements[2:] {
+          if member, ok := element.(*ast.Name); ok {
+            members = append(members, member)
+          }
+        }
+        names = append(names, ast.NewDefineEnum(name, members).Names()...)
+      }
+    }
+  }
+  return names
+}
@@ -5,15 +5,104 @@ import (
   "github.com/kedebug/LispEx/ast"
   "github.com/kedebug/LispEx/constants"
   "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/macro"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "runtime"
 )
 
-func ParseFromString(name, program string) []ast.Node {
+// a syntax error in the form starting at Pos
+type Error struct {
+  Pos     string
+  Message string
+}
+
+func (e *Error) Error() string {
+  return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+func ParseFromString(name, program string) ([]ast.Node, error) {
   return Parse(lexer.NewLexer(name, program))
 }
 
-func Parse(l *lexer.Lexer) []ast.Node {
-  elements := PreParser(l, make([]ast.Node, 0), " ")
-  return ParseList(elements)
+func ReadFromString(name, program string) ([]ast.Node, error) {
+  return Read(lexer.NewLexer(name, program))
+}
+
+// returns a *lexer.Error or an *Error when the program is malformed
+func Parse(l *lexer.Lexer) ([]ast.Node, error) {
+  forms, err := Read(l)
+  if err != nil {
+    return nil, err
+  }
+  return Expand(l.Name(), forms)
+}
+
+// the forms of the program as written: lists are *ast.Tuple, macros
+// are not expanded and special forms not parsed yet. a formatter
+// needs no more
+func Read(l *lexer.Lexer) (forms []ast.Node, err error) {
+  defer l.Drain()
+  defer recoverError(l.Name(), &err)
+  return PreParser(l, make([]ast.Node, 0), " "), nil
+}
+
+// the program of the forms read from name, macros expanded and special
+// forms parsed into their nodes, which the evaluator runs. the macros
+// defined among them are only known to the program
+func Expand(name string, forms []ast.Node) (nodes []ast.Node, err error) {
+  return ExpandAt(name, forms, &ast.SyntaxEnv{Macros: scope.NewMacros()})
+}
+
+// like Expand, at the top level of an interpreter made by
+// ast.NewTopSyntaxEnv: definitions of macros among the forms
+// take effect there
+func ExpandAt(name string, forms []ast.Node, top *ast.SyntaxEnv) (nodes []ast.Node, err error) {
+  defer recoverError(name, &err)
+  enclose(forms, top)
+  nodes = ParseBody(forms)
+  for _, name := range definedNames(forms) {
+    top.Macros.Undefine(name)
+  }
+  return nodes, nil
+}
+
+func recoverError(name string, err *error) {
+  if e := recover(); e != nil {
+    switch e.(type) {
+    case *lexer.Error, *Error:
+      *err = e.(error)
+    case runtime.Error:
+      panic(e)
+    default:
+      *err = &Error{Pos: name, Message: fmt.Sprint(e)}
+    }
+  }
+}
+
+// like ParseFromString but panics with the error wrapped
+// in a value.SyntaxError, for the REPL which recovers anyway
+func MustParseFromString(name, program string) []ast.Node {
+  nodes, err := ParseFromString(name, program)
+  if err != nil {
+    panic(&value.SyntaxError{Err: err})
+  }
+  return nodes
+}
+
+// turn a panic raised while parsing the form at pos into an *Error,
+// errors of inner forms already carry their own position
+func locate(pos string) {
+  if e := recover(); e != nil {
+    switch e.(type) {
+    case *lexer.Error, *Error, runtime.Error:
+      panic(e)
+    }
+    if pos == "" {
+      panic(e)
+    }
+    panic(&Error{Pos: pos, Message: fmt.Sprint(e)})
+  }
 }
 
 func ParseNode(node ast.Node) ast.Node {
@@ -21,6 +110,7 @@ func ParseNode(node ast.Node) ast.Node {
   if !ok {
     return node
   }
+  defer locate(tuple.Pos)
   elements := tuple.Elements
   if len(elements) == 0 {
     panic(fmt.Errorf("syntax error, empty list"))
@@ -28,55 +118,29 @@ func ParseNode(node ast.Node) ast.Node {
   switch elements[0].(type) {
   case *ast.Name:
     name := elements[0].(*ast.Name)
-    switch name.Identifier {
-    case constants.DEFINE:
-      return ParseDefine(tuple)
-    case constants.BEGIN:
-      return ParseBegin(tuple)
-    case constants.LAMBDA:
-      return ParseLambda(tuple)
-    case constants.LET:
-      fallthrough
-    case constants.LET_STAR:
-      fallthrough
-    case constants.LET_REC:
-      return ParseLetFamily(tuple)
-    case constants.GO:
-      return ParseGo(tuple)
-    case constants.SELECT:
-      return ParseSelect(tuple)
-    case constants.IF:
-      return ParseIf(tuple)
-    case constants.SET:
-      return ParseSet(tuple)
-    case constants.APPLY:
-      return ParseApply(tuple)
-    case constants.QUOTE:
-      return ParseQuote(tuple)
-    case constants.QUASIQUOTE:
-      // parse "unquote" and "unquote-splicing" in "quasiquote"
-      // so they never go through ParseNode
-      return ParseQuasiquote(tuple, 1)
-    case constants.UNQUOTE:
-      panic(fmt.Sprint("unquote: not in quasiquote"))
-    case constants.UNQUOTE_SPLICING:
-      panic(fmt.Sprint("unquote-splicing: not in quasiquote"))
-    case constants.DELAY:
-      return ParseDelay(tuple)
-    case constants.FORCE:
-      return ParseForce(tuple)
-    default:
-      return ParseCall(tuple)
+    switch Denote(tuple.Syntax, name.Identifier) {
+    case CoreForm:
+      if parse, ok := coreForms[name.Identifier]; ok {
+        return parse(tuple)
+      }
+      panic(fmt.Sprintf("%s: not allowed in an expression context, given: %s", name, tuple))
+    case Macro:
+      return ParseNode(expand(tuple, name))
     }
+    return ParseCall(tuple)
   case *ast.Tuple:
     //(1). currying
     //  ((foo <arguments>) <arguments>)
     //(2). lambda
     //  ((lambda <formals> <body>) <arguments>)
     return ParseCall(tuple)
-  default:
-    panic(fmt.Sprintf("%s: not a procedure", tuple))
+  case *ast.String, *ast.Vector:
+    // ("abc" 0) indexes the string when data is applicable
+    if top := tuple.Syntax.Top(); top != nil && top.ApplicableData {
+      return ParseCall(tuple)
+    }
   }
+  panic(fmt.Sprintf("%s: not a procedure", tuple))
 }
 
 func ParseList(nodes []ast.Node) []ast.Node {
@@ -87,6 +151,60 @@ func ParseList(nodes []ast.Node) []ast.Node {
   return parsed
 }
 
+// the top level and bodies of procedures and let forms are definition
+// contexts: definitions may appear there, or in a begin form there whose
+// definitions are spliced into the enclosing scope, but nowhere else
+func ParseBody(nodes []ast.Node) []ast.Node {
+  bindVariables(definedNames(nodes), nodes)
+  var parsed []ast.Node
+  for _, node := range nodes {
+    parsed = append(parsed, ParseDefinition(node))
+  }
+  return parsed
+}
+
+func ParseDefinition(node ast.Node) ast.Node {
+  tuple, ok := node.(*ast.Tuple)
+  if !ok || len(tuple.Elements) == 0 {
+    return ParseNode(node)
+  }
+  name, ok := tuple.Elements[0].(*ast.Name)
+  if !ok {
+    return ParseNode(node)
+  }
+  defer locate(tuple.Pos)
+  switch Denote(tuple.Syntax, name.Identifier) {
+  case CoreForm:
+    if parse, ok := definitionForms[name.Identifier]; ok {
+      return parse(tuple)
+    }
+  case Macro:
+    // a macro may expand to definitions
+    return ParseDefinition(expand(tuple, name))
+  }
+  return ParseNode(node)
+}
+
+func ParseDefineSyntax(tuple *ast.Tuple) *ast.DefineSyntax {
+  // (define-syntax <keyword> (syntax-rules (<literal> ...) (<pattern> <template>) ...))
+
+  elements := tuple.Elements
+  if len(elements) != 3 {
+    panic(fmt.Sprint("define-syntax: bad syntax, expected a keyword and syntax-rules"))
+  }
+  name, ok := elements[1].(*ast.Name)
+  if !ok {
+    panic(fmt.Sprint("define-syntax: expected an identifier, given: ", elements[1]))
+  }
+  rules, _ := elements[2].(*ast.Tuple)
+  if top := tuple.Syntax.Top(); top != nil && top.Macros != nil {
+    macro.Define(top.Macros, macro.NewSyntaxRules(name.Identifier, elements[2]))
+  }
+  define := ast.NewDefineSyntax(name.Identifier, rules)
+  define.Pos = tuple.Pos
+  return define
+}
+
 func ParseBlock(tuple *ast.Tuple) *ast.Block {
   elements := tuple.Elements
   exprs := ParseList(elements)
@@ -108,8 +226,21 @@ func ParseGo(tuple *ast.Tuple) *ast.Go {
   if len(elements) != 2 {
     panic(fmt.Sprint("go: bad syntax, only expected 1 expression"))
   }
-  expr := ParseNode(elements[1])
-  return ast.NewGo(expr)
+  goroutine := ast.NewGo(ParseNode(elements[1]))
+  goroutine.Pos = tuple.Pos
+  return goroutine
+}
+
+func ParseNursery(tuple *ast.Tuple) *ast.Nursery {
+  // (nursery <expression1> <expression2> ...)
+
+  elements := tuple.Elements
+  if len(elements) < 2 {
+    panic(fmt.Sprint("nursery: bad syntax, no expression in body"))
+  }
+  nursery := ast.NewNursery(ast.NewBlock(ParseBody(elements[1:])))
+  nursery.Pos = tuple.Pos
+  return nursery
 }
 
 func ParseApply(tuple *ast.Tuple) *ast.Apply {
@@ -122,50 +253,117 @@ func ParseApply(tuple *ast.Tuple) *ast.Apply {
   }
   proc := ParseNode(elements[1])
   args := ParseList(elements[2:])
-  return ast.NewApply(proc, args)
+  apply := ast.NewApply(proc, args)
+  apply.Pos = tuple.Pos
+  return apply
 }
 
 func ParseSelect(tuple *ast.Tuple) *ast.Select {
   // (select <clause1> <clause2> ...)
+  // (priority-select <clause1> <clause2> ...)
   //  <clause> = (<case> <expression1> <expression2>)
-  //    <case> = (<chan-send> | <chan-recv> | <default>)
+  //    <case> = (<chan-send> | <chan-recv> | (<variable> <chan-recv>) | <default>)
 
   elements := tuple.Elements
+  form := elements[0].(*ast.Name).Identifier
   if len(elements) < 2 {
-    panic(fmt.Sprint("select: bad syntax (missing clauses), expected at least 1"))
+    panic(fmt.Sprintf("%s: bad syntax (missing clauses), expected at least 1", form))
   }
   elements = elements[1:]
   clauses := make([][]ast.Node, len(elements))
+  receivers := make([]*ast.Name, len(elements))
+  defaults := 0
   for i, clause := range elements {
-    if _, ok := clause.(*ast.Tuple); ok {
-      exprs := clause.(*ast.Tuple).Elements
-      if len(exprs) == 0 {
-        panic(fmt.Sprint("select: bad syntax (missing select cases), given: ()"))
-      }
-      clauses[i] = ParseList(exprs)
-      if call, ok := clauses[i][0].(*ast.Call); ok {
-        if name, ok := call.Callee.(*ast.Name); ok {
-          if name.Identifier == constants.CHAN_SEND {
-            if len(call.Args) != 2 {
-              panic(fmt.Sprintf("%s: arguments mismatch, expected 2", constants.CHAN_SEND))
-            }
-            continue
-          } else if name.Identifier == constants.CHAN_RECV {
-            if len(call.Args) != 1 {
-              panic(fmt.Sprintf("%s: arguments mismatch, expected 1", constants.CHAN_SEND))
-            }
-            continue
-          }
-        }
-      } else if name, ok := clauses[i][0].(*ast.Name); ok {
-        if name.Identifier == constants.DEFAULT {
-          continue
+    clauses[i], receivers[i] = parseSelectClause(form, i+1, clause)
+    if name, ok := clauses[i][0].(*ast.Name); ok && name.Identifier == constants.DEFAULT {
+      defaults++
+      if defaults > 1 {
+        message := fmt.Sprintf("%s: bad clause %d: %s, more than one default clause", form, i+1, clause)
+        if pos := clause.(*ast.Tuple).Pos; pos != "" {
+          panic(&Error{Pos: pos, Message: message})
         }
+        panic(message)
       }
     }
-    panic(fmt.Sprint("select: bad syntax, given: ", clause))
   }
-  return ast.NewSelect(clauses)
+  selection := ast.NewSelect(clauses)
+  for _, receiver := range receivers {
+    if receiver != nil {
+      selection.Receivers = receivers
+      break
+    }
+  }
+  selection.Priority = form == constants.PRIORITY_SELECT
+  selection.Pos = tuple.Pos
+  return selection
+}
+
+// the shapes a select case may take, for error messages
+const selectCases = "(<-chan ch), (chan<- ch value), (var (<-chan ch)) or default"
+
+// parse the n-th clause of a select, the clause of a receive binding
+// (var (<-chan ch)) becomes ((<-chan ch) (lambda (var) body...))
+func parseSelectClause(form string, n int, clause ast.Node) ([]ast.Node, *ast.Name) {
+  tuple, ok := clause.(*ast.Tuple)
+  if !ok || len(tuple.Elements) == 0 {
+    panic(fmt.Sprintf("%s: bad clause %d: %s, expected (<case> expr ...) where <case> is %s", form, n, clause, selectCases))
+  }
+  defer locate(tuple.Pos)
+  exprs := tuple.Elements
+  bad := func(format string, args ...interface{}) {
+    panic(fmt.Sprintf("%s: bad clause %d: %s, ", form, n, clause) + fmt.Sprintf(format, args...))
+  }
+
+  switch exprs[0].(type) {
+  case *ast.Name:
+    if exprs[0].(*ast.Name).Identifier != constants.DEFAULT {
+      bad("expected <case> to be %s", selectCases)
+    }
+    return ParseList(exprs), nil
+  case *ast.Tuple:
+    test := exprs[0].(*ast.Tuple).Elements
+    if len(test) == 0 {
+      bad("expected <case> to be %s", selectCases)
+    }
+    if receiver, ok := test[0].(*ast.Name); ok && len(test) == 2 && isCase(test[1], constants.CHAN_RECV) {
+      recv := test[1].(*ast.Tuple)
+      if len(recv.Elements) != 2 {
+        bad("%s expects (%s ch), given: %s", constants.CHAN_RECV, constants.CHAN_RECV, recv)
+      }
+      if len(exprs) == 1 {
+        bad("expected a body using %s", receiver)
+      }
+      params := ast.NewTuple([]ast.Node{receiver})
+      lambda := ParseLambda(ast.NewTuple(append([]ast.Node{ast.NewName(constants.LAMBDA), params}, exprs[1:]...)))
+      return []ast.Node{ParseNode(recv), lambda}, receiver
+    }
+    switch {
+    case isCase(exprs[0], constants.CHAN_SEND):
+      if len(test) != 3 {
+        bad("%s expects (%s ch value), given: %s", constants.CHAN_SEND, constants.CHAN_SEND, exprs[0])
+      }
+    case isCase(exprs[0], constants.CHAN_RECV):
+      if len(test) != 2 {
+        bad("%s expects (%s ch), given: %s", constants.CHAN_RECV, constants.CHAN_RECV, exprs[0])
+      }
+    case len(test) == 2 && isCase(test[1], constants.CHAN_RECV):
+      bad("a receive binding expects (var (%s ch)), given: %s", constants.CHAN_RECV, exprs[0])
+    default:
+      bad("expected <case> to be %s", selectCases)
+    }
+    return ParseList(exprs), nil
+  }
+  bad("expected <case> to be %s", selectCases)
+  return nil, nil
+}
+
+// whether node is a form headed by the name keyword
+func isCase(node ast.Node, keyword string) bool {
+  if tuple, ok := node.(*ast.Tuple); ok && len(tuple.Elements) > 0 {
+    name, ok := tuple.Elements[0].(*ast.Name)
+    return ok && name.Identifier == keyword
+  }
+  return false
 }
 
 func ParseLetFamily(tuple *ast.Tuple) ast.Node {
@@ -179,11 +377,26 @@ func ParseLetFamily(tuple *ast.Tuple) ast.Node {
     panic(fmt.Sprintf("%s: bad syntax, no expression in body", elements[0]))
   }
 
+  if name, ok := elements[1].(*ast.Name); ok && elements[0].(*ast.Name).Identifier == constants.LET {
+    return parseNamedLet(tuple, name)
+  }
   if _, ok := elements[1].(*ast.Tuple); !ok {
     panic(fmt.Sprintf("%s: bad syntax, expected bindings, given: %s", elements[0], elements[1]))
   }
 
   bindings := elements[1].(*ast.Tuple).Elements
+  var names []string
+  for _, binding := range bindings {
+    if tuple, ok := binding.(*ast.Tuple); ok && len(tuple.Elements) > 0 {
+      names = append(names, formalNames(tuple.Elements[0])...)
+    }
+  }
+  if elements[0].(*ast.Name).Identifier == constants.LET {
+    bindVariables(names, elements[2:])
+  } else {
+    // the inits of let* and letrec see the bindings too
+    bindVariables(names, elements[1:])
+  }
   patterns := make([]*ast.Name, len(bindings))
   exprs := make([]ast.Node, len(bindings))
   for i, binding := range bindings {
@@ -192,6 +405,7 @@ func ParseLetFamily(tuple *ast.Tuple) ast.Node {
         if name, ok := tuple.Elements[0].(*ast.Name); ok {
           patterns[i] = name
           exprs[i] = ParseNode(tuple.Elements[1])
+          nameLambda(exprs[i], name.Identifier)
           continue
         }
       }
@@ -199,7 +413,7 @@ func ParseLetFamily(tuple *ast.Tuple) ast.Node {
     panic(fmt.Sprintf("%s: bad syntax, not an identifer and expression for a binding %s", elements[0], binding))
   }
 
-  body := ast.NewBlock(ParseList(elements[2:]))
+  body := ast.NewBlock(ParseBody(elements[2:]))
   name, _ := elements[0].(*ast.Name)
   switch name.Identifier {
   case constants.LET:
@@ -213,6 +427,138 @@ func ParseLetFamily(tuple *ast.Tuple) ast.Node {
   }
 }
 
+// (let name ((var init) ...) body...) is
+// ((letrec ((name (lambda (var ...) body...))) name) init ...)
+func parseNamedLet(tuple *ast.Tuple, name *ast.Name) ast.Node {
+  elements := tuple.Elements
+  if len(elements) < 4 {
+    panic(fmt.Sprintf("%s: bad syntax, no expression in body", elements[0]))
+  }
+  bindings, ok := elements[2].(*ast.Tuple)
+  if !ok {
+    panic(fmt.Sprintf("%s: bad syntax, expected bindings, given: %s", elements[0], elements[2]))
+  }
+  var vars, inits []ast.Node
+  for _, binding := range bindings.Elements {
+    if tuple, ok := binding.(*ast.Tuple); ok && len(tuple.Elements) == 2 {
+      if _, ok := tuple.Elements[0].(*ast.Name); ok {
+        vars = append(vars, tuple.Elements[0])
+        inits = append(inits, tuple.Elements[1])
+        continue
+      }
+    }
+    panic(fmt.Sprintf("%s: bad syntax, not an identifer and expression for a binding %s", elements[0], binding))
+  }
+  lambda := ast.NewTuple(append([]ast.Node{ast.NewName(constants.LAMBDA), ast.NewTuple(vars)}, elements[3:]...))
+  binding := ast.NewTuple([]ast.Node{name, lambda})
+  letrec := ast.NewTuple([]ast.Node{ast.NewName(constants.LET_REC), ast.NewTuple([]ast.Node{binding}), name})
+  call := ast.NewTuple(append([]ast.Node{letrec}, inits...))
+  call.Pos = tuple.Pos
+  return ParseNode(call)
+}
+
+func ParseLetValues(tuple *ast.Tuple) *ast.LetValues {
+  // (let-values ((<formals1> <init1>) ...) <body>)
+  //  each <formals> is like those of lambda
+
+  elements := tuple.Elements
+  keyword := elements[0].(*ast.Name).Identifier
+  if len(elements) < 3 {
+    panic(fmt.Sprintf("%s: bad syntax, no expression in body", keyword))
+  }
+  bindings, ok := elements[1].(*ast.Tuple)
+  if !ok {
+    panic(fmt.Sprintf("%s: bad syntax, expected bindings, given: %s", keyword, elements[1]))
+  }
+  var names []string
+  for _, binding := range bindings.Elements {
+    if tuple, ok := binding.(*ast.Tuple); ok && len(tuple.Elements) == 2 {
+      names = append(names, formalNames(tuple.Elements[0])...)
+      continue
+    }
+    panic(fmt.Sprintf("%s: bad syntax, expected (formals init), given: %s", keyword, binding))
+  }
+  sequential := keyword == constants.LET_STAR_VALUES
+  if sequential {
+    bindVariables(names, elements[1:])
+  } else {
+    bindVariables(names, elements[2:])
+  }
+  formals := make([]ast.Node, len(bindings.Elements))
+  exprs := make([]ast.Node, len(bindings.Elements))
+  for i, binding := range bindings.Elements {
+    parts := binding.(*ast.Tuple).Elements
+    switch parts[0].(type) {
+    case *ast.Name:
+      formals[i] = parts[0]
+    case *ast.Tuple:
+      formals[i] = ExpandFormals(parts[0].(*ast.Tuple).Elements)
+      if _, ok := formals[i].(*ast.Name); ok {
+        panic(fmt.Sprintf("%s: illegal use of `.'", keyword))
+      }
+    default:
+      panic(fmt.Sprintf("%s: bad syntax, expected formals, given: %s", keyword, parts[0]))
+    }
+    exprs[i] = ParseNode(parts[1])
+  }
+  body := ast.NewBlock(ParseBody(elements[2:]))
+  letValues := ast.NewLetValues(formals, exprs, body, sequential)
+  letValues.Pos = tuple.Pos
+  return letValues
+}
+
+func ParseDo(tuple *ast.Tuple) *ast.Do {
+  // (do ((<variable1> <init1> <step1>) ...) (<test> <expression> ...) <command> ...)
+  //  a <step> may be omitted
+
+  elements := tuple.Elements
+  if len(elements) < 3 {
+    panic(fmt.Sprintf("%s: bad syntax, expected bindings and a test, given: %s", constants.DO, tuple))
+  }
+  specs, ok := elements[1].(*ast.Tuple)
+  if !ok {
+    panic(fmt.Sprintf("%s: bad syntax, expected bindings, given: %s", constants.DO, elements[1]))
+  }
+  exit, ok := elements[2].(*ast.Tuple)
+  if !ok || len(exit.Elements) == 0 {
+    panic(fmt.Sprintf("%s: bad syntax, expected (test expression ...), given: %s", constants.DO, elements[2]))
+  }
+  vars := make([]*ast.Name, len(specs.Elements))
+  inits := make([]ast.Node, len(specs.Elements))
+  steps := make([]ast.Node, len(specs.Elements))
+  var names []string
+  var scoped []ast.Node
+  for i, spec := range specs.Elements {
+    if tuple, ok := spec.(*ast.Tuple); ok && (len(tuple.Elements) == 2 || len(tuple.Elements) == 3) {
+      if name, ok := tuple.Elements[0].(*ast.Name); ok {
+        vars[i] = name
+        names = append(names, name.Identifier)
+        scoped = append(scoped, tuple.Elements[2:]...)
+        continue
+      }
+    }
+    panic(fmt.Sprintf("%s: bad syntax, expected (variable init [step]), given: %s", constants.DO, spec))
+  }
+  // the steps, the test and the body see the variables, the inits don't
+  bindVariables(names, append(scoped, elements[2:]...))
+  for i, spec := range specs.Elements {
+    parts := spec.(*ast.Tuple).Elements
+    inits[i] = ParseNode(parts[1])
+    if len(parts) == 3 {
+      steps[i] = ParseNode(parts[2])
+    }
+  }
+  test := ParseNode(exit.Elements[0])
+  var result, body ast.Node
+  if len(exit.Elements) > 1 {
+    result = ast.NewBlock(ParseList(exit.Elements[1:]))
+  }
+  if len(elements) > 3 {
+    body = ast.NewBlock(ParseList(elements[3:]))
+  }
+  return ast.NewDo(vars, inits, steps, test, result, body)
+}
+
 func ParseQuote(tuple *ast.Tuple) *ast.Quote {
   // (quote <datum>)
   // '<datum>
@@ -259,15 +605,46 @@ func ParseQuasiquote(tuple *ast.Tuple, level int) ast.Node {
     } else {
       if tuple1, ok := node.(*ast.Tuple); ok {
         return ast.NewQuasiquote(ExpandList(tuple1.Elements))
+      } else if _, ok := node.(*ast.UnquoteSplicing); ok {
+        // `,@(1 2)
+        panic(fmt.Sprint("unquote-splicing: invalid context within quasiquote"))
       } else {
         return ast.NewQuasiquote(node)
       }
     }
+  case *ast.Vector:
+    // `#(1 ,x ,@y)
+    node := parseVectorTemplate(elements[1].(*ast.Vector), level)
+    if level > 1 {
+      elements[1] = node
+      return tuple
+    }
+    return ast.NewQuasiquote(node)
   default:
     return ast.NewQuasiquote(elements[1])
   }
 }
 
+// the elements of a vector within a quasiquote are a template like
+// those of a list, evaluated to a list turned into the vector
+func parseVectorTemplate(vector *ast.Vector, level int) ast.Node {
+  return ast.NewVectorTemplate(ExpandList(parseTemplateElements(vector.Elements, level)))
+}
+
+func parseTemplateElements(elements []ast.Node, level int) []ast.Node {
+  slice := make([]ast.Node, 0, len(elements))
+  for _, node := range elements {
+    switch node.(type) {
+    case *ast.Tuple:
+      node = ParseNestedQuasiquote(node.(*ast.Tuple), level)
+    case *ast.Vector:
+      node = parseVectorTemplate(node.(*ast.Vector), level)
+    }
+    slice = append(slice, node)
+  }
+  return slice
+}
+
 func ParseNestedQuasiquote(tuple *ast.Tuple, level int) ast.Node {
   // tuple can be:
   //  (unquote <datum>)
@@ -291,14 +668,7 @@ func ParseNestedQuasiquote(tuple *ast.Tuple, level int) ast.Node {
       return ParseQuasiquote(tuple, level+1)
     }
   }
-  slice := make([]ast.Node, 0, len(elements))
-  for _, node := range elements {
-    if _, ok := node.(*ast.Tuple); ok {
-      node = ParseNestedQuasiquote(node.(*ast.Tuple), level)
-    }
-    slice = append(slice, node)
-  }
-  return ast.NewTuple(slice)
+  return ast.NewTuple(parseTemplateElements(elements, level))
 }
 
 func ParseUnquote(tuple *ast.Tuple, level int) ast.Node {
@@ -345,20 +715,117 @@ func ParseDefine(tuple *ast.Tuple) *ast.Define {
     }
     pattern := elements[1].(*ast.Name)
     value := ParseNode(elements[2])
-    return ast.NewDefine(pattern, value)
+    nameLambda(value, pattern.Identifier)
+    define := ast.NewDefine(pattern, value)
+    define.Pos = tuple.Pos
+    return define
 
   case *ast.Tuple:
     // (define (<variable> <formals>) <body>)
     // (define (<variable> . <formal>) <body>)
-    tail := ast.NewBlock(ParseList(elements[2:]))
+    bindVariables(formalNames(elements[1]), elements[2:])
+    tail := ast.NewBlock(ParseBody(elements[2:]))
     function := ParseFunction(elements[1].(*ast.Tuple), tail)
-    return ast.NewDefine(function.Caller, function)
+    for lambda, ok := function.Body.(*ast.Lambda); ok; lambda, ok = lambda.Body.(*ast.Lambda) {
+      lambda.Pos = tuple.Pos
+    }
+    define := ast.NewDefine(function.Caller, function)
+    define.Pos = tuple.Pos
+    return define
 
   default:
     panic(fmt.Sprint("unsupported parser type ", elements[1]))
   }
 }
 
+func ParseDefineConstant(tuple *ast.Tuple) *ast.Define {
+  // (define-constant <variable> <expression>)
+  // (define-constant (<variable> <formals>) <body>)
+
+  define := ParseDefine(tuple)
+  define.Constant = true
+  return define
+}
+
+func ParseDefineContract(tuple *ast.Tuple) *ast.DefineContract {
+  // (define/contract <variable> <contract> <expression>)
+  // (define/contract (<variable> <formals>) <contract> <body>)
+  //  <contract> = (-> <domain1> ... <range>)
+  //  where each <domain> and the <range> are predicates
+
+  elements := tuple.Elements
+  if len(elements) < 4 {
+    panic(fmt.Sprint("define/contract: bad syntax (missing expressions) ", tuple))
+  }
+  contract, ok := elements[2].(*ast.Tuple)
+  if !ok || len(contract.Elements) < 2 {
+    panic(fmt.Sprint("define/contract: bad syntax, expected (-> <domain> ... <range>), given: ", elements[2]))
+  }
+  if arrow, ok := contract.Elements[0].(*ast.Name); !ok || arrow.Identifier != "->" {
+    panic(fmt.Sprint("define/contract: bad syntax, expected (-> <domain> ... <range>), given: ", elements[2]))
+  }
+  predicates := ParseList(contract.Elements[1:])
+
+  define := ParseDefine(ast.NewTuple(append([]ast.Node{elements[0], elements[1]}, elements[3:]...)))
+  domain := predicates[:len(predicates)-1]
+  return ast.NewDefineContract(define, domain, predicates[len(predicates)-1], tuple.Pos)
+}
+
+func ParseDefineEnum(tuple *ast.Tuple) *ast.DefineEnum {
+  // (define-enum <name> <member1> ...)
+  //  each <member> is a distinct name
+
+  elements := tuple.Elements
+  if len(elements) < 3 {
+    panic(fmt.Sprintf("%s: bad syntax, expected a name and members, given: %s", constants.DEFINE_ENUM, tuple))
+  }
+  name, ok := elements[1].(*ast.Name)
+  if !ok {
+    panic(fmt.Sprintf("%s: bad syntax, expected a name, given: %s", constants.DEFINE_ENUM, elements[1]))
+  }
+  members := make([]*ast.Name, len(elements)-2)
+  seen := make(map[string]bool)
+  for i, element := range elements[2:] {
+    member, ok := element.(*ast.Name)
+    if !ok {
+      panic(fmt.Sprintf("%s: bad syntax, expected a member name, given: %s", constants.DEFINE_ENUM, element))
+    }
+    if seen[member.Identifier] {
+      panic(fmt.Sprintf("%s: duplicate member: %s", constants.DEFINE_ENUM, member))
+    }
+    seen[member.Identifier] = true
+    members[i] = member
+  }
+  define := ast.NewDefineEnum(name, members)
+  define.Pos = tuple.Pos
+  return define
+}
+
+func ParseTheEnvironment(tuple *ast.Tuple) *ast.TheEnvironment {
+  // (the-environment)
+
+  if len(tuple.Elements) != 1 {
+    panic(fmt.Sprint("the-environment: bad syntax, expected no expressions"))
+  }
+  return ast.NewTheEnvironment()
+}
+
+func ParseAnnotation(tuple *ast.Tuple) *ast.Annotation {
+  // (: <variable> <type>)
+  //  <type> = <name> | (-> <type1> ... <result>)
+  //  kept unparsed for the type checker
+
+  elements := tuple.Elements
+  if len(elements) != 3 {
+    panic(fmt.Sprint("annotation: bad syntax, expected a name and a type, given: ", tuple))
+  }
+  name, ok := elements[1].(*ast.Name)
+  if !ok {
+    panic(fmt.Sprint("annotation: bad syntax, expected a name, given: ", elements[1]))
+  }
+  return ast.NewAnnotation(name, elements[2])
+}
+
 func ParseFunction(tuple *ast.Tuple, tail ast.Node) *ast.Function {
   //  expand definition: e.g.
   //  ((f x) y) <body> =>
@@ -368,11 +835,15 @@ func ParseFunction(tuple *ast.Tuple, tail ast.Node) *ast.Function {
   lambda := ast.NewLambda(nil, tail)
   for {
     elements := tuple.Elements
+    if len(elements) == 0 {
+      panic(fmt.Sprint("define: bad syntax, missing procedure name"))
+    }
     lambda.Params = ExpandFormals(elements[1:])
 
     // len(elements) must be greater than 0
     switch elements[0].(type) {
     case *ast.Name:
+      nameLambda(lambda, elements[0].(*ast.Name).Identifier)
       return ast.NewFunction(elements[0].(*ast.Name), lambda)
     case *ast.Tuple:
       tuple = elements[0].(*ast.Tuple)
@@ -390,7 +861,16 @@ func ParseCall(tuple *ast.Tuple) *ast.Call {
   }
   callee := ParseNode(elements[0])
   args := ParseList(elements[1:])
-  return ast.NewCall(callee, args)
+  call := ast.NewCall(callee, args)
+  call.Pos = tuple.Pos
+  return call
+}
+
+// a lambda bound to a name is named after it in backtraces
+func nameLambda(node ast.Node, name string) {
+  if lambda, ok := node.(*ast.Lambda); ok && lambda.Name == "" {
+    lambda.Name = name
+  }
 }
 
 func ParseLambda(tuple *ast.Tuple) *ast.Lambda {
@@ -405,16 +885,18 @@ func ParseLambda(tuple *ast.Tuple) *ast.Lambda {
     panic(fmt.Sprint("lambda: bad syntax: ", tuple))
   }
   pattern := elements[1]
-  body := ast.NewBlock(ParseList(elements[2:]))
+  bindVariables(formalNames(pattern), elements[2:])
+  body := ast.NewBlock(ParseBody(elements[2:]))
 
+  var lambda *ast.Lambda
   switch pattern.(type) {
   case *ast.Name:
-    return ast.NewLambda(pattern, body)
+    lambda = ast.NewLambda(pattern, body)
   case *ast.Tuple:
     formals := ExpandFormals(pattern.(*ast.Tuple).Elements)
     _, ok := formals.(*ast.Pair)
     if ok || formals == ast.NilPair {
-      return ast.NewLambda(formals, body)
+      lambda = ast.NewLambda(formals, body)
     } else {
       // (. <variable>) is not allowed
       panic(fmt.Sprint("lambda: illegal use of `.'"))
@@ -422,6 +904,8 @@ func ParseLambda(tuple *ast.Tuple) *ast.Lambda {
   default:
     panic(fmt.Sprint("unsupported parser type ", pattern))
   }
+  lambda.Pos = tuple.Pos
+  return lambda
 }
 
 func ParseIf(tuple *ast.Tuple) *ast.If {
@@ -439,6 +923,122 @@ func ParseIf(tuple *ast.Tuple) *ast.If {
   }
 }
 
+func ParseCond(tuple *ast.Tuple) *ast.Cond {
+  // (cond <clause1> <clause2> ...)
+  //  <clause> = (<test> <expression1> ...) | (<test> => <receiver>)
+  //           | (<test>) | (else <expression1> <expression2> ...)
+
+  elements := tuple.Elements
+  if len(elements) < 2 {
+    panic(fmt.Sprintf("%s: bad syntax (missing clauses), expected at least 1", constants.COND))
+  }
+  clauses := make([]*ast.CondClause, len(elements)-1)
+  for i, element := range elements[1:] {
+    exprs := clauseElements(constants.COND, element, i+1 == len(clauses))
+    clause := &ast.CondClause{}
+    if isName(exprs[0], constants.ELSE) {
+      clause.Body = ast.NewBlock(ParseList(exprs[1:]))
+    } else {
+      clause.Test = ParseNode(exprs[0])
+      clause.Body, clause.Arrow = parseClauseBody(constants.COND, element, exprs[1:])
+    }
+    clauses[i] = clause
+  }
+  cond := ast.NewCond(clauses)
+  cond.Pos = tuple.Pos
+  return cond
+}
+
+func ParseCase(tuple *ast.Tuple) *ast.Case {
+  // (case <key> <clause1> <clause2> ...)
+  //  <clause> = ((<datum1> ...) <expression1> ...) | ((<datum1> ...) => <receiver>)
+  //           | (else <expression1> ...) | (else => <receiver>)
+
+  elements := tuple.Elements
+  if len(elements) < 3 {
+    panic(fmt.Sprintf("%s: bad syntax (missing clauses), expected a key and at least 1", constants.CASE))
+  }
+  key := ParseNode(elements[1])
+  clauses := make([]*ast.CaseClause, len(elements)-2)
+  for i, element := range elements[2:] {
+    exprs := clauseElements(constants.CASE, element, i+1 == len(clauses))
+    clause := &ast.CaseClause{}
+    if !isName(exprs[0], constants.ELSE) {
+      data, ok := exprs[0].(*ast.Tuple)
+      if !ok {
+        panic(fmt.Sprintf("%s: bad clause %s, expected a list of data or else", constants.CASE, element))
+      }
+      clause.Data = make([]value.Value, len(data.Elements))
+      for j, datum := range data.Elements {
+        clause.Data[j] = ast.ToDatum(datum)
+      }
+    }
+    if len(exprs) == 1 {
+      panic(fmt.Sprintf("%s: bad clause %s, expected at least 1 expression", constants.CASE, element))
+    }
+    clause.Body, clause.Arrow = parseClauseBody(constants.CASE, element, exprs[1:])
+    clauses[i] = clause
+  }
+  expr := ast.NewCase(key, clauses)
+  expr.Pos = tuple.Pos
+  return expr
+}
+
+// the elements of a clause of form, an else clause must be the last
+func clauseElements(form string, clause ast.Node, last bool) []ast.Node {
+  tuple, ok := clause.(*ast.Tuple)
+  if !ok || len(tuple.Elements) == 0 {
+    panic(fmt.Sprintf("%s: bad clause %s, expected a non-empty list", form, clause))
+  }
+  if isName(tuple.Elements[0], constants.ELSE) && !last {
+    panic(fmt.Sprintf("%s: bad clause %s, else must be the last clause", form, clause))
+  }
+  return tuple.Elements
+}
+
+// the body of a clause after its test, or the receiver of
+// the test's value after =>. (test) has no body
+func parseClauseBody(form string, clause ast.Node, exprs []ast.Node) (ast.Node, bool) {
+  if len(exprs) == 0 {
+    return nil, false
+  }
+  if isName(exprs[0], constants.ARROW) {
+    if len(exprs) != 2 {
+      panic(fmt.Sprintf("%s: bad clause %s, expected exactly 1 receiver after =>", form, clause))
+    }
+    return ParseNode(exprs[1]), true
+  }
+  return ast.NewBlock(ParseList(exprs)), false
+}
+
+// whether node is the identifier name, like else in a clause
+func isName(node ast.Node, name string) bool {
+  identifier, ok := node.(*ast.Name)
+  return ok && identifier.Identifier == name
+}
+
+func ParseWhen(tuple *ast.Tuple) *ast.When {
+  // (when <test> <expression1> <expression2> ...)
+  // (unless <test> <expression1> <expression2> ...)
+
+  elements := tuple.Elements
+  form := elements[0].(*ast.Name).Identifier
+  if len(elements) < 3 {
+    panic(fmt.Sprintf("%s: bad syntax, expected a test and at least 1 expression, given: %s", form, tuple))
+  }
+  body := ast.NewBlock(ParseList(elements[2:]))
+  return ast.NewWhen(ParseNode(elements[1]), body, form == constants.UNLESS)
+}
+
+func ParseLogic(tuple *ast.Tuple) *ast.Logic {
+  // (and <expression1> ...)
+  // (or <expression1> ...)
+
+  elements := tuple.Elements
+  form := elements[0].(*ast.Name).Identifier
+  return ast.NewLogic(ParseList(elements[1:]), form == constants.OR)
+}
+
 func ParseSet(tuple *ast.Tuple) *ast.Set {
   elements := tuple.Elements
   if len(elements) != 3 {
@@ -449,7 +1049,9 @@ func ParseSet(tuple *ast.Tuple) *ast.Set {
     panic(fmt.Sprintf("set!: not an indentifier in %s", tuple))
   }
   value := ParseNode(elements[2])
-  return ast.NewSet(pattern.(*ast.Name), value)
+  set := ast.NewSet(pattern.(*ast.Name), value)
+  set.Pos = tuple.Pos
+  return set
 }
 
 func ParseDelay(tuple *ast.Tuple) *ast.Delay {
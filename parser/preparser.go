@@ -11,49 +11,57 @@ func PreParser(l *lexer.Lexer, elements []ast.Node, delimiter string) []ast.Node
 
   for token := l.NextToken(); token.Type != lexer.TokenEOF; token = l.NextToken() {
     switch token.Type {
-    case lexer.TokenIdentifier:
-      elements = append(elements, ast.NewName(token.Value))
+    case lexer.TokenIdentifier, lexer.TokenBarIdentifier:
+      name := ast.NewName(token.Value)
+      name.Pos = fmt.Sprintf("%s:%d:%d", l.Name(), token.Line, token.Column)
+      name.Barred = token.Type == lexer.TokenBarIdentifier
+      elements = append(elements, name)
 
     case lexer.TokenIntegerLiteral:
-      elements = append(elements, ast.NewInt(token.Value))
+      elements = append(elements, ast.NewInteger(token.Value))
     case lexer.TokenFloatLiteral:
       elements = append(elements, ast.NewFloat(token.Value))
+    case lexer.TokenRationalLiteral:
+      elements = append(elements, ast.NewRational(token.Value))
     case lexer.TokenStringLiteral:
       elements = append(elements, ast.NewString(token.Value))
+    case lexer.TokenCharLiteral:
+      elements = append(elements, ast.NewChar(token.Value))
 
     case lexer.TokenOpenParen:
-      tuple := ast.NewTuple(PreParser(l, make([]ast.Node, 0), "("))
-      elements = append(elements, tuple)
+      pos := fmt.Sprintf("%s:%d:%d", l.Name(), token.Line, token.Column)
+      elements = append(elements, preParseTuple(l, pos))
+    case lexer.TokenOpenVector:
+      pos := fmt.Sprintf("%s:%d:%d", l.Name(), token.Line, token.Column)
+      elements = append(elements, ast.NewVector(preParseTuple(l, pos).Elements))
     case lexer.TokenCloseParen:
       if delimiter != "(" {
-        panic(fmt.Sprint("read: unexpected `)'"))
+        panic(&Error{Pos: fmt.Sprintf("%s:%d:%d", l.Name(), token.Line, token.Column), Message: "read: unexpected `)'"})
       }
       return elements
 
     case lexer.TokenQuote:
-      quote := []ast.Node{ast.NewName(constants.QUOTE)}
-      quote = append(quote, PreParser(l, make([]ast.Node, 0), "'")...)
-      elements = append(elements, ast.NewTuple(quote))
+      elements = append(elements, preParseShorthand(l, token, constants.QUOTE, "'"))
     case lexer.TokenQuasiquote:
-      quasiquote := []ast.Node{ast.NewName(constants.QUASIQUOTE)}
-      quasiquote = append(quasiquote, PreParser(l, make([]ast.Node, 0), "`")...)
-      elements = append(elements, ast.NewTuple(quasiquote))
+      elements = append(elements, preParseShorthand(l, token, constants.QUASIQUOTE, "`"))
     case lexer.TokenUnquote:
-      unquote := []ast.Node{ast.NewName(constants.UNQUOTE)}
-      unquote = append(unquote, PreParser(l, make([]ast.Node, 0), ",")...)
-      elements = append(elements, ast.NewTuple(unquote))
+      elements = append(elements, preParseShorthand(l, token, constants.UNQUOTE, ","))
     case lexer.TokenUnquoteSplicing:
-      unquoteSplicing := []ast.Node{ast.NewName(constants.UNQUOTE_SPLICING)}
-      unquoteSplicing = append(unquoteSplicing, PreParser(l, make([]ast.Node, 0), ",@")...)
-      elements = append(elements, ast.NewTuple(unquoteSplicing))
+      elements = append(elements, preParseShorthand(l, token, constants.UNQUOTE_SPLICING, ",@"))
+
+    case lexer.TokenDatumComment:
+      // the datum is read and dropped, a shorthand before
+      // the comment applies to the datum after it
+      PreParser(l, make([]ast.Node, 0), "#;")
+      continue
 
     case lexer.TokenError:
-      panic(fmt.Errorf("token error: %s", token.Value))
+      panic(&lexer.Error{Name: l.Name(), Line: token.Line, Column: token.Column, Message: token.Value})
     default:
       panic(fmt.Errorf("unexpected token type: %v", token.Type))
     }
     switch delimiter {
-    case "'", "`", ",", ",@":
+    case "'", "`", ",", ",@", "#;":
       return elements
     }
   }
@@ -62,3 +70,18 @@ func PreParser(l *lexer.Lexer, elements []ast.Node, delimiter string) []ast.Node
   }
   return elements
 }
+
+func preParseTuple(l *lexer.Lexer, pos string) *ast.Tuple {
+  defer locate(pos)
+  tuple := ast.NewTuple(PreParser(l, make([]ast.Node, 0), "("))
+  tuple.Pos = pos
+  return tuple
+}
+
+// 'x reads as (quote x) and so on, at the position of the shorthand
+func preParseShorthand(l *lexer.Lexer, token lexer.Token, keyword, delimiter string) *ast.Tuple {
+  elements := []ast.Node{ast.NewName(keyword)}
+  tuple := ast.NewTuple(PreParser(l, elements, delimiter))
+  tuple.Pos = fmt.Sprintf("%s:%d:%d", l.Name(), token.Line, token.Column)
+  return tuple
+}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/macro"
+  "github.com/kedebug/LispEx/scope"
+)
+
+// what an identifier at the head of a list denotes to the parser
+type Denotation int
+
+const (
+  Variable Denotation = iota
+  CoreForm
+  Macro
+)
+
+// the parsers of the special forms by keyword. definitions are only
+// allowed in definition contexts, where ParseDefinition parses them
+var (
+  coreForms       map[string]func(*ast.Tuple) ast.Node
+  definitionForms map[string]func(*ast.Tuple) ast.Node
+)
+
+// the tables refer to the parsers, which refer to the tables
+func init() {
+  coreForms = map[string]func(*ast.Tuple) ast.Node{
+    constants.THE_ENVIRONMENT: func(tuple *ast.Tuple) ast.Node { return ParseTheEnvironment(tuple) },
+    constants.BEGIN:           func(tuple *ast.Tuple) ast.Node { return ParseBegin(tuple) },
+    constants.LAMBDA:          func(tuple *ast.Tuple) ast.Node { return ParseLambda(tuple) },
+    constants.LET:             ParseLetFamily,
+    constants.LET_STAR:        ParseLetFamily,
+    constants.LET_REC:         ParseLetFamily,
+    constants.LET_VALUES:      func(tuple *ast.Tuple) ast.Node { return ParseLetValues(tuple) },
+    constants.LET_STAR_VALUES: func(tuple *ast.Tuple) ast.Node { return ParseLetValues(tuple) },
+    constants.DO:              func(tuple *ast.Tuple) ast.Node { return ParseDo(tuple) },
+    constants.GO:              func(tuple *ast.Tuple) ast.Node { return ParseGo(tuple) },
+    constants.NURSERY:         func(tuple *ast.Tuple) ast.Node { return ParseNursery(tuple) },
+    constants.SELECT:          func(tuple *ast.Tuple) ast.Node { return ParseSelect(tuple) },
+    constants.PRIORITY_SELECT: func(tuple *ast.Tuple) ast.Node { return ParseSelect(tuple) },
+    constants.IF:              func(tuple *ast.Tuple) ast.Node { return ParseIf(tuple) },
+    constants.COND:            func(tuple *ast.Tuple) ast.Node { return ParseCond(tuple) },
+    constants.CASE:            func(tuple *ast.Tuple) ast.Node { return ParseCase(tuple) },
+    constants.WHEN:            func(tuple *ast.Tuple) ast.Node { return ParseWhen(tuple) },
+    constants.UNLESS:          func(tuple *ast.Tuple) ast.Node { return ParseWhen(tuple) },
+    constants.AND:             func(tuple *ast.Tuple) ast.Node { return ParseLogic(tuple) },
+    constants.OR:              func(tuple *ast.Tuple) ast.Node { return ParseLogic(tuple) },
+    constants.SET:             func(tuple *ast.Tuple) ast.Node { return ParseSet(tuple) },
+    constants.APPLY:           func(tuple *ast.Tuple) ast.Node { return ParseApply(tuple) },
+    constants.QUOTE:           func(tuple *ast.Tuple) ast.Node { return ParseQuote(tuple) },
+    // unquote and unquote-splicing are parsed by
+    // quasiquote, so they never go through ParseNode
+    constants.QUASIQUOTE:       func(tuple *ast.Tuple) ast.Node { return ParseQuasiquote(tuple, 1) },
+    constants.UNQUOTE:          func(*ast.Tuple) ast.Node { panic("unquote: not in quasiquote") },
+    constants.UNQUOTE_SPLICING: func(*ast.Tuple) ast.Node { panic("unquote-splicing: not in quasiquote") },
+    constants.DELAY:            func(tuple *ast.Tuple) ast.Node { return ParseDelay(tuple) },
+    constants.FORCE:            func(tuple *ast.Tuple) ast.Node { return ParseForce(tuple) },
+  }
+  definitionForms = map[string]func(*ast.Tuple) ast.Node{
+    constants.DEFINE:          func(tuple *ast.Tuple) ast.Node { return ParseDefine(tuple) },
+    constants.DEFINE_CONSTANT: func(tuple *ast.Tuple) ast.Node { return ParseDefineConstant(tuple) },
+    constants.DEFINE_CONTRACT: func(tuple *ast.Tuple) ast.Node { return ParseDefineContract(tuple) },
+    constants.DEFINE_SYNTAX:   func(tuple *ast.Tuple) ast.Node { return ParseDefineSyntax(tuple) },
+    constants.DEFINE_ENUM:     func(tuple *ast.Tuple) ast.Node { return ParseDefineEnum(tuple) },
+    constants.ANNOTATE:        func(tuple *ast.Tuple) ast.Node { return ParseAnnotation(tuple) },
+    // the definitions of a begin form are spliced into the enclosing scope
+    constants.BEGIN: func(tuple *ast.Tuple) ast.Node {
+      return ast.NewBegin(ast.NewBlock(ParseBody(tuple.Elements[1:])))
+    },
+  }
+}
+
+// what name denotes in env: a variable bound there, else a special form
+// or a macro defined so far at its top level, else a variable of the
+// top level
+func Denote(env *ast.SyntaxEnv, name string) Denotation {
+  switch {
+  case env.IsVariable(name):
+    return Variable
+  case coreForms[name] != nil || definitionForms[name] != nil:
+    return CoreForm
+  case macro.Lookup(macrosOf(env), name) != nil:
+    return Macro
+  }
+  return Variable
+}
+
+// the macros of the top level env is in, nil when it has none
+func macrosOf(env *ast.SyntaxEnv) *scope.Macros {
+  if top := env.Top(); top != nil {
+    return top.Macros
+  }
+  return nil
+}
+
+// whether a list starting with name is a special form or the use
+// of a macro defined at the top level of env when it is asked
+func IsKeyword(env *ast.SyntaxEnv, name string) bool {
+  return Denote(env.Top(), name) != Variable
+}
+
+// the use of a macro expanded, the lists put in by the macro are
+// at the top level of the use rather than where it is written
+func expand(tuple *ast.Tuple, name *ast.Name) ast.Node {
+  expanded := macro.Lookup(macrosOf(tuple.Syntax), name.Identifier).Expand(tuple)
+  enclose([]ast.Node{expanded}, tuple.Syntax.Top())
+  return expanded
+}
+
+// put the lists among nodes which are in no environment yet at top
+func enclose(nodes []ast.Node, top *ast.SyntaxEnv) {
+  for _, node := range nodes {
+    if tuple, ok := node.(*ast.Tuple); ok && tuple.Syntax == nil {
+      tuple.Syntax = top
+      enclose(tuple.Elements, top)
+    }
+  }
+}
+
+// names are bound to variables around nodes, before they are parsed:
+// the tuples within them take the environment binding them. names that
+// are no keywords denote variables anyway and are left out
+func bindVariables(names []string, nodes []ast.Node) {
+  var env *ast.SyntaxEnv
+  for _, node := range nodes {
+    if tuple, ok := node.(*ast.Tuple); ok {
+      env = tuple.Syntax
+      break
+    }
+  }
+  var keywords []string
+  for _, name := range names {
+    if IsKeyword(env, name) {
+      keywords = append(keywords, name)
+    }
+  }
+  if len(keywords) > 0 {
+    bindTuples(keywords, make(map[*ast.SyntaxEnv]*ast.SyntaxEnv), nodes)
+  }
+}
+
+// envs has the environment made for each one the tuples were in
+func bindTuples(names []string, envs map[*ast.SyntaxEnv]*ast.SyntaxEnv, nodes []ast.Node) {
+  for _, node := range nodes {
+    tuple, ok := node.(*ast.Tuple)
+    if !ok {
+      continue
+    }
+    env, ok := envs[tuple.Syntax]
+    if !ok {
+      env = ast.NewSyntaxEnv(tuple.Syntax, names)
+      envs[tuple.Syntax] = env
+    }
+    tuple.Syntax = env
+    bindTuples(names, envs, tuple.Elements)
+  }
+}
@@ -0,0 +1,277 @@
+package quickcheck
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/value"
+  "math"
+  "math/rand"
+)
+
+// a generated value together with its shrinks, simpler values
+// tried in order when a property fails on this one.
+// shrinks are computed lazily since most samples pass
+type Sample struct {
+  Value  value.Value
+  Shrink func() []*Sample
+}
+
+func noShrink() []*Sample {
+  return nil
+}
+
+// size grows with the number of tests run so far,
+// bounding integers and the length of strings and lists
+type Generator struct {
+  Name     string
+  Generate func(r *rand.Rand, size int) *Sample
+}
+
+func (self *Generator) String() string {
+  return fmt.Sprintf("#<generator:%s>", self.Name)
+}
+
+// integers between -size and size, or between lo and hi when bounded,
+// shrinking towards zero or the bound closest to it
+func Integer() *Generator {
+  return &Generator{"integer", func(r *rand.Rand, size int) *Sample {
+    n := int64(size)
+    return integerSample(r.Int63n(2*n+1)-n, 0)
+  }}
+}
+
+func IntegerBetween(lo, hi int64) *Generator {
+  if lo > hi {
+    panic(fmt.Sprintf("gen-integer: empty range %d to %d", lo, hi))
+  }
+  origin := int64(0)
+  if lo > 0 {
+    origin = lo
+  } else if hi < 0 {
+    origin = hi
+  }
+  return &Generator{fmt.Sprintf("integer %d %d", lo, hi), func(r *rand.Rand, size int) *Sample {
+    return integerSample(lo+r.Int63n(hi-lo+1), origin)
+  }}
+}
+
+// n - (n - origin), n - (n - origin)/2, n - (n - origin)/4, ...
+func integerSample(n, origin int64) *Sample {
+  return &Sample{value.NewIntValue(n), func() []*Sample {
+    var shrinks []*Sample
+    for delta := n - origin; delta != 0; delta /= 2 {
+      shrinks = append(shrinks, integerSample(n-delta, origin))
+    }
+    return shrinks
+  }}
+}
+
+func Float() *Generator {
+  return &Generator{"float", func(r *rand.Rand, size int) *Sample {
+    return floatSample((r.Float64()*2 - 1) * float64(size))
+  }}
+}
+
+// zero, the integral part, then half the value
+func floatSample(f float64) *Sample {
+  return &Sample{value.NewFloatValue(f), func() []*Sample {
+    var shrinks []*Sample
+    if f != 0 {
+      shrinks = append(shrinks, floatSample(0))
+    }
+    if truncated := math.Trunc(f); truncated != f && truncated != 0 {
+      shrinks = append(shrinks, floatSample(truncated))
+    }
+    if math.Abs(f) >= 1 {
+      shrinks = append(shrinks, floatSample(f/2))
+    }
+    return shrinks
+  }}
+}
+
+func Boolean() *Generator {
+  return &Generator{"boolean", func(r *rand.Rand, size int) *Sample {
+    return booleanSample(r.Intn(2) == 1)
+  }}
+}
+
+func booleanSample(b bool) *Sample {
+  if !b {
+    return &Sample{value.NewBoolValue(false), noShrink}
+  }
+  return &Sample{value.NewBoolValue(true), func() []*Sample {
+    return []*Sample{booleanSample(false)}
+  }}
+}
+
+// printable ascii strings, shrinking like lists of characters
+// where every character shrinks towards `a'
+func String() *Generator {
+  return &Generator{"string", func(r *rand.Rand, size int) *Sample {
+    chars := make([]*Sample, r.Intn(size+1))
+    for i := range chars {
+      chars[i] = charSample(byte(' ' + r.Intn('~'-' '+1)))
+    }
+    return stringSample(chars)
+  }}
+}
+
+func charSample(c byte) *Sample {
+  return &Sample{value.NewStringValue(string(c)), func() []*Sample {
+    if c == 'a' {
+      return nil
+    }
+    return []*Sample{charSample('a')}
+  }}
+}
+
+func stringSample(chars []*Sample) *Sample {
+  s := ""
+  for _, c := range chars {
+    s += c.Value.(*value.StringValue).Value
+  }
+  return &Sample{value.NewStringValue(s), func() []*Sample {
+    var shrinks []*Sample
+    for _, shrunk := range shrinkElements(chars) {
+      shrinks = append(shrinks, stringSample(shrunk))
+    }
+    return shrinks
+  }}
+}
+
+// lists of up to size elements
+func List(element *Generator) *Generator {
+  return &Generator{"list " + element.Name, func(r *rand.Rand, size int) *Sample {
+    elements := make([]*Sample, r.Intn(size+1))
+    for i := range elements {
+      elements[i] = element.Generate(r, size)
+    }
+    return listSample(elements)
+  }}
+}
+
+func listSample(elements []*Sample) *Sample {
+  values := make([]value.Value, len(elements))
+  for i, element := range elements {
+    values[i] = element.Value
+  }
+  return &Sample{converter.SliceToPairValues(values), func() []*Sample {
+    var shrinks []*Sample
+    for _, shrunk := range shrinkElements(elements) {
+      shrinks = append(shrinks, listSample(shrunk))
+    }
+    return shrinks
+  }}
+}
+
+// drop the first or second half, then single elements,
+// then shrink the elements one at a time
+func shrinkElements(elements []*Sample) [][]*Sample {
+  var shrinks [][]*Sample
+  n := len(elements)
+  if n > 1 {
+    shrinks = append(shrinks, elements[n/2:], elements[:n/2])
+  }
+  for i := range elements {
+    shrinks = append(shrinks, without(elements, i))
+  }
+  for i, element := range elements {
+    for _, shrunk := range element.Shrink() {
+      shrinks = append(shrinks, replace(elements, i, shrunk))
+    }
+  }
+  return shrinks
+}
+
+func without(elements []*Sample, i int) []*Sample {
+  result := append([]*Sample{}, elements[:i]...)
+  return append(result, elements[i+1:]...)
+}
+
+func replace(elements []*Sample, i int, sample *Sample) []*Sample {
+  result := append([]*Sample{}, elements...)
+  result[i] = sample
+  return result
+}
+
+// one of the values, shrinking towards the first
+func Elements(values []value.Value) *Generator {
+  if len(values) == 0 {
+    panic(fmt.Sprint("gen-elements: expected non-empty list"))
+  }
+  return &Generator{"elements", func(r *rand.Rand, size int) *Sample {
+    return elementSample(values, r.Intn(len(values)))
+  }}
+}
+
+func elementSample(values []value.Value, i int) *Sample {
+  return &Sample{values[i], func() []*Sample {
+    var shrinks []*Sample
+    for j := 0; j < i; j++ {
+      shrinks = append(shrinks, elementSample(values, j))
+    }
+    return shrinks
+  }}
+}
+
+// a value of one of the generators picked at random
+func OneOf(generators []*Generator) *Generator {
+  if len(generators) == 0 {
+    panic(fmt.Sprint("gen-one-of: expected at least one generator"))
+  }
+  return &Generator{"one-of", func(r *rand.Rand, size int) *Sample {
+    return generators[r.Intn(len(generators))].Generate(r, size)
+  }}
+}
+
+// user types are built by mapping a procedure over values of other
+// generators; they shrink by shrinking those values and mapping again
+func Map(name string, proc func([]value.Value) value.Value, generators []*Generator) *Generator {
+  return &Generator{name, func(r *rand.Rand, size int) *Sample {
+    sources := make([]*Sample, len(generators))
+    for i, generator := range generators {
+      sources[i] = generator.Generate(r, size)
+    }
+    return mapSample(proc, sources)
+  }}
+}
+
+func mapSample(proc func([]value.Value) value.Value, sources []*Sample) *Sample {
+  values := make([]value.Value, len(sources))
+  for i, source := range sources {
+    values[i] = source.Value
+  }
+  return &Sample{proc(values), func() []*Sample {
+    var shrinks []*Sample
+    for i, source := range sources {
+      for _, shrunk := range source.Shrink() {
+        shrinks = append(shrinks, mapSample(proc, replace(sources, i, shrunk)))
+      }
+    }
+    return shrinks
+  }}
+}
+
+// values of the generator accepted by keep, shrinks included
+func SuchThat(keep func(value.Value) bool, generator *Generator) *Generator {
+  return &Generator{generator.Name, func(r *rand.Rand, size int) *Sample {
+    for tries := 0; tries < 100; tries++ {
+      if sample := generator.Generate(r, size+tries/10); keep(sample.Value) {
+        return filterSample(keep, sample)
+      }
+    }
+    panic(fmt.Sprint("gen-such-that: no value accepted after 100 tries"))
+  }}
+}
+
+func filterSample(keep func(value.Value) bool, sample *Sample) *Sample {
+  return &Sample{sample.Value, func() []*Sample {
+    var shrinks []*Sample
+    for _, shrunk := range sample.Shrink() {
+      if keep(shrunk.Value) {
+        shrinks = append(shrinks, filterSample(keep, shrunk))
+      }
+    }
+    return shrinks
+  }}
+}
@@ -0,0 +1,87 @@
+package quickcheck
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/value"
+  "math/rand"
+)
+
+const (
+  maxSize    = 100
+  maxShrinks = 1000
+)
+
+// outcome of checking a property, on failure Counterexample is the
+// simplest failing value found and Error the panic it raised, if any
+type Result struct {
+  Passed         bool
+  Tests          int
+  Shrinks        int
+  Seed           int64
+  Counterexample value.Value
+  Error          string
+}
+
+// property holds for a value unless it returns #f or panics
+type Property func(value.Value) value.Value
+
+// run the property against tests values of the generator,
+// a failure is shrunk to a smaller counterexample
+func Check(generator *Generator, property Property, tests int, seed int64) *Result {
+  r := rand.New(rand.NewSource(seed))
+  for i := 0; i < tests; i++ {
+    sample := generator.Generate(r, i%maxSize)
+    if ok, err := holds(property, sample.Value); !ok {
+      result := &Result{Tests: i + 1, Seed: seed}
+      result.Counterexample, result.Error, result.Shrinks = shrink(property, sample, err)
+      return result
+    }
+  }
+  return &Result{Passed: true, Tests: tests, Seed: seed}
+}
+
+// walk down the shrinks, always taking the first that still fails
+func shrink(property Property, sample *Sample, err string) (value.Value, string, int) {
+  shrinks, tries := 0, 0
+  for tries < maxShrinks {
+    failed := false
+    for _, candidate := range sample.Shrink() {
+      tries++
+      if ok, e := holds(property, candidate.Value); !ok {
+        sample, err, failed = candidate, e, true
+        shrinks++
+        break
+      }
+      if tries >= maxShrinks {
+        break
+      }
+    }
+    if !failed {
+      break
+    }
+  }
+  return sample.Value, err, shrinks
+}
+
+func holds(property Property, val value.Value) (ok bool, err string) {
+  defer func() {
+    if e := recover(); e != nil {
+      ok, err = false, fmt.Sprint(e)
+    }
+  }()
+  if result, isBool := property(val).(*value.BoolValue); isBool && !result.Value {
+    return false, ""
+  }
+  return true, ""
+}
+
+func (self *Result) Report(name string) string {
+  if self.Passed {
+    return fmt.Sprintf("+++ %s: OK, passed %d tests.\n", name, self.Tests)
+  }
+  report := fmt.Sprintf("*** %s: Falsified after %d tests and %d shrinks (seed %d):\n", name, self.Tests, self.Shrinks, self.Seed)
+  if self.Error != "" {
+    report += fmt.Sprintf("error: %s\n", self.Error)
+  }
+  return report + fmt.Sprintf("%s\n", self.Counterexample)
+}
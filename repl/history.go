@@ -0,0 +1,43 @@
+package repl
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// keeps the most recent results of an interactive session bound
+// to $1 (latest), $2, ... and the last error raised to $e
+type History struct {
+  env    *scope.Scope
+  values []value.Value
+  size   int
+}
+
+func NewHistory(env *scope.Scope, size int) *History {
+  return &History{env: env, size: size}
+}
+
+func (self *History) Record(val value.Value) {
+  if val == nil {
+    return
+  }
+  self.values = append([]value.Value{val}, self.values...)
+  if len(self.values) > self.size {
+    self.values = self.values[:self.size]
+  }
+  for i, val := range self.values {
+    self.env.Put(fmt.Sprintf("$%d", i+1), val)
+  }
+}
+
+// $e is bound to the object raised, as a handler would be given it, so
+// that it can be inspected or raised again. the failures no handler is
+// given, like the memory limit being exceeded, are bound as conditions
+func (self *History) RecordError(err interface{}) {
+  obj, ok := value.ConditionOf(err)
+  if !ok {
+    obj = value.NewCondition("", fmt.Sprint(err), nil)
+  }
+  self.env.Put("$e", obj)
+}
@@ -0,0 +1,124 @@
+package repl
+
+import (
+  "strings"
+  "unicode"
+)
+
+// forms whose body is indented by two columns instead of being
+// aligned with their first argument
+var bodyForms = map[string]bool{
+  "begin":           true,
+  "define":          true,
+  "define-constant": true,
+  "define/contract": true,
+  "define-syntax":   true,
+  "go":              true,
+  "lambda":          true,
+  "let":             true,
+  "let*":            true,
+  "let*-values":     true,
+  "let-values":      true,
+  "letrec":          true,
+  "nursery":         true,
+  "priority-select": true,
+  "select":          true,
+  "syntax-rules":    true,
+}
+
+// the lines of the form typed in an interactive session, which may
+// span several lines until its parentheses are balanced. continuation
+// lines are kept with the indentation the prompt showed for them
+type Input struct {
+  lines []string
+}
+
+func (self *Input) Empty() bool {
+  return len(self.lines) == 0
+}
+
+// add a line typed after the columns given by Indent,
+// returns whether the input now holds complete forms
+func (self *Input) Add(line string) bool {
+  if _, inString := self.scan(); !self.Empty() && !inString {
+    line = strings.Repeat(" ", self.Indent()) + strings.TrimLeftFunc(line, unicode.IsSpace)
+  }
+  self.lines = append(self.lines, line)
+  opens, inString := self.scan()
+  return len(opens) == 0 && !inString
+}
+
+func (self *Input) Text() string {
+  return strings.Join(self.lines, "\n")
+}
+
+func (self *Input) Reset() {
+  self.lines = nil
+}
+
+// the column to indent the next line at, like lisp editors do:
+// the body of a form like define or let two columns past its open
+// parenthesis, the arguments of a call aligned with the first one
+func (self *Input) Indent() int {
+  opens, inString := self.scan()
+  if inString || len(opens) == 0 {
+    return 0
+  }
+  open := opens[len(opens)-1]
+  line := []rune(self.lines[open.line])
+  rest := string(line[open.column+1:])
+  operator := rest
+  if i := strings.IndexAny(rest, " \t()\";"); i >= 0 {
+    operator = rest[:i]
+  }
+  if operator == "" {
+    return open.column + 1
+  }
+  if bodyForms[operator] {
+    return open.column + 2
+  }
+  after := strings.TrimLeft(rest[len(operator):], " \t")
+  if after == "" || after[0] == ';' {
+    return open.column + 1
+  }
+  return open.column + 1 + len([]rune(rest)) - len([]rune(after))
+}
+
+// position of an open parenthesis
+type position struct {
+  line, column int
+}
+
+// the parentheses left open, innermost last, and whether
+// the input ends within a string. extra closing parentheses
+// are left for the parser to report
+func (self *Input) scan() (opens []position, inString bool) {
+  for i, line := range self.lines {
+    runes := []rune(line)
+    for j := 0; j < len(runes); j++ {
+      r := runes[j]
+      switch {
+      case inString:
+        if r == '\\' {
+          j++
+        } else if r == '"' {
+          inString = false
+        }
+      case r == '"':
+        inString = true
+      case r == ';':
+        j = len(runes)
+      case r == '#' && j+1 < len(runes) && runes[j+1] == '\\':
+        // #\( is a character
+        j += 2
+      case r == '(':
+        opens = append(opens, position{i, j})
+      case r == ')':
+        if len(opens) > 0 {
+          opens = opens[:len(opens)-1]
+        }
+      }
+    }
+  }
+  return opens, inString
+}
@@ -0,0 +1,115 @@
+package repl
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/analysis"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// a worker of par-map-isolated is an interpreter of its own, built
+// like the one of host with its builtins, settings and prelude, which
+// shares no scope with the program: the procedure is closed again over
+// the worker's scope, the variables it refers to copied there unless
+// they are bound by the root scope of host, which the worker has its
+// own of, and its arguments and results are copied both ways
+func newIsolatedWorker(host *scope.Scope, proc value.Value) func(value.Value) value.Value {
+  root := host.NewRootLike()
+  if name, source := root.Prelude(); source != "" {
+    EvalSource(name, source, root)
+  }
+  root.Freeze()
+  in := &isolator{env: NewTopLevel(root), copies: make(map[value.Value]value.Value)}
+  f := in.copy(proc)
+  return func(arg value.Value) value.Value {
+    arg = (&isolator{env: in.env, copies: make(map[value.Value]value.Value)}).copy(arg)
+    result := value.Invoke(f, []value.Value{arg})
+    out := &isolator{copies: make(map[value.Value]value.Value)}
+    return out.copy(result)
+  }
+}
+
+// copies values into the scope of a worker, or out of it when env is
+// nil. strings, pairs and bytevectors can be changed, so they are
+// copied; copies keeps the copy of each, for data shared or cyclic
+type isolator struct {
+  env    *scope.Scope
+  copies map[value.Value]value.Value
+}
+
+func (self *isolator) copy(val value.Value) value.Value {
+  if val == nil {
+    return nil
+  }
+  if copied, ok := self.copies[val]; ok {
+    return copied
+  }
+  switch val.(type) {
+  case *value.IntValue, *value.BigIntValue, *value.RatValue, *value.FloatValue, *value.BoolValue, *value.CharValue,
+    *value.Symbol, *value.EmptyPairValue:
+    return val
+  case *value.StringValue:
+    copied := value.NewStringValue(val.(*value.StringValue).Value)
+    self.copies[val] = copied
+    return copied
+  case *value.Bytevector:
+    copied := value.NewBytevector(append([]byte(nil), val.(*value.Bytevector).Value...))
+    self.copies[val] = copied
+    return copied
+  case *value.Bitvector:
+    bv := val.(*value.Bitvector)
+    copied := value.NewBitvector(bv.Len)
+    copy(copied.Words, bv.Words)
+    self.copies[val] = copied
+    return copied
+  case *value.VectorValue:
+    items := val.(*value.VectorValue).Value
+    copied := value.NewVectorValue(make([]value.Value, len(items)))
+    self.copies[val] = copied
+    for i, item := range items {
+      copied.Value[i] = self.copy(item)
+    }
+    return copied
+  case *value.PairValue:
+    pair := val.(*value.PairValue)
+    copied := value.NewPairValue(nil, nil)
+    self.copies[val] = copied
+    copied.First, copied.Second = self.copy(pair.First), self.copy(pair.Second)
+    return copied
+  case *value.Closure:
+    closure := val.(*value.Closure)
+    lambda, ok := closure.Body.(*ast.Lambda)
+    if self.env == nil || !ok {
+      break
+    }
+    frame := scope.NewLocalScope(self.env)
+    copied := value.NewClosure(frame, lambda)
+    self.copies[val] = copied
+    env := closure.Env.(*scope.Scope)
+    for _, name := range analysis.FreeNames(lambda) {
+      // definitions of the program shadowing those of the
+      // root scope are copied too
+      bound := env.FindScope(name)
+      if bound == nil || bound.Parent() == nil {
+        continue
+      }
+      if v, ok := bound.LookupLocal(name).(value.Value); ok {
+        frame.Put(name, self.copy(v))
+      }
+    }
+    return copied
+  case value.PrimFunc:
+    // builtins are looked up by name in the worker
+    if self.env == nil {
+      break
+    }
+    if prim, ok := self.env.Lookup(val.String()).(value.PrimFunc); ok {
+      return prim
+    }
+  }
+  if self.env == nil {
+    panic(fmt.Sprint("par-map-isolated: can't return from a worker: ", val))
+  }
+  panic(fmt.Sprint("par-map-isolated: can't send to a worker: ", val))
+}
@@ -0,0 +1,43 @@
+package repl
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/value"
+  "sort"
+)
+
+// the goroutines started by `go' which are still running, grouped by
+// the position of the form which started them, or "" when none are:
+//   ;; 3 goroutines still running:
+//   ;;   2 started at server.ss:12
+//   ;;   1 started at server.ss:20
+func GoroutineReport() string {
+  counts := value.RunningGoroutines()
+  if len(counts) == 0 {
+    return ""
+  }
+  positions := make([]string, 0, len(counts))
+  total := 0
+  for pos, count := range counts {
+    positions = append(positions, pos)
+    total += count
+  }
+  sort.Strings(positions)
+  report := fmt.Sprintf(";; %d goroutine%s still running:\n", total, plural(total))
+  for _, pos := range positions {
+    if pos == "" {
+      // built from data, without a source position
+      report += fmt.Sprintf(";;   %d started at an unknown position\n", counts[pos])
+    } else {
+      report += fmt.Sprintf(";;   %d started at %s\n", counts[pos], pos)
+    }
+  }
+  return report
+}
+
+func plural(n int) string {
+  if n == 1 {
+    return ""
+  }
+  return "s"
+}
@@ -0,0 +1,229 @@
+package repl
+
+import (
+  "errors"
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "path/filepath"
+  "runtime"
+  "strings"
+  "sync"
+)
+
+// every loaded file is evaluated in its own module scope, a child of the
+// shared root scope, and its bindings are then imported into the scope
+// that loaded it; modules defining the same names don't clobber each other.
+// modules are remembered by name, the file name without directory and
+// extension, so they can be reloaded. top-level names are looked up
+// through the scope on every reference, so closures defined earlier
+// pick up the reloaded definitions. errors raised while loading
+// are reported with the chain of files being loaded.
+type Loader struct {
+  root    *scope.Scope
+  env     *scope.Scope
+  dir     string
+  modules *modules
+}
+
+type module struct {
+  filename string
+  env      *scope.Scope
+}
+
+// shared by the loader of a program and those of the modules it loads
+type modules struct {
+  table map[string]*module
+  // the files being evaluated, a file loading itself
+  // again, directly or not, would never finish
+  loading map[string]bool
+  mutex   sync.Mutex
+}
+
+// an error raised by a top-level form of a loaded file, Trace has the
+// position of the form in each file being loaded, outermost first
+type LoadError struct {
+  Trace []string
+  Err   error
+}
+
+// e.g. while loading a.ss:3 → b.ss:7: x: undefined identifier
+func (e *LoadError) Error() string {
+  return fmt.Sprintf("while loading %s: %s", strings.Join(e.Trace, " → "), e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+  return e.Err
+}
+
+func NewLoader(root, env *scope.Scope) *Loader {
+  shared := &modules{table: make(map[string]*module), loading: make(map[string]bool)}
+  return &Loader{root: root, env: env, modules: shared}
+}
+
+// top-level scope of a program or an interactive session, `load',
+// `reload', `load-history', `apropos' and `read' are bound in a scope between root and it,
+// which then holds only the bindings of the program
+func NewTopLevel(root *scope.Scope) *scope.Scope {
+  outer := scope.NewScope(root)
+  env := scope.NewScope(outer)
+  NewLoader(root, env).bind(outer)
+  outer.Put("apropos", primitives.LookupBuiltin("apropos").With(primitives.NewApropos(env.Names)))
+  newWorker := func(proc value.Value) func(value.Value) value.Value { return newIsolatedWorker(root, proc) }
+  outer.Put("par-map-isolated", primitives.LookupBuiltin("par-map-isolated").With(primitives.NewParMapIsolated(newWorker)))
+  outer.Put("read", primitives.LookupBuiltin("read").With(primitives.NewRead(readDatum)))
+  return env
+}
+
+// bind `load', `reload' and `load-history' in the loader's scope
+func (self *Loader) Register() {
+  self.bind(self.env)
+}
+
+func (self *Loader) bind(env *scope.Scope) {
+  env.Put("load", &loadPrimitive{value.Primitive{"load"}, self, false})
+  env.Put("reload", &loadPrimitive{value.Primitive{"reload"}, self, true})
+  env.Put("load-history", &loadHistoryPrimitive{value.Primitive{"load-history"}, self})
+}
+
+// relative names loaded from within a module are resolved
+// against the directory of that module, or its URL
+func (self *Loader) Load(filename string) {
+  filename = resolve(self.dir, filename)
+  name := moduleName(filename)
+  self.modules.mutex.Lock()
+  m, ok := self.modules.table[name]
+  if !ok || m.filename != filename {
+    // the module's own `load' imports into the module
+    outer := scope.NewScope(self.root)
+    m = &module{filename: filename, env: scope.NewScope(outer)}
+    loader := &Loader{root: self.root, env: m.env, dir: moduleDir(filename), modules: self.modules}
+    loader.bind(outer)
+    self.modules.table[name] = m
+  }
+  self.modules.mutex.Unlock()
+  self.eval(m)
+}
+
+func (self *Loader) Reload(name string) {
+  self.modules.mutex.Lock()
+  m, ok := self.modules.table[name]
+  self.modules.mutex.Unlock()
+  if !ok {
+    panic(fmt.Sprint("reload: module was not loaded: ", name))
+  }
+  self.eval(m)
+}
+
+func (self *Loader) eval(m *module) {
+  self.modules.mutex.Lock()
+  if self.modules.loading[m.filename] {
+    self.modules.mutex.Unlock()
+    panic(fmt.Sprint("load: circular load of ", m.filename))
+  }
+  self.modules.loading[m.filename] = true
+  self.modules.mutex.Unlock()
+  defer func() {
+    self.modules.mutex.Lock()
+    delete(self.modules.loading, m.filename)
+    self.modules.mutex.Unlock()
+  }()
+
+  exprs, err := ReadSource(m.filename, m.env.RemoteEnabled())
+  if err != nil {
+    panic(fmt.Sprint("load: ", err))
+  }
+  nodes := parse(m.filename, string(exprs), m.env)
+  analyze(nodes)
+  for _, node := range nodes {
+    evalForm(node, m)
+  }
+  self.env.PutAll(m.env)
+}
+
+// errors of the form are raised as a LoadError
+// with the position of the form added to the trace
+func evalForm(node ast.Node, m *module) {
+  defer func() {
+    if e := recover(); e != nil {
+      pos := formPosition(node, m.filename)
+      switch e.(type) {
+      case runtime.Error:
+        panic(e)
+      case error:
+        // a load in a procedure is wrapped in a backtrace
+        var load *LoadError
+        if errors.As(e.(error), &load) {
+          load.Trace = append([]string{pos}, load.Trace...)
+          panic(e)
+        }
+        panic(&LoadError{Trace: []string{pos}, Err: e.(error)})
+      default:
+        panic(&LoadError{Trace: []string{pos}, Err: &value.Error{Message: fmt.Sprint(e)}})
+      }
+    }
+  }()
+  node.Eval(m.env)
+}
+
+// "file:line" for the forms which keep their line
+func formPosition(node ast.Node, filename string) string {
+  var pos string
+  switch node.(type) {
+  case *ast.Call:
+    pos = node.(*ast.Call).Pos
+  case *ast.Apply:
+    pos = node.(*ast.Apply).Pos
+  case *ast.Define:
+    pos = node.(*ast.Define).Pos
+  case *ast.DefineContract:
+    pos = node.(*ast.DefineContract).Pos
+  case *ast.DefineEnum:
+    pos = node.(*ast.DefineEnum).Pos
+  case *ast.Set:
+    pos = node.(*ast.Set).Pos
+  case *ast.Go:
+    pos = node.(*ast.Go).Pos
+  }
+  if pos == "" {
+    return filename
+  }
+  return pos
+}
+
+func moduleName(filename string) string {
+  base := baseName(filename)
+  return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// (load "file.ss"), (reload 'file) or (reload "file.ss")
+type loadPrimitive struct {
+  value.Primitive
+  loader *Loader
+  reload bool
+}
+
+func (self *loadPrimitive) Apply(args []value.Value) value.Value {
+  if len(args) != 1 {
+    panic(fmt.Sprintf("%s: arguments mismatch, expected 1", self.Name))
+  }
+  switch args[0].(type) {
+  case *value.StringValue:
+    filename := args[0].(*value.StringValue).Value
+    if self.reload {
+      self.loader.Reload(moduleName(filename))
+    } else {
+      self.loader.Load(filename)
+    }
+  case *value.Symbol:
+    if !self.reload {
+      panic(fmt.Sprint("load: expected string, given: ", args[0]))
+    }
+    self.loader.Reload(args[0].(*value.Symbol).Value)
+  default:
+    panic(fmt.Sprintf("%s: expected string, given: %s", self.Name, args[0]))
+  }
+  return nil
+}
@@ -0,0 +1,150 @@
+package repl
+
+import (
+  "bufio"
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/parser"
+  "github.com/kedebug/LispEx/value"
+  "io"
+  "strings"
+  "unicode"
+)
+
+// the next datum of reader as the parser reads it, or the eof object.
+// only the characters of the datum are taken from reader, so that
+// what follows it can be read by read-line or read-char
+func readDatum(reader *bufio.Reader) value.Value {
+  for {
+    text, err := datumText(reader)
+    if err != nil {
+      panic(fmt.Sprint("read: ", err))
+    }
+    if strings.TrimSpace(text) == "" {
+      return value.EOF
+    }
+    // the errors of the parser tell their position in the datum,
+    // e.g. "read:1:4: ...", read being the name of the source
+    forms, err := parser.ReadFromString("read", text)
+    if err != nil {
+      panic(fmt.Sprint(err))
+    }
+    // a datum comment, #; x, reads as nothing
+    if len(forms) > 0 {
+      return datum(forms[0])
+    }
+  }
+}
+
+// the datum of a form read. its errors are told like those of the
+// parser, whose source is named read: "read: illegal use of `.'"
+func datum(node ast.Node) value.Value {
+  defer func() {
+    if err := recover(); err != nil {
+      panic(fmt.Sprint("read: ", err))
+    }
+  }()
+  return ast.ToDatum(node)
+}
+
+// the text of the next datum: a list up to its closing parenthesis, a
+// string up to its closing quote or an atom up to the next delimiter,
+// after the prefixes quoting it. comments before it are skipped
+func datumText(reader *bufio.Reader) (string, error) {
+  var text strings.Builder
+  depth, atom, start := 0, false, 0
+  for {
+    r, _, err := reader.ReadRune()
+    if err == io.EOF {
+      if depth > 0 {
+        return "", fmt.Errorf("unexpected end of input in %s", text.String())
+      }
+      return text.String(), nil
+    } else if err != nil {
+      return "", err
+    }
+    if atom && depth == 0 && (unicode.IsSpace(r) || strings.ContainsRune("()\";|", r)) {
+      switch prefix := text.String()[start:]; {
+      case r == '(' && (prefix == "#" || prefix == "#u8"):
+        // #( and #u8( open vectors
+      case r == ';' && prefix == "#":
+        // #; comments out the datum after it
+        text.WriteRune(r)
+        atom = false
+        continue
+      default:
+        reader.UnreadRune()
+        return text.String(), nil
+      }
+    }
+    switch {
+    case r == ';':
+      if _, err := reader.ReadString('\n'); err != nil && err != io.EOF {
+        return "", err
+      }
+      text.WriteRune('\n')
+      continue
+    case unicode.IsSpace(r):
+      text.WriteRune(r)
+      continue
+    case r == '"' || r == '|':
+      // a string or a symbol like |odd name|
+      text.WriteRune(r)
+      if err := delimitedText(reader, &text, r); err != nil {
+        return "", err
+      }
+    case r == '(':
+      depth++
+      text.WriteRune(r)
+    case r == ')':
+      if depth == 0 {
+        return "", fmt.Errorf("unexpected `%c'", r)
+      }
+      depth--
+      text.WriteRune(r)
+    case r == '\\' && strings.HasSuffix(text.String(), "#"):
+      // #\( is a character, not a list
+      text.WriteRune(r)
+      next, _, err := reader.ReadRune()
+      if err != nil {
+        return "", fmt.Errorf("unexpected end of input in %s", text.String())
+      }
+      text.WriteRune(next)
+      continue
+    case depth == 0 && !atom && strings.ContainsRune("'`,@", r):
+      text.WriteRune(r)
+      continue
+    default:
+      if depth == 0 && !atom {
+        atom, start = true, text.Len()
+      }
+      text.WriteRune(r)
+      continue
+    }
+    atom = false
+    if depth == 0 {
+      return text.String(), nil
+    }
+  }
+}
+
+// the rest of a string literal or a symbol between bars,
+// escapes included, after its opening delimiter
+func delimitedText(reader *bufio.Reader, text *strings.Builder, delimiter rune) error {
+  for {
+    r, _, err := reader.ReadRune()
+    if err != nil {
+      return fmt.Errorf("unexpected end of input in %s", text.String())
+    }
+    text.WriteRune(r)
+    if r == '\\' {
+      r, _, err = reader.ReadRune()
+      if err != nil {
+        return fmt.Errorf("unexpected end of input in %s", text.String())
+      }
+      text.WriteRune(r)
+    } else if r == delimiter {
+      return nil
+    }
+  }
+}
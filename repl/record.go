@@ -0,0 +1,22 @@
+package repl
+
+import (
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "reflect"
+)
+
+// define a record type mirroring the struct of example, e.g.
+// RegisterStruct(env, MyConfig{}), with its constructor, predicate,
+// accessors and modifiers. values of the struct passed to lisp with
+// converter.ToValue become records, converter.RecordToStruct reads
+// them back after lisp code edited them
+func RegisterStruct(env *scope.Scope, example interface{}) *value.RecordType {
+  rt := converter.RegisterStruct(reflect.TypeOf(example))
+  for _, builtin := range primitives.RecordBuiltins(rt) {
+    env.Put(builtin.Name, builtin)
+  }
+  return rt
+}
@@ -0,0 +1,85 @@
+package repl
+
+import (
+  "fmt"
+  "io"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "path"
+  "path/filepath"
+  "strings"
+)
+
+// the largest program read from a URL
+const MaxRemoteSize = 1 << 20
+
+func IsURL(filename string) bool {
+  return strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://")
+}
+
+// the source of a program, from a file or a URL. programs are read
+// from http and https URLs only when remote is, as with -allow-urls
+func ReadSource(filename string, remote bool) ([]byte, error) {
+  if !IsURL(filename) {
+    return ioutil.ReadFile(filename)
+  }
+  if !remote {
+    return nil, fmt.Errorf("%s: reading programs from URLs is disabled, see -allow-urls", filename)
+  }
+  response, err := http.Get(filename)
+  if err != nil {
+    return nil, err
+  }
+  defer response.Body.Close()
+  if response.StatusCode < 200 || response.StatusCode > 299 {
+    return nil, fmt.Errorf("%s: %s", filename, response.Status)
+  }
+  // one byte more tells a program of the size limit from a larger one
+  source, err := ioutil.ReadAll(io.LimitReader(response.Body, MaxRemoteSize+1))
+  if err != nil {
+    return nil, err
+  }
+  if len(source) > MaxRemoteSize {
+    return nil, fmt.Errorf("%s: larger than %d bytes", filename, MaxRemoteSize)
+  }
+  return source, nil
+}
+
+// filename relative to dir, the directory of the module loading
+// it or, for a module read from a URL, that URL
+func resolve(dir, filename string) string {
+  if dir == "" || IsURL(filename) || filepath.IsAbs(filename) {
+    return filename
+  }
+  if !IsURL(dir) {
+    return filepath.Join(dir, filename)
+  }
+  base, err := url.Parse(dir)
+  if err != nil {
+    return filename
+  }
+  ref, err := url.Parse(filename)
+  if err != nil {
+    return filename
+  }
+  return base.ResolveReference(ref).String()
+}
+
+// the directory of a module against which it loads others
+func moduleDir(filename string) string {
+  if IsURL(filename) {
+    return filename
+  }
+  return filepath.Dir(filename)
+}
+
+// the file name of a URL without its query
+func baseName(filename string) string {
+  if IsURL(filename) {
+    if u, err := url.Parse(filename); err == nil {
+      return path.Base(u.Path)
+    }
+  }
+  return filepath.Base(filename)
+}
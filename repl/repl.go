@@ -1,20 +1,172 @@
 package repl
 
 import (
+  "context"
+  "errors"
   "fmt"
+  "github.com/kedebug/LispEx/analysis"
   "github.com/kedebug/LispEx/ast"
   "github.com/kedebug/LispEx/parser"
   "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "io"
+  "runtime"
+  "strings"
 )
 
 // read-eval-print loop
 func REPL(exprs string, env *scope.Scope) string {
+  return Print(EvalSource("<REPL>", exprs, env))
+}
+
+// the phases EvalSource goes through, for tools which stop at one of
+// them: a formatter only reads a program, a linter reads and expands
+// it. Read returns the forms as written, lists being *ast.Tuple
+func Read(name, exprs string) ([]ast.Node, error) {
+  return parser.ReadFromString(name, exprs)
+}
+
+// the forms read with their macros expanded and special forms parsed.
+// errors of both phases are a *lexer.Error or a *parser.Error
+func Expand(name string, forms []ast.Node) ([]ast.Node, error) {
+  return parser.Expand(name, forms)
+}
+
+// the value of each expanded form, evaluated in env
+func Eval(nodes []ast.Node, env *scope.Scope) []value.Value {
+  defer value.Evaluating(value.ProgramEvaluator)()
+  analyze(nodes)
+  defer ast.Undeclare(ast.Declare(nodes, env), env)
+  return ast.EvalList(nodes, env)
+}
+
+// reads, expands and evaluates the program, source positions
+// of its errors refer to name
+func EvalSource(name, exprs string, env *scope.Scope) []value.Value {
+  return Eval(parse(name, exprs, env), env)
+}
+
+// like EvalSource, the value of each top-level form is written to out
+// as soon as it is evaluated while env.DisplayResults() is on
+func EvalPrinting(name, exprs string, env *scope.Scope, out io.Writer) {
+  defer value.Evaluating(value.ProgramEvaluator)()
+  sexprs := parse(name, exprs, env)
+  analyze(sexprs)
+  defer ast.Undeclare(ast.Declare(sexprs, env), env)
+  for _, node := range sexprs {
+    if val := node.Eval(env); val != nil && env.DisplayResults() {
+      fmt.Fprintln(out, val)
+    }
+  }
+}
+
+// the passes preparing a program for evaluation
+func analyze(nodes []ast.Node) {
+  analysis.ConvertClosures(nodes)
+  analysis.MarkReusableScopes(nodes)
+}
+
+// like EvalSource, returning the error raised instead of panicking:
+// a *value.SyntaxError, *value.UnboundVariable, *value.TypeError,
+// *value.ArityError or else a *value.Error, wrapped in a *LoadError
+// when raised by a loaded file. bugs of the interpreter itself,
+// runtime errors, still panic
+func Run(name, exprs string, env *scope.Scope) (values []value.Value, err error) {
+  defer recoverError(&err)
+  return EvalSource(name, exprs, env), nil
+}
+
+// like EvalPrinting, returning the error raised like Run
+func RunPrinting(name, exprs string, env *scope.Scope, out io.Writer) (err error) {
+  defer recoverError(&err)
+  EvalPrinting(name, exprs, env, out)
+  return nil
+}
+
+func recoverError(err *error) {
+  if e := recover(); e != nil {
+    switch e.(type) {
+    case runtime.Error:
+      panic(e)
+    case error:
+      *err = e.(error)
+    default:
+      *err = &value.Error{Message: fmt.Sprint(e)}
+    }
+  }
+}
+
+// the message of err for the user, after the source position
+// where it was raised if known and the procedure it was raised in,
+// followed by the calls leading there, e.g.
+//  a.ss:2:3 in procedure f: car: expected pair, given: 1
+//    called at a.ss:5:3 in procedure g
+//    called at a.ss:7:1
+// the messages of syntax errors and load errors tell their positions
+func FormatError(err error) string {
+  var load *LoadError
+  if errors.As(err, &load) {
+    return err.Error()
+  }
+  var where []string
+  var located value.Positioned
+  if errors.As(err, &located) && located.Position() != "" {
+    where = append(where, located.Position())
+  }
+  var backtrace *value.Backtrace
+  if !errors.As(err, &backtrace) {
+    backtrace = &value.Backtrace{Err: err}
+  }
+  trace := backtrace.Trace
+  if len(trace) > 0 {
+    where = append(where, "in "+procedureName(trace[0].Procedure))
+  }
+  text := err.Error()
+  if len(where) > 0 {
+    text = strings.Join(where, " ") + ": " + text
+  }
+  // recursive calls repeat the same line
+  var lines []string
+  var counts []int
+  for i, frame := range trace {
+    line := "called at " + frame.Pos
+    if i+1 < len(trace) {
+      line += " in " + procedureName(trace[i+1].Procedure)
+    }
+    if n := len(lines); n > 0 && lines[n-1] == line {
+      counts[n-1]++
+      continue
+    }
+    lines, counts = append(lines, line), append(counts, 1)
+  }
+  for i, line := range lines {
+    if i == maxBacktrace && len(lines) > maxBacktrace+1 {
+      text += fmt.Sprintf("\n  ... %d more", len(lines)-i-1)
+      i, line = len(lines)-1, lines[len(lines)-1]
+    } else if i > maxBacktrace {
+      break
+    }
+    text += "\n  " + line
+    if counts[i] > 1 {
+      text += fmt.Sprintf(" (%d times)", counts[i])
+    }
+  }
+  return text
+}
+
+// like Run, the I/O started by the program is canceled once ctx is
+// done. ctx is the context of the root scope of env while it runs
+func RunContext(ctx context.Context, name, exprs string, env *scope.Scope) ([]value.Value, error) {
+  previous := env.Context()
+  env.SetContext(ctx)
+  defer env.SetContext(previous)
+  return Run(name, exprs, env)
+}
+
+func Print(values []value.Value) string {
   result := ""
   first := true
 
-  sexprs := parser.ParseFromString("<REPL>", exprs)
-  values := ast.EvalList(sexprs, env)
-
   for _, val := range values {
     if val != nil {
       if first {
@@ -27,3 +179,13 @@ func REPL(exprs string, env *scope.Scope) string {
   }
   return result
 }
+
+// calls listed by FormatError before skipping to the outermost
+const maxBacktrace = 20
+
+func procedureName(name string) string {
+  if name == "" {
+    return "an anonymous procedure"
+  }
+  return "procedure " + name
+}
@@ -0,0 +1,139 @@
+package repl
+
+import (
+  "context"
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/parser"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "time"
+)
+
+// the keyword of the per test timeout, (test name #:timeout ms body...)
+const timeoutKeyword = "#:timeout"
+
+// the outcome of a (test name [#:timeout ms] body...) form, which
+// passes unless its body raises an error or returns #f
+type TestResult struct {
+  Name    string
+  Pos     string
+  Elapsed time.Duration
+  // nil when the test passed
+  Err error
+}
+
+// evaluates the top-level forms of the program in env, the test forms
+// among them each under a deadline: timeout, or the milliseconds given
+// with #:timeout. a test still running then is stopped at its next
+// procedure call and fails while the others go on, so that a loop in
+// one of them doesn't hang the suite. err is that of a form which is
+// not a test, the tests after it are not run
+func RunTests(name, exprs string, env *scope.Scope, timeout time.Duration) (results []*TestResult, err error) {
+  forms, err := parser.Read(lexer.NewFoldingLexer(name, exprs, env.FoldCase()))
+  if err != nil {
+    return nil, &value.SyntaxError{Err: err}
+  }
+  env.SetInterruptible(true)
+  defer env.SetInterruptible(false)
+  for _, form := range forms {
+    if tuple, ok := form.(*ast.Tuple); ok && isTest(tuple) {
+      results = append(results, runTest(name, tuple, env, timeout))
+      continue
+    }
+    if err := runForm(name, form, env); err != nil {
+      return results, err
+    }
+  }
+  return results, nil
+}
+
+func isTest(tuple *ast.Tuple) bool {
+  if len(tuple.Elements) == 0 {
+    return false
+  }
+  name, ok := tuple.Elements[0].(*ast.Name)
+  return ok && name.Identifier == "test"
+}
+
+func runForm(name string, form ast.Node, env *scope.Scope) (err error) {
+  defer recoverError(&err)
+  nodes, err := parser.ExpandAt(name, []ast.Node{form}, ast.NewTopSyntaxEnv(env))
+  if err != nil {
+    return &value.SyntaxError{Err: err}
+  }
+  Eval(nodes, env)
+  return nil
+}
+
+func runTest(name string, tuple *ast.Tuple, env *scope.Scope, timeout time.Duration) *TestResult {
+  result := &TestResult{Pos: tuple.Pos}
+  body, err := testParts(tuple, result, &timeout)
+  if err != nil {
+    result.Err = err
+    return result
+  }
+  nodes, err := parser.ExpandAt(name, body, ast.NewTopSyntaxEnv(env))
+  if err != nil {
+    result.Err = &value.SyntaxError{Err: err}
+    return result
+  }
+  ctx, cancel := context.WithTimeout(env.Context(), timeout)
+  defer cancel()
+  previous := env.Context()
+  env.SetContext(ctx)
+  defer env.SetContext(previous)
+  start := time.Now()
+  // definitions of the test stay within it
+  result.Err = evalTest(nodes, scope.NewScope(env))
+  result.Elapsed = time.Since(start)
+  if result.Err != nil && ctx.Err() == context.DeadlineExceeded {
+    result.Err = fmt.Errorf("timed out after %v", timeout)
+  }
+  return result
+}
+
+// the body of (test name [#:timeout ms] body...), setting
+// the name of result and timeout if the test has one
+func testParts(tuple *ast.Tuple, result *TestResult, timeout *time.Duration) ([]ast.Node, error) {
+  elements := tuple.Elements
+  if len(elements) < 3 {
+    return nil, fmt.Errorf("test: bad syntax, expected a name and a body, given: %s", tuple)
+  }
+  name, ok := elements[1].(*ast.String)
+  if !ok {
+    return nil, fmt.Errorf("test: expected a string as name, given: %s", elements[1])
+  }
+  result.Name = name.Value
+  body := elements[2:]
+  if keyword, ok := body[0].(*ast.Name); ok && keyword.Identifier == timeoutKeyword {
+    if len(body) < 3 {
+      return nil, fmt.Errorf("test: bad syntax, expected milliseconds and a body after %s, given: %s", timeoutKeyword, tuple)
+    }
+    ms, ok := body[1].(*ast.Int)
+    if !ok || ms.Value <= 0 {
+      return nil, fmt.Errorf("test: expected milliseconds after %s, given: %s", timeoutKeyword, body[1])
+    }
+    *timeout = time.Duration(ms.Value) * time.Millisecond
+    body = body[2:]
+  }
+  return body, nil
+}
+
+func evalTest(nodes []ast.Node, env *scope.Scope) (err error) {
+  defer recoverError(&err)
+  values := Eval(nodes, env)
+  if last, ok := values[len(values)-1].(*value.BoolValue); ok && !last.Value {
+    return fmt.Errorf("returned #f")
+  }
+  return nil
+}
+
+// e.g. "ok    sum (2ms)", "FAIL  loop: timed out after 100ms"
+func (self *TestResult) String() string {
+  if self.Err == nil {
+    return fmt.Sprintf("ok    %s (%s)", self.Name, self.Elapsed.Round(time.Millisecond))
+  }
+  return fmt.Sprintf("FAIL  %s: %s", self.Name, FormatError(self.Err))
+}
@@ -0,0 +1,160 @@
+package repl
+
+import (
+  "errors"
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "io/ioutil"
+  "os"
+  "strings"
+  "sync"
+)
+
+const (
+  prompt             = ">>> "
+  continuationPrompt = "... "
+  escape             = '\\'
+)
+
+// a record of an interactive session written to a file: the lines
+// typed, after the prompt they were typed at, and what the session
+// printed in between. (load-history "file") evaluates the forms of
+// a transcript again. lines printed starting with '>', '.' or the
+// escape itself are escaped with a backslash, so that no output is
+// mistaken for a line typed
+type Transcript struct {
+  Filename string
+  file     *os.File
+  // whether the output written last left its line unfinished
+  midline bool
+  mutex   sync.Mutex
+}
+
+func NewTranscript(filename string) (*Transcript, error) {
+  file, err := os.Create(filename)
+  if err != nil {
+    return nil, err
+  }
+  return &Transcript{Filename: filename, file: file}, nil
+}
+
+// record the lines of an input, as returned by Input.Text. a prompt
+// always starts a line, after output like (display 1) did not end it
+func (self *Transcript) Input(text string) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  if self.midline {
+    fmt.Fprintln(self.file)
+    self.midline = false
+  }
+  for i, line := range strings.Split(text, "\n") {
+    if i == 0 {
+      fmt.Fprintln(self.file, prompt+line)
+    } else {
+      fmt.Fprintln(self.file, continuationPrompt+line)
+    }
+  }
+}
+
+// record output of the session
+func (self *Transcript) Write(p []byte) (int, error) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  escaped := make([]byte, 0, len(p))
+  for _, b := range p {
+    if !self.midline && (b == '>' || b == '.' || b == escape) {
+      escaped = append(escaped, escape)
+    }
+    escaped = append(escaped, b)
+    self.midline = b != '\n'
+  }
+  if _, err := self.file.Write(escaped); err != nil {
+    return 0, err
+  }
+  return len(p), nil
+}
+
+func (self *Transcript) Close() error {
+  return self.file.Close()
+}
+
+// the forms typed in a transcript. the other lines, output, escaped
+// or not, and REPL commands like :undo, are left blank so that the
+// lines of the forms keep their number
+func TranscriptSource(text string) string {
+  lines := strings.Split(text, "\n")
+  for i, line := range lines {
+    switch {
+    case strings.HasPrefix(line, prompt) && !strings.HasPrefix(line, prompt+":"):
+      lines[i] = line[len(prompt):]
+    case strings.HasPrefix(line, continuationPrompt):
+      lines[i] = line[len(continuationPrompt):]
+    default:
+      lines[i] = ""
+    }
+  }
+  return strings.Join(lines, "\n")
+}
+
+// evaluate the forms of a transcript in the loader's scope, each on
+// its own, writing their values or their errors like the REPL did
+func (self *Loader) LoadHistory(filename string) {
+  self.modules.mutex.Lock()
+  if self.modules.loading[filename] {
+    self.modules.mutex.Unlock()
+    panic(fmt.Sprint("load-history: circular load of ", filename))
+  }
+  self.modules.loading[filename] = true
+  self.modules.mutex.Unlock()
+  defer func() {
+    self.modules.mutex.Lock()
+    delete(self.modules.loading, filename)
+    self.modules.mutex.Unlock()
+  }()
+
+  text, err := ioutil.ReadFile(filename)
+  if err != nil {
+    panic(fmt.Sprint("load-history: ", err))
+  }
+  nodes := parse(filename, TranscriptSource(string(text)), self.env)
+  analyze(nodes)
+  for _, node := range nodes {
+    val, err := replayForm(node, self.env)
+    var interrupted *value.Interrupted
+    switch {
+    case errors.As(err, &interrupted):
+      panic(err)
+    case err != nil:
+      fmt.Fprintln(primitives.Output(), FormatError(err))
+    case val != nil:
+      fmt.Fprintln(primitives.Output(), val)
+    }
+  }
+}
+
+// the value of node evaluated in env, or the error raised like Run
+func replayForm(node ast.Node, env *scope.Scope) (val value.Value, err error) {
+  defer recoverError(&err)
+  return node.Eval(env), nil
+}
+
+// (load-history "session.txt")
+type loadHistoryPrimitive struct {
+  value.Primitive
+  loader *Loader
+}
+
+func (self *loadHistoryPrimitive) Apply(args []value.Value) value.Value {
+  if len(args) != 1 {
+    panic(fmt.Sprint("load-history: arguments mismatch, expected 1"))
+  }
+  filename, ok := args[0].(*value.StringValue)
+  if !ok {
+    panic(fmt.Sprint("load-history: expected string, given: ", args[0]))
+  }
+  self.loader.LoadHistory(filename.Value)
+  return nil
+}
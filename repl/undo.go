@@ -0,0 +1,53 @@
+package repl
+
+import (
+  "github.com/kedebug/LispEx/scope"
+)
+
+// the global bindings each input of an interactive session defined
+// or assigned, so that `:undo' can revert experimental redefinitions
+// one input at a time, the most recent first. only the last depth
+// inputs which changed a binding can be undone, the changes of older
+// ones are forgotten
+type Undo struct {
+  env    *scope.Scope
+  inputs []*scope.AuditLog
+  depth  int
+}
+
+func NewUndo(env *scope.Scope, depth int) *Undo {
+  return &Undo{env: env, depth: depth}
+}
+
+// called before each input is evaluated
+func (self *Undo) Begin() {
+  // the previous input, evaluated by now, is
+  // only kept if it changed a binding
+  if n := len(self.inputs); n > 0 && len(self.inputs[n-1].Changes()) == 0 {
+    self.inputs = self.inputs[:n-1]
+  }
+  if len(self.inputs) == self.depth {
+    self.inputs = append(self.inputs[:0], self.inputs[1:]...)
+  }
+  log := scope.NewAuditLog()
+  self.inputs = append(self.inputs, log)
+  self.env.SetAuditLog(log)
+}
+
+// revert the bindings changed by the last input which changed any,
+// returns the changes undone, none if there is nothing left to undo
+func (self *Undo) Undo() []*scope.Change {
+  for len(self.inputs) > 0 {
+    log := self.inputs[len(self.inputs)-1]
+    self.inputs = self.inputs[:len(self.inputs)-1]
+    changes := log.Changes()
+    if len(changes) == 0 {
+      continue
+    }
+    for i := len(changes) - 1; i >= 0; i-- {
+      log.Rollback(changes[i])
+    }
+    return changes
+  }
+  return nil
+}
@@ -0,0 +1,47 @@
+package repl
+
+import (
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/parser"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+)
+
+// the program read and expanded at the top level of the interpreter
+// of env, with its settings. its special forms and macros are counted
+// as written if env counts its usage, rather than as expanded: a cond
+// is counted as a cond and not as the ifs it expands to
+func parse(name, exprs string, env *scope.Scope) []ast.Node {
+  forms, err := parser.Read(lexer.NewFoldingLexer(name, exprs, env.FoldCase()))
+  if err != nil {
+    panic(&value.SyntaxError{Err: err})
+  }
+  top := ast.NewTopSyntaxEnv(env)
+  if env.Usage() != nil {
+    countForms(forms, top, env)
+  }
+  nodes, err := parser.ExpandAt(name, forms, top)
+  if err != nil {
+    panic(&value.SyntaxError{Err: err})
+  }
+  return nodes
+}
+
+// quoted data is no code, whatever its lists start with
+func countForms(forms []ast.Node, top *ast.SyntaxEnv, env *scope.Scope) {
+  for _, form := range forms {
+    tuple, ok := form.(*ast.Tuple)
+    if !ok || len(tuple.Elements) == 0 {
+      continue
+    }
+    if name, ok := tuple.Elements[0].(*ast.Name); ok && parser.IsKeyword(top, name.Identifier) {
+      env.CountForm(name.Identifier)
+      if name.Identifier == constants.QUOTE {
+        continue
+      }
+    }
+    countForms(tuple.Elements, top, env)
+  }
+}
@@ -0,0 +1,95 @@
+package repl
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "github.com/kedebug/LispEx/value"
+  "io"
+  "os"
+  "time"
+)
+
+// the outcome of the nth evaluation started by Watch
+type watchResult struct {
+  n   int
+  ctx context.Context
+  err error
+}
+
+// evaluates filename with eval, then polls its modification time every
+// interval and evaluates it again after every change, until ctx is done.
+// eval is to evaluate the file in a fresh root scope whose context is
+// the one given and which is interruptible: the context of an evaluation
+// is canceled before the next one starts, so that the previous run and
+// the goroutines it started stop at their next procedure call, sleep or
+// I/O instead of running along with the new one. an error is printed
+// to out only when it differs from the one printed last
+func Watch(ctx context.Context, filename string, interval time.Duration, eval func(ctx context.Context) error, out io.Writer) {
+  var modified time.Time
+  runs, last := 0, 0
+  reported := ""
+  report := func(message string) {
+    if message != "" && message != reported {
+      fmt.Fprintln(out, message)
+    }
+    reported = message
+  }
+
+  cancel := func() {}
+  defer func() { cancel() }()
+  results := make(chan watchResult)
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+  for {
+    info, err := os.Stat(filename)
+    if err != nil {
+      report(err.Error())
+    } else if info.ModTime() != modified {
+      modified = info.ModTime()
+      cancel()
+      runs++
+      n := runs
+      run, stop := context.WithCancel(ctx)
+      cancel = stop
+      fmt.Fprintf(out, ";; %s evaluating %s\n", time.Now().Format("15:04:05"), filename)
+      go func() {
+        result := watchResult{n, run, watchEval(run, eval)}
+        select {
+        case results <- result:
+        case <-ctx.Done():
+        }
+      }()
+    }
+
+    select {
+    case <-ctx.Done():
+      return
+    case result := <-results:
+      // the error of a run stopped for a newer one is not
+      // reported, nor a result coming after a newer one
+      canceled := result.ctx.Err() != nil && errors.Is(result.err, context.Canceled)
+      if canceled || result.n < last {
+        continue
+      }
+      last = result.n
+      if result.err != nil {
+        report(FormatError(result.err))
+      } else {
+        report("")
+      }
+    case <-ticker.C:
+    }
+  }
+}
+
+// errors of the program, like deadlocks, are reported
+// without the stack of the interpreter
+func watchEval(ctx context.Context, eval func(ctx context.Context) error) (err error) {
+  defer func() {
+    if e := recover(); e != nil {
+      err = &value.Error{Message: fmt.Sprint(e)}
+    }
+  }()
+  return eval(ctx)
+}
@@ -0,0 +1,97 @@
+package scope
+
+import (
+  "fmt"
+  "sync"
+)
+
+// a global binding defined or assigned by a script,
+// Old is nil when the name wasn't bound in the scope before
+type Change struct {
+  Form string
+  Name string
+  Old  interface{}
+  New  interface{}
+  Pos  string
+
+  scope       *Scope
+  wasConstant bool
+}
+
+// e.g. plugin.ss:3: set! limit: 10 -> 20
+func (self *Change) String() string {
+  old := "unbound"
+  if self.Old != nil {
+    old = fmt.Sprint(self.Old)
+  }
+  return fmt.Sprintf("%s: %s %s: %s -> %s", self.Pos, self.Form, self.Name, old, self.New)
+}
+
+// the changes scripts make to the global bindings of an interpreter,
+// in order, so that plugin hosts can review what a plugin changed
+// and roll back some of it
+type AuditLog struct {
+  mutex   sync.Mutex
+  changes []*Change
+}
+
+func NewAuditLog() *AuditLog {
+  return &AuditLog{}
+}
+
+func (self *AuditLog) Changes() []*Change {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  return append([]*Change{}, self.changes...)
+}
+
+// restore the binding as it was before the change, unbinding names
+// the change defined. later changes of the binding are undone too,
+// roll back changes from the last to keep them consistent
+func (self *AuditLog) Rollback(change *Change) {
+  env := change.scope
+  env.mutex.Lock()
+  defer env.mutex.Unlock()
+  if change.Old == nil {
+    delete(env.env, change.Name)
+  } else {
+    env.env[change.Name] = change.Old
+  }
+  if change.wasConstant {
+    env.constants[change.Name] = true
+  } else {
+    delete(env.constants, change.Name)
+  }
+  env.changed()
+}
+
+// record every define and set! of global bindings evaluated in
+// the interpreter of the root scope of self, nil stops recording
+func (self *Scope) SetAuditLog(log *AuditLog) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.audit = log
+}
+
+// called before a define or set! of a script changes the binding
+// of name in self, which is recorded if self is a global scope
+func (self *Scope) Audit(form, name string, val interface{}, pos string) {
+  if self.local {
+    return
+  }
+  root := self.root()
+  root.mutex.RLock()
+  log := root.audit
+  root.mutex.RUnlock()
+  if log == nil {
+    return
+  }
+  self.mutex.RLock()
+  change := &Change{Form: form, Name: name, Old: self.env[name], New: val, Pos: pos,
+    scope: self, wasConstant: self.constants[name]}
+  self.mutex.RUnlock()
+  log.mutex.Lock()
+  defer log.mutex.Unlock()
+  log.changes = append(log.changes, change)
+}
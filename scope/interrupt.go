@@ -0,0 +1,47 @@
+package scope
+
+import (
+  "github.com/kedebug/LispEx/value"
+  "sync/atomic"
+)
+
+// number of interpreters stopping their evaluation once their
+// context is done, calls only look at contexts while there is any
+var interruptible int32
+
+// once the context of the root scope of self is done, evaluation in
+// its interpreter stops at the next procedure call with a
+// *value.Interrupted, rather than only the I/O it started. a runner
+// turns this on to put a deadline on the code it evaluates
+func (self *Scope) SetInterruptible(on bool) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  if root.interruptible != on {
+    if on {
+      atomic.AddInt32(&interruptible, 1)
+    } else {
+      atomic.AddInt32(&interruptible, -1)
+    }
+  }
+  root.interruptible = on
+}
+
+// raises a *value.Interrupted if evaluation in self is to stop
+func (self *Scope) CheckInterrupted() {
+  if atomic.LoadInt32(&interruptible) == 0 {
+    return
+  }
+  root := self.root()
+  root.mutex.RLock()
+  on, ctx := root.interruptible, root.context
+  root.mutex.RUnlock()
+  if !on || ctx == nil {
+    return
+  }
+  select {
+  case <-ctx.Done():
+    panic(&value.Interrupted{Err: ctx.Err()})
+  default:
+  }
+}
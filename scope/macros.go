@@ -0,0 +1,53 @@
+package scope
+
+import (
+  "sync"
+)
+
+// the macros defined by define-syntax in the programs of an
+// interpreter, by keyword. macros are expanded when forms are
+// parsed, so a macro is known to every program parsed after its
+// definition, but not to those of other interpreters. the rules
+// are a *macro.SyntaxRules, which this package doesn't know
+type Macros struct {
+  mutex sync.RWMutex
+  rules map[string]interface{}
+}
+
+func NewMacros() *Macros {
+  return &Macros{rules: make(map[string]interface{})}
+}
+
+func (self *Macros) Define(keyword string, rules interface{}) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  self.rules[keyword] = rules
+}
+
+// the rules of the macro named keyword, nil if there is none
+func (self *Macros) Lookup(keyword string) interface{} {
+  if self == nil {
+    return nil
+  }
+  self.mutex.RLock()
+  defer self.mutex.RUnlock()
+  return self.rules[keyword]
+}
+
+// a variable defined at the top level replaces the macro
+func (self *Macros) Undefine(keyword string) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  delete(self.rules, keyword)
+}
+
+// the macros of the interpreter of the root scope of self
+func (self *Scope) Macros() *Macros {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  if root.macros == nil {
+    root.macros = NewMacros()
+  }
+  return root.macros
+}
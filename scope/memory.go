@@ -0,0 +1,222 @@
+package scope
+
+import (
+  "github.com/kedebug/LispEx/value"
+  "sync"
+  "sync/atomic"
+)
+
+// number of interpreters with a memory limit, values
+// are only accounted for while there is any
+var limited int32
+
+// memory used by the values of an interpreter, the bytes allocated
+// since it was last measured added to what was measured then
+type quota struct {
+  mutex sync.Mutex
+  limit int64
+  used  int64
+}
+
+// limit the approximate memory held by the values of the interpreter
+// of the root scope of self, 0 removes the limit. going over it raises
+// a *value.OutOfMemory, leaving the interpreter usable once the values
+// are dropped. lists, strings, records, vectors, hash tables,
+// bytevectors and bitvectors built by builtins are accounted for as
+// they are allocated, and those whose size is given, like by
+// make-vector, are checked before; when their total goes over the
+// limit, the values reachable from the scope of the allocation are
+// measured to tell the memory still in use from garbage
+func (self *Scope) SetMemoryLimit(bytes int64) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  if root.quota == nil {
+    root.quota = new(quota)
+  }
+  if (root.quota.limit > 0) != (bytes > 0) {
+    if bytes > 0 {
+      atomic.AddInt32(&limited, 1)
+    } else {
+      atomic.AddInt32(&limited, -1)
+    }
+  }
+  root.quota.limit = bytes
+}
+
+// approximate bytes held by the values reachable from the scope
+func (self *Scope) MemoryUsed() int64 {
+  return newMeasure().scope(self)
+}
+
+// the quota of the interpreter, nil without a limit
+func (self *Scope) memoryQuota() *quota {
+  if atomic.LoadInt32(&limited) == 0 {
+    return nil
+  }
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.quota
+}
+
+// check that size more bytes, about to be allocated by a builtin
+// called in self, keep within the limit
+func (self *Scope) Reserve(size int64) {
+  q := self.memoryQuota()
+  if size == 0 || q == nil {
+    return
+  }
+
+  q.mutex.Lock()
+  defer q.mutex.Unlock()
+  if q.limit <= 0 || size <= q.limit-q.used {
+    return
+  }
+  used := newMeasure().scope(self)
+  if size > q.limit-used {
+    q.used = used
+    panic(&value.OutOfMemory{Used: used + size, Limit: q.limit})
+  }
+  q.used = used
+}
+
+// account for val, just returned by a builtin called in self
+func (self *Scope) Allocated(val value.Value) {
+  size := shallowSize(val)
+  q := self.memoryQuota()
+  if size == 0 || q == nil {
+    return
+  }
+
+  q.mutex.Lock()
+  defer q.mutex.Unlock()
+  q.used += size
+  if q.limit <= 0 || q.used <= q.limit {
+    return
+  }
+  m := newMeasure()
+  used := m.scope(self) + m.value(val)
+  if used > q.limit {
+    q.used = 0
+    panic(&value.OutOfMemory{Used: used, Limit: q.limit})
+  }
+  // leave some room before measuring again,
+  // which takes as long as there are values
+  q.used = used
+  if room := q.limit / 8; q.used > q.limit-room {
+    q.used = q.limit - room
+  }
+}
+
+const (
+  wordSize    = 8
+  pairSize    = 4 * wordSize
+  bindingSize = 6 * wordSize
+  entrySize   = 8 * wordSize
+)
+
+// bytes of the value itself, without the values it refers to
+func shallowSize(val value.Value) int64 {
+  switch val.(type) {
+  case *value.PairValue:
+    return pairSize
+  case *value.StringValue:
+    return 2*wordSize + int64(len(val.(*value.StringValue).Value))
+  case *value.Record:
+    return 4*wordSize + 2*wordSize*int64(len(val.(*value.Record).Values))
+  case *value.VectorValue:
+    return 3*wordSize + 2*wordSize*int64(len(val.(*value.VectorValue).Value))
+  case *value.HashTable:
+    return 8*wordSize + entrySize*int64(val.(*value.HashTable).Len())
+  case *value.Bytevector:
+    return 3*wordSize + int64(len(val.(*value.Bytevector).Value))
+  case *value.Bitvector:
+    return 4*wordSize + wordSize*int64(len(val.(*value.Bitvector).Words))
+  }
+  return 0
+}
+
+// sums up the sizes of values, counting values shared
+// by several others, and scopes, only once
+type measure struct {
+  seen map[interface{}]bool
+}
+
+func newMeasure() *measure {
+  return &measure{seen: make(map[interface{}]bool)}
+}
+
+func (self *measure) scope(s *Scope) int64 {
+  var size int64
+  for ; s != nil && !self.seen[s]; s = s.parent {
+    self.seen[s] = true
+    s.mutex.RLock()
+    values := make([]interface{}, 0, len(s.env))
+    for name, val := range s.env {
+      size += bindingSize + int64(len(name))
+      values = append(values, val)
+    }
+    s.mutex.RUnlock()
+    for _, val := range values {
+      if val, ok := val.(value.Value); ok {
+        size += self.value(val)
+      }
+    }
+  }
+  return size
+}
+
+func (self *measure) value(val value.Value) int64 {
+  var size int64
+  for {
+    switch val.(type) {
+    case *value.IntValue, *value.FloatValue, *value.CharValue:
+      return size + 2*wordSize
+    case *value.StringValue, *value.Record, *value.VectorValue, *value.HashTable, *value.Bytevector, *value.Bitvector:
+      if self.seen[val] {
+        return size
+      }
+      self.seen[val] = true
+      size += shallowSize(val)
+      switch val.(type) {
+      case *value.Record:
+        for _, field := range val.(*value.Record).Values {
+          size += self.value(field)
+        }
+      case *value.VectorValue:
+        for _, item := range val.(*value.VectorValue).Value {
+          size += self.value(item)
+        }
+      case *value.HashTable:
+        for _, pair := range val.(*value.HashTable).Pairs() {
+          size += self.value(pair[0]) + self.value(pair[1])
+        }
+      }
+      return size
+    case *value.Symbol:
+      return size + 2*wordSize + int64(len(val.(*value.Symbol).Value))
+    case *value.Closure:
+      if self.seen[val] {
+        return size
+      }
+      self.seen[val] = true
+      size += 2 * wordSize
+      if env, ok := val.(*value.Closure).Env.(*Scope); ok {
+        size += self.scope(env)
+      }
+      return size
+    case *value.PairValue:
+      // walk lists iteratively, they may be long
+      if self.seen[val] {
+        return size
+      }
+      self.seen[val] = true
+      pair := val.(*value.PairValue)
+      size += pairSize + self.value(pair.First)
+      val = pair.Second
+    default:
+      return size
+    }
+  }
+}
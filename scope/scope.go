@@ -1,62 +1,336 @@
 package scope
 
 import (
+  "context"
+  "fmt"
   "github.com/kedebug/LispEx/value"
   "github.com/kedebug/LispEx/value/primitives"
+  "sort"
+  "sync"
+  "sync/atomic"
 )
 
+// bumped whenever a binding of a global scope is defined or assigned,
+// invalidating every cached lookup
+var generation uint64
+
+// bindings may be read and replaced concurrently,
+// e.g. by goroutines running while a file is reloaded
 type Scope struct {
-  parent *Scope
-  env    map[string]interface{}
+  parent    *Scope
+  env       map[string]interface{}
+  constants map[string]bool
+  // bindings declared ahead of their definitions, see Declare
+  declared map[string]interface{}
+  frozen   bool
+  local    bool
+  mutex    sync.RWMutex
+  // of the I/O builtins, set on root scopes only
+  context context.Context
+  // of the memory held by the interpreter, likewise
+  quota *quota
+  // recording the changes of global bindings, likewise
+  audit *AuditLog
+  // whether the values of top-level forms are printed, likewise
+  displayResults bool
+  // of the special forms and builtins used, likewise
+  usage *Usage
+  // whether evaluation stops once context is done, likewise
+  interruptible bool
+  // defined by define-syntax, likewise
+  macros *Macros
+  // the settings a host chooses for the interpreter, likewise
+  contracts      *value.Setting
+  applicableData bool
+  foldCase       bool
+  remoteEnabled  bool
+  // whether the builtins of unrestricted are left out, likewise
+  restricted bool
+  // the library evaluated before the program, likewise
+  preludeName   string
+  preludeSource string
 }
 
 func NewScope(parent *Scope) *Scope {
   return &Scope{
-    parent: parent,
-    env:    make(map[string]interface{}),
+    parent:    parent,
+    env:       make(map[string]interface{}),
+    constants: make(map[string]bool),
+  }
+}
+
+// scope of a procedure call or a let form, as opposed
+// to the global scopes top-level code is evaluated in
+func NewLocalScope(parent *Scope) *Scope {
+  scope := NewScope(parent)
+  scope.local = true
+  return scope
+}
+
+// local scopes nothing refers to once their form is evaluated
+// are recycled, saving the allocation of their maps
+var localScopes = sync.Pool{New: func() interface{} {
+  return &Scope{
+    env:       make(map[string]interface{}),
+    constants: make(map[string]bool),
+    local:     true,
+  }
+}}
+
+// like NewLocalScope, the scope is to be given back by Release
+func AcquireLocalScope(parent *Scope) *Scope {
+  scope := localScopes.Get().(*Scope)
+  scope.parent = parent
+  return scope
+}
+
+func (self *Scope) Release() {
+  for name := range self.env {
+    delete(self.env, name)
+  }
+  for name := range self.constants {
+    delete(self.constants, name)
+  }
+  self.parent = nil
+  self.frozen = false
+  localScopes.Put(self)
+}
+
+func (self *Scope) Parent() *Scope {
+  return self.parent
+}
+
+// the innermost enclosing scope which is not local
+func (self *Scope) Global() *Scope {
+  for self.local && self.parent != nil {
+    self = self.parent
   }
+  return self
 }
 
 func NewRootScope() *Scope {
   root := NewScope(nil)
-  root.Put("+", primitives.NewAdd())
-  root.Put("-", primitives.NewSub())
-  root.Put("*", primitives.NewMult())
-  root.Put("/", primitives.NewDiv())
-  root.Put("=", primitives.NewEq())
-  root.Put(">", primitives.NewGt())
-  root.Put(">=", primitives.NewGtE())
-  root.Put("<", primitives.NewLt())
-  root.Put("<=", primitives.NewLtE())
-  root.Put("%", primitives.NewMod())
-  root.Put("and", primitives.NewAnd())
-  root.Put("or", primitives.NewOr())
-  root.Put("eqv?", primitives.NewIsEqv())
-  root.Put("type-of", primitives.NewTypeOf())
-  root.Put("display", primitives.NewDisplay())
-  root.Put("newline", primitives.NewNewline())
-  root.Put("car", primitives.NewCar())
-  root.Put("cdr", primitives.NewCdr())
-  root.Put("cons", primitives.NewCons())
-  root.Put("make-chan", primitives.NewMakeChan())
-  root.Put("close-chan", primitives.NewCloseChan())
-  root.Put("<-chan", primitives.NewChanRecv())
-  root.Put("chan<-", primitives.NewChanSend())
-  root.Put("sleep", primitives.NewSleep())
-  root.Put("random", primitives.NewRandom())
+  for _, builtin := range primitives.Builtins {
+    root.Put(builtin.Name, builtin)
+  }
+  root.Put("apropos", primitives.LookupBuiltin("apropos").With(primitives.NewApropos(root.Names)))
+  root.Put("sleep", primitives.LookupBuiltin("sleep").With(primitives.NewSleep(root.DynamicContext)))
+  root.Put("http-get", primitives.LookupBuiltin("http-get").With(primitives.NewHTTPGet(root.DynamicContext)))
+  root.Put("ws-connect", primitives.LookupBuiltin("ws-connect").With(primitives.NewWSConnect(root.DynamicContext)))
+  root.Put("ws-recv", primitives.LookupBuiltin("ws-recv").With(primitives.NewWSRecv(root.DynamicContext)))
+  root.Put("display-results", primitives.LookupBuiltin("display-results").With(primitives.NewDisplayResults(root.DisplayResults, root.SetDisplayResults)))
+  contracts := root.Contracts()
+  root.Put("contracts-enabled", primitives.LookupBuiltin("contracts-enabled").With(primitives.NewContractsEnabled(contracts.On, contracts.Set)))
   root.Put("#t", value.NewBoolValue(true))
   root.Put("#f", value.NewBoolValue(false))
   return root
 }
 
+// the builtins reaching outside the interpreter: files, the network,
+// the command line and the settings of the process
+var unrestricted = []string{
+  "http-get", "ws-connect", "ws-send!", "ws-recv", "ws-chan", "ws-close",
+  "open-input-file", "open-output-file", "for-each-line", "read-file-bytes",
+  "open-input-gzip-file", "open-input-zlib-file", "archive-entries", "open-input-archive-entry", "archive-create",
+  "command-line", "set-max-procs!", "set-go-pool-size!",
+}
+
+// a root scope for code which is not trusted, like the answers of the
+// tutorial: it binds every builtin but those listed in unrestricted
+func NewRestrictedRootScope() *Scope {
+  root := NewRootScope()
+  for _, name := range unrestricted {
+    delete(root.env, name)
+  }
+  root.restricted = true
+  return root
+}
+
+// a new root scope binding the builtins of the interpreter of self,
+// with its settings and prelude, for the interpreters its programs
+// start like the workers of par-map-isolated. the caller evaluates
+// the prelude in it
+func (self *Scope) NewRootLike() *Scope {
+  root := self.root()
+  root.mutex.RLock()
+  restricted := root.restricted
+  root.mutex.RUnlock()
+  like := NewRootScope()
+  if restricted {
+    like = NewRestrictedRootScope()
+  }
+  like.Contracts().Set(self.Contracts().On())
+  like.SetApplicableData(self.ApplicableData())
+  like.SetFoldCase(self.FoldCase())
+  like.SetRemoteEnabled(self.RemoteEnabled())
+  like.SetPrelude(self.Prelude())
+  return like
+}
+
+// the I/O started by builtins of the root scope, like http-get or
+// sleep, is canceled when its context is done. embedders set it to
+// impose a deadline on scripts, it is context.Background by default
+func (self *Scope) SetContext(ctx context.Context) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.context = ctx
+}
+
+func (self *Scope) Context() context.Context {
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  if root.context == nil {
+    return context.Background()
+  }
+  return root.context
+}
+
+// the context of the I/O started by the calling goroutine: the one of
+// the innermost nursery it evaluates within, canceled on the first
+// error raised there, or the context of the root scope
+func (self *Scope) DynamicContext() context.Context {
+  if ctx := value.CurrentDynamic().Context; ctx != nil {
+    return ctx
+  }
+  return self.Context()
+}
+
+// scripts run by repl.EvalPrinting print the value of each top-level
+// form while this is on, scripts turn it on with (display-results #t)
+func (self *Scope) SetDisplayResults(on bool) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.displayResults = on
+}
+
+func (self *Scope) DisplayResults() bool {
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.displayResults
+}
+
+func (self *Scope) root() *Scope {
+  for self.parent != nil {
+    self = self.parent
+  }
+  return self
+}
+
+func (self *Scope) changed() {
+  if !self.local {
+    atomic.AddUint64(&generation, 1)
+  }
+}
+
 func (self *Scope) Put(name string, value interface{}) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  self.checkFrozen(name)
   self.env[name] = value
+  delete(self.declared, name)
+  self.changed()
+}
+
+// binds name to a placeholder until it is defined by Put, e.g. the
+// procedures defined further down the file being loaded. unlike the
+// bindings of Put it is neither listed by Names nor audited
+func (self *Scope) Declare(name string, value interface{}) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  if self.declared == nil {
+    self.declared = make(map[string]interface{})
+  }
+  self.declared[name] = value
+  self.changed()
+}
+
+// removes the placeholder of name if it was never defined
+func (self *Scope) Undeclare(name string) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  if _, ok := self.declared[name]; ok {
+    delete(self.declared, name)
+    self.changed()
+  }
 }
 
 func (self *Scope) PutAll(other *Scope) {
+  other.mutex.RLock()
+  defer other.mutex.RUnlock()
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  for name := range other.env {
+    self.checkFrozen(name)
+  }
   for name, value := range other.env {
     self.env[name] = value
   }
+  self.changed()
+}
+
+func (self *Scope) PutConstant(name string, value interface{}) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  self.checkFrozen(name)
+  self.env[name] = value
+  self.constants[name] = true
+  self.changed()
+}
+
+// no binding of a frozen scope can be defined or assigned anymore,
+// though names can still be shadowed in child scopes
+func (self *Scope) Freeze() {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  self.frozen = true
+}
+
+// the mutex is held by the caller, so that no binding is
+// changed between the check and the change
+func (self *Scope) checkFrozen(name string) {
+  if self.frozen {
+    panic(fmt.Sprint("cannot change binding of a frozen scope: ", name))
+  }
+}
+
+func (self *Scope) IsConstant(name string) bool {
+  self.mutex.RLock()
+  defer self.mutex.RUnlock()
+  return self.frozen || self.constants[name]
+}
+
+// names bound in this scope and its ancestors, sorted
+func (self *Scope) Names() []string {
+  seen := make(map[string]bool)
+  var names []string
+  for env := self; env != nil; env = env.parent {
+    env.mutex.RLock()
+    for name := range env.env {
+      if !seen[name] {
+        seen[name] = true
+        names = append(names, name)
+      }
+    }
+    env.mutex.RUnlock()
+  }
+  sort.Strings(names)
+  return names
+}
+
+// names bound in this scope only, sorted
+func (self *Scope) LocalNames() []string {
+  self.mutex.RLock()
+  names := make([]string, 0, len(self.env))
+  for name := range self.env {
+    names = append(names, name)
+  }
+  self.mutex.RUnlock()
+  sort.Strings(names)
+  return names
 }
 
 func (self *Scope) Lookup(name string) interface{} {
@@ -71,9 +345,14 @@ func (self *Scope) Lookup(name string) interface{} {
 }
 
 func (self *Scope) LookupLocal(name string) interface{} {
+  self.mutex.RLock()
+  defer self.mutex.RUnlock()
   if v, ok := self.env[name]; ok {
     return v
   }
+  if v, ok := self.declared[name]; ok {
+    return v
+  }
   return nil
 }
 
@@ -86,3 +365,35 @@ func (self *Scope) FindScope(name string) *Scope {
     return nil
   }
 }
+
+// a global binding resolved from one place in the program,
+// e.g. the procedure of a call site. it is safe for concurrent use
+type Cache struct {
+  entry atomic.Value
+}
+
+type cacheEntry struct {
+  global     *Scope
+  generation uint64
+  value      interface{}
+}
+
+// like Lookup, local scopes are searched every time but a binding
+// found in the global scopes is cached until any of them changes
+func (self *Scope) LookupCached(name string, cache *Cache) interface{} {
+  env := self
+  for ; env.local && env.parent != nil; env = env.parent {
+    if value := env.LookupLocal(name); value != nil {
+      return value
+    }
+  }
+  current := atomic.LoadUint64(&generation)
+  if entry, ok := cache.entry.Load().(*cacheEntry); ok && entry.global == env && entry.generation == current {
+    return entry.value
+  }
+  value := env.Lookup(name)
+  if value != nil {
+    cache.entry.Store(&cacheEntry{env, current, value})
+  }
+  return value
+}
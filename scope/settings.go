@@ -0,0 +1,83 @@
+package scope
+
+import (
+  "github.com/kedebug/LispEx/value"
+)
+
+// whether define/contract checks the calls of the procedures defined
+// in the interpreter of self, on unless turned off. contracts refer
+// to the setting, those defined before it is turned off skip their
+// checks too
+func (self *Scope) Contracts() *value.Setting {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  if root.contracts == nil {
+    root.contracts = value.NewSetting(true)
+  }
+  return root.contracts
+}
+
+// let (v i) be (vector-ref v i) and (s i) be (string-ref s i), off
+// unless a host opts in since standard scheme has no such calls
+func (self *Scope) SetApplicableData(on bool) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.applicableData = on
+}
+
+func (self *Scope) ApplicableData() bool {
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.applicableData
+}
+
+// read Foo as foo in the programs evaluated by the interpreter, like
+// older R5RS implementations, as if they started with #!fold-case
+func (self *Scope) SetFoldCase(fold bool) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.foldCase = fold
+}
+
+func (self *Scope) FoldCase() bool {
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.foldCase
+}
+
+// programs are loaded from http and https URLs only
+// once enabled, as with -allow-urls
+func (self *Scope) SetRemoteEnabled(enabled bool) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.remoteEnabled = enabled
+}
+
+func (self *Scope) RemoteEnabled() bool {
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.remoteEnabled
+}
+
+// the library a host evaluates in the root scope before the program,
+// stdlib.ss or the file given with -stdlib, recorded for NewRootLike
+func (self *Scope) SetPrelude(name, source string) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  root.preludeName, root.preludeSource = name, source
+}
+
+func (self *Scope) Prelude() (name, source string) {
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.preludeName, root.preludeSource
+}
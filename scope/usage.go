@@ -0,0 +1,97 @@
+package scope
+
+import (
+  "sync"
+  "sync/atomic"
+)
+
+// number of interpreters counting their usage, nothing
+// is counted while there is none
+var counting int32
+
+// how often the special forms and macros were written in the programs
+// an interpreter evaluated, and how often they called each builtin,
+// for embedders to learn which features their scripts rely on. the
+// counts are only ever read by the embedder
+type Usage struct {
+  mutex    sync.Mutex
+  forms    map[string]int64
+  builtins map[string]int64
+}
+
+func NewUsage() *Usage {
+  return &Usage{forms: make(map[string]int64), builtins: make(map[string]int64)}
+}
+
+func (self *Usage) Forms() map[string]int64 {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  return copyCounts(self.forms)
+}
+
+func (self *Usage) Builtins() map[string]int64 {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  return copyCounts(self.builtins)
+}
+
+// start counting from zero again
+func (self *Usage) Reset() {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  self.forms = make(map[string]int64)
+  self.builtins = make(map[string]int64)
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+  copied := make(map[string]int64, len(counts))
+  for name, n := range counts {
+    copied[name] = n
+  }
+  return copied
+}
+
+// count the usage of the interpreter of the root scope
+// of self in usage, nil stops counting
+func (self *Scope) SetUsage(usage *Usage) {
+  root := self.root()
+  root.mutex.Lock()
+  defer root.mutex.Unlock()
+  if (root.usage != nil) != (usage != nil) {
+    if usage != nil {
+      atomic.AddInt32(&counting, 1)
+    } else {
+      atomic.AddInt32(&counting, -1)
+    }
+  }
+  root.usage = usage
+}
+
+// the usage counted for the interpreter of self, if any
+func (self *Scope) Usage() *Usage {
+  if atomic.LoadInt32(&counting) == 0 {
+    return nil
+  }
+  root := self.root()
+  root.mutex.RLock()
+  defer root.mutex.RUnlock()
+  return root.usage
+}
+
+// a special form or macro written in a program evaluated in self
+func (self *Scope) CountForm(keyword string) {
+  if usage := self.Usage(); usage != nil {
+    usage.mutex.Lock()
+    usage.forms[keyword]++
+    usage.mutex.Unlock()
+  }
+}
+
+// a builtin called from self
+func (self *Scope) CountBuiltin(name string) {
+  if usage := self.Usage(); usage != nil {
+    usage.mutex.Lock()
+    usage.builtins[name]++
+    usage.mutex.Unlock()
+  }
+}
@@ -0,0 +1,10 @@
+package stdlib
+
+import (
+  _ "embed"
+)
+
+// the standard library, built into the binary so that
+// it runs from any directory
+//go:embed stdlib.ss
+var Source string
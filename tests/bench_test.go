@@ -0,0 +1,58 @@
+package tests
+
+import (
+  "github.com/kedebug/LispEx/repl"
+  "github.com/kedebug/LispEx/scope"
+  "io/ioutil"
+  "testing"
+)
+
+// top level with stdlib loaded and the definitions evaluated
+func benchEnv(definitions string, b *testing.B) *scope.Scope {
+  lib, err := ioutil.ReadFile("../stdlib/stdlib.ss")
+  if err != nil {
+    b.Fatal(err)
+  }
+  root := scope.NewRootScope()
+  repl.REPL(string(lib), root)
+  root.Freeze()
+  env := repl.NewTopLevel(root)
+  repl.REPL(definitions, env)
+  return env
+}
+
+func benchEval(definitions, expr string, b *testing.B) {
+  env := benchEnv(definitions, b)
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    repl.REPL(expr, env)
+  }
+}
+
+// hot loop calling stdlib procedures
+func BenchmarkStdlibLoop(b *testing.B) {
+  benchEval(`
+    (define (loop n acc)
+      (if (zero? n)
+        acc
+        (loop (- n 1) (+ acc (abs (- 50 n))))))`, "(loop 1000 0)", b)
+}
+
+func BenchmarkRecursion(b *testing.B) {
+  benchEval(`
+    (define (fib n)
+      (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))`, "(fib 15)", b)
+}
+
+func BenchmarkClosures(b *testing.B) {
+  benchEval(`
+    (define (make-adder n) (lambda (x) (+ x n)))
+    (define (sum-adders n acc)
+      (if (= n 0) acc (sum-adders (- n 1) ((make-adder n) acc))))`, "(sum-adders 1000 0)", b)
+}
+
+func BenchmarkListOps(b *testing.B) {
+  benchEval(`
+    (define (range n) (if (= n 0) '() (cons n (range (- n 1)))))
+    (define xs (range 200))`, "(length (map (lambda (x) (* x x)) (filter odd? xs)))", b)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+  "github.com/kedebug/LispEx/lexer"
+  "github.com/kedebug/LispEx/parser"
+  "github.com/kedebug/LispEx/repl"
+  "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "io/ioutil"
+  "path/filepath"
+  "runtime"
+  "strings"
+  "testing"
+)
+
+// malformed programs are reported by panicking with a message,
+// runtime errors like an index out of range are bugs
+func checkPanic(t *testing.T, input string) {
+  if err := recover(); err != nil {
+    if _, ok := err.(runtime.Error); ok {
+      t.Fatalf("%q: %v", input, err)
+    }
+  }
+}
+
+func addCorpus(f *testing.F) {
+  files, _ := filepath.Glob("*.ss")
+  for _, file := range files {
+    if data, err := ioutil.ReadFile(file); err == nil {
+      f.Add(string(data))
+    }
+  }
+  for _, input := range []string{"", "(", ")", "'", "`,@", "(())", "(. 1)", "(1 . )", "#", "\"", "-", "1e", "(lambda)", "(define)", "; comment at the end"} {
+    f.Add(input)
+  }
+}
+
+func FuzzLexer(f *testing.F) {
+  addCorpus(f)
+  f.Fuzz(func(t *testing.T, input string) {
+    l := lexer.NewLexer("fuzz", input)
+    for {
+      token := l.NextToken()
+      if token.Type == lexer.TokenEOF || token.Type == lexer.TokenError {
+        break
+      }
+    }
+  })
+}
+
+func FuzzParse(f *testing.F) {
+  addCorpus(f)
+  f.Fuzz(func(t *testing.T, input string) {
+    defer checkPanic(t, input)
+    parser.ParseFromString("fuzz", input)
+  })
+}
+
+// builtins without side effects that always return,
+// special forms able to loop or block are rejected below
+var fuzzBuiltins = []string{
+  "+", "-", "*", "/", "%", "=", "<", ">", "<=", ">=",
+  "car", "cdr", "cons", "eqv?", "eq?", "equal?", "type-of",
+  "null?", "pair?", "list?", "number?", "integer?", "real?", "string?",
+  "symbol?", "boolean?", "procedure?", "template", "string->number",
+}
+
+var fuzzForms = []string{"lambda", "define", "let", "letrec", "go", "select", "delay", "force", "load", "reload"}
+
+func fuzzEnv() *scope.Scope {
+  env := scope.NewScope(nil)
+  for _, name := range fuzzBuiltins {
+    if builtin := primitives.LookupBuiltin(name); builtin != nil {
+      env.Put(name, builtin)
+    }
+  }
+  env.Put("#t", value.NewBoolValue(true))
+  env.Put("#f", value.NewBoolValue(false))
+  return env
+}
+
+func FuzzEval(f *testing.F) {
+  addCorpus(f)
+  f.Fuzz(func(t *testing.T, input string) {
+    for _, form := range fuzzForms {
+      if strings.Contains(input, form) {
+        return
+      }
+    }
+    defer checkPanic(t, input)
+    repl.REPL(input, fuzzEnv())
+  })
+}
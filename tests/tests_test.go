@@ -1,14 +1,51 @@
 package tests
 
 import (
+  "bytes"
+  "context"
+  "errors"
+  "fmt"
+  "github.com/kedebug/LispEx/analysis"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/doc"
+  "github.com/kedebug/LispEx/learn"
+  "github.com/kedebug/LispEx/lispex"
+  "github.com/kedebug/LispEx/parser"
   "github.com/kedebug/LispEx/repl"
   "github.com/kedebug/LispEx/scope"
+  "github.com/kedebug/LispEx/typecheck"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "github.com/kedebug/LispEx/websocket"
+  "io"
   "io/ioutil"
+  "math"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+  "sync"
   "testing"
+  "time"
+  "unsafe"
 )
 
 func testFile(filename string, t *testing.T) string {
-  lib, err := ioutil.ReadFile("../stdlib.ss")
+  return testFileWithPrelude("", filename, t)
+}
+
+// prelude is evaluated between stdlib and the file,
+// the values it produces are not part of the result
+func testFileWithPrelude(prelude, filename string, t *testing.T) string {
+  return testFileIn(scope.NewRootScope(), prelude, filename, t)
+}
+
+// like testFileWithPrelude, in the interpreter of root
+func testFileIn(root *scope.Scope, prelude, filename string, t *testing.T) string {
+  lib, err := ioutil.ReadFile("../stdlib/stdlib.ss")
   if err != nil {
     t.Error(err)
   }
@@ -16,7 +53,39 @@ func testFile(filename string, t *testing.T) string {
   if err != nil {
     t.Error(err)
   }
-  return repl.REPL(string(lib)+string(exprs), scope.NewRootScope())
+  root.SetPrelude("stdlib.ss", string(lib))
+  repl.REPL(string(lib), root)
+  root.Freeze()
+  env := repl.NewTopLevel(root)
+  repl.REPL(prelude, env)
+  return repl.Print(repl.EvalSource(filename, string(exprs), env))
+}
+
+// stdout written while running body
+func captureOutput(body func(), t *testing.T) string {
+  reader, writer, err := os.Pipe()
+  if err != nil {
+    t.Fatal(err)
+  }
+  stdout := os.Stdout
+  os.Stdout = writer
+  defer func() { os.Stdout = stdout }()
+
+  output := make(chan string)
+  go func() {
+    data, _ := ioutil.ReadAll(reader)
+    output <- string(data)
+  }()
+  body()
+  writer.Close()
+  return <-output
+}
+
+// the panic raised while evaluating exprs
+func testError(exprs string, env *scope.Scope) (err interface{}) {
+  defer func() { err = recover() }()
+  repl.REPL(exprs, env)
+  return nil
 }
 
 func TestIf(t *testing.T) {
@@ -92,7 +161,7 @@ func TestQuasiquote(t *testing.T) {
 func TestStdlib(t *testing.T) {
   result := testFile("stdlib_test.ss", t)
 
-  expected := "#t\n#t\n#f\n#t\n#f\n#f\n#t\n#f\n#t\n#t\n#t\n#t\n#f\n#t\n#t"
+  expected := "#t\n#t\n#f\n#t\n#t\n#f\n#t\n#f\n#t\n#t\n#t\n#t\n#f\n#t\n#t"
   expected += "\n1\n3\n(2)\n(4)\n1"
   expected += "\n#f\n#t\n#t\n#f\n#f\n#t\n#f\n#f"
   expected += "\n6\n4\n0\n288\n1\n(3 4 5 6)\n(2 4)"
@@ -105,6 +174,7 @@ func TestStdlib(t *testing.T) {
 func TestSelect(t *testing.T) {
   result := testFile("select_test.ss", t)
   expected := "\"hello world\"\n3\n1\n42\n2\n42"
+  expected += "\n7\n#t\n100\nnone\nlate\n21\n#t"
 
   if expected != result {
     t.Error("expected: ", expected, " evaluated: ", result)
@@ -149,3 +219,2796 @@ func TestPromise(t *testing.T) {
     t.Error("expected: ", expected, " evaluated: ", result)
   }
 }
+
+func TestXML(t *testing.T) {
+  result := testFile("xml_test.ss", t)
+
+  expected := "(*TOP* (feed (entry (@ (id \"1\")) (title \"first\")) (entry (@ (id \"2\")) (title \"second & last\"))))"
+  expected += "\n((title \"first\") (title \"second & last\"))"
+  expected += "\n(\"1\" \"2\")"
+  expected += "\n(\"first\" \"second & last\")"
+  expected += "\n\"<feed><entry id=\\\"1\\\"><title>first</title></entry><entry id=\\\"2\\\"><title>second &amp; last</title></entry></feed>\""
+  expected += "\n\"<p class=\\\"note\\\">1 &lt; 2<br/></p>\""
+  expected += "\n(\"/x\")\n\"onelink\""
+  expected += "\n(*TOP* (br) (img (@ (src \"x\"))))\n(*TOP* (script \"if (a < b) {}\"))\n(*TOP* (p \"a\"))"
+  expected += "\n(*TOP* (ul (li \"a\") (li \"b\")) (ul (li \"c\" (ul (li \"d\")))))"
+  expected += "\n(*TOP* (p (@ (class \"note\")) \"1 < 2 ©\") (p \"x < y\"))"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestCodec(t *testing.T) {
+  result := testFile("codec_test.ss", t)
+
+  expected := "((\"name\" . \"web\") (\"replicas\" . 3) (\"ratio\" . 0.5) (\"debug\" . #f)"
+  expected += " (\"tags\" \"blue\" \"green\") (\"env\" (\"HOME\" . \"/srv\") (\"PATH\" . \"/bin:/usr/bin\"))"
+  expected += " (\"ports\" 80 443) (\"services\" ((\"name\" . \"db\") (\"image\" . \"postgres\")) ((\"name\" . \"cache\"))))"
+  expected += "\n3\n\"/bin:/usr/bin\""
+  expected += "\n\"ports:\\xa;  - 80\\xa;  - 443\\xa;env:\\xa;  debug: true\\xa;  level: \\\"true\\\"\\xa;\""
+  expected += "\n#t"
+  expected += "\n((\"title\" . \"example\") (\"package\" (\"name\" . \"lispex\") (\"version\" . 16)"
+  expected += " (\"authors\" \"a\" \"b\") (\"meta\" (\"ratio\" . 1000.5)))"
+  expected += " (\"bin\" ((\"name\" . \"one\")) ((\"name\" . \"two\") (\"point\" (\"x\" . 1) (\"y\" . 2)))))"
+  expected += "\n\"name = \\\"lispex\\\"\\xa;\\xa;[deps]\\xa;go = \\\"1.2\\\"\\xa;\""
+  expected += "\n#t"
+  expected += "\n((b) c)\n(2 . two)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestTemplate(t *testing.T) {
+  result := testFile("template_test.ss", t)
+
+  expected := "\"Hello, world!\"\n\"3 items, ann\""
+  expected += "\n\"Deploy: web=ok db=down\"\n\"[blue][green] no errors\""
+  expected += "\n\"<ul><li>blue</li><li>green</li></ul>\""
+  expected += "\n\"&lt;script&gt;x&lt;/script&gt;: <b>hi</b> & bye / <b>hi</b> & bye / &lt;b&gt;hi&lt;/b&gt; &amp; bye\""
+  expected += "\n\"[] [] []\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestArgParse(t *testing.T) {
+  result := testFile("argparse_test.ss", t)
+
+  expected := "((verbose . #f) (replicas . 1) (env . \"dev\") (service . \"web\"))"
+  expected += "\n((verbose . #t) (replicas . 3) (env . \"prod\") (service . \"db\"))"
+  expected += "\n\"usage: deploy [options] service\\xa;"
+  expected += "\\xa;arguments:\\xa;  service              service to deploy\\xa;"
+  expected += "\\xa;options:\\xa;  -h, --help           show this help message"
+  expected += "\\xa;  -v, --verbose        print more"
+  expected += "\\xa;  --replicas INTEGER   number of replicas (default: 1)"
+  expected += "\\xa;  --env STRING         target environment (default: \\\"dev\\\")\\xa;\""
+  expected += "\n()"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestLearn(t *testing.T) {
+  lib, err := ioutil.ReadFile("../stdlib/stdlib.ss")
+  if err != nil {
+    t.Error(err)
+  }
+  sandbox := learn.NewSandbox(string(lib))
+  for _, lesson := range learn.Lessons {
+    if result, ok := sandbox.Check(lesson, lesson.Solution); !ok {
+      t.Error("lesson ", lesson.Title, " expected: ", lesson.Expected, " evaluated: ", result)
+    }
+  }
+  if _, ok := sandbox.Check(learn.Lessons[1], "square"); ok {
+    t.Error("definitions leaked out of the sandbox")
+  }
+  for _, answer := range []string{"(http-get \"http://localhost/\")", "(ws-connect \"ws://localhost/\")", "(open-input-file \"/etc/passwd\")", "(load \"/etc/passwd\")"} {
+    if result, _ := sandbox.Check(learn.Lessons[0], answer); !strings.Contains(result, "undefined identifier") {
+      t.Error("expected ", answer, " to be unbound in the sandbox, evaluated: ", result)
+    }
+  }
+
+  input := "(+ 4 5)\n(* 3\n (+ 4 5))\n:skip\n(string-length \"(\") ; (\n:quit\n"
+  var output bytes.Buffer
+  learn.Run(string(lib), strings.NewReader(input), &output)
+  for _, expected := range []string{"Not quite, expected 27", "... 27\nCorrect!", "Lesson 2/", "Lesson 3/", ">>> 1\nNot quite"} {
+    if !strings.Contains(output.String(), expected) {
+      t.Error("expected output to contain: ", expected, " output: ", output.String())
+    }
+  }
+}
+
+// the lines Watch prints, one per write
+type watchOutput chan string
+
+func (self watchOutput) Write(p []byte) (int, error) {
+  self <- string(p)
+  return len(p), nil
+}
+
+func TestWatch(t *testing.T) {
+  filename := filepath.Join(t.TempDir(), "watched.ss")
+  modified := time.Now()
+  write := func(source string) {
+    modified = modified.Add(time.Second)
+    if err := ioutil.WriteFile(filename, []byte(source), 0644); err != nil {
+      t.Fatal(err)
+    }
+    if err := os.Chtimes(filename, modified, modified); err != nil {
+      t.Fatal(err)
+    }
+  }
+  results := make(chan string, 10)
+  eval := func(ctx context.Context) error {
+    exprs, err := ioutil.ReadFile(filename)
+    if err != nil {
+      return err
+    }
+    root := scope.NewRootScope()
+    root.SetContext(ctx)
+    root.SetInterruptible(true)
+    values, err := repl.Run(filename, string(exprs), root)
+    results <- repl.Print(values)
+    return err
+  }
+  output := make(watchOutput, 100)
+  next := func() string {
+    select {
+    case line := <-output:
+      return line
+    case <-time.After(5 * time.Second):
+      t.Fatal("expected watch to print a line")
+      return ""
+    }
+  }
+
+  write("(go (do () (#f))) (do () (#f))")
+  ctx, cancel := context.WithCancel(context.Background())
+  watching := make(chan bool)
+  go func() {
+    repl.Watch(ctx, filename, 10*time.Millisecond, eval, output)
+    close(watching)
+  }()
+  if line := next(); !strings.HasPrefix(line, ";; ") {
+    t.Error("expected an evaluation to start, printed: ", line)
+  }
+  time.Sleep(50 * time.Millisecond)
+  write("(+ 1 2)")
+  if line := next(); !strings.HasPrefix(line, ";; ") {
+    t.Error("expected an evaluation to start, printed: ", line)
+  }
+  // the loop of the previous run is stopped, it returns nothing
+  for returned := map[string]bool{}; !returned["3"] || !returned[""]; {
+    select {
+    case result := <-results:
+      returned[result] = true
+    case <-time.After(5 * time.Second):
+      t.Fatal("expected the previous run to stop and the file written to evaluate to 3")
+    }
+  }
+  // and so is its goroutine
+  for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+    running := 0
+    for pos, count := range value.RunningGoroutines() {
+      if strings.HasPrefix(pos, filename) {
+        running += count
+      }
+    }
+    if running == 0 {
+      break
+    }
+    if time.Now().After(deadline) {
+      t.Fatal("expected the goroutine of the previous run to stop")
+    }
+  }
+
+  // an error is printed again only once it changes
+  write("(car 1)")
+  for _, expected := range []string{";; ", "car: expected pair, given: 1"} {
+    if line := next(); !strings.Contains(line, expected) {
+      t.Error("expected watch to print: ", expected, " printed: ", line)
+    }
+  }
+  write("(car 1)")
+  if line := next(); !strings.HasPrefix(line, ";; ") {
+    t.Error("expected an evaluation to start, printed: ", line)
+  }
+  <-results
+  write("(car 2)")
+  for _, expected := range []string{";; ", "car: expected pair, given: 2"} {
+    if line := next(); !strings.Contains(line, expected) {
+      t.Error("expected watch to print: ", expected, " printed: ", line)
+    }
+  }
+  cancel()
+  <-watching
+}
+
+func TestHistory(t *testing.T) {
+  env := scope.NewRootScope()
+  history := repl.NewHistory(env, 2)
+  for _, line := range []string{"(+ 1 2)", "(define x 5)", "(* 10 10)", "(cons $1 $2)"} {
+    for _, val := range repl.EvalSource("<REPL>", line, env) {
+      history.Record(val)
+    }
+  }
+  history.RecordError("car: expected pair, given: 1")
+  result := repl.REPL("$1 $2 $e (error? $e) (condition-message $e)", env)
+
+  expected := "(100 . 3)\n100\ncar: expected pair, given: 1\n#t\n\"car: expected pair, given: 1\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  _, err := repl.Run("<REPL>", "(raise 'boom)", env)
+  history.RecordError(err)
+  result = repl.REPL("$e (symbol? $e) (call-with-guard (lambda (e) (cons 'again e)) (lambda () (raise $e)))", env)
+  if expected := "boom\n#t\n(again . boom)"; expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestDescribe(t *testing.T) {
+  var result string
+  output := captureOutput(func() { result = testFile("describe_test.ss", t) }, t)
+
+  expected := "(caddar cadddr caddr)\n(ws-chan ws-close ws-connect ws-recv ws-send!)"
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  expected = "procedure: compound procedure\n  arity: 1\n  doc: Square of x.\n"
+  expected += "  defined at: describe_test.ss:3:1\n"
+  expected += "procedure: compound procedure\n  arity: at least 0\n  defined at: <REPL>:40:1\n"
+  expected += "procedure: builtin procedure `car'\n  arity: 1\n  signature: (car pair)\n"
+  expected += "  doc: first element of the pair\ninteger: 42\n"
+  expected += "procedure: compound procedure\n  arity: 1\n  defined at: describe_test.ss:9:3\n"
+  if expected != output {
+    t.Error("expected: ", expected, " printed: ", output)
+  }
+}
+
+func TestLoad(t *testing.T) {
+  dir, err := ioutil.TempDir("", "lispex")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+  files := map[string]string{
+    "greeting.ss": "(define (greet) 'hello)",
+    "a.ss":        "(define (helper) 'a) (define (from-a) (helper))",
+    "b.ss":        "(load \"a.ss\") (define (helper) 'b) (define (from-b) (cons (from-a) (helper)))",
+  }
+  for name, content := range files {
+    if err := ioutil.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  root := scope.NewRootScope()
+  env := repl.NewTopLevel(root)
+  result := repl.REPL(fmt.Sprintf("(load \"%s/greeting.ss\")", dir), env)
+  result += repl.REPL("(define (greet-all) (cons (greet) '())) (greet-all)", env)
+  if err := ioutil.WriteFile(dir+"/greeting.ss", []byte("(define (greet) 'bonjour)"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  result += "\n" + repl.REPL("(reload 'greeting) (greet-all)", env)
+  result += "\n" + repl.REPL(fmt.Sprintf("(load \"%s/b.ss\") (from-b) (from-a) (helper)", dir), env)
+
+  expected := "(hello)\n(bonjour)\n(a . b)\na\nb"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+  if root.LookupLocal("helper") != nil {
+    t.Error("module definitions leaked into the root scope")
+  }
+}
+
+func TestConstant(t *testing.T) {
+  result := testFile("constant_test.ss", t)
+  expected := "10\n(1 2)\n4\n#<environment>"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  root := scope.NewRootScope()
+  root.Freeze()
+  env := repl.NewTopLevel(root)
+  repl.REPL("(define-constant limit 10) (define x 1) (freeze! (the-environment))", env)
+  errors := map[string]string{
+    "(define limit 11)":            "define: cannot change constant: limit",
+    "(set! limit 11)":              "set!: cannot change constant: limit",
+    "(define-constant limit 11)":   "define-constant: cannot change constant: limit",
+    "(set! x 2)":                   "set!: cannot change constant: x",
+    "(define y 2)":                 "define: cannot change constant: y",
+    "(set! car cdr)":               "set!: cannot change constant: car",
+    "((lambda () (set! car cdr)))": "set!: cannot change constant: car",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+
+  other := scope.NewScope(nil)
+  other.Put("car", value.NewIntValue(1))
+  for _, change := range []func(){
+    func() { root.Put("car", value.NewIntValue(1)) },
+    func() { root.PutConstant("car", value.NewIntValue(1)) },
+    func() { root.PutAll(other) },
+  } {
+    err := func() (err interface{}) {
+      defer func() { err = recover() }()
+      change()
+      return nil
+    }()
+    if expected := "cannot change binding of a frozen scope: car"; fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+  if _, ok := root.Lookup("car").(*value.IntValue); ok {
+    t.Error("changed a binding of a frozen scope")
+  }
+}
+
+func TestBuiltins(t *testing.T) {
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(car)":                  "car: arguments mismatch, expected 1, given: 0",
+    "(cons 1)":               "cons: arguments mismatch, expected 2, given: 1",
+    "(make-chan 1 2)":        "make-chan: arguments mismatch, expected 0 to 1, given: 2",
+    "(-)":                    "-: arguments mismatch, expected at least 1, given: 0",
+    "(car 1)":                "car: expected pair, given: 1",
+    "(car (if #f #f))":       "car: expected pair, given: #<unspecified>",
+    "(+ 1 \"2\")":            "+: expected number, given: \"2\"",
+    "(chan<- 1 2)":           "chan<-: expected channel, given: 1",
+    "(argparse \"p\" '() 3)": "argparse: expected list, given: 3",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+
+  for _, builtin := range primitives.Builtins {
+    if builtin.Doc == "" {
+      t.Error("builtin without documentation: ", builtin.Name)
+    }
+  }
+  for _, expected := range []string{"- `(+ number ...)`: sum of the numbers\n", "- `(make-chan [integer])`: "} {
+    if !strings.Contains(primitives.Documentation(), expected) {
+      t.Error("expected documentation to contain: ", expected)
+    }
+  }
+}
+
+func TestPredicates(t *testing.T) {
+  result := testFile("predicate_test.ss", t)
+
+  expected := "(#t #f #f)\n(#t #f #f)\n(#t #t #f #f)\n(#t #f #f #f)\n(#t #f #f)"
+  expected += "\n(#t #t #f #t #f)\n(#t #t #f #f)\n(#t #t #f #f)\n(#t #t #t #f)\n(#f #f)"
+  expected += "\n(#t #f #t #f)\n(#f #f #f)\n(#t (#f x) (#t #t))\n(#t #t #t #t #t #t #f #f #f)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestNet(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+    for i := 1; i <= 2; i++ {
+      fmt.Fprintf(w, "line %d\r\n", i)
+      w.(http.Flusher).Flush()
+    }
+  })
+  mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+    conn, err := websocket.Upgrade(w, r)
+    if err != nil {
+      return
+    }
+    defer conn.Close()
+    for {
+      message, err := conn.ReadMessage()
+      if err != nil {
+        return
+      }
+      conn.WriteText(strings.ToUpper(message))
+    }
+  })
+  mux.HandleFunc("/burst", func(w http.ResponseWriter, r *http.Request) {
+    conn, err := websocket.Upgrade(w, r)
+    if err != nil {
+      return
+    }
+    defer conn.Close()
+    for _, message := range []string{"one", "two", "three"} {
+      conn.WriteText(message)
+    }
+    conn.ReadMessage()
+  })
+  mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+    conn, err := websocket.Upgrade(w, r)
+    if err != nil {
+      return
+    }
+    defer conn.Close()
+    conn.WriteText(strings.Repeat("x", websocket.MaxMessageSize+1))
+  })
+  server := httptest.NewServer(mux)
+  defer server.Close()
+
+  ws := "ws" + strings.TrimPrefix(server.URL, "http")
+  prelude := fmt.Sprintf("(define stream-url \"%s/stream\")", server.URL)
+  prelude += fmt.Sprintf("(define echo-url \"%s/echo\")", ws)
+  prelude += fmt.Sprintf("(define burst-url \"%s/burst\")", ws)
+  prelude += fmt.Sprintf("(define huge-url \"%s/huge\")", ws)
+  result := testFileWithPrelude(prelude, "net_test.ss", t)
+
+  expected := "port\n\"line 1\"\n\"line 2\"\n#<eof>"
+  expected += "\n\"HELLO\"\n\"OVER SELECT\"\n#<eof>"
+  expected += "\n\"one\"\n#<eof>\n#<eof>"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestTypecheck(t *testing.T) {
+  exprs, err := ioutil.ReadFile("typecheck_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  nodes, err := parser.ParseFromString("typecheck_test.ss", string(exprs))
+  if err != nil {
+    t.Fatal(err)
+  }
+  result := strings.Join(typecheck.Check(nodes), "\n")
+  expected := strings.Join([]string{
+    "nothing: unknown type ()",
+    "name: declared string, defined as integer",
+    "(+ s 1): argument 1 expected number, given string",
+    "shout: declared to return string, returns number",
+    "spread: declared integer, defined as number",
+    "(square \"three\"): argument 1 expected number, given string",
+    "(fact 1.5): argument 1 expected integer, given float",
+    "(twice 1 2): expected 1 arguments, given 2",
+    "(sum-all 1 2 \"3\"): argument 3 expected number, given string",
+    "(make-chan 1 2 3): expected at most 1 arguments, given 3",
+    "(car 1): argument 1 expected pair, given integer",
+  }, "\n")
+
+  if expected != result {
+    t.Error("expected: ", expected, " checked: ", result)
+  }
+
+  // annotations are ignored when evaluating
+  result = testFile("typecheck_test.ss", t)
+  expected = "9\n120"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestContract(t *testing.T) {
+  result := testFile("contract_test.ss", t)
+  expected := "120\n2\n2.5\n#t\n6"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  root := scope.NewRootScope()
+  root.Freeze()
+  env := repl.NewTopLevel(root)
+  repl.REPL("(define/contract (half n) (-> integer? integer?)\n  (/ n 2))", env)
+  errors := map[string]string{
+    "(half 3)":          "half: contract violation, result expected: integer?, given: 3/2, blaming: half at <REPL>:1:1",
+    "\n(half \"x\")":    "half: contract violation, argument 1 expected: integer?, given: \"x\", blaming: caller at <REPL>:2:1",
+    "(half 1 2)":        "half: contract violation, expected 1 arguments, given: 2, blaming: caller at <REPL>:1:1",
+    "(apply half '(5))": "half: contract violation, result expected: integer?, given: 5/2, blaming: half at <REPL>:1:1",
+    "(define/contract (f x) (-> number? number? number?) x)": "define/contract: f: contract expects 2 arguments, procedure takes 1",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); err != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+
+  // disabled contracts skip the checks
+  repl.REPL("(contracts-enabled #f)", env)
+  defer repl.REPL("(contracts-enabled #t)", env)
+  result = repl.REPL("(half 3) (contracts-enabled)", env)
+  expected = "3/2\n#f"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestProperty(t *testing.T) {
+  var result string
+  output := captureOutput(func() { result = testFile("property_test.ss", t) }, t)
+
+  expected := "#t\n#t\n#t\n#t\n#f\n#f\n#f"
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  expected = "+++ reverse-involutive: OK, passed 100 tests.\n"
+  expected += "+++ length-of-map: OK, passed 100 tests.\n"
+  expected += "+++ max-is-an-upper-bound: OK, passed 100 tests.\n"
+  expected += "+++ points-are-lists: OK, passed 50 tests.\n"
+  expected += "*** sums-are-small: Falsified after 16 tests and 6 shrinks (seed 42):\n(10)\n"
+  expected += "*** cars-of-pairs: Falsified after 2 tests and 0 shrinks (seed 42):\n(1 #f)\n"
+  expected += "*** car-of-anything: Falsified after 1 tests and 0 shrinks (seed 42):\n"
+  expected += "error: car: expected pair, given: ()\n()\n"
+  if expected != output {
+    t.Error("expected: ", expected, " printed: ", output)
+  }
+}
+
+func TestLexerErrors(t *testing.T) {
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(display \"hello)": "<REPL>:1:10: unterminated string starting at line 1",
+    "(+ 1\n   \"a\\\"b": "<REPL>:2:4: unterminated string starting at line 2",
+    "(+ 1 \x7f)":        "<REPL>:1:6: invalid character #\\x7f",
+    "\n  [1 2]":         "<REPL>:2:3: invalid character #\\[",
+    "(+ 1/0 1)":         "<REPL>:1:4: division by zero in number literal: 1/0",
+    "(+ 1/2x 1)":        "<REPL>:1:4: bad number syntax: \"1/2x\"",
+    "1e999":             "<REPL>:1:1: number literal out of range: 1e999",
+    "(+ 12abc 1)":       "<REPL>:1:4: bad number syntax: \"12a\"",
+    "(list #\\spaces)":  "<REPL>:1:7: unknown character name: #\\spaces",
+    "#\\":               "<REPL>:1:1: bad character syntax: \"#\\\\\"",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+
+  result := repl.REPL("-0x10 0x1f 1e3 -5 -6/4 99999999999999999999 -0x8000000000000001 (define (-> x) x) (-> 'ok) ; a comment at the end", env)
+  expected := "-16\n31\n1000.0\n-5\n-3/2\n99999999999999999999\n-9223372036854775809\nok"
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestParseErrors(t *testing.T) {
+  errors := map[string]string{
+    "(define x 1)\n(if)":               "test.ss:2:1: incorrect format of if: (if)",
+    "(let ((x 1))\n  (lambda))":        "test.ss:2:3: lambda: bad syntax: (lambda)",
+    "(+ 1 2))":                         "test.ss:1:8: read: unexpected `)'",
+    "(define (f)\n  (+ 1 2)":           "test.ss:1:1: unclosed delimeter, expected: `('",
+    "'":                                "test.ss: unclosed delimeter, expected: `''",
+    "(display \"hi)":                   "test.ss:1:10: unterminated string starting at line 1",
+    "(priority-select)":                "test.ss:1:1: priority-select: bad syntax (missing clauses), expected at least 1",
+    "(select\n  ((<-chan c 1) 2))":     "test.ss:2:3: select: bad clause 1: ((<-chan c 1) 2), <-chan expects (<-chan ch), given: (<-chan c 1)",
+    "(select (default 1)\n  (x 2))":    "test.ss:2:3: select: bad clause 2: (x 2), expected <case> to be (<-chan ch), (chan<- ch value), (var (<-chan ch)) or default",
+    "(select ((x (<-chan c))))":        "test.ss:1:9: select: bad clause 1: ((x (<-chan c))), expected a body using x",
+    "(select (((x) (<-chan c)) x))":    "test.ss:1:9: select: bad clause 1: (((x) (<-chan c)) x), a receive binding expects (var (<-chan ch)), given: ((x) (<-chan c))",
+    "(select (default 1) (default 2))": "test.ss:1:21: select: bad clause 2: (default 2), more than one default clause",
+  }
+  for program, expected := range errors {
+    nodes, err := parser.ParseFromString("test.ss", program)
+    if nodes != nil || fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " returned: ", err)
+    }
+  }
+
+  nodes, err := parser.ParseFromString("test.ss", "(define (f x) x) (f 1)")
+  if err != nil || len(nodes) != 2 {
+    t.Error("expected 2 nodes, returned: ", nodes, err)
+  }
+}
+
+func TestMacro(t *testing.T) {
+  result := testFile("macro_test.ss", t)
+  expected := "b\n(negative zero positive)\ntwo\n3\nouter\n(2 1)"
+  expected += "\n(1 2 6)\n((a 1 2) (b) (c 3))\n(1 (2 3))\n10\n5\n42\ndone"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  errors := map[string]string{
+    "(define-syntax loop (syntax-rules () ((_ x) (loop x))))\n(loop 1)": "test.ss:2:1: loop: expanded 10000 times, the macro may expand to itself forever",
+    "(define-syntax two (syntax-rules () ((_ a b) a)))\n(two 1)":        "test.ss:2:1: two: bad syntax, no rule matches (two 1)",
+    "(define-syntax flat (syntax-rules () ((_ x ...) x)))\n(flat 1)":    "test.ss:2:1: flat: x is followed by ... in the pattern but not in the template",
+    "(define-syntax bad 1)": "test.ss:1:1: bad: expected (syntax-rules (literal ...) (pattern template) ...), given: 1",
+  }
+  for program, expected := range errors {
+    if _, err := parser.ParseFromString("test.ss", program); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " returned: ", err)
+    }
+  }
+}
+
+func TestQuasiquoteNesting(t *testing.T) {
+  result := testFile("quasiquote_nesting_test.ss", t)
+
+  expected := "'5\n`,'5\n`(a (unquote 1 2))\n`(a ,@(1 2))\n`(1 . ,5)"
+  expected += "\n(quote a b)\n(1 . ,x)\n(define-macro swap! `(,'swap! ,@args))"
+  expected += "\n(a c)\n(1 1 2)\nkept\n(5)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "'(a #;)": "<REPL>:1:7: read: unexpected `)'",
+    "'a #;":   "<REPL>: unclosed delimeter, expected: `#;'",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestQuasiquoteConformance(t *testing.T) {
+  result := testFile("quasiquote_conformance_test.ss", t)
+
+  expected := "(1 2 3)\n(1 2 3 4)\n(1 2 3)\n(a b c)"
+  expected += "\n(1 2)\n()\n(())\n3"
+  expected += "\n(1 . 2)\n(1 2 3)\n(1 2 . 3)\n(1 2 3 . 4)\n(1 2 . 3)\n(1 . 2)"
+  expected += "\n(1 2 3)\n(1 2)\n(1 2 1 2)"
+  expected += "\n(a `(b ,(c 3)))\n(1 `(2 ,(3 4 5)))\n(1 `,@(2 3 4))\n`(1 ,(+ 1 2 3))"
+  expected += "\n(#t #f \"s\" 1.5)\n(1 '2)\n(1 4 9)"
+  expected += "\n#(1 2 3 4)\n#()\n#()\n(a #(b 3) . #(3 4))\n#(1 #(3 4) (3 4))\n(1 `#(,(+ 1 3)))"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "`,@'(1 2)":             "<REPL>:1:1: unquote-splicing: invalid context within quasiquote",
+    "`(1 . ,@'(2 3))":       "<REPL>:1:1: unquote-splicing: invalid context within quasiquote",
+    "`(1 ,@,x)":             "<REPL>:1:7: unquote: not in quasiquote",
+    "(list 1 ,@x)":          "<REPL>:1:9: unquote-splicing: not in quasiquote",
+    "`(,@(cdr '(1 . 2)) 3)": "unquote-splicing: expected list?, given: 2",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestBegin(t *testing.T) {
+  result := testFile("begin_test.ss", t)
+  expected := "3\n6\n30\n25\n2"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(if #t (define x 1))":             "<REPL>:1:8: define: not allowed in an expression context, given: (define x 1)",
+    "(+ 1 (define x 2))":               "<REPL>:1:6: define: not allowed in an expression context, given: (define x 2)",
+    "(let ((y (define x 1))) y)":       "<REPL>:1:10: define: not allowed in an expression context, given: (define x 1)",
+    "(if #t (begin (define x 1) x))":   "<REPL>:1:15: define: not allowed in an expression context, given: (define x 1)",
+    "(define x (define-constant y 1))": "<REPL>:1:11: define-constant: not allowed in an expression context, given: (define-constant y 1)",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestClosure(t *testing.T) {
+  result := testFile("closure_test.ss", t)
+  expected := "7\n1\n2\n(1 2)\n#t\n11\n(1 2 3)\n10\n50"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // captures of the innermost lambda, nil if it keeps the scope chain
+  captures := map[string][]string{
+    "(lambda (x) (+ x 1))":                             []string{},
+    "(lambda (n) (lambda (x) (+ x n)))":                []string{"n"},
+    "(lambda (x y) (lambda () (list x y scale)))":      []string{"x", "y"},
+    "(let* ((a 1) (b a)) (lambda () (list a b)))":      []string{"a", "b"},
+    "(let ((n 0)) (lambda () (set! n (+ n 1)) n))":     nil,
+    "(letrec ((f (lambda () 1))) (lambda () (f)))":     nil,
+    "(lambda () (define (g) 1) (lambda () (g)))":       nil,
+    "(lambda (x) (lambda () (the-environment)))":       nil,
+    "(lambda (x) (lambda () `(x ,(+ 1 2))))":           []string{},
+    "(lambda (x) (lambda (y) (lambda () (list x y))))": []string{"x", "y"},
+  }
+  for program, expected := range captures {
+    nodes, err := parser.ParseFromString("test.ss", program)
+    if err != nil {
+      t.Fatal(err)
+    }
+    analysis.ConvertClosures(nodes)
+    lambda := innermostLambda(nodes[0])
+    if expected == nil {
+      if lambda.Flat {
+        t.Error(program, " expected to keep its scope, captures: ", lambda.Captures)
+      }
+    } else if !lambda.Flat || fmt.Sprint(lambda.Captures) != fmt.Sprint(expected) {
+      t.Error(program, " expected to capture ", expected, ", flat: ", lambda.Flat, " captures: ", lambda.Captures)
+    }
+  }
+}
+
+// first lambda found going down the last expression of each body
+func innermostLambda(node ast.Node) *ast.Lambda {
+  var found *ast.Lambda
+  for node != nil {
+    switch node.(type) {
+    case *ast.Lambda:
+      found = node.(*ast.Lambda)
+      node = found.Body
+    case *ast.Let:
+      node = node.(*ast.Let).Body
+    case *ast.LetStar:
+      node = node.(*ast.LetStar).Body
+    case *ast.LetRec:
+      node = node.(*ast.LetRec).Body
+    case *ast.Block:
+      exprs := node.(*ast.Block).Exprs
+      node = exprs[len(exprs)-1]
+    default:
+      node = nil
+    }
+  }
+  return found
+}
+
+func TestGlobalCache(t *testing.T) {
+  result := testFile("cache_test.ss", t)
+  expected := "1\n2\n3\n3\nlocal\n3\ninner\n3"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestFixnum(t *testing.T) {
+  result := testFile("fixnum_test.ss", t)
+  expected := "5\n-1\n-20\n#t\n#t\n3.5\n18446744073709551616\n4294967295\n5050\n2\n42\nredefined"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(+ 1 'a)":    "+: expected number, given: a",
+    "(< \"1\" 2)": "<: expected number, given: \"1\"",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestReusableScopes(t *testing.T) {
+  result := testFile("escape_test.ss", t)
+  expected := "3628800\n(1 2 3)\n(10 20 21)\n120\n10\n(1 2 3 4 5)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  reusable := map[string]bool{
+    "(lambda (n) (* n n))":                              true,
+    "(lambda (n) (lambda () n))":                        true,
+    "(lambda (n) (let ((m n)) (lambda () (set! m 1))))": false,
+    "(lambda (n) (delay n))":                            false,
+    "(lambda (n) (go n))":                               false,
+    "(lambda (n) (the-environment))":                    false,
+    "(lambda (n) (define (f) (g)) (define (g) n) (f))":  false,
+    "(lambda (n) (let ((m n)) (+ m 1)))":                true,
+  }
+  for program, expected := range reusable {
+    nodes, err := parser.ParseFromString("test.ss", program)
+    if err != nil {
+      t.Fatal(err)
+    }
+    analysis.ConvertClosures(nodes)
+    analysis.MarkReusableScopes(nodes)
+    if lambda := nodes[0].(*ast.Lambda); lambda.Reusable != expected {
+      t.Error(program, " expected reusable: ", expected)
+    }
+  }
+}
+
+func TestGoroutinePool(t *testing.T) {
+  result := testFile("goroutine_test.ss", t)
+  expected := "0\n3\n6\n#t\n3\n(outer inner)\n1\n2"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+  value.WaitGoroutines()
+  if count := value.GoroutineCount(); count != 0 {
+    t.Error("expected every goroutine returned, running: ", count)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(set-max-procs! 0)":     "set-max-procs!: expected positive integer, given: 0",
+    "(set-go-pool-size! -1)": "set-go-pool-size!: expected non-negative integer, given: -1",
+    "(set-go-pool-size! 'a)": "set-go-pool-size!: expected integer, given: a",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestChars(t *testing.T) {
+  result := testFile("char_test.ss", t)
+
+  expected := "#\\a\n#\\space\n#\\A\n(#\\( #\\))\n#t\n#f\nchar\n#t\n#t"
+  expected += "\n#t\n#t\n#f\n#t\n#t\n#f\n#t\n#t"
+  expected += "\n#t\n#t\n#t\n#t\n#f\n#t\n#t\n#t\n#f"
+  expected += "\n(\"apple\" \"banana\" \"fig\" \"pear\")"
+  expected += "\n#\\A\n#\\λ\n(#t #f #t #t)\n(#t #f #t)\n65\n#\\λ\n(7 3 9 #f)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(char<? #\\a \"b\")":   "char<?: expected char, given: \"b\"",
+    "(string=? \"a\")":      "string=?: arguments mismatch, expected at least 2, given: 1",
+    "(integer->char 55296)": "integer->char: not the code of a character: 55296",
+    "(char-upcase \"a\")":   "char-upcase: expected char, given: \"a\"",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestStrings(t *testing.T) {
+  result := testFile("string_test.ss", t)
+
+  expected := "\"a b c\"\n\"a, b, c\"\n\"\""
+  expected += "\n\"hello  \"\n\"  hello\"\n\"hello\"\n\"hello\"\n\"hix\""
+  expected += "\n4\n#f\n2\n2\n#f\n3"
+  expected += "\n#t\n#f\n#t\n#t"
+  expected += "\n\"foobar\"\n\"\"\n#\\é\n\"ab\"\n\"zzz\"\n(#\\a #\\b #\\c)\n(#\\b #\\c)\n\"hi\""
+  expected += "\n#t\n\"abc\"\n\"ff\"\n\"-1/11\"\n\"2.5\"\n12345678901234567890\n\"ab\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(string-join '(\"a\" 1))":   "string-join: expected list of strings, given: (\"a\" 1)",
+    "(string-ref \"ab\" 2)":      "string-ref: index 2 out of bounds for a string of length 2",
+    "(list->string '(#\\a 1))":   "list->string: expected char, given: 1",
+    "(number->string 0.5 2)":     "number->string: inexact numbers are only written in radix 10, given: 0.5",
+    "(string-trim \"a\" 1)":      "string-trim: expected string or char, given: 1",
+    "(string-index \"a\" \"a\")": "string-index: expected char or procedure, given: \"a\"",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestStringFoldcase(t *testing.T) {
+  result := testFile("string_foldcase_test.ss", t)
+
+  expected := "\"strasse\"\n\"σασ\"\n#\\a\n#t\n#t"
+  expected += "\n#t\n#t\n#f\n#t\n#f\n#t"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(string-foldcase #\\a)":      "string-foldcase: expected string, given: #\\a",
+    "(char-foldcase \"a\")":       "char-foldcase: expected char, given: \"a\"",
+    "(string-collate<? \"a\" 'b)": "string-collate<?: expected string, given: b",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestStringToNumber(t *testing.T) {
+  result := testFile("string_to_number_test.ss", t)
+
+  expected := "42\n-17\n3.25\n1000.0\n-0.025\n0.5"
+  expected += "\n255\n-5\n15\n255\n10\n1/4\n2\n2.0\n100\n16\n99999999999999999999"
+  expected += "\n#f\n#f\n#f\n#f\n#f\n3/2\n#f\n#f\n#f\n#f"
+  expected += "\n(30 negative invalid)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(string->number 12)":       "string->number: expected string, given: 12",
+    "(string->number \"12\" 3)": "string->number: expected radix 2, 8, 10 or 16, given: 3",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+type counter struct {
+  n int
+}
+
+func TestGoFunction(t *testing.T) {
+  exprs, err := ioutil.ReadFile("go_function_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  env := scope.NewRootScope()
+  functions := map[string]interface{}{
+    "words": strings.Fields,
+    "sum": func(ns []int) int {
+      total := 0
+      for _, n := range ns {
+        total += n
+      }
+      return total
+    },
+    "word-counts": func(words []string) map[string]int {
+      counts := make(map[string]int)
+      for _, word := range words {
+        counts[word]++
+      }
+      return counts
+    },
+    "lookup": func(m map[string]interface{}, key string) interface{} {
+      return m[key]
+    },
+    "divide": func(a, b float64) (float64, error) {
+      if b == 0 {
+        return 0, fmt.Errorf("division by zero")
+      }
+      return a / b, nil
+    },
+    "max-of": func(first int, rest ...int) int {
+      for _, n := range rest {
+        if n > first {
+          first = n
+        }
+      }
+      return first
+    },
+    "new-counter": func() *counter {
+      return &counter{}
+    },
+    "increment": func(c *counter, by ...int) int {
+      c.n++
+      for _, n := range by {
+        c.n += n - 1
+      }
+      return c.n
+    },
+    "split-pair": func(s string) (string, string) {
+      i := strings.Index(s, "=")
+      return s[:i], s[i+1:]
+    },
+  }
+  for name, fn := range functions {
+    env.Put(name, primitives.WrapGo(name, "", fn))
+  }
+  result := repl.Print(repl.EvalSource("go_function_test.ss", string(exprs), env))
+
+  expected := "(\"a\" \"quick\" \"fox\")\n10\n((\"a\" . 3) (\"b\" . 2) (\"c\" . 1))"
+  expected += "\n4\n(\"a\" \"b\")\n3.5\n3\n9\nopaque\n1\n2\n12\n(\"key\" \"value\")"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  errors := map[string]string{
+    "(sum '(1 \"2\"))": "sum: expected list of integer, given: (1 \"2\")",
+    "(divide 1 0)":     "divide: division by zero",
+    "(max-of)":         "max-of: arguments mismatch, expected at least 1, given: 0",
+    "(increment 1)":    "increment: expected *tests.counter, given: 1",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+type ServerConfig struct {
+  ListenAddr string
+  MaxConns   int
+  Debug      bool `lisp:"debug?"`
+  Tags       []string
+  secret     string
+}
+
+func TestRecord(t *testing.T) {
+  exprs, err := ioutil.ReadFile("record_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  env := scope.NewRootScope()
+  repl.RegisterStruct(env, ServerConfig{})
+  config := ServerConfig{ListenAddr: ":8080", MaxConns: 64, Tags: []string{"prod"}, secret: "hidden"}
+  env.Put("config", converter.ToValue(config))
+  result := repl.Print(repl.EvalSource("record_test.ss", string(exprs), env))
+
+  expected := "#t\n\":8080\"\n64\n(\"prod\")\nserver-config"
+  expected += "\n#<server-config listen-addr: \":9090\" max-conns: 10 debug?: #t tags: ()>\n#t\n#f"
+  expected += "\n#<server-config listen-addr: \":8080\" max-conns: 256 debug?: #f tags: (\"edited\" \"prod\")>"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  var edited ServerConfig
+  converter.RecordToStruct(env.Lookup("config").(value.Value), &edited)
+  if edited.MaxConns != 256 || edited.ListenAddr != ":8080" || strings.Join(edited.Tags, ",") != "edited,prod" {
+    t.Error("unexpected struct: ", edited)
+  }
+
+  errors := map[string]string{
+    "(make-server-config \":80\" 1 #f)":              "make-server-config: arguments mismatch, expected 4, given: 3",
+    "(set-server-config-max-conns! config \"many\")": "set-server-config-max-conns!: expected integer, given: \"many\"",
+    "(server-config-tags 1)":                         "server-config-tags: expected server-config, given: 1",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestGoChannel(t *testing.T) {
+  exprs, err := ioutil.ReadFile("go_channel_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  events, done := make(chan interface{}), make(chan interface{})
+  replies := value.NewChannel(0)
+  env := scope.NewRootScope()
+  env.Put("events", converter.FromGoChannel(events))
+  env.Put("done", converter.FromGoChannel(done))
+  env.Put("replies", replies)
+
+  received := make(chan interface{}, 1)
+  go func() {
+    events <- []string{"a", "b"}
+    received <- <-converter.ToGoChannel(replies)
+    for i := 0; i < 3; i++ {
+      events <- i
+    }
+    close(done)
+  }()
+  result := repl.Print(repl.EvalSource("go_channel_test.ss", string(exprs), env))
+
+  if expected := "(\"a\" \"b\")\n3"; expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+  reply := fmt.Sprintf("%#v", <-received)
+  if expected := `[]interface {}{"first", []interface {}{"a", "b"}}`; reply != expected {
+    t.Error("expected: ", expected, " received: ", reply)
+  }
+
+  // closing a forwarded channel from lisp stops the forwarding
+  ticks, counts := make(chan interface{}, 3), make(chan int, 3)
+  for i := 1; i <= 3; i++ {
+    ticks <- i
+    counts <- i
+  }
+  env.Put("ticks", converter.FromGoChannel(ticks))
+  env.Put("counts", converter.ToValue(counts))
+  result = repl.Print(repl.EvalSource("<REPL>", "(<-chan ticks) (<-chan counts) (chan-close ticks) (chan-close counts)", env))
+  if expected := "1\n1"; expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+  time.Sleep(10 * time.Millisecond)
+}
+
+func TestCallback(t *testing.T) {
+  exprs, err := ioutil.ReadFile("callback_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  env := scope.NewRootScope()
+  functions := map[string]interface{}{
+    "sort-ints": func(ns []int, less func(a, b int) bool) []int {
+      sort.Slice(ns, func(i, j int) bool { return less(ns[i], ns[j]) })
+      return ns
+    },
+    "apply-all": func(f func(int) int, ns []int) []int {
+      for i := range ns {
+        ns[i] = f(ns[i])
+      }
+      return ns
+    },
+    "try-call": func(f func(int) (int, error), n int) string {
+      result, err := f(n)
+      if err != nil {
+        return "error: " + err.Error()
+      }
+      return fmt.Sprint(result)
+    },
+    "min-max": func(f func([]int) (int, int)) []int {
+      lo, hi := f(nil)
+      return []int{lo, hi}
+    },
+  }
+  for name, fn := range functions {
+    env.Put(name, primitives.WrapGo(name, "", fn))
+  }
+  result := repl.Print(repl.EvalSource("callback_test.ss", string(exprs), env))
+
+  expected := "(1 3 5 9)\n(9 5 3 1)\n(1 4 9)\n\"42\""
+  expected += "\n\"error: car: expected pair, given: 41\"\n(1 9)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // concurrent calls of a serialized callback never interleave
+  var count func() int
+  repl.REPL("(define n 0)", env)
+  converter.BindFunc(repl.EvalSource("<REPL>", "(lambda () (set! n (+ n 1)) n)", env)[0], &count, true)
+  var wg sync.WaitGroup
+  for i := 0; i < 50; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      count()
+    }()
+  }
+  wg.Wait()
+  if n := count(); n != 51 {
+    t.Error("expected: 51 evaluated: ", n)
+  }
+
+  if err := testError("(sort-ints '(1 2) 3)", env); fmt.Sprint(err) != "sort-ints: expected procedure, given: 3" {
+    t.Error("expected: sort-ints: expected procedure, given: 3 raised: ", err)
+  }
+}
+
+func TestErrorTypes(t *testing.T) {
+  env := scope.NewRootScope()
+  env.Put("open", primitives.WrapGo("open", "", func(name string) error {
+    _, err := os.Open(name)
+    return err
+  }))
+
+  var syntax *value.SyntaxError
+  if _, err := repl.Run("errors", "(car '(1)", env); !errors.As(err, &syntax) {
+    t.Error("expected a syntax error, raised: ", err)
+  }
+  var unbound *value.UnboundVariable
+  if _, err := repl.Run("errors", "(car xs)", env); !errors.As(err, &unbound) || unbound.Name != "xs" {
+    t.Error("expected xs to be unbound, raised: ", err)
+  }
+  var typeError *value.TypeError
+  if _, err := repl.Run("errors", "(car 1)", env); !errors.As(err, &typeError) {
+    t.Error("expected a type error, raised: ", err)
+  }
+  var arity *value.ArityError
+  if _, err := repl.Run("errors", "((lambda (x) x))", env); !errors.As(err, &arity) {
+    t.Error("expected an arity error, raised: ", err)
+  }
+  if _, err := repl.Run("errors", "(let-values (((a b) (values 1 2 3))) a)", env); !errors.As(err, &arity) || arity.Pos != "errors:1:1" {
+    t.Error("expected an arity error at errors:1:1, raised: ", err)
+  }
+  // called by a builtin, the procedure is blamed
+  _, err := repl.Run("errors", "(define (thunk x) x) (dynamic-wind thunk thunk thunk)", env)
+  if !errors.As(err, &arity) || arity.Pos != "errors:1:1" || arity.Message != "thunk: missing arguments" {
+    t.Error("expected thunk to miss arguments at errors:1:1, raised: ", err)
+  }
+  if _, err := repl.Run("errors", "(open \"/no/such/file\")", env); !errors.Is(err, os.ErrNotExist) {
+    t.Error("expected the error of open to be wrapped, raised: ", err)
+  }
+  var generic *value.Error
+  if _, err := repl.Run("errors", "(force 1)", env); !errors.As(err, &generic) {
+    t.Error("expected an error, raised: ", err)
+  }
+  if values, err := repl.Run("errors", "(+ 1 2)", env); err != nil || repl.Print(values) != "3" {
+    t.Error("expected: 3 evaluated: ", values, err)
+  }
+}
+
+func TestContext(t *testing.T) {
+  hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    <-r.Context().Done()
+  }))
+  defer hung.Close()
+
+  env := scope.NewRootScope()
+  ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+  defer cancel()
+  start := time.Now()
+  if _, err := repl.RunContext(ctx, "context", fmt.Sprintf("(http-get %q)", hung.URL), env); !errors.Is(err, context.DeadlineExceeded) {
+    t.Error("expected the deadline to be exceeded, raised: ", err)
+  }
+  if _, err := repl.RunContext(ctx, "context", "(sleep 10000)", env); !errors.Is(err, context.DeadlineExceeded) {
+    t.Error("expected the deadline to be exceeded, raised: ", err)
+  }
+  if elapsed := time.Since(start); elapsed > 5*time.Second {
+    t.Error("expected the I/O to be interrupted, took: ", elapsed)
+  }
+
+  // the context only applies while the program runs
+  if values, err := repl.Run("context", "(sleep 1) 'done", env); err != nil || repl.Print(values) != "done" {
+    t.Error("expected: done evaluated: ", values, err)
+  }
+}
+
+func TestMemoryLimit(t *testing.T) {
+  env := scope.NewRootScope()
+  env.SetMemoryLimit(1 << 20)
+  defer env.SetMemoryLimit(0)
+
+  // 100000 pairs of integers take several megabytes
+  build := "(define (build n acc) (if (= n 0) acc (build (- n 1) (cons n acc))))"
+  var oom *value.OutOfMemory
+  if _, err := repl.Run("memory", build+"(define xs (build 100000 '()))", env); !errors.As(err, &oom) {
+    t.Fatal("expected to run out of memory, raised: ", err)
+  }
+  if oom.Used <= oom.Limit || oom.Limit != 1<<20 {
+    t.Error("unexpected usage: ", oom)
+  }
+
+  // garbage is not held against the limit
+  churn := "(define (churn n) (if (= n 0) 'done (begin (build 100 '()) (churn (- n 1)))))"
+  if values, err := repl.Run("memory", churn+"(churn 2000)", env); err != nil || repl.Print(values) != "done" {
+    t.Error("expected: done evaluated: ", values, err)
+  }
+  if used := env.MemoryUsed(); used > 1<<20 {
+    t.Error("expected the values to be dropped, using: ", used)
+  }
+
+  // vectors are accounted for, and checked before they are allocated
+  vectors := "(define (vectors n acc) (if (= n 0) acc (vectors (- n 1) (cons (make-vector 10 0) acc))))"
+  for _, exprs := range []string{vectors + "(define vs (vectors 20000 '()))", "(make-vector 50000000 0)", "(random-bytes 100000000000)", "(make-string 10000000)"} {
+    if _, err := repl.Run("memory", exprs, env); !errors.As(err, &oom) {
+      t.Errorf("%s: expected to run out of memory, raised: %v", exprs, err)
+    }
+  }
+
+  // so are hash tables, bytevectors and bitvectors
+  base := env.MemoryUsed()
+  fill := "(define h (make-hash)) (define (fill n) (if (> n 0) (begin (hash-set! h n n) (fill (- n 1))))) (fill 1000)"
+  repl.Run("memory", fill+"(define b (random-bytes 100000)) (define bits (make-bitvector 800000))", env)
+  if used := env.MemoryUsed() - base; used < 1000*64+100000+100000 {
+    t.Error("expected the tables and vectors to be measured, using: ", used)
+  }
+}
+
+func TestAuditLog(t *testing.T) {
+  exprs, err := ioutil.ReadFile("audit_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  env := scope.NewRootScope()
+  repl.REPL("(define limit 10) (define greeting \"hello\")", env)
+  log := scope.NewAuditLog()
+  env.SetAuditLog(log)
+  repl.EvalSource("audit_test.ss", string(exprs), env)
+
+  var changes []string
+  for _, change := range log.Changes() {
+    changes = append(changes, change.String())
+  }
+  expected := []string{
+    "audit_test.ss:1:1: define limit: 10 -> 20",
+    "audit_test.ss:2:1: set! greeting: \"hello\" -> \"hi\"",
+    "audit_test.ss:3:1: define helper: unbound -> #<procedure>",
+    "audit_test.ss:4:1: define-constant answer: unbound -> 42",
+  }
+  if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+    t.Error("expected: ", expected, " recorded: ", changes)
+  }
+
+  // roll back everything but the new helper, from the last change
+  all := log.Changes()
+  for i := len(all) - 1; i >= 0; i-- {
+    if all[i].Name != "helper" {
+      log.Rollback(all[i])
+    }
+  }
+  if result := repl.REPL("limit greeting (procedure? helper)", env); result != "10\n\"hello\"\n#t" {
+    t.Error("expected: 10 \"hello\" #t evaluated: ", result)
+  }
+  if err := testError("answer", env); fmt.Sprint(err) != "answer: undefined identifier" {
+    t.Error("expected answer to be unbound, raised: ", err)
+  }
+}
+
+func TestUndo(t *testing.T) {
+  root := scope.NewRootScope()
+  repl.REPL("(define limit 10)", root)
+  root.Freeze()
+  env := repl.NewTopLevel(root)
+  undo := repl.NewUndo(env, 3)
+
+  inputs := []string{
+    "(define (double x) (* x 2))",
+    "(define limit 20) (set! limit 30)",
+    "(double limit)",
+    "(define (double x) (+ x x x))",
+  }
+  for _, input := range inputs {
+    undo.Begin()
+    repl.REPL(input, env)
+  }
+  if result := repl.REPL("(double limit)", env); result != "90" {
+    t.Error("expected: 90 evaluated: ", result)
+  }
+  if changes := undo.Undo(); len(changes) != 1 || changes[0].Name != "double" {
+    t.Error("expected the redefinition of double to be undone, undone: ", changes)
+  }
+  if result := repl.REPL("(double limit)", env); result != "60" {
+    t.Error("expected: 60 evaluated: ", result)
+  }
+  // the input without definitions is skipped
+  if changes := undo.Undo(); len(changes) != 2 {
+    t.Error("expected the define and set! of limit to be undone, undone: ", changes)
+  }
+  if result := repl.REPL("(double limit)", env); result != "20" {
+    t.Error("expected: 20 evaluated: ", result)
+  }
+  undo.Undo()
+  if err := testError("double", env); fmt.Sprint(err) != "double: undefined identifier" {
+    t.Error("expected double to be unbound, raised: ", err)
+  }
+  if changes := undo.Undo(); changes != nil {
+    t.Error("expected nothing to undo, undone: ", changes)
+  }
+
+  // the oldest inputs changing a binding are forgotten
+  for _, input := range []string{"(define a 1)", "(define b 2)", "(+ 1 2)", "(define c 3)", "(define d 4)"} {
+    undo.Begin()
+    repl.REPL(input, env)
+  }
+  for _, name := range []string{"d", "c", "b"} {
+    if changes := undo.Undo(); len(changes) != 1 || changes[0].Name != name {
+      t.Error("expected the definition of ", name, " to be undone, undone: ", changes)
+    }
+  }
+  if changes := undo.Undo(); changes != nil {
+    t.Error("expected the definition of a to be forgotten, undone: ", changes)
+  }
+}
+
+func TestEnvironmentAlist(t *testing.T) {
+  result := testFile("environment_alist_test.ss", t)
+
+  expected := "((greeting . \"hello\") (limit . 10))\n62"
+  expected += "\n((answer . 42) (limit . 20))\n((\"c\" . 3))\n()\n2\n3"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  repl.REPL("(define limit 10)", env)
+  env.Freeze()
+  errors := map[string]string{
+    "(alist->environment! (the-environment) '(limit))":       "alist->environment!: expected (name . value), given: limit",
+    "(alist->environment! (the-environment) '((other . 1)))": "alist->environment!: cannot change constant: other",
+    "(environment-diff '((1 . 2)) '())":                      "environment-diff: expected (name . value), given: (1 . 2)",
+    "(environment-diff 'a '())":                              "environment-diff: expected environment or association list, given: a",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestDisplayResults(t *testing.T) {
+  exprs, err := ioutil.ReadFile("display_results_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  env := repl.NewTopLevel(scope.NewRootScope())
+  var out bytes.Buffer
+  repl.EvalPrinting("display_results_test.ss", string(exprs), env, &out)
+  if expected := "#t\n10\n\"shown\"\n"; out.String() != expected {
+    t.Errorf("expected: %q printed: %q", expected, out.String())
+  }
+
+  env.SetDisplayResults(true)
+  out.Reset()
+  repl.EvalPrinting("<REPL>", "(define y 2) y", env, &out)
+  if out.String() != "2\n" {
+    t.Errorf("expected: %q printed: %q", "2\n", out.String())
+  }
+}
+
+func TestWalk(t *testing.T) {
+  program := "(define (square x) (* x x)) (let ((n 2)) (if (> n 1) (square n) '(square 1)))"
+  nodes, err := parser.ParseFromString("<walk>", program)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  // quoted data and bound names are visited too
+  var names []string
+  for _, node := range nodes {
+    ast.Inspect(node, func(node ast.Node) bool {
+      if name, ok := node.(*ast.Name); ok {
+        names = append(names, name.Identifier)
+      }
+      _, quoted := node.(*ast.Quote)
+      return !quoted
+    })
+  }
+  if result := strings.Join(names, " "); result != "square square x * x x n > n square n" {
+    t.Error("expected: square square x * x x n > n square n visited: ", result)
+  }
+
+  // rename square everywhere but in quoted data and double the integers
+  for i, node := range nodes {
+    quoted := make(map[ast.Node]bool)
+    ast.Inspect(node, func(node ast.Node) bool {
+      if quote, ok := node.(*ast.Quote); ok {
+        ast.Inspect(quote.Body, func(node ast.Node) bool { quoted[node] = true; return true })
+      }
+      return true
+    })
+    nodes[i] = ast.Rewrite(node, func(node ast.Node) ast.Node {
+      if name, ok := node.(*ast.Name); ok && name.Identifier == "square" && !quoted[node] {
+        return ast.NewName("sq")
+      }
+      if n, ok := node.(*ast.Int); ok && !quoted[node] {
+        return ast.NewInt(fmt.Sprint(2 * n.Value))
+      }
+      return node
+    })
+  }
+  env := scope.NewRootScope()
+  if result := repl.Print(ast.EvalList(nodes, env)); result != "16" {
+    t.Error("expected: 16 evaluated: ", result)
+  }
+  if env.Lookup("sq") == nil || env.Lookup("square") != nil {
+    t.Error("expected square to be renamed sq")
+  }
+
+  err = nil
+  func() {
+    defer func() { err = fmt.Errorf("%v", recover()) }()
+    ast.Rewrite(nodes[0], func(node ast.Node) ast.Node {
+      if name, ok := node.(*ast.Name); ok && name.Identifier == "sq" {
+        return ast.NewInt("1")
+      }
+      return node
+    })
+  }()
+  if fmt.Sprint(err) != "rewrite: expected a name, given: 1" {
+    t.Error("expected the pattern to stay a name, raised: ", err)
+  }
+}
+
+func TestDatum(t *testing.T) {
+  exprs, err := ioutil.ReadFile("datum_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  nodes, err := parser.ParseFromString("datum_test.ss", string(exprs))
+  if err != nil {
+    t.Fatal(err)
+  }
+  // the data print like the source and parse back to the same program
+  forms := strings.Split(strings.TrimSpace(string(exprs)), "\n")
+  var parsed []ast.Node
+  for i, node := range nodes {
+    datum := ast.ToDatum(node)
+    if fmt.Sprint(datum) != forms[i] {
+      t.Error("expected: ", forms[i], " converted: ", datum)
+    }
+    node, err := parser.FromDatum(datum)
+    if err != nil {
+      t.Fatal(err)
+    }
+    parsed = append(parsed, node)
+  }
+  lib, err := ioutil.ReadFile("../stdlib/stdlib.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  env := scope.NewRootScope()
+  repl.REPL(string(lib), env)
+  expected := "(10 1 2 'x)\n#t\n(9 6 9/2 5.0 1 forced none #\\a)"
+  if result := repl.Print(ast.EvalList(parsed, env)); result != expected {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  errors := map[string]value.Value{
+    "<datum>: lambda: bad syntax: (lambda)":   value.NewPairValue(value.NewSymbol("lambda"), nil),
+    "<datum>: no syntax for #<opaque string>": value.NewOpaque("x"),
+  }
+  for expected, datum := range errors {
+    if _, err := parser.FromDatum(datum); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestArithmetic(t *testing.T) {
+  result := testFile("arithmetic_test.ss", t)
+  expected := "3\n3.5\n1.0"
+  expected += "\n-5\n-0.0\n8.0\n1.5\n9007199254740992"
+  expected += "\n6\n3.0\n6.0"
+  expected += "\n2\n2\n1/2\n1/2\n3.0\n2.0\n9007199254740993"
+  expected += "\n#t\n#f\n#t\n#t"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestFloatRoundTrip(t *testing.T) {
+  env := scope.NewRootScope()
+  result := repl.REPL("2.0 (* 1.5 4) 0.1 (+ 0.1 0.2) 1e21 1.5e-7 -0.0 +inf.0 -inf.0 +nan.0 (string->number \"-inf.0\") '(+inf.0 . x)", env)
+  expected := "2.0\n6.0\n0.1\n0.30000000000000004\n1e+21\n1.5e-07\n-0.0\n+inf.0\n-inf.0\n+nan.0\n-inf.0\n(+inf.0 . x)"
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // printed floats read back as the same bits
+  for _, f := range []float64{0.1, 1.0 / 3, 2, -1e-300, 1e300, 123456789.125, math.MaxFloat64, math.SmallestNonzeroFloat64, math.Inf(1)} {
+    text := value.NewFloatValue(f).String()
+    val, ok := repl.EvalSource("<float>", text, env)[0].(*value.FloatValue)
+    if !ok || math.Float64bits(val.Value) != math.Float64bits(f) {
+      t.Error("expected ", text, " to read back as ", f, ", read: ", val)
+    }
+  }
+}
+
+func TestFoldCaseDirective(t *testing.T) {
+  result := testFile("fold_case_directive_test.ss", t)
+  expected := "Hello\n10\nhello\n#t\n#\\A\n#t\nHello\n\"Strings Keep Case\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  env.SetFoldCase(true)
+  if result := repl.REPL("(define Foo 1) (+ foo FOO) #!no-fold-case 'Foo", env); result != "2\nFoo" {
+    t.Error("expected: 2 Foo evaluated: ", result)
+  }
+  if err := testError("#!fold-cases", env); fmt.Sprint(err) != "<REPL>:1:1: unknown directive #!fold-cases" {
+    t.Error("expected an unknown directive, raised: ", err)
+  }
+}
+
+func TestLoadErrors(t *testing.T) {
+  dir, err := ioutil.TempDir("", "lispex")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+  files := map[string]string{
+    "a.ss":      "(define x 1)\n(load \"b.ss\")",
+    "b.ss":      "(define y 2)\n\n(car y)",
+    "self.ss":   "(load \"other.ss\")",
+    "other.ss":  "(define z 3)\n(load \"self.ss\")",
+    "syntax.ss": "(load \"broken.ss\")",
+    "broken.ss": "(define z 3)\n(define)",
+  }
+  for name, content := range files {
+    if err := ioutil.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  env := repl.NewTopLevel(scope.NewRootScope())
+  messages := map[string]string{
+    "a.ss":      "while loading a.ss:2:1 → b.ss:3:1: car: expected pair, given: 2",
+    "self.ss":   "while loading self.ss:1:1 → other.ss:2:1: load: circular load of self.ss",
+    "syntax.ss": "while loading syntax.ss:1:1: broken.ss:2:1: define: bad syntax (missing expressions) (define)",
+  }
+  for name, expected := range messages {
+    _, err := repl.Run("<test>", fmt.Sprintf("(load \"%s/%s\")", dir, name), env)
+    if message := strings.Replace(fmt.Sprint(err), dir+"/", "", -1); message != expected {
+      t.Error("expected: ", expected, " raised: ", message)
+    }
+  }
+
+  _, err = repl.Run("<test>", fmt.Sprintf("(load \"%s/a.ss\")", dir), env)
+  var loadError *repl.LoadError
+  var typeError *value.TypeError
+  if !errors.As(err, &loadError) || len(loadError.Trace) != 2 || !errors.As(err, &typeError) {
+    t.Error("expected a load error wrapping a type error, raised: ", err)
+  }
+}
+
+func TestInput(t *testing.T) {
+  input := &repl.Input{}
+  lines := []struct {
+    line     string
+    complete bool
+    indent   int
+  }{
+    {"(define (area shape) ; width height", false, 2},
+    {"  (let ((w (car shape))", false, 8},
+    {"(h (cdr shape)))", false, 4},
+    {"(display \"a (string\"", false, 13},
+    {"#\\( w", false, 13},
+    {"h)))", true, 0},
+  }
+  for _, line := range lines {
+    if complete := input.Add(line.line); complete != line.complete {
+      t.Errorf("%q: expected complete %v", line.line, line.complete)
+    }
+    if !line.complete && input.Indent() != line.indent {
+      t.Errorf("%q: expected indent %d, indented %d", line.line, line.indent, input.Indent())
+    }
+  }
+  expected := "(define (area shape) ; width height\n  (let ((w (car shape))\n        (h (cdr shape)))\n    (display \"a (string\"\n             #\\( w\n             h)))"
+  if input.Text() != expected {
+    t.Error("expected: ", expected, " typed: ", input.Text())
+  }
+
+  // strings spanning lines are kept as typed
+  input.Reset()
+  if input.Add("(list \"one") || input.Add("  two\"") || !input.Add(")") {
+    t.Error("expected the form to end with the last line")
+  }
+  if input.Text() != "(list \"one\n  two\"\n      )" {
+    t.Errorf("expected the string kept, typed: %q", input.Text())
+  }
+}
+
+func TestInspect(t *testing.T) {
+  var out bytes.Buffer
+  commands := strings.NewReader("1\n0\nu\nu\nu\n7\n2\n1\nq\n")
+  env := scope.NewRootScope()
+  env.Put("inspect", primitives.LookupBuiltin("inspect").With(primitives.NewInspect(commands, &out)))
+  repl.REPL("(inspect '(1 (2 . 3) \"four\"))", env)
+
+  expected := `pair: (1 (2 . 3) "four")
+  0. 1
+  1. (2 . 3)
+  2. "four"
+inspect> pair: (2 . 3)
+  0. car: 2
+  1. cdr: 3
+inspect> integer: 2
+inspect> pair: (2 . 3)
+  0. car: 2
+  1. cdr: 3
+inspect> pair: (1 (2 . 3) "four")
+  0. 1
+  1. (2 . 3)
+  2. "four"
+inspect> already at the top
+pair: (1 (2 . 3) "four")
+  0. 1
+  1. (2 . 3)
+  2. "four"
+inspect> type a field number from 0 to 2, u to go up or q to quit
+pair: (1 (2 . 3) "four")
+  0. 1
+  1. (2 . 3)
+  2. "four"
+inspect> string: "four"
+inspect> no fields, type u to go up or q to quit
+string: "four"
+inspect> `
+  if out.String() != expected {
+    t.Errorf("expected: %q inspected: %q", expected, out.String())
+  }
+
+  rt := repl.RegisterStruct(env, ServerConfig{})
+  long := strings.Repeat("x", 100)
+  record := value.NewRecord(rt, []value.Value{value.NewStringValue(long), value.NewIntValue(8), value.NewBoolValue(false), value.NilPairValue})
+  fields := primitives.InspectFields(record)
+  if len(fields) != 4 || fields[0].Label != "listen-addr" || fields[1].Label != "max-conns" {
+    t.Error("expected the fields of the record, inspected: ", fields)
+  }
+  out.Reset()
+  env.Put("inspect", primitives.LookupBuiltin("inspect").With(primitives.NewInspect(strings.NewReader(""), &out)))
+  env.Put("config", record)
+  repl.REPL("(inspect config)", env)
+  if lines := strings.Split(out.String(), "\n"); len(lines[0]) != len("server-config: ")+72 || !strings.HasSuffix(lines[0], "...") {
+    t.Error("expected a summary cut at 72 characters, inspected: ", lines[0])
+  }
+
+  out.Reset()
+  commands = strings.NewReader("1\n1\nq\n")
+  env.Put("inspect", primitives.LookupBuiltin("inspect").With(primitives.NewInspect(commands, &out)))
+  repl.REPL("(inspect '#(1 (2 3)))", env)
+  expected = `vector: #(1 (2 3))
+  0. 1
+  1. (2 3)
+inspect> pair: (2 3)
+  0. 2
+  1. 3
+inspect> integer: 3
+inspect> `
+  if out.String() != expected {
+    t.Errorf("expected: %q inspected: %q", expected, out.String())
+  }
+  for _, obj := range []value.Value{value.NewBytevector([]byte{1, 255}), value.NewBitvector(2)} {
+    if fields := primitives.InspectFields(obj); len(fields) != 2 {
+      t.Error("expected a field for each element of ", obj, ", inspected: ", fields)
+    }
+  }
+}
+
+func TestTranscript(t *testing.T) {
+  dir, err := ioutil.TempDir("", "lispex")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+  filename := dir + "/session.txt"
+  transcript, err := repl.NewTranscript(filename)
+  if err != nil {
+    t.Fatal(err)
+  }
+  var out bytes.Buffer
+  primitives.SetOutput(io.MultiWriter(&out, transcript))
+  env := repl.NewTopLevel(scope.NewRootScope())
+  inputs := []string{"(define (double x)\n  (* x 2))", ":undo", "(display (double 4))", "(double 21)"}
+  inputs = append(inputs, "(car 1)", "(display \">>> (double 1)\\n\")", "(double 2)")
+  for _, input := range inputs {
+    transcript.Input(input)
+    if strings.HasPrefix(input, ":") {
+      continue
+    }
+    values, err := repl.Run("<REPL>", input, env)
+    if err != nil {
+      fmt.Fprintln(primitives.Output(), repl.FormatError(err))
+    } else if len(values) > 0 && values[0] != nil {
+      fmt.Fprintln(primitives.Output(), repl.Print(values))
+    }
+  }
+  primitives.SetOutput(nil)
+  transcript.Close()
+
+  text, err := ioutil.ReadFile(filename)
+  if err != nil {
+    t.Fatal(err)
+  }
+  // printed lines looking like prompts are escaped
+  expected := ">>> (define (double x)\n...   (* x 2))\n>>> :undo\n>>> (display (double 4))\n8\n>>> (double 21)\n42\n"
+  expected += ">>> (car 1)\n<REPL>:1:1: car: expected pair, given: 1\n>>> (display \">>> (double 1)\\n\")\n\\>>> (double 1)\n>>> (double 2)\n4\n"
+  printed := "842\n<REPL>:1:1: car: expected pair, given: 1\n>>> (double 1)\n4\n"
+  if string(text) != expected || out.String() != printed {
+    t.Errorf("expected: %q recorded: %q printed: %q", expected, text, out.String())
+  }
+  source := "(define (double x)\n  (* x 2))\n\n(display (double 4))\n\n(double 21)\n\n(car 1)\n\n(display \">>> (double 1)\\n\")\n\n(double 2)\n\n"
+  if read := repl.TranscriptSource(string(text)); read != source {
+    t.Errorf("expected the typed forms, read: %q", read)
+  }
+
+  // the forms after one raising an error are evaluated too
+  replayed := captureOutput(func() {
+    repl.REPL(fmt.Sprintf("(load-history \"%s\")", filename), repl.NewTopLevel(scope.NewRootScope()))
+  }, t)
+  if replayed != strings.Replace(printed, "<REPL>:1:1", filename+":8:1", 1) {
+    t.Errorf("expected the session printed again, printed: %q", replayed)
+  }
+
+  ioutil.WriteFile(filename, []byte(fmt.Sprintf(">>> (load-history \"%s\")\n", filename)), 0644)
+  replayed = captureOutput(func() {
+    repl.REPL(fmt.Sprintf("(load-history \"%s\")", filename), repl.NewTopLevel(scope.NewRootScope()))
+  }, t)
+  if !strings.Contains(replayed, "load-history: circular load of "+filename) {
+    t.Error("expected a circular load error, printed: ", replayed)
+  }
+}
+
+func TestGoroutineReport(t *testing.T) {
+  env := repl.NewTopLevel(scope.NewRootScope())
+  repl.EvalSource("leaks.ss", "(define c (make-chan))\n(define (spawn n) (if (> n 0) (begin (go (<-chan c)) (spawn (- n 1)))))\n(spawn 2)", env)
+  if report := repl.GoroutineReport(); !strings.Contains(report, ";;   2 started at leaks.ss:2:38\n") {
+    t.Error("expected the blocked goroutines reported, reported: ", report)
+  }
+  if counts := value.RunningGoroutines(); counts["leaks.ss:2:38"] != 2 {
+    t.Error("expected 2 goroutines running, running: ", counts)
+  }
+
+  repl.REPL("(chan<- c 1) (chan<- c 2)", env)
+  done := make(chan bool)
+  go func() {
+    value.WaitGoroutines()
+    done <- true
+  }()
+  select {
+  case <-done:
+  case <-time.After(time.Second):
+    t.Fatal("expected the goroutines to return")
+  }
+  if report := repl.GoroutineReport(); strings.Contains(report, "leaks.ss") {
+    t.Error("expected no goroutine left, reported: ", report)
+  }
+}
+
+func TestDeadlock(t *testing.T) {
+  env := repl.NewTopLevel(scope.NewRootScope())
+  source := "(define c (make-chan))\n(define d (make-chan))\n(go (chan<- c (<-chan d)))\n(go (select ((<-chan d) 1) ((<-chan c) 2)))\n(<-chan c)"
+  raised := make(chan error)
+  go func() {
+    _, err := repl.Run("deadlock.ss", source, env)
+    raised <- err
+  }()
+  var err error
+  select {
+  case err = <-raised:
+  case <-time.After(2 * time.Second):
+    t.Fatal("expected the deadlock to be detected")
+  }
+  var deadlock *value.DeadlockError
+  if !errors.As(err, &deadlock) {
+    t.Fatal("expected a deadlock error, raised: ", err)
+  }
+  blocked := strings.Join(deadlock.Blocked, "\n")
+  for _, site := range []string{"<-chan at deadlock.ss:3", "select at deadlock.ss:4", "<-chan at deadlock.ss:5"} {
+    if !strings.Contains(blocked, site) {
+      t.Errorf("expected %s blocked, blocked: %s", site, blocked)
+    }
+  }
+
+  // goroutines meeting on channels are not deadlocked
+  result := repl.Print(repl.EvalSource("<REPL>", "(define (worker n) (if (> n 0) (begin (chan<- c n) (worker (- n 1)))))\n(go (worker 3))\n(+ (<-chan c) (<-chan c) (<-chan c))", env))
+  if result != "6" {
+    t.Error("expected: 6 evaluated: ", result)
+  }
+
+  // a host goroutine calling back into lisp may still be unblocked
+  env.Put("host-async", primitives.WrapGo("host-async", "", func(f func()) { go f() }))
+  source = "(host-async (lambda () (<-chan c)))\n(go (begin (sleep 300) (chan<- d 'x)))\n(<-chan d)"
+  result = repl.Print(repl.EvalSource("<REPL>", source, env))
+  repl.REPL("(chan<- c 1)", env)
+  if result != "x" {
+    t.Error("expected: x evaluated: ", result)
+  }
+}
+
+func TestNursery(t *testing.T) {
+  result := testFile("nursery_test.ss", t)
+  expected := "started\n6\n55\nx\n#t"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := repl.NewTopLevel(scope.NewRootScope())
+  messages := map[string]string{
+    "(nursery (go (car 1)) (go (sleep 20)) 'unreached)":    "car: expected pair, given: 1",
+    "(nursery (car 2) (go (sleep 20)))":                    "car: expected pair, given: 2",
+    "(nursery (define c (make-chan)) (go (<-chan c)) 'ok)": "deadlock, every goroutine is blocked:\n  <-chan at <REPL>:1:37\n  nursery at <REPL>:1:1",
+  }
+  for exprs, expected := range messages {
+    if message := fmt.Sprint(testError(exprs, env)); message != expected {
+      t.Errorf("%s: expected %q, raised %q", exprs, expected, message)
+    }
+  }
+
+  // the first error cancels the sleep of the other goroutines
+  start := time.Now()
+  if message := fmt.Sprint(testError("(nursery (go (sleep 5000)) (go (sleep 5000)) (sleep 10) (car 3))", env)); message != "car: expected pair, given: 3" {
+    t.Error("expected the error of the body, raised: ", message)
+  }
+  if elapsed := time.Since(start); elapsed > time.Second {
+    t.Error("expected the goroutines of the nursery to be canceled, waited: ", elapsed)
+  }
+}
+
+func TestChanTry(t *testing.T) {
+  result := testFile("chan_try_test.ss", t)
+  expected := "(#f)\n#t\n#f\n(#t . a)\n(#f)\n42\n(#f . #<eof>)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestHash(t *testing.T) {
+  result := testFile("hash_test.ss", t)
+  expected := "42\nnone\n2\n2\n#<hash (\"Alice\" . 2) (\"bob\" . 3)>\none\n(#t #f hash)\n(\"b\" \"c\")\n(2 . two)\n#f"
+  expected += "\n#t\n#f\n(a c)\n4\n(same other symbol)\n(#t #f #t #t)\n2000"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  if _, err := repl.Run("hash", `(hash-ref (make-hash) 'missing)`, env); err == nil || !strings.Contains(err.Error(), "no value for the key: missing") {
+    t.Error("expected a missing key error, given: ", err)
+  }
+}
+
+func TestSubstring(t *testing.T) {
+  result := testFile("substring_test.ss", t)
+  expected := "\"x\"\n\"42\"\n(\"let x = 43\" \"Let x = 42\")\n(\"let x = 43\" \"let\" 10)\n\"él\"\n(\"the\" \"quick\" \"brown\" \"fox\")"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // large parts of a string share its bytes, small ones are copied
+  text := value.NewStringValue(strings.Repeat("token ", 1000))
+  tail := primitives.NewSubstring().Apply([]value.Value{text, value.NewIntValue(6)}).(*value.StringValue)
+  if unsafe.StringData(tail.Value) != unsafe.StringData(text.Value[6:]) {
+    t.Error("expected the substring to share the bytes of the string")
+  }
+  token := primitives.NewSubstring().Apply([]value.Value{text, value.NewIntValue(6), value.NewIntValue(11)}).(*value.StringValue)
+  if token.Value != "token" || unsafe.StringData(token.Value) == unsafe.StringData(text.Value[6:]) {
+    t.Error("expected a copy of the token, given: ", token.Value)
+  }
+
+  env := scope.NewRootScope()
+  if _, err := repl.Run("substring", `(substring "abc" 2 5)`, env); err == nil || !strings.Contains(err.Error(), "range 2 to 5 out of bounds") {
+    t.Error("expected a range error, given: ", err)
+  }
+}
+
+func TestInterp(t *testing.T) {
+  interp, err := lispex.NewInterp()
+  if err != nil {
+    t.Fatal(err)
+  }
+  other, err := lispex.NewInterp()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  interp.Define("greeting", "hello")
+  interp.Define("limits", []int{1, 2, 3})
+  interp.Define("shout", strings.ToUpper)
+  val, err := interp.Eval(`(define total (fold + 0 limits)) (list (shout greeting) total)`)
+  if err != nil || val.String() != `("HELLO" 6)` {
+    t.Error("expected: (\"HELLO\" 6) evaluated: ", val, err)
+  }
+  if val, ok := interp.Lookup("total"); !ok || val.String() != "6" {
+    t.Error("expected total to be 6, given: ", val)
+  }
+  if _, ok := other.Lookup("total"); ok {
+    t.Error("expected the definitions of an interpreter to be unknown to the others")
+  }
+  var unbound *value.UnboundVariable
+  if _, err := other.Eval("greeting"); !errors.As(err, &unbound) {
+    t.Error("expected an unbound variable error, given: ", err)
+  }
+  if val, err := interp.Eval(""); val != nil || err != nil {
+    t.Error("expected no value for no forms, given: ", val, err)
+  }
+
+  // macros and settings are those of each interpreter
+  if _, err := interp.Eval("(define-syntax swap! (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))"); err != nil {
+    t.Error("expected swap! to be defined, raised: ", err)
+  }
+  if _, err := other.Eval("(define a 1) (define b 2) (swap! a b)"); !errors.As(err, &unbound) {
+    t.Error("expected swap! to be unbound in the other interpreter, raised: ", err)
+  }
+  interp.Eval("(define/contract (half n) (-> even? integer?) (quotient n 2)) (contracts-enabled #f)")
+  other.Eval("(define/contract (half n) (-> even? integer?) (quotient n 2))")
+  if _, err := other.Eval("(half 3)"); err == nil {
+    t.Error("expected the contracts of the other interpreter to be checked")
+  }
+  if val, err := interp.Eval("(half 3)"); err != nil || val.String() != "1" {
+    t.Error("expected: 1 evaluated: ", val, err)
+  }
+  interp.Scope().SetApplicableData(true)
+  if val, err := interp.Eval(`("abc" 1)`); err != nil || val.String() != `#\b` {
+    t.Error("expected: #\\b evaluated: ", val, err)
+  }
+  if _, err := other.Eval(`("abc" 1)`); err == nil {
+    t.Error("expected strings not to be callable in the other interpreter")
+  }
+
+  squares := func(n int) <-chan int {
+    ch := make(chan int)
+    go func() {
+      for i := 1; i <= n; i++ {
+        ch <- i * i
+      }
+      close(ch)
+    }()
+    return ch
+  }
+  sum := func(ch <-chan float64) float64 {
+    total := 0.0
+    for x := range ch {
+      total += x
+    }
+    return total
+  }
+  tally := func(words []string) map[string]int {
+    counts := make(map[string]int)
+    for _, word := range words {
+      counts[word]++
+    }
+    return counts
+  }
+  for name, fn := range map[string]interface{}{"squares": squares, "sum": sum, "tally": tally} {
+    if err := interp.Register(name, fn); err != nil {
+      t.Error(err)
+    }
+  }
+  source := `(define c (make-chan))
+(go (begin (chan<- c 1) (chan<- c 2.5) (close-chan c)))
+(define s (squares 3))
+(list (<-chan s) (<-chan s) (<-chan s) (sum c) (tally '("a" "b" "a")))`
+  if val, err := interp.Eval(source); err != nil || val.String() != `(1 4 9 3.5 (("a" . 2) ("b" . 1)))` {
+    t.Error("expected: (1 4 9 3.5 ((\"a\" . 2) (\"b\" . 1))) evaluated: ", val, err)
+  }
+  if err := interp.Register("limit", 10); fmt.Sprint(err) != "limit: expected a function, given: int" {
+    t.Error("expected a function error, given: ", err)
+  }
+
+  if val, err := interp.EvalFile("lambda_test.ss"); err != nil || val == nil {
+    t.Error("expected the value of the last form of the file, given: ", val, err)
+  }
+  if _, err := interp.EvalFile("missing.ss"); !os.IsNotExist(err) {
+    t.Error("expected a missing file error, given: ", err)
+  }
+}
+
+func TestFiles(t *testing.T) {
+  result := testFile("files_test.ss", t)
+  expected := "(\"ERROR disk full\" \"ERROR disk full again\")\n\"INFO start\"\n\"done\"\n#<eof>\n70"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  if _, err := repl.Run("files", `(for-each-line display "testdata/missing.log")`, env); err == nil || !strings.Contains(err.Error(), "no such file") {
+    t.Error("expected a missing file error, given: ", err)
+  }
+}
+
+func TestErrorPositions(t *testing.T) {
+  env := repl.NewTopLevel(scope.NewRootScope())
+  repl.REPL("(define (pair-of x y) (cons x y))", env)
+  tests := map[string]string{
+    "(car 1)":                     "errors.ss:2:1: car: expected pair, given: 1",
+    "(cons 1)":                    "errors.ss:2:1: cons: arguments mismatch, expected 2, given: 1",
+    "(car\n  xs)":                 "errors.ss:3:3: xs: undefined identifier",
+    "(pair-of 1)":                 "errors.ss:2:1: missing arguments",
+    "((lambda (f)\n  (f 1)) car)": "errors.ss:3:3 in an anonymous procedure: car: expected pair, given: 1\n  called at errors.ss:2:1",
+    "((car '(1)) 2)":              "errors.ss:2:1: 1: not allowed in a call context, in: ((car '(1)) 2)",
+    "(car '(1)":                   "errors.ss:2:1: unclosed delimeter, expected: `('",
+  }
+  for program, expected := range tests {
+    _, err := repl.Run("errors.ss", "\n"+program, env)
+    if err == nil || repl.FormatError(err) != expected {
+      t.Error("expected: ", expected, " raised: ", repl.FormatError(err))
+    }
+  }
+
+  // messages leave the position out
+  var typeError *value.TypeError
+  if _, err := repl.Run("errors.ss", "(car 1)", env); !errors.As(err, &typeError) || typeError.Error() != "car: expected pair, given: 1" || typeError.Pos != "errors.ss:1:1" {
+    t.Error("expected a type error at errors.ss:1:1, raised: ", err)
+  }
+  var out bytes.Buffer
+  if err := repl.RunPrinting("errors.ss", "1 (car 1)", env, &out); repl.FormatError(err) != "errors.ss:1:3: car: expected pair, given: 1" {
+    t.Error("expected a type error, raised: ", err)
+  }
+  if out.String() != "" {
+    t.Error("expected nothing printed without display-results, given: ", out.String())
+  }
+}
+
+func TestBacktrace(t *testing.T) {
+  env := repl.NewTopLevel(scope.NewRootScope())
+  program := `(define (first-of x)
+  (car x))
+(define (count-down n)
+  (if (= n 0)
+      (first-of n)
+      (count-down (- n 1))))
+(define twice
+  (lambda (f x) (f (f x))))
+(twice count-down 3)`
+  _, err := repl.Run("trace.ss", program, env)
+  expected := "trace.ss:2:3 in procedure first-of: car: expected pair, given: 0"
+  expected += "\n  called at trace.ss:5:7 in procedure count-down"
+  expected += "\n  called at trace.ss:6:7 in procedure count-down (3 times)"
+  expected += "\n  called at trace.ss:8:20 in procedure twice"
+  expected += "\n  called at trace.ss:9:1"
+  if repl.FormatError(err) != expected {
+    t.Error("expected: ", expected, " raised: ", repl.FormatError(err))
+  }
+
+  // the error keeps its type and message
+  var typeError *value.TypeError
+  var backtrace *value.Backtrace
+  if !errors.As(err, &typeError) || !errors.As(err, &backtrace) || len(backtrace.Trace) != 6 || err.Error() != "car: expected pair, given: 0" {
+    t.Error("expected a type error with 6 calls in its backtrace, raised: ", err)
+  }
+
+  // long backtraces are cut short
+  repl.REPL("(define (deep n) (if (= n 0) (car n) (+ 1 (deeper n)))) (define (deeper n) (deep (- n 1)))", env)
+  _, err = repl.Run("deep.ss", "(deep 30)", env)
+  if text := repl.FormatError(err); strings.Count(text, "\n") != 22 || !strings.Contains(text, "\n  ... 40 more\n  called at deep.ss:1:1") {
+    t.Error("expected a backtrace cut after 20 calls, raised: ", text)
+  }
+}
+
+func TestParMapIsolated(t *testing.T) {
+  result := testFile("par_map_test.ss", t)
+  expected := "(55 610 6765 75025)\n(3 6 9)\n(1 3)\n(7)\n0\n(\"zb\" \"zd\")\n(\"ab\" \"cd\")\n()\n(1/3 2/3)\n(15511210043330985984000000)\n(10 -20)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // errors of the workers are raised by par-map-isolated, and
+  // values which can't be copied are not sent to them
+  env := repl.NewTopLevel(scope.NewRootScope())
+  _, err := repl.Run("par.ss", "(par-map-isolated (lambda (x) (/ 1 x)) '(1 0))", env)
+  if err == nil || err.Error() != "`/' division by zero" {
+    t.Error("expected a division by zero, raised: ", err)
+  }
+  _, err = repl.Run("par.ss", "(define c (make-chan)) (par-map-isolated (lambda (x) c) '(1))", env)
+  if err == nil || !strings.HasPrefix(err.Error(), "par-map-isolated: can't send to a worker: ") {
+    t.Error("expected a channel not to be sent to a worker, raised: ", err)
+  }
+
+  // workers evaluate the prelude of the host rather than stdlib.ss
+  root := scope.NewRootScope()
+  root.SetPrelude("prelude.ss", "(define (twice x) (* 2 x))")
+  repl.REPL("(define (twice x) (* 2 x))", root)
+  root.Freeze()
+  if result := repl.REPL("(par-map-isolated twice '(1 2))", repl.NewTopLevel(root)); result != "(2 4)" {
+    t.Error("expected workers to evaluate the prelude, evaluated: ", result)
+  }
+}
+
+func TestNumericTower(t *testing.T) {
+  result := testFile("numeric_tower_test.ss", t)
+  expected := "1267650600228229401496703205376\n1/3\n1\n9999999999999999999800000000000000000001"
+  expected += "\n9223372036854775808\n9223372036854775807\n15511210043330985984000000"
+  expected += "\n0.3333333333333333\n1/2\n(-3 -1 1 -1.0)\n(4 288 0 1)\n(1/4 8.0 1/8 1)"
+  expected += "\n(#t #t #f)\n(#t #t #t #t #f)\n(rational integer)\n(3 2 2.0)\n(#t #t #t)"
+  expected += "\n(-3/2 18446744073709551616)\n(+inf.0 -inf.0 -inf.0 +nan.0 +inf.0 +inf.0)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(/ 1/2 0)":               "`/' division by zero",
+    "(/ 1 0)":                 "`/' division by zero",
+    "(modulo 5 0)":            "modulo: undefined for 0",
+    "(quotient 1/2 1)":        "quotient: expected integer, given: 1/2",
+    "(inexact->exact +inf.0)": "inexact->exact: no exact number for +inf.0",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestPhases(t *testing.T) {
+  program := `(define-syntax swap!
+  (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))
+(define x 1)
+(define y 2)
+(swap! x y)
+(cons x y)`
+  // the forms as written, then expanded, then evaluated
+  forms, err := repl.Read("phases.ss", program)
+  if err != nil || len(forms) != 5 || forms[3].String() != "(swap! x y)" {
+    t.Fatal("expected 5 forms read, the 4th unexpanded, read: ", forms, err)
+  }
+  if _, ok := forms[3].(*ast.Tuple); !ok {
+    t.Error("expected a form read to be a tuple, read: ", forms[3])
+  }
+  nodes, err := repl.Expand("phases.ss", forms)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, ok := nodes[3].(*ast.Let); !ok {
+    t.Error("expected the macro call to expand to a let, expanded: ", nodes[3])
+  }
+  env := repl.NewTopLevel(scope.NewRootScope())
+  if result := repl.Print(repl.Eval(nodes, env)); result != "(2 . 1)" {
+    t.Error("expected: (2 . 1) evaluated: ", result)
+  }
+
+  // reading checks the lexical syntax only, expanding the forms
+  forms, err = repl.Read("bad.ss", "(if)")
+  if err != nil {
+    t.Error("expected (if) to be read, raised: ", err)
+  }
+  if _, err = repl.Expand("bad.ss", forms); fmt.Sprint(err) != "bad.ss:1:1: incorrect format of if: (if)" {
+    t.Error("expected a syntax error expanding (if), raised: ", err)
+  }
+  if _, err = repl.Read("bad.ss", "(car '(1 2)"); err == nil {
+    t.Error("expected an error reading an unclosed list")
+  }
+}
+
+func TestRemoteLoad(t *testing.T) {
+  files := map[string]string{
+    "/lib/main.ss":  "(load \"util.ss\") (define (twice x) (double (double x)))",
+    "/lib/util.ss":  "(define (double x) (* 2 x))",
+    "/lib/large.ss": strings.Repeat(" ", repl.MaxRemoteSize+1),
+  }
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    content, ok := files[r.URL.Path]
+    if !ok {
+      http.NotFound(w, r)
+      return
+    }
+    io.WriteString(w, content)
+  }))
+  defer server.Close()
+
+  env := repl.NewTopLevel(scope.NewRootScope())
+  load := func(name string) string {
+    _, err := repl.Run("<REPL>", fmt.Sprintf("(load %q)", server.URL+name), env)
+    return fmt.Sprint(err)
+  }
+  result := load("/lib/main.ss")
+  env.SetRemoteEnabled(true)
+  result += "\n" + load("/lib/main.ss")
+  result += "\n" + repl.REPL("(twice 3)", env)
+  result += "\n" + load("/lib/large.ss")
+  result += "\n" + load("/lib/missing.ss")
+  result = strings.Replace(result, server.URL, "URL", -1)
+
+  expected := "load: URL/lib/main.ss: reading programs from URLs is disabled, see -allow-urls"
+  expected += "\n<nil>\n12"
+  expected += fmt.Sprintf("\nload: URL/lib/large.ss: larger than %d bytes", repl.MaxRemoteSize)
+  expected += "\nload: URL/lib/missing.ss: 404 Not Found"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestDynamicWind(t *testing.T) {
+  result := testFile("dynamic_wind_test.ss", t)
+  expected := "result\n(after during before)\n3\n(done cleanup after during before)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // after is called when an error unwinds out of the thunk
+  env := repl.NewTopLevel(scope.NewRootScope())
+  repl.REPL("(define left '()) (define c (make-chan))", env)
+  messages := map[string]string{
+    "(dynamic-wind (lambda () 1) (lambda () (car 1)) (lambda () (set! left (cons 'error left))))":       "car: expected pair, given: 1",
+    "(dynamic-wind (lambda () 1) (lambda () (<-chan c)) (lambda () (set! left (cons 'deadlock left))))": "deadlock, every goroutine is blocked:\n  <-chan at <REPL>:1:40",
+  }
+  for exprs, expected := range messages {
+    if message := fmt.Sprint(testError(exprs, env)); message != expected {
+      t.Errorf("%s: expected %q, raised %q", exprs, expected, message)
+    }
+  }
+  if twice := repl.REPL("(and (pair? (cdr left)) (null? (cdr (cdr left))))", env); twice != "#t" {
+    t.Error("expected after to be called twice, called: ", repl.REPL("left", env))
+  }
+}
+
+func TestConditions(t *testing.T) {
+  result := testFile("condition_test.ss", t)
+  expected := "(caught . oops)\n\"car: expected pair, given: 1\"\n(withdraw \"insufficient funds:\" (10))"
+  expected += "\nother\n42\n43\n0\n(handled unwound)\n\"handler returned from a non-continuable exception:\""
+  expected += "\n(outer (inner x))\n\"handler returned from a non-continuable exception:\"\n\"car: expected pair, given: 1\""
+  expected += "\n(around x)\ninner\ncondition"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := repl.NewTopLevel(scope.NewRootScope())
+  messages := map[string]string{
+    "(raise 'boom)":                     "<REPL>:1:1: uncaught exception: boom",
+    "(error 'f \"bad value:\" 1 \"a\")": "<REPL>:1:1: f: bad value: 1 \"a\"",
+    "(with-exception-handler (lambda (e) (raise e)) (lambda () (car 1)))":         "car: expected pair, given: 1",
+    "(with-exception-handler (lambda (e) 'ignored) (lambda () (+ 1 (raise 'x))))": "raise: handler returned from a non-continuable exception: x",
+  }
+  for exprs, expected := range messages {
+    _, err := repl.Run("<REPL>", exprs, env)
+    if message := repl.FormatError(err); message != expected {
+      t.Errorf("%s: expected %q, raised %q", exprs, expected, message)
+    }
+  }
+}
+
+func TestUsageHook(t *testing.T) {
+  interp, err := lispex.NewInterp()
+  if err != nil {
+    t.Fatal(err)
+  }
+  var forms, builtins map[string]int64
+  interp.SetUsageHook(func(f, b map[string]int64) { forms, builtins = f, b })
+
+  interp.Eval(`(define (sign x) (cond ((< x 0) 'negative) ((> x 0) 'positive) (else 'zero)))
+               (list (sign -1) (sign 2) '(if quoted))`)
+  expected := "map[cond:1 define:1 quote:4]"
+  if fmt.Sprint(forms) != expected {
+    t.Error("expected forms: ", expected, " counted: ", forms)
+  }
+  if builtins["<"] != 2 || builtins[">"] != 1 {
+    t.Error("expected < called twice and > once, counted: ", builtins)
+  }
+
+  // the counts are those of each evaluation
+  interp.Eval("(+ 1 2)")
+  if len(forms) != 0 || builtins["+"] != 1 || builtins["<"] != 0 {
+    t.Error("expected only + counted, given: ", forms, builtins)
+  }
+  interp.SetUsageHook(nil)
+  forms = nil
+  interp.Eval("(if #t 1 2)")
+  if forms != nil {
+    t.Error("expected no report without a hook, given: ", forms)
+  }
+}
+
+func TestWrite(t *testing.T) {
+  var result string
+  output := captureOutput(func() { result = testFile("write_test.ss", t) }, t)
+  expected := "\"tab\\x9;here, \\\"quoted\\\" \\\\ bell\\x7;\""
+  expected += "\n(#\\alarm #\\backspace #\\delete #\\escape #\\null #\\return #\\tab #\\λ)"
+  expected += "\n\"a\\x0;b\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+  expected = "plain \"text\"\n(a b c)\n(\"a\" #\\b c \"line\\xa;\")\n"
+  expected += "#<unspecified>\n(1 #<unspecified>)\n#<unspecified>\n"
+  if expected != output {
+    t.Error("expected output: ", expected, " printed: ", output)
+  }
+}
+
+func TestVectors(t *testing.T) {
+  result := testFile("vector_test.ss", t)
+
+  expected := "#(1 \"a\" (b c) #\\d)\n#(x #t)\n(0 one 0)\none\n0\n(1 2 3)\n#(1 2)\n#(7 7 7)"
+  expected += "\n#t\n#f\n#t\n#(0)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(vector-ref #(1 2) 2)":  "vector-ref: index 2 out of bounds for a vector of length 2",
+    "(vector-set! #() -1 0)": "vector-set!: index -1 out of bounds for a vector of length 0",
+    "(make-vector -1)":       "make-vector: expected a length, given: -1",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestShadowing(t *testing.T) {
+  result := testFile("shadow_test.ss", t)
+  expected := "(1 2 3)\n5\n18\n3\n40\n(1 2)\nyes\nexpanded"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestSyntaxEnv(t *testing.T) {
+  env := scope.NewRootScope()
+  top := ast.NewTopSyntaxEnv(env)
+  local := ast.NewSyntaxEnv(ast.NewSyntaxEnv(top, []string{"if"}), []string{"x"})
+  denotations := []struct {
+    env      *ast.SyntaxEnv
+    name     string
+    expected parser.Denotation
+  }{
+    {nil, "if", parser.CoreForm},
+    {nil, "define", parser.CoreForm},
+    {top, "unless-zero", parser.Macro},
+    {top, "car", parser.Variable},
+    {local, "if", parser.Variable},
+    {local, "unless-zero", parser.Macro},
+    // macros are only known to the interpreter defining them
+    {nil, "unless-zero", parser.Variable},
+    {ast.NewTopSyntaxEnv(scope.NewRootScope()), "unless-zero", parser.Variable},
+  }
+  repl.REPL("(define-syntax unless-zero (syntax-rules () ((_ n e) (if (= n 0) #f e))))", env)
+  for _, d := range denotations {
+    if denotation := parser.Denote(d.env, d.name); denotation != d.expected {
+      t.Error("expected: ", d.expected, " for ", d.name, ", returned: ", denotation)
+    }
+  }
+}
+
+func TestRunTests(t *testing.T) {
+  exprs, err := ioutil.ReadFile("runner_test.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  lib, _ := ioutil.ReadFile("../stdlib/stdlib.ss")
+  root := scope.NewRootScope()
+  repl.REPL(string(lib), root)
+  results, err := repl.RunTests("runner_test.ss", string(exprs), repl.NewTopLevel(root), time.Second)
+  if err != nil {
+    t.Fatal(err)
+  }
+  expected := []string{
+    "square", "",
+    "wrong", "returned #f",
+    "loop", "timed out after 50ms",
+    "guarded loop", "timed out after 50ms",
+    "sleepy", "timed out after 20ms",
+    "error", "runner_test.ss:7:15: car: expected pair, given: ()",
+    "local", "",
+    "after", "",
+  }
+  if len(results) != len(expected)/2 {
+    t.Fatal("expected ", len(expected)/2, " results, returned: ", results)
+  }
+  for i, result := range results {
+    message := ""
+    if result.Err != nil {
+      message = repl.FormatError(result.Err)
+    }
+    if result.Name != expected[2*i] || message != expected[2*i+1] {
+      t.Error("expected: ", expected[2*i], ": ", expected[2*i+1], " returned: ", result)
+    }
+  }
+
+  // evaluation is only interrupted while tests run
+  env := scope.NewRootScope()
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  if _, err := repl.RunContext(ctx, "test", "((lambda (x) x) 1)", env); err != nil {
+    t.Error("expected no interruption, raised: ", err)
+  }
+  if _, err := repl.RunTests("test", "(define x", env, time.Second); err == nil {
+    t.Error("expected a syntax error")
+  }
+}
+
+func TestPorts(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "saved.ss")
+  result := testFileWithPrelude(fmt.Sprintf("(define path %q)", path), "port_test.ss", t)
+
+  expected := "\"\\\"a\\\\\\\"b\\\" and c\\xa;(1 #\\\\x \\\"y\\\")\""
+  expected += "\n(define x '(1 2))\n#(3 \"4\" #\\()\nsym\n#\\space\n#\\5\n#\\5\n\"\"\n\"rest of line\"\n#<eof>\n#<eof>"
+  expected += "\n(#t #f #t)\n(saved \"data\" 1.5)\n\" tail\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := repl.NewTopLevel(scope.NewRootScope())
+  errors := map[string]string{
+    "(display 1 (open-input-string \"\"))":        "display: expected output port, given: #<port string>",
+    "(read-char (open-output-string))":            "read-char: expected input port, given: #<port string>",
+    "(read (open-input-string \"(1 2\"))":         "read: unexpected end of input in (1 2",
+    "(read (open-input-string \"(1 . 2 3)\"))":    "read: illegal use of `.'",
+    "(read (open-input-string \"(. 2)\"))":        "read: illegal use of `.'",
+    "(read (open-input-string \"(1 . )\"))":       "read: illegal use of `.'",
+    "(read (open-input-string \"[1 2]\"))":        "read:1:1: invalid character #\\[",
+    "(read (open-input-string \"(1 #\\\\xzz)\"))": "read:1:4: unknown character name: #\\xzz",
+    "(get-output-string (current-output-port))":   "get-output-string: expected a port of open-output-string, given: #<port stdout>",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestWriteRead(t *testing.T) {
+  result := testFile("write_read_test.ss", t)
+  expected := "#t\n|odd name|\n(|12| |a\\|b| |#t|)\n#t\n\"x y\"\n\"(x y z)\""
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestApplicableData(t *testing.T) {
+  env := scope.NewRootScope()
+  if err := testError("(define w (vector 1 2)) (w 0)", env); fmt.Sprint(err) != "#(1 2): not allowed in a call context, in: (w 0)" {
+    t.Error("expected vectors not to be callable by default, raised: ", err)
+  }
+
+  root := scope.NewRootScope()
+  root.SetApplicableData(true)
+  result := testFileIn(root, "", "applicable_test.ss", t)
+  expected := "b\n#\\é\n40\n#\\c"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  errors := map[string]string{
+    "(#(1 2) 2)":  "vector-ref: index 2 out of bounds for a vector of length 2",
+    "(\"ab\" 'x)": "string-ref: expected integer, given: x",
+    "(1 0)":       "<REPL>:1:1: (1 0): not a procedure",
+  }
+  env.SetApplicableData(true)
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestThreading(t *testing.T) {
+  result := testFile("threading_test.ss", t)
+  expected := "30\n20\n4\n1\n(#t #f #t #t #f #t)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+}
+
+func TestConditionals(t *testing.T) {
+  result := testFile("conditionals_test.ss", t)
+  expected := "#f\n2\n4\n(#t #f 2 #f)\n(negative zero one many #t)"
+  expected += "\n(small letter (#\\x char) (\"s\" other))\ndone\nran\n(unless when)"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(cond)":                       "<REPL>:1:1: cond: bad syntax (missing clauses), expected at least 1",
+    "(cond (else 1) (#t 2))":       "<REPL>:1:1: cond: bad clause (else 1), else must be the last clause",
+    "(cond (1 => car cdr))":        "<REPL>:1:1: cond: bad clause (1 => car cdr), expected exactly 1 receiver after =>",
+    "(case 1 (1 'one))":            "<REPL>:1:1: case: bad clause (1 (quote one)), expected a list of data or else",
+    "(when #t)":                    "<REPL>:1:1: when: bad syntax, expected a test and at least 1 expression, given: (when #t)",
+    "(define (f) (cond (#t 1) 2))": "<REPL>:1:13: cond: bad clause 2, expected a non-empty list",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestIteration(t *testing.T) {
+  result := testFile("iteration_test.ss", t)
+  expected := "(1 2 3 4)\n8\n(3 2 1 0)\n(0 1 4)\ndone\n(2 1 0)\nkept"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(do ((i 0)))":       "<REPL>:1:1: do: bad syntax, expected bindings and a test, given: (do ((i 0)))",
+    "(do ((i)) (#t))":    "<REPL>:1:1: do: bad syntax, expected (variable init [step]), given: (i)",
+    "(do ((i 0)) () i)":  "<REPL>:1:1: do: bad syntax, expected (test expression ...), given: ()",
+    "(let loop ((i)) i)": "<REPL>:1:1: let: bad syntax, not an identifer and expression for a binding (i)",
+    "(let loop ((i 0)))": "<REPL>:1:1: let: bad syntax, no expression in body",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestForwardReferences(t *testing.T) {
+  result := testFile("forward_test.ss", t)
+  expected := "#t\n#t\n#t\n9\nvalue"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  // declarations of procedures never defined don't outlive the file
+  env := scope.NewRootScope()
+  testError("(car '()) (define (g) 1)", env)
+  if err := testError("(g)", env); err == nil {
+    t.Error("expected g to be unbound, evaluated: ", env.Lookup("g"))
+  }
+}
+
+func TestDefineEnum(t *testing.T) {
+  result := testFile("enum_test.ss", t)
+  expected := "#<color red>\n#t\ncolor\n#f\n#t\n#f\nblue\n#t\n#f\n#t\n#f\n(running stopped stopped)\n(resting busy resting none)\n(#t #f #t)\nno"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(define-enum color)":                          "<REPL>:1:1: define-enum: bad syntax, expected a name and members, given: (define-enum color)",
+    "(define-enum (color) red)":                    "<REPL>:1:1: define-enum: bad syntax, expected a name, given: (color)",
+    "(define-enum color red 1)":                    "<REPL>:1:1: define-enum: bad syntax, expected a member name, given: 1",
+    "(define-enum color red green red)":            "<REPL>:1:1: define-enum: duplicate member: red",
+    "(define-enum color red) (color->symbol 'red)": "color->symbol: expected color, given: red",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestBitvector(t *testing.T) {
+  result := testFile("bitvector_test.ss", t)
+  expected := "#*0101000000\n#t\n#f\n2\n10\n#t\n#f"
+  expected += "\n#*0101000000\n#*0101010101\n#*0000010101\n#*1010101010\n70\n#t\n168"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(make-bitvector -1)":                                  "make-bitvector: expected a length, given: -1",
+    "(bitvector-ref (make-bitvector 3) 3)":                 "bitvector-ref: index 3 out of bounds for a bitvector of length 3",
+    "(bitvector-set! (make-bitvector 3) 0 1)":              "bitvector-set!: expected bool, given: 1",
+    "(bitvector-or (make-bitvector 3) (make-bitvector 4))": "bitvector-or: expected bitvectors of length 3, given one of length 4",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestChanClose(t *testing.T) {
+  result := testFile("chan_close_test.ss", t)
+  expected := "1\n(2 #t)\n#t\n(#t #f)\n#t\n#f\n(a b)\n#t\nclosed"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(define c (make-chan 1)) (close-chan c) (chan-close c)":                    "chan-close: channel already closed",
+    "(define c (make-chan 1)) (close-chan c) (chan<- c 1)":                      "chan<- at <REPL>:1:41: send on closed channel",
+    "(define c (make-chan 1)) (close-chan c) (chan-try-send c 1)":               "chan-try-send: send on closed channel",
+    "(define c (make-chan 1)) (close-chan c) (select ((chan<- c 1)) (default))": "select at <REPL>:1:41: send on closed channel",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestValues(t *testing.T) {
+  result := testFile("values_test.ss", t)
+  expected := "(3 2)\nnone\n14\n6\nvalues\n(3 2)\n1\n2\n1"
+  expected += "\n(3 2 1 (2 3) ())\nouter\n5\nempty\n2"
+
+  if expected != result {
+    t.Error("expected: ", expected, " evaluated: ", result)
+  }
+
+  env := scope.NewRootScope()
+  errors := map[string]string{
+    "(let-values (((a b) (values 1 2 3))) a)": "let-values: 3 values given for the formals (a b)",
+    "(let*-values (((a b . c) 1)) a)":         "let*-values: 1 values given for the formals (a b . c)",
+    "(let-values ((a)) a)":                    "<REPL>:1:1: let-values: bad syntax, expected (formals init), given: (a)",
+    "(let-values ((1 2)) a)":                  "<REPL>:1:1: let-values: bad syntax, expected formals, given: 1",
+    "(let-values a a)":                        "<REPL>:1:1: let-values: bad syntax, expected bindings, given: a",
+    "(let-values ())":                         "<REPL>:1:1: let-values: bad syntax, no expression in body",
+  }
+  for exprs, expected := range errors {
+    if err := testError(exprs, env); fmt.Sprint(err) != expected {
+      t.Error("expected: ", expected, " raised: ", err)
+    }
+  }
+}
+
+func TestDoc(t *testing.T) {
+  source := "(define zero 0)\n\n;; the first\n;; of a pair\n(define (first p) (car p))\n(define (twice f x) \"applies f two times\" (f (f x)))\n(define ((adder n) x) (+ n x))"
+  stdlib, err := doc.Stdlib(source)
+  if err != nil {
+    t.Fatal(err)
+  }
+  expected := []string{"zero", "(first p): the first of a pair", "(twice f x): applies f two times", "((adder n) x)"}
+  var entries []string
+  for _, entry := range stdlib {
+    if entry.Doc == "" {
+      entries = append(entries, entry.Signature)
+    } else {
+      entries = append(entries, entry.Signature+": "+entry.Doc)
+    }
+  }
+  if strings.Join(entries, "\n") != strings.Join(expected, "\n") {
+    t.Error("expected: ", expected, " documented: ", entries)
+  }
+
+  builtins := doc.Builtins()
+  markdown := doc.Markdown(builtins, stdlib)
+  for _, expected := range []string{"## Builtins\n\n- `(+ number ...)`: sum of the numbers\n", "## Standard library\n\n- `zero`\n- `(first p)`: the first of a pair\n"} {
+    if !strings.Contains(markdown, expected) {
+      t.Error("expected markdown to contain: ", expected)
+    }
+  }
+  if page := doc.HTML(builtins, stdlib); !strings.Contains(page, "<dt id=\"&lt;\"><code>(&lt; number number number ...)</code></dt>") {
+    t.Error("expected escaped signatures in the page")
+  }
+  if entry := doc.Lookup(builtins, "make-chan"); entry == nil || entry.String() != "(make-chan [integer])\n  builtin, new channel with an optional buffer size\n" {
+    t.Error("expected the entry of make-chan, found: ", entry)
+  }
+
+  lib, err := ioutil.ReadFile("../stdlib/stdlib.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  if stdlib, err := doc.Stdlib(string(lib)); err != nil || doc.Lookup(stdlib, "list-tail") == nil {
+    t.Error("expected the standard library documented, error: ", err)
+  }
+}
+
+// each program of examples/ prints what its .out file holds. the
+// first argument of a program is the URL of examples/site
+func TestExamples(t *testing.T) {
+  lib, err := ioutil.ReadFile("../stdlib/stdlib.ss")
+  if err != nil {
+    t.Fatal(err)
+  }
+  site := httptest.NewServer(http.FileServer(http.Dir("../examples/site")))
+  defer site.Close()
+
+  files, err := filepath.Glob("../examples/*.ss")
+  if err != nil || len(files) == 0 {
+    t.Fatal("no examples: ", err)
+  }
+  for _, filename := range files {
+    exprs, err := ioutil.ReadFile(filename)
+    if err != nil {
+      t.Fatal(err)
+    }
+    expected, err := ioutil.ReadFile(strings.TrimSuffix(filename, ".ss") + ".out")
+    if err != nil {
+      t.Fatal(err)
+    }
+    root := scope.NewRootScope()
+    args := []string{filename, site.URL}
+    root.Put("command-line", primitives.LookupBuiltin("command-line").With(primitives.NewCommandLine(args)))
+    repl.REPL(string(lib), root)
+    root.Freeze()
+    env := repl.NewTopLevel(root)
+    var runErr error
+    result := captureOutput(func() { _, runErr = repl.Run(filename, string(exprs), env) }, t)
+    if runErr != nil {
+      t.Error(filename, ": ", runErr)
+    } else if string(expected) != result {
+      t.Error(filename, ": expected: ", string(expected), " evaluated: ", result)
+    }
+  }
+}
@@ -0,0 +1,528 @@
+package typecheck
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/ast"
+  "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/value/primitives"
+  "strings"
+)
+
+// Check infers simple types across the top-level definitions of a program
+// and reports where they contradict the annotations (: name type).
+// unannotated names and anything the checker can't infer get the type
+// `any', which is compatible with everything, so unannotated code passes.
+//
+// types are written as
+//  any number integer float string bool symbol list pair procedure channel
+//  (-> <param> ... <result>), with `<type> ...' as last parameter for rest args
+func Check(nodes []ast.Node) []string {
+  checker := &checker{env: make(map[string]Type)}
+  // annotations apply to the whole program wherever they appear
+  for _, node := range nodes {
+    if annotation, ok := node.(*ast.Annotation); ok {
+      checker.annotate(annotation)
+    }
+  }
+  for _, node := range nodes {
+    checker.infer(node, checker.env)
+  }
+  return checker.errors
+}
+
+type Type interface {
+  String() string
+}
+
+type Basic string
+
+const (
+  Any       Basic = "any"
+  Number    Basic = "number"
+  Integer   Basic = "integer"
+  Float     Basic = "float"
+  String    Basic = "string"
+  Bool      Basic = "bool"
+  Symbol    Basic = "symbol"
+  List      Basic = "list"
+  Pair      Basic = "pair"
+  Procedure Basic = "procedure"
+  Channel   Basic = "channel"
+)
+
+func (self Basic) String() string {
+  return string(self)
+}
+
+type Func struct {
+  Params []Type
+  Rest   Type
+  Result Type
+}
+
+func (self *Func) String() string {
+  s := "(->"
+  for _, param := range self.Params {
+    s += " " + param.String()
+  }
+  if self.Rest != nil {
+    s += " " + self.Rest.String() + " ..."
+  }
+  return s + " " + self.Result.String() + ")"
+}
+
+// whether a value of type from can be used where type to is expected
+func Assignable(from, to Type) bool {
+  if from == Any || to == Any || from == to {
+    return true
+  }
+  switch to {
+  case Number:
+    return from == Integer || from == Float
+  case List:
+    return from == Pair
+  case Pair:
+    return from == List
+  case Procedure:
+    _, ok := from.(*Func)
+    return ok
+  }
+  if f, ok := to.(*Func); ok {
+    if from == Procedure {
+      return true
+    }
+    if g, ok := from.(*Func); ok {
+      if len(g.Params) != len(f.Params) || (g.Rest == nil) != (f.Rest == nil) {
+        return false
+      }
+      for i := range g.Params {
+        if !Assignable(f.Params[i], g.Params[i]) {
+          return false
+        }
+      }
+      return Assignable(g.Result, f.Result)
+    }
+  }
+  return false
+}
+
+func ParseType(node ast.Node) (Type, error) {
+  switch node.(type) {
+  case *ast.Name:
+    name := strings.TrimSuffix(node.(*ast.Name).Identifier, "?")
+    switch name {
+    case "boolean":
+      return Bool, nil
+    case "real":
+      return Number, nil
+    case "any", "number", "integer", "float", "string", "bool", "symbol", "list", "pair", "procedure", "channel":
+      return Basic(name), nil
+    }
+  case *ast.Tuple:
+    elements := node.(*ast.Tuple).Elements
+    if len(elements) == 0 {
+      break
+    }
+    name, ok := elements[0].(*ast.Name)
+    if !ok || name.Identifier != "->" || len(elements) < 2 {
+      break
+    }
+    result, err := ParseType(elements[len(elements)-1])
+    if err != nil {
+      return nil, err
+    }
+    fn := &Func{Result: result}
+    params := elements[1 : len(elements)-1]
+    for i, param := range params {
+      if name, ok := param.(*ast.Name); ok && name.Identifier == "..." {
+        if i != len(params)-1 || i == 0 {
+          return nil, fmt.Errorf("bad rest parameter in %s", node)
+        }
+        fn.Rest = fn.Params[len(fn.Params)-1]
+        fn.Params = fn.Params[:len(fn.Params)-1]
+        break
+      }
+      typ, err := ParseType(param)
+      if err != nil {
+        return nil, err
+      }
+      fn.Params = append(fn.Params, typ)
+    }
+    return fn, nil
+  }
+  return nil, fmt.Errorf("unknown type %s", node)
+}
+
+type checker struct {
+  env    map[string]Type
+  errors []string
+}
+
+func (self *checker) report(format string, args ...interface{}) {
+  self.errors = append(self.errors, fmt.Sprintf(format, args...))
+}
+
+func (self *checker) annotate(annotation *ast.Annotation) {
+  typ, err := ParseType(annotation.Type)
+  if err != nil {
+    self.report("%s: %s", annotation.Pattern, err)
+    return
+  }
+  self.env[annotation.Pattern.Identifier] = typ
+}
+
+func extend(env map[string]Type) map[string]Type {
+  extended := make(map[string]Type, len(env))
+  for name, typ := range env {
+    extended[name] = typ
+  }
+  return extended
+}
+
+func (self *checker) lookup(name string, env map[string]Type) Type {
+  if typ, ok := env[name]; ok {
+    return typ
+  }
+  if builtin := primitives.LookupBuiltin(name); builtin != nil {
+    return builtinType(builtin)
+  }
+  return Any
+}
+
+func (self *checker) infer(node ast.Node, env map[string]Type) Type {
+  switch node.(type) {
+  case *ast.Int:
+    return Integer
+  case *ast.Float:
+    return Float
+  case *ast.Rational:
+    return rationalType(node.(*ast.Rational))
+  case *ast.String:
+    return String
+  case *ast.Quote:
+    return datumType(node.(*ast.Quote).Body)
+  case *ast.Name:
+    return self.lookup(node.(*ast.Name).Identifier, env)
+  case *ast.Define:
+    define := node.(*ast.Define)
+    name := define.Pattern.Identifier
+    declared, annotated := env[name]
+    var lambda *ast.Lambda
+    switch define.Value.(type) {
+    case *ast.Function:
+      lambda, _ = define.Value.(*ast.Function).Body.(*ast.Lambda)
+    case *ast.Lambda:
+      lambda = define.Value.(*ast.Lambda)
+    }
+    var actual Type
+    if fn, ok := declared.(*Func); ok && lambda != nil {
+      actual = self.checkLambda(name, lambda, fn, env)
+    } else {
+      actual = self.infer(define.Value, env)
+    }
+    if annotated {
+      if !Assignable(actual, declared) {
+        self.report("%s: declared %s, defined as %s", name, declared, actual)
+      }
+    } else {
+      env[name] = actual
+    }
+    return Any
+  case *ast.DefineContract:
+    return self.infer(node.(*ast.DefineContract).Define, env)
+  case *ast.Set:
+    set := node.(*ast.Set)
+    actual := self.infer(set.Value, env)
+    if declared, ok := env[set.Pattern.Identifier]; ok && !Assignable(actual, declared) {
+      self.report("%s: declared %s, assigned %s in %s", set.Pattern, declared, actual, set)
+    }
+    return Any
+  case *ast.Function:
+    return self.infer(node.(*ast.Function).Body, env)
+  case *ast.Lambda:
+    return self.inferLambda(node.(*ast.Lambda), nil, env)
+  case *ast.If:
+    branch := node.(*ast.If)
+    self.infer(branch.Test, env)
+    then := self.infer(branch.Then, env)
+    if branch.Else == nil {
+      return Any
+    }
+    return join(then, self.infer(branch.Else, env))
+  case *ast.Cond, *ast.Case, *ast.When, *ast.Logic:
+    for _, child := range ast.Children(node) {
+      self.infer(child, env)
+    }
+    return Any
+  case *ast.Do:
+    loop := node.(*ast.Do)
+    extended := extend(env)
+    // the steps may bind the variables to values of other types
+    for i, name := range loop.Vars {
+      self.infer(loop.Inits[i], env)
+      extended[name.Identifier] = Any
+    }
+    for _, child := range append(append([]ast.Node{}, loop.Steps...), loop.Test, loop.Result, loop.Body) {
+      if child != nil {
+        self.infer(child, extended)
+      }
+    }
+    return Any
+  case *ast.Begin:
+    return self.infer(node.(*ast.Begin).Body, env)
+  case *ast.Block:
+    var typ Type = Any
+    for _, expr := range node.(*ast.Block).Exprs {
+      typ = self.infer(expr, env)
+    }
+    return typ
+  case *ast.Let:
+    let := node.(*ast.Let)
+    return self.inferLet(let.Patterns, let.Exprs, let.Body, env, false)
+  case *ast.LetStar:
+    let := node.(*ast.LetStar)
+    return self.inferLet(let.Patterns, let.Exprs, let.Body, env, true)
+  case *ast.LetRec:
+    let := node.(*ast.LetRec)
+    return self.inferLet(let.Patterns, let.Exprs, let.Body, env, true)
+  case *ast.LetValues:
+    let := node.(*ast.LetValues)
+    extended := extend(env)
+    for i, formals := range let.Formals {
+      if let.Sequential {
+        self.infer(let.Exprs[i], extended)
+      } else {
+        self.infer(let.Exprs[i], env)
+      }
+      for _, name := range ast.FormalNames(formals) {
+        extended[name.Identifier] = Any
+      }
+    }
+    return self.infer(let.Body, extended)
+  case *ast.Call:
+    return self.inferCall(node.(*ast.Call), env)
+  case *ast.Go:
+    self.infer(node.(*ast.Go).Expr, env)
+  case *ast.Nursery:
+    return self.infer(node.(*ast.Nursery).Body, extend(env))
+  case *ast.Delay:
+    self.infer(node.(*ast.Delay).Expr, env)
+  }
+  return Any
+}
+
+func (self *checker) inferLet(patterns []*ast.Name, exprs []ast.Node, body ast.Node, env map[string]Type, sequential bool) Type {
+  extended := extend(env)
+  for i, pattern := range patterns {
+    if sequential {
+      extended[pattern.Identifier] = self.infer(exprs[i], extended)
+    } else {
+      extended[pattern.Identifier] = self.infer(exprs[i], env)
+    }
+  }
+  return self.infer(body, extended)
+}
+
+// an annotated procedure, its body is checked against the declaration
+func (self *checker) checkLambda(name string, lambda *ast.Lambda, declared *Func, env map[string]Type) Type {
+  actual := self.inferLambda(lambda, declared, env).(*Func)
+  if !Assignable(actual.Result, declared.Result) {
+    self.report("%s: declared to return %s, returns %s", name, declared.Result, actual.Result)
+  }
+  return declared
+}
+
+func (self *checker) inferLambda(lambda *ast.Lambda, declared *Func, env map[string]Type) Type {
+  extended := extend(env)
+  fn := &Func{}
+  params := lambda.Params
+  for i := 0; ; i++ {
+    var typ Type = Any
+    if pair, ok := params.(*ast.Pair); ok {
+      if declared != nil && i < len(declared.Params) {
+        typ = declared.Params[i]
+      } else if declared != nil && declared.Rest != nil {
+        typ = declared.Rest
+      }
+      if name, ok := pair.First.(*ast.Name); ok {
+        extended[name.Identifier] = typ
+      }
+      fn.Params = append(fn.Params, typ)
+      params = pair.Second
+      continue
+    }
+    if name, ok := params.(*ast.Name); ok {
+      extended[name.Identifier] = List
+      fn.Rest = Any
+      if declared != nil && declared.Rest != nil {
+        fn.Rest = declared.Rest
+      }
+    }
+    break
+  }
+  if declared != nil {
+    if len(fn.Params) != len(declared.Params) || (fn.Rest == nil) != (declared.Rest == nil) {
+      self.report("%s: declared %s, but takes %d parameters", lambda, declared, len(fn.Params))
+    }
+  }
+  fn.Result = self.infer(lambda.Body, extended)
+  return fn
+}
+
+func (self *checker) inferCall(call *ast.Call, env map[string]Type) Type {
+  args := make([]Type, len(call.Args))
+  for i, arg := range call.Args {
+    args[i] = self.infer(arg, env)
+  }
+  fn, ok := self.infer(call.Callee, env).(*Func)
+  if !ok {
+    return Any
+  }
+  if len(args) < len(fn.Params) || (fn.Rest == nil && len(args) > len(fn.Params)) {
+    self.report("%s: expected %d arguments, given %d", call, len(fn.Params), len(args))
+    return fn.Result
+  }
+  if max := self.maxArgs(call, env); max >= 0 && len(args) > max {
+    self.report("%s: expected at most %d arguments, given %d", call, max, len(args))
+    return fn.Result
+  }
+  for i, arg := range args {
+    expected := fn.Rest
+    if i < len(fn.Params) {
+      expected = fn.Params[i]
+    }
+    if !Assignable(arg, expected) {
+      self.report("%s: argument %d expected %s, given %s", call, i+1, expected, arg)
+    }
+  }
+  return arithmeticResult(call, args, fn.Result)
+}
+
+// the optional arguments of a builtin are checked like rest
+// arguments, the most it accepts is that of its registration spec.
+// -1 when unbounded or not calling a builtin
+func (self *checker) maxArgs(call *ast.Call, env map[string]Type) int {
+  name, ok := call.Callee.(*ast.Name)
+  if !ok {
+    return -1
+  }
+  if _, ok := env[name.Identifier]; ok {
+    return -1
+  }
+  if builtin := primitives.LookupBuiltin(name.Identifier); builtin != nil {
+    return builtin.Max
+  }
+  return -1
+}
+
+// the numeric builtins give integers for integer arguments
+func arithmeticResult(call *ast.Call, args []Type, result Type) Type {
+  if result != Number {
+    return result
+  }
+  name, ok := call.Callee.(*ast.Name)
+  if !ok {
+    return result
+  }
+  switch name.Identifier {
+  case "+", "-", "*", "%":
+  default:
+    return result
+  }
+  for _, arg := range args {
+    if arg != Integer {
+      return result
+    }
+  }
+  return Integer
+}
+
+func join(a, b Type) Type {
+  switch {
+  case a == b:
+    return a
+  case Assignable(a, Number) && Assignable(b, Number) && a != Any && b != Any:
+    return Number
+  }
+  return Any
+}
+
+// the type of a bignum or a fraction literal
+func rationalType(node *ast.Rational) Type {
+  if node.Value.IsInt() {
+    return Integer
+  }
+  return Number
+}
+
+func datumType(node ast.Node) Type {
+  switch node.(type) {
+  case *ast.Int:
+    return Integer
+  case *ast.Float:
+    return Float
+  case *ast.Rational:
+    return rationalType(node.(*ast.Rational))
+  case *ast.String:
+    return String
+  case *ast.Name:
+    if _, ok := node.(*ast.Name).Datum().(*value.BoolValue); ok {
+      return Bool
+    }
+    return Symbol
+  case *ast.EmptyPair:
+    return List
+  case *ast.Pair:
+    return Pair
+  }
+  return Any
+}
+
+var resultTypes = map[string]Type{
+  "+": Number, "-": Number, "*": Number, "/": Number, "%": Integer,
+  "=": Bool, "<": Bool, ">": Bool, "<=": Bool, ">=": Bool,
+  "eqv?": Bool, "equal?": Bool,
+  "cons": Pair, "make-chan": Channel, "type-of": Symbol,
+  "string-join": String, "string-trim": String, "string-trim-right": String, "string-trim-both": String,
+  "string-foldcase": String,
+}
+
+var argTypes = map[*primitives.ArgType]Type{
+  primitives.IntegerArg:   Integer,
+  primitives.NumberArg:    Number,
+  primitives.BoolArg:      Bool,
+  primitives.StringArg:    String,
+  primitives.SymbolArg:    Symbol,
+  primitives.PairArg:      Pair,
+  primitives.ListArg:      List,
+  primitives.ProcedureArg: Procedure,
+  primitives.ChannelArg:   Channel,
+}
+
+// signature of a builtin from its registration spec
+func builtinType(builtin *primitives.Builtin) Type {
+  convert := func(argType *primitives.ArgType) Type {
+    if typ, ok := argTypes[argType]; ok {
+      return typ
+    }
+    return Any
+  }
+  fn := &Func{Result: Any}
+  if typ, ok := resultTypes[builtin.Name]; ok {
+    fn.Result = typ
+  } else if strings.HasSuffix(builtin.Name, "?") {
+    fn.Result = Bool
+  }
+  for i := 0; i < builtin.Min; i++ {
+    if i < len(builtin.Args) {
+      fn.Params = append(fn.Params, convert(builtin.Args[i]))
+    } else {
+      fn.Params = append(fn.Params, convert(builtin.Args[len(builtin.Args)-1]))
+    }
+  }
+  if builtin.Max != builtin.Min {
+    // optional arguments are checked like rest arguments
+    fn.Rest = convert(builtin.Args[len(builtin.Args)-1])
+  }
+  return fn
+}
@@ -0,0 +1,24 @@
+package value
+
+import (
+  "fmt"
+)
+
+// apply a procedure to evaluated arguments,
+// lets primitives call back into closures
+func Invoke(proc Value, args []Value) Value {
+  switch proc.(type) {
+  case *Closure:
+    closure := proc.(*Closure)
+    if body, ok := closure.Body.(Callable); ok {
+      return body.Call(closure.Env, args)
+    }
+    panic(fmt.Sprint("unexpected type: ", closure.Body))
+  case *Contract:
+    return proc.(*Contract).Call(args, "")
+  case PrimFunc:
+    return proc.(PrimFunc).Apply(args)
+  default:
+    panic(fmt.Sprint("expected a procedure, given: ", proc))
+  }
+}
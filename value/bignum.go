@@ -0,0 +1,32 @@
+package value
+
+import (
+  "math/big"
+)
+
+// an exact integer too large for an IntValue. the number package
+// only makes them for integers which don't fit in an int64
+type BigIntValue struct {
+  Value *big.Int
+}
+
+func NewBigIntValue(val *big.Int) *BigIntValue {
+  return &BigIntValue{Value: val}
+}
+
+func (self *BigIntValue) String() string {
+  return self.Value.String()
+}
+
+// an exact fraction whose denominator isn't 1, e.g. 1/3
+type RatValue struct {
+  Value *big.Rat
+}
+
+func NewRatValue(val *big.Rat) *RatValue {
+  return &RatValue{Value: val}
+}
+
+func (self *RatValue) String() string {
+  return self.Value.RatString()
+}
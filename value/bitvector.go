@@ -0,0 +1,60 @@
+package value
+
+import (
+  "math/bits"
+  "strings"
+)
+
+// a fixed number of bits packed 64 to a word, for flags and
+// sieves where a vector of booleans would take a word per bit
+type Bitvector struct {
+  Words []uint64
+  Len   int
+}
+
+func NewBitvector(n int) *Bitvector {
+  return &Bitvector{Words: make([]uint64, (n+63)/64), Len: n}
+}
+
+func (self *Bitvector) Get(i int) bool {
+  return self.Words[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (self *Bitvector) Set(i int, on bool) {
+  if on {
+    self.Words[i/64] |= 1 << uint(i%64)
+  } else {
+    self.Words[i/64] &^= 1 << uint(i%64)
+  }
+}
+
+// number of bits set
+func (self *Bitvector) Count() int {
+  count := 0
+  for _, word := range self.Words {
+    count += bits.OnesCount64(word)
+  }
+  return count
+}
+
+// clears the bits past Len in the last word, so
+// that whole words can be counted and compared
+func (self *Bitvector) Trim() {
+  if rest := self.Len % 64; rest != 0 {
+    self.Words[len(self.Words)-1] &= 1<<uint(rest) - 1
+  }
+}
+
+// e.g. #*0110, bit 0 first
+func (self *Bitvector) String() string {
+  var b strings.Builder
+  b.WriteString("#*")
+  for i := 0; i < self.Len; i++ {
+    if self.Get(i) {
+      b.WriteByte('1')
+    } else {
+      b.WriteByte('0')
+    }
+  }
+  return b.String()
+}
@@ -4,8 +4,16 @@ type BoolValue struct {
   Value bool
 }
 
+var (
+  trueValue  = &BoolValue{Value: true}
+  falseValue = &BoolValue{Value: false}
+)
+
 func NewBoolValue(val bool) *BoolValue {
-  return &BoolValue{Value: val}
+  if val {
+    return trueValue
+  }
+  return falseValue
 }
 
 func (self *BoolValue) String() string {
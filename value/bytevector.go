@@ -0,0 +1,24 @@
+package value
+
+import (
+  "strconv"
+  "strings"
+)
+
+// a sequence of bytes, as in R7RS
+type Bytevector struct {
+  Value []byte
+}
+
+func NewBytevector(val []byte) *Bytevector {
+  return &Bytevector{Value: val}
+}
+
+// e.g. #u8(1 2 255)
+func (self *Bytevector) String() string {
+  items := make([]string, len(self.Value))
+  for i, b := range self.Value {
+    items[i] = strconv.Itoa(int(b))
+  }
+  return "#u8(" + strings.Join(items, " ") + ")"
+}
@@ -1,15 +1,60 @@
 package value
 
-import "fmt"
+import (
+  "fmt"
+  "runtime"
+  "sync"
+)
 
 type Channel struct {
   Value chan Value
+  // made by make-chan: only lisp code uses it, so a goroutine
+  // blocked on it may be deadlocked. channels fed by the host,
+  // like those of websockets, may always get a value later
+  Local  bool
+  mutex  sync.Mutex
+  closed bool
 }
 
 func NewChannel(size int) *Channel {
   return &Channel{Value: make(chan Value, size)}
 }
 
+// closes the channel unless it already is, returns whether it did
+func (self *Channel) Close() bool {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  if self.closed {
+    return false
+  }
+  self.closed = true
+  close(self.Value)
+  return true
+}
+
+// sends val for a host forwarder, returns false instead of panicking
+// when lisp closed the channel, before or while the send waits
+func (self *Channel) Forward(val Value) (sent bool) {
+  defer func() {
+    if err := recover(); err != nil {
+      sent = false
+    }
+  }()
+  self.Value <- val
+  return true
+}
+
 func (self *Channel) String() string {
   return fmt.Sprint(self.Value)
 }
+
+// deferred around sends, turns the runtime panic of a send on
+// a closed channel into an error naming site, e.g. "chan<-"
+func CheckSendOnClosed(site string) {
+  if err := recover(); err != nil {
+    if e, ok := err.(runtime.Error); ok && e.Error() == "send on closed channel" {
+      panic(fmt.Sprintf("%s: send on closed channel", site))
+    }
+    panic(err)
+  }
+}
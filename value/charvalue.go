@@ -0,0 +1,37 @@
+package value
+
+import (
+  "fmt"
+  "unicode"
+)
+
+type CharValue struct {
+  Value rune
+}
+
+func NewCharValue(val rune) *CharValue {
+  return &CharValue{Value: val}
+}
+
+var charNames = map[rune]string{
+  '\a': "alarm",
+  '\b': "backspace",
+  0x7f: "delete",
+  0x1b: "escape",
+  '\n': "newline",
+  0:    "null",
+  '\r': "return",
+  ' ':  "space",
+  '\t': "tab",
+}
+
+// written as read: #\a, #\space or #\x<hex>
+func (self *CharValue) String() string {
+  if name, ok := charNames[self.Value]; ok {
+    return "#\\" + name
+  }
+  if unicode.IsPrint(self.Value) {
+    return fmt.Sprintf("#\\%c", self.Value)
+  }
+  return fmt.Sprintf("#\\x%x", self.Value)
+}
@@ -12,3 +12,17 @@ func NewClosure(env, body interface{}) *Closure {
 func (self *Closure) String() string {
   return "#<procedure>"
 }
+
+// implemented by closure bodies to expose their signature,
+// doc string and where they are defined for introspection
+type Signature interface {
+  Arity() (int, bool)
+  Doc() string
+  Location() string
+}
+
+// implemented by closure bodies to run
+// the body in env with the arguments bound
+type Callable interface {
+  Call(env interface{}, args []Value) Value
+}
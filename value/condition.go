@@ -0,0 +1,93 @@
+package value
+
+import (
+  "errors"
+  "fmt"
+  "runtime"
+  "strings"
+)
+
+// the condition objects errors are caught as by guard and
+// with-exception-handler: those raised by `error', with the procedure
+// raising them, if given, a message and irritants, and the errors of
+// builtins, with their message. Err is the error a condition was made
+// from, raising the condition again keeps its position and backtrace.
+// Pos is the position of the call of `error'
+type Condition struct {
+  Who       string
+  Message   string
+  Irritants []Value
+  Err       error
+  Pos       string
+}
+
+func NewCondition(who, message string, irritants []Value) *Condition {
+  return &Condition{Who: who, Message: message, Irritants: irritants}
+}
+
+// e.g. vector-ref: index out of range 5 #(1 2)
+func (self *Condition) Error() string {
+  parts := []string{self.Message}
+  for _, irritant := range self.Irritants {
+    parts = append(parts, irritant.String())
+  }
+  text := strings.Join(parts, " ")
+  if self.Who != "" {
+    text = self.Who + ": " + text
+  }
+  return text
+}
+
+func (self *Condition) Unwrap() error {
+  return self.Err
+}
+
+func (self *Condition) Position() string {
+  return self.Pos
+}
+
+func (self *Condition) String() string {
+  return "#<condition " + self.Error() + ">"
+}
+
+// an object raised by `raise' which isn't a condition
+type Raised struct {
+  Payload Value
+  Pos     string
+}
+
+func (e *Raised) Error() string {
+  return "uncaught exception: " + e.Payload.String()
+}
+
+func (e *Raised) Position() string {
+  return e.Pos
+}
+
+// the object a handler is given for e, an error recovered from:
+// the object raised by `raise', or a condition. ok is false for the
+// failures no program can handle, bugs of the interpreter, the
+// memory limit being exceeded and interrupted evaluation
+func ConditionOf(e interface{}) (obj Value, ok bool) {
+  if _, ok := e.(runtime.Error); ok {
+    return nil, false
+  }
+  err, ok := e.(error)
+  if !ok {
+    return &Condition{Message: fmt.Sprint(e)}, true
+  }
+  var memory *OutOfMemory
+  var interrupted *Interrupted
+  if errors.As(err, &memory) || errors.As(err, &interrupted) {
+    return nil, false
+  }
+  var raised *Raised
+  if errors.As(err, &raised) {
+    return raised.Payload, true
+  }
+  var condition *Condition
+  if errors.As(err, &condition) {
+    return condition, true
+  }
+  return &Condition{Message: err.Error(), Err: err}, true
+}
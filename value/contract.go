@@ -0,0 +1,86 @@
+package value
+
+import (
+  "fmt"
+)
+
+// a procedure defined with define/contract, every call checks
+// the arguments against Domain and the result against Range.
+// Pos is where the procedure was defined, blamed when the
+// result breaks the contract. no call is checked while the
+// Checks of the interpreter it was defined in are off
+type Contract struct {
+  Name   string
+  Proc   Value
+  Domain []Value
+  Range  Value
+  // printed forms of the predicates, for error messages
+  DomainNames []string
+  RangeName   string
+  Pos         string
+  Checks      *Setting
+}
+
+func NewContract(name string, proc Value, domain []Value, rang Value, domainNames []string, rangeName, pos string, checks *Setting) *Contract {
+  return &Contract{
+    Name:        name,
+    Proc:        proc,
+    Domain:      domain,
+    Range:       rang,
+    DomainNames: domainNames,
+    RangeName:   rangeName,
+    Pos:         pos,
+    Checks:      checks,
+  }
+}
+
+func (self *Contract) String() string {
+  return "#<procedure>"
+}
+
+// apply the procedure, caller is the position of the call site
+// blamed for bad arguments, empty when unknown
+func (self *Contract) Call(args []Value, caller string) Value {
+  if !self.Checks.On() {
+    return applyAt(self.Proc, args, caller)
+  }
+  if len(args) != len(self.Domain) {
+    panic(fmt.Sprintf("%s: contract violation, expected %d arguments, given: %d, %s",
+      self.Name, len(self.Domain), len(args), blame("caller", caller)))
+  }
+  for i, arg := range args {
+    if !satisfies(self.Domain[i], arg) {
+      panic(fmt.Sprintf("%s: contract violation, argument %d expected: %s, given: %s, %s",
+        self.Name, i+1, self.DomainNames[i], arg, blame("caller", caller)))
+    }
+  }
+  result := applyAt(self.Proc, args, caller)
+  if !satisfies(self.Range, result) {
+    panic(fmt.Sprintf("%s: contract violation, result expected: %s, given: %s, %s",
+      self.Name, self.RangeName, result, blame(self.Name, self.Pos)))
+  }
+  return result
+}
+
+// nested contracts blame the same caller
+func applyAt(proc Value, args []Value, caller string) Value {
+  if contract, ok := proc.(*Contract); ok {
+    return contract.Call(args, caller)
+  }
+  return Invoke(proc, args)
+}
+
+// anything but #f satisfies a predicate
+func satisfies(predicate Value, val Value) bool {
+  if result, ok := Invoke(predicate, []Value{val}).(*BoolValue); ok {
+    return result.Value
+  }
+  return true
+}
+
+func blame(party, pos string) string {
+  if pos == "" {
+    return "blaming: " + party
+  }
+  return fmt.Sprintf("blaming: %s at %s", party, pos)
+}
@@ -0,0 +1,139 @@
+package value
+
+import (
+  "reflect"
+  "sort"
+  "sync"
+  "time"
+)
+
+// how long every goroutine must stay blocked to be deadlocked:
+// two goroutines about to meet on a channel may both be
+// registered as blocked for a moment
+const deadlockDelay = 50 * time.Millisecond
+
+// the channel operations of lisp code blocked on local channels, by
+// operation, and how many goroutines evaluate lisp code. epoch
+// changes whenever one blocks or resumes and whenever a goroutine
+// starts or stops evaluating, so that a deadlock is only reported
+// when nothing happened in between
+var deadlocks = struct {
+  sync.Mutex
+  blocked    map[int64]string
+  lastID     int64
+  epoch      int64
+  current    *deadlock
+  evaluators [3]int64
+}{blocked: make(map[int64]string), current: newDeadlock()}
+
+// wake is closed once the operations blocked when it was
+// created are found deadlocked, Blocked is set before
+type deadlock struct {
+  wake    chan struct{}
+  blocked []string
+}
+
+func newDeadlock() *deadlock {
+  return &deadlock{wake: make(chan struct{})}
+}
+
+// the kinds of goroutines evaluating lisp code
+type Evaluator int
+
+const (
+  // evaluating a program for the host, which may evaluate another
+  // later: goroutines blocked between programs are not deadlocked
+  ProgramEvaluator Evaluator = iota
+  // started by the interpreter, for `go' or a worker
+  SpawnedEvaluator
+  // a host goroutine calling back into lisp, which the host may still
+  // unblock: no deadlock is reported while one evaluates
+  CallbackEvaluator
+)
+
+// registers the calling goroutine as evaluating lisp code until the
+// function returned is called
+func Evaluating(kind Evaluator) func() {
+  deadlocks.Lock()
+  deadlocks.evaluators[kind]++
+  deadlocks.epoch++
+  deadlocks.Unlock()
+  return func() {
+    deadlocks.Lock()
+    deadlocks.evaluators[kind]--
+    deadlocks.epoch++
+    // the goroutines left may all be blocked
+    checkDeadlock()
+    deadlocks.Unlock()
+  }
+}
+
+// like reflect.Select for cases without a default, when the channels
+// are local and every goroutine evaluating lisp code is blocked like
+// this one, the operations panic with a *DeadlockError instead of
+// blocking forever. site describes the operation for the error
+func BlockingSelect(cases []reflect.SelectCase, local bool, site string) (int, reflect.Value, bool) {
+  if !local {
+    return reflect.Select(cases)
+  }
+  poll := append(cases[:len(cases):len(cases)], reflect.SelectCase{Dir: reflect.SelectDefault})
+  if chosen, recv, ok := reflect.Select(poll); chosen < len(cases) {
+    return chosen, recv, ok
+  }
+
+  deadlocks.Lock()
+  deadlocks.lastID++
+  id := deadlocks.lastID
+  deadlocks.blocked[id] = site
+  deadlocks.epoch++
+  current := deadlocks.current
+  checkDeadlock()
+  deadlocks.Unlock()
+  defer func() {
+    deadlocks.Lock()
+    delete(deadlocks.blocked, id)
+    deadlocks.epoch++
+    deadlocks.Unlock()
+  }()
+
+  wake := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(current.wake)}
+  chosen, recv, ok := reflect.Select(append(poll[:len(cases)], wake))
+  if chosen == len(cases) {
+    panic(&DeadlockError{current.blocked})
+  }
+  return chosen, recv, ok
+}
+
+// as many operations blocked as goroutines evaluating lisp code while
+// a program is, none of them called back by the host
+func deadlocked() bool {
+  evaluators := deadlocks.evaluators
+  if evaluators[ProgramEvaluator] == 0 || evaluators[CallbackEvaluator] > 0 {
+    return false
+  }
+  return int64(len(deadlocks.blocked)) >= evaluators[ProgramEvaluator]+evaluators[SpawnedEvaluator]
+}
+
+// called with deadlocks locked whenever an operation blocks or a
+// goroutine stops evaluating, wakes the blocked operations up if they still
+// are after deadlockDelay
+func checkDeadlock() {
+  if !deadlocked() {
+    return
+  }
+  epoch := deadlocks.epoch
+  time.AfterFunc(deadlockDelay, func() {
+    deadlocks.Lock()
+    defer deadlocks.Unlock()
+    if deadlocks.epoch != epoch || !deadlocked() {
+      return
+    }
+    current := deadlocks.current
+    for _, site := range deadlocks.blocked {
+      current.blocked = append(current.blocked, site)
+    }
+    sort.Strings(current.blocked)
+    deadlocks.current = newDeadlock()
+    close(current.wake)
+  })
+}
@@ -0,0 +1,78 @@
+package value
+
+import (
+  "bytes"
+  "context"
+  "runtime"
+  "strconv"
+  "sync"
+)
+
+// the dynamic context of the lisp code a goroutine evaluates: what
+// depends on the forms being evaluated rather than on where the code
+// is written, like the nursery a `go' form started within a called
+// procedure joins. goroutines started by `go' inherit the context of
+// the one starting them, but for its exception handlers
+type Dynamic struct {
+  // the goroutine group of the innermost nursery, nil outside any
+  Nursery interface{}
+  // the context of the I/O started meanwhile, nil for the one of
+  // the root scope
+  Context context.Context
+  // the exception handlers installed, innermost first
+  Handlers *Handler
+}
+
+// an exception handler installed by with-exception-handler, or by
+// guard when Proc is nil, and the handlers installed around it
+type Handler struct {
+  Proc  Value
+  Outer *Handler
+}
+
+// the dynamic contexts by goroutine, only those evaluating within
+// a form which set one are present
+var dynamics = struct {
+  sync.Mutex
+  contexts map[int64]Dynamic
+}{contexts: make(map[int64]Dynamic)}
+
+// the dynamic context of the calling goroutine
+func CurrentDynamic() Dynamic {
+  dynamics.Lock()
+  defer dynamics.Unlock()
+  return dynamics.contexts[goroutineID()]
+}
+
+// call body with dynamic as the context of the calling goroutine,
+// restoring the previous one once it returns or panics
+func WithDynamic(dynamic Dynamic, body func() Value) Value {
+  id := goroutineID()
+  dynamics.Lock()
+  previous, ok := dynamics.contexts[id]
+  dynamics.contexts[id] = dynamic
+  dynamics.Unlock()
+  defer func() {
+    dynamics.Lock()
+    if ok {
+      dynamics.contexts[id] = previous
+    } else {
+      delete(dynamics.contexts, id)
+    }
+    dynamics.Unlock()
+  }()
+  return body()
+}
+
+// go has no goroutine-local storage, the id is read from
+// the first line of the stack trace: "goroutine 18 [running]:"
+func goroutineID() int64 {
+  var buf [64]byte
+  line := buf[:runtime.Stack(buf[:], false)]
+  line = bytes.TrimPrefix(line, []byte("goroutine "))
+  if i := bytes.IndexByte(line, ' '); i >= 0 {
+    line = line[:i]
+  }
+  id, _ := strconv.ParseInt(string(line), 10, 64)
+  return id
+}
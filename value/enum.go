@@ -0,0 +1,41 @@
+package value
+
+import (
+  "fmt"
+)
+
+// the type of the values defined by (define-enum color red green blue),
+// each member is a distinct value of its own, only eq? to itself
+type EnumType struct {
+  Name    string
+  Members []*Enum
+}
+
+type Enum struct {
+  Type    *EnumType
+  Name    string
+  Ordinal int
+}
+
+func NewEnumType(name string, members []string) *EnumType {
+  et := &EnumType{Name: name, Members: make([]*Enum, len(members))}
+  for i, member := range members {
+    et.Members[i] = &Enum{Type: et, Name: member, Ordinal: i}
+  }
+  return et
+}
+
+// the member named name, nil when there is none
+func (self *EnumType) Member(name string) *Enum {
+  for _, member := range self.Members {
+    if member.Name == name {
+      return member
+    }
+  }
+  return nil
+}
+
+// e.g. #<color red>
+func (self *Enum) String() string {
+  return fmt.Sprintf("#<%s %s>", self.Type.Name, self.Name)
+}
@@ -0,0 +1,14 @@
+package value
+
+// first-class reference to a scope, as returned by (the-environment)
+type Environment struct {
+  Scope interface{}
+}
+
+func NewEnvironment(scope interface{}) *Environment {
+  return &Environment{Scope: scope}
+}
+
+func (self *Environment) String() string {
+  return "#<environment>"
+}
@@ -0,0 +1,159 @@
+package value
+
+import (
+  "fmt"
+  "strings"
+)
+
+// errors raised by evaluation, so that embedders can tell them apart
+// with errors.As. other failures raise an *Error or a plain message,
+// which repl.Run turns into an *Error. Pos is the source position of
+// the code raising the error when it is known, messages leave it out
+// so that programs catching errors see the same message anywhere
+
+// implemented by the errors knowing where they were raised
+type Positioned interface {
+  Position() string
+}
+
+// an error raised by a builtin, Err is the go error causing it if any
+type Error struct {
+  Message string
+  Err     error
+}
+
+func (e *Error) Error() string {
+  return e.Message
+}
+
+func (e *Error) Unwrap() error {
+  return e.Err
+}
+
+// a program which can't be read or parsed,
+// Err is the *lexer.Error or *parser.Error
+type SyntaxError struct {
+  Err error
+}
+
+func (e *SyntaxError) Error() string {
+  return e.Err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+  return e.Err
+}
+
+// a name without a binding in scope
+type UnboundVariable struct {
+  Name string
+  Pos  string
+}
+
+func (e *UnboundVariable) Error() string {
+  return fmt.Sprintf("%s: undefined identifier", e.Name)
+}
+
+func (e *UnboundVariable) Position() string {
+  return e.Pos
+}
+
+// an argument of the wrong type
+type TypeError struct {
+  Message string
+  Pos     string
+}
+
+func (e *TypeError) Error() string {
+  return e.Message
+}
+
+func (e *TypeError) Position() string {
+  return e.Pos
+}
+
+// a procedure applied to the wrong number of arguments
+type ArityError struct {
+  Message string
+  Pos     string
+}
+
+func (e *ArityError) Error() string {
+  return e.Message
+}
+
+func (e *ArityError) Position() string {
+  return e.Pos
+}
+
+// a call on the way to an error: the procedure called, "" when it
+// has no name, and the position of the call
+type Frame struct {
+  Procedure string
+  Pos       string
+}
+
+// an error raised inside calls of procedures, Trace has the calls
+// it went through, innermost first. the message is that of Err
+type Backtrace struct {
+  Err   error
+  Trace []Frame
+}
+
+func (e *Backtrace) Error() string {
+  return e.Err.Error()
+}
+
+func (e *Backtrace) Unwrap() error {
+  return e.Err
+}
+
+// add the call frame to the backtrace of the error raised, e,
+// errors which aren't Go errors yet become an *Error
+func AddFrame(e interface{}, frame Frame) *Backtrace {
+  switch e.(type) {
+  case *Backtrace:
+    err := e.(*Backtrace)
+    err.Trace = append(err.Trace, frame)
+    return err
+  case error:
+    return &Backtrace{Err: e.(error), Trace: []Frame{frame}}
+  default:
+    return &Backtrace{Err: &Error{Message: fmt.Sprint(e)}, Trace: []Frame{frame}}
+  }
+}
+
+// an interpreter holding more memory than its limit,
+// both approximate numbers of bytes
+type OutOfMemory struct {
+  Used  int64
+  Limit int64
+}
+
+func (e *OutOfMemory) Error() string {
+  return fmt.Sprintf("out of memory: about %d bytes in use, limit %d", e.Used, e.Limit)
+}
+
+// evaluation stopped because the context of the interpreter
+// is done, see scope.SetInterruptible
+type Interrupted struct {
+  Err error
+}
+
+func (e *Interrupted) Error() string {
+  return "interrupted: " + e.Err.Error()
+}
+
+func (e *Interrupted) Unwrap() error {
+  return e.Err
+}
+
+// channel operations which can never complete: every goroutine of the
+// program is blocked on one. Blocked describes them, e.g. "<-chan at a.ss:3"
+type DeadlockError struct {
+  Blocked []string
+}
+
+func (e *DeadlockError) Error() string {
+  return "deadlock, every goroutine is blocked:\n  " + strings.Join(e.Blocked, "\n  ")
+}
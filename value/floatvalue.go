@@ -1,6 +1,10 @@
 package value
 
-import "strconv"
+import (
+  "math"
+  "strconv"
+  "strings"
+)
 
 type FloatValue struct {
   Value float64
@@ -11,5 +15,37 @@ func NewFloatValue(val float64) *FloatValue {
 }
 
 func (self *FloatValue) String() string {
-  return strconv.FormatFloat(self.Value, 'f', -1, 64)
+  return FormatFloat(self.Value)
+}
+
+// the shortest text reading back as the same float. it always has a
+// point or an exponent, so that 2.0 is not read back as the integer 2,
+// and the special values are written +inf.0, -inf.0 and +nan.0
+func FormatFloat(f float64) string {
+  switch {
+  case math.IsInf(f, 1):
+    return "+inf.0"
+  case math.IsInf(f, -1):
+    return "-inf.0"
+  case math.IsNaN(f):
+    return "+nan.0"
+  }
+  s := strconv.FormatFloat(f, 'g', -1, 64)
+  if !strings.ContainsAny(s, ".e") {
+    s += ".0"
+  }
+  return s
+}
+
+// the float written by FormatFloat, or by a float literal
+func ParseFloat(text string) (float64, error) {
+  switch text {
+  case "+inf.0":
+    return math.Inf(1), nil
+  case "-inf.0":
+    return math.Inf(-1), nil
+  case "+nan.0", "-nan.0":
+    return math.NaN(), nil
+  }
+  return strconv.ParseFloat(text, 64)
 }
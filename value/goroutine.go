@@ -0,0 +1,151 @@
+package value
+
+import (
+  "sync"
+  "sync/atomic"
+)
+
+// goroutines started by `go' which haven't returned yet
+var goroutines int64
+
+// the source positions the goroutines still running were
+// started at, by goroutine. finished is signaled as they return
+var (
+  running      = make(map[int64]string)
+  lastID       int64
+  runningMutex sync.Mutex
+  finished     = sync.NewCond(&runningMutex)
+)
+
+// with a pool, at most its size of goroutines run at once and `go'
+// queues the bodies of the others, started in order as running ones
+// return, so a program spawning lots of them is slowed down instead
+// of running out of memory. `go' never waits: a goroutine of a full
+// pool starting another would wait for itself. nil when unbounded
+var (
+  pool      *goroutinePool
+  poolMutex sync.Mutex
+)
+
+type goroutinePool struct {
+  size   int
+  active int
+  queue  []func()
+}
+
+func GoroutineCount() int64 {
+  return atomic.LoadInt64(&goroutines)
+}
+
+// a size of 0 removes the bound, goroutines already running
+// or queued keep their slot in the previous pool
+func SetGoroutinePoolSize(size int) {
+  poolMutex.Lock()
+  defer poolMutex.Unlock()
+  if size > 0 {
+    pool = &goroutinePool{size: size}
+  } else {
+    pool = nil
+  }
+}
+
+func GoroutinePoolSize() int {
+  poolMutex.Lock()
+  defer poolMutex.Unlock()
+  if pool == nil {
+    return 0
+  }
+  return pool.size
+}
+
+// run body in a goroutine, or once a slot of the pool is free,
+// within the dynamic context of the calling goroutine. its errors
+// aren't given to the exception handlers of that one. pos is the
+// position of the `go' form starting it
+func Spawn(pos string, body func()) {
+  dynamic := CurrentDynamic()
+  dynamic.Handlers = nil
+  runningMutex.Lock()
+  lastID++
+  id := lastID
+  running[id] = pos
+  runningMutex.Unlock()
+  // registered before the goroutine starts, lest the one starting it
+  // blocks and is found deadlocked in between
+  evaluated := Evaluating(SpawnedEvaluator)
+  run := func() {
+    defer func() {
+      runningMutex.Lock()
+      delete(running, id)
+      finished.Broadcast()
+      runningMutex.Unlock()
+      atomic.AddInt64(&goroutines, -1)
+      evaluated()
+    }()
+    if dynamic == (Dynamic{}) {
+      body()
+      return
+    }
+    WithDynamic(dynamic, func() Value {
+      body()
+      return nil
+    })
+  }
+
+  poolMutex.Lock()
+  slots := pool
+  if slots == nil {
+    poolMutex.Unlock()
+    atomic.AddInt64(&goroutines, 1)
+    go run()
+    return
+  }
+  if slots.active == slots.size {
+    slots.queue = append(slots.queue, run)
+    poolMutex.Unlock()
+    return
+  }
+  slots.active++
+  poolMutex.Unlock()
+  atomic.AddInt64(&goroutines, 1)
+  go slots.drain(run)
+}
+
+// run body, then the bodies queued meanwhile until none is left
+func (self *goroutinePool) drain(body func()) {
+  for body != nil {
+    body()
+    poolMutex.Lock()
+    body = nil
+    if len(self.queue) > 0 {
+      body = self.queue[0]
+      self.queue = self.queue[1:]
+      atomic.AddInt64(&goroutines, 1)
+    } else {
+      self.active--
+    }
+    poolMutex.Unlock()
+  }
+}
+
+// how many of the goroutines still running were started
+// at each position, e.g. {"server.ss:12": 3}
+func RunningGoroutines() map[string]int {
+  runningMutex.Lock()
+  defer runningMutex.Unlock()
+  counts := make(map[string]int)
+  for _, pos := range running {
+    counts[pos]++
+  }
+  return counts
+}
+
+// block until every goroutine started by `go' has returned,
+// including those they start themselves
+func WaitGoroutines() {
+  runningMutex.Lock()
+  defer runningMutex.Unlock()
+  for len(running) > 0 {
+    finished.Wait()
+  }
+}
@@ -0,0 +1,113 @@
+package value
+
+import (
+  "fmt"
+  "sync"
+)
+
+type hashEntry struct {
+  Key   Value
+  Value Value
+}
+
+// a hash table keeps its keys in buckets by the text of their hash,
+// keys of a bucket are told apart by Equal. two keys which are Equal
+// must have the same Hash. goroutines may share a table, its
+// methods lock it
+type HashTable struct {
+  Equal   func(x, y Value) bool
+  Hash    func(key Value) string
+  buckets map[string][]*hashEntry
+  // the entries in the order they were added, for printing
+  entries []*hashEntry
+  mutex   sync.RWMutex
+}
+
+func NewHashTable(equal func(x, y Value) bool, hash func(key Value) string) *HashTable {
+  return &HashTable{Equal: equal, Hash: hash, buckets: make(map[string][]*hashEntry)}
+}
+
+func (self *HashTable) find(key Value) (string, *hashEntry) {
+  h := self.Hash(key)
+  for _, entry := range self.buckets[h] {
+    if self.Equal(key, entry.Key) {
+      return h, entry
+    }
+  }
+  return h, nil
+}
+
+// the value of key, ok is false when there is none
+func (self *HashTable) Get(key Value) (Value, bool) {
+  self.mutex.RLock()
+  defer self.mutex.RUnlock()
+  if _, entry := self.find(key); entry != nil {
+    return entry.Value, true
+  }
+  return nil, false
+}
+
+// an existing key keeps the spelling it was first added with
+func (self *HashTable) Set(key, val Value) {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  h, entry := self.find(key)
+  if entry != nil {
+    entry.Value = val
+    return
+  }
+  entry = &hashEntry{key, val}
+  self.buckets[h] = append(self.buckets[h], entry)
+  self.entries = append(self.entries, entry)
+}
+
+// ok is false when there was no value for key
+func (self *HashTable) Delete(key Value) bool {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  h, entry := self.find(key)
+  if entry == nil {
+    return false
+  }
+  self.buckets[h] = removeEntry(self.buckets[h], entry)
+  if len(self.buckets[h]) == 0 {
+    delete(self.buckets, h)
+  }
+  self.entries = removeEntry(self.entries, entry)
+  return true
+}
+
+func removeEntry(entries []*hashEntry, entry *hashEntry) []*hashEntry {
+  for i, e := range entries {
+    if e == entry {
+      return append(entries[:i], entries[i+1:]...)
+    }
+  }
+  return entries
+}
+
+func (self *HashTable) Len() int {
+  self.mutex.RLock()
+  defer self.mutex.RUnlock()
+  return len(self.entries)
+}
+
+// the keys and values in the order they were added
+func (self *HashTable) Pairs() [][2]Value {
+  self.mutex.RLock()
+  defer self.mutex.RUnlock()
+  pairs := make([][2]Value, len(self.entries))
+  for i, entry := range self.entries {
+    pairs[i] = [2]Value{entry.Key, entry.Value}
+  }
+  return pairs
+}
+
+// e.g. #<hash ("a" . 1) ("b" . 2)>
+func (self *HashTable) String() string {
+  s := "#<hash"
+  for _, pair := range self.Pairs() {
+    s += fmt.Sprintf(" (%s . %s)", pair[0], pair[1])
+  }
+  return s + ">"
+}
@@ -6,7 +6,25 @@ type IntValue struct {
   Value int64
 }
 
+// integers are never modified, so small ones
+// are preallocated and shared
+const (
+  minSharedInt = -128
+  maxSharedInt = 1023
+)
+
+var sharedInts = func() []*IntValue {
+  ints := make([]*IntValue, maxSharedInt-minSharedInt+1)
+  for i := range ints {
+    ints[i] = &IntValue{Value: int64(i + minSharedInt)}
+  }
+  return ints
+}()
+
 func NewIntValue(val int64) *IntValue {
+  if val >= minSharedInt && val <= maxSharedInt {
+    return sharedInts[val-minSharedInt]
+  }
   return &IntValue{Value: val}
 }
 
@@ -0,0 +1,17 @@
+package value
+
+import "fmt"
+
+// a Go value passed through lisp code untouched, for values
+// without a lisp counterpart or which must keep their identity
+type Opaque struct {
+  Value interface{}
+}
+
+func NewOpaque(val interface{}) *Opaque {
+  return &Opaque{Value: val}
+}
+
+func (self *Opaque) String() string {
+  return fmt.Sprintf("#<opaque %T>", self.Value)
+}
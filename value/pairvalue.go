@@ -1,6 +1,7 @@
 package value
 
 import (
+  "bytes"
   "fmt"
 )
 
@@ -29,25 +30,82 @@ func NewPairValue(first, second Value) *PairValue {
   return &PairValue{First: first, Second: second}
 }
 
+// (quote x) is written 'x, likewise for the other reader shorthands
+var shorthands = map[string]string{
+  "quote":            "'",
+  "quasiquote":       "`",
+  "unquote":          ",",
+  "unquote-splicing": ",@",
+}
+
+// the shorthand of a list like (quote x), which has exactly one datum
+func shorthand(pair *PairValue) (string, bool) {
+  symbol, ok := pair.First.(*Symbol)
+  if !ok {
+    return "", false
+  }
+  rest, ok := pair.Second.(*PairValue)
+  if !ok || rest.Second != NilPairValue {
+    return "", false
+  }
+  prefix, ok := shorthands[symbol.Value]
+  return prefix, ok
+}
+
 func (self *PairValue) String() string {
-  if self.Second == NilPairValue {
-    return fmt.Sprintf("(%s)", self.First)
-  }
-  length := len(self.Second.String())
-  switch self.First.String() {
-  case "quote":
-    return fmt.Sprintf("'%s", self.Second.String()[1:length-1])
-  case "unquote":
-    return fmt.Sprintf(",%s", self.Second.String()[1:length-1])
-  case "quasiquote":
-    return fmt.Sprintf("`%s", self.Second.String()[1:length-1])
-  case "unquote-splicing":
-    return fmt.Sprintf(",@%s", self.Second.String()[1:length-1])
-  }
-  switch self.Second.(type) {
+  return self.format(WrittenString)
+}
+
+// how write prints val. the value of forms like (if #f #f) is
+// unspecified, it is written #<unspecified>
+func WrittenString(val Value) string {
+  if val == nil {
+    return "#<unspecified>"
+  }
+  return fmt.Sprintf("%s", val)
+}
+
+// the list with its elements written by str
+func (self *PairValue) format(str func(Value) string) string {
+  if prefix, ok := shorthand(self); ok {
+    return prefix + str(self.Second.(*PairValue).First)
+  }
+  var buf bytes.Buffer
+  fmt.Fprintf(&buf, "(%s", str(self.First))
+  tail := self.Second
+  for {
+    pair, ok := tail.(*PairValue)
+    if !ok {
+      break
+    }
+    if _, ok := shorthand(pair); ok {
+      // (a unquote x) is (a . (unquote x)), written (a . ,x)
+      break
+    }
+    fmt.Fprintf(&buf, " %s", str(pair.First))
+    tail = pair.Second
+  }
+  if tail != NilPairValue {
+    fmt.Fprintf(&buf, " . %s", str(tail))
+  }
+  buf.WriteString(")")
+  return buf.String()
+}
+
+// how display prints val: like String, but strings and characters,
+// in lists too, as their text rather than as they are read
+func DisplayString(val Value) string {
+  switch val.(type) {
+  case *StringValue:
+    return val.(*StringValue).Value
+  case *CharValue:
+    return string(val.(*CharValue).Value)
+  case *Symbol:
+    return val.(*Symbol).Value
   case *PairValue:
-    return fmt.Sprintf("(%s %s", self.First, self.Second.String()[1:])
-  default:
-    return fmt.Sprintf("(%s . %s)", self.First, self.Second)
+    return val.(*PairValue).format(DisplayString)
+  case *VectorValue:
+    return val.(*VectorValue).format(DisplayString)
   }
+  return WrittenString(val)
 }
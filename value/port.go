@@ -0,0 +1,45 @@
+package value
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+)
+
+// a port reads from Reader or writes to Writer, the other one is nil
+type Port struct {
+  Name   string
+  Reader *bufio.Reader
+  Writer io.Writer
+  Closer io.Closer
+}
+
+func NewInputPort(name string, reader io.ReadCloser) *Port {
+  return &Port{Name: name, Reader: bufio.NewReader(reader), Closer: reader}
+}
+
+// closing the port closes writer if it is an io.Closer
+func NewOutputPort(name string, writer io.Writer) *Port {
+  closer, _ := writer.(io.Closer)
+  return &Port{Name: name, Writer: writer, Closer: closer}
+}
+
+func (self *Port) Close() error {
+  if self.Closer == nil {
+    return nil
+  }
+  return self.Closer.Close()
+}
+
+func (self *Port) String() string {
+  return fmt.Sprintf("#<port %s>", self.Name)
+}
+
+type EOFObject struct {
+}
+
+var EOF = &EOFObject{}
+
+func (self *EOFObject) String() string {
+  return "#<eof>"
+}
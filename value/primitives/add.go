@@ -1,7 +1,7 @@
 package primitives
 
 import (
-  "fmt"
+  "github.com/kedebug/LispEx/number"
   "github.com/kedebug/LispEx/value"
 )
 
@@ -14,24 +14,9 @@ func NewAdd() *Add {
 }
 
 func (self *Add) Apply(args []value.Value) value.Value {
-  var val1 int64
-  var val2 float64
-  isfloat := false
-
+  var sum value.Value = value.NewIntValue(0)
   for _, arg := range args {
-    switch arg.(type) {
-    case *value.IntValue:
-      val1 += arg.(*value.IntValue).Value
-    case *value.FloatValue:
-      isfloat = true
-      val2 += arg.(*value.FloatValue).Value
-    default:
-      panic(fmt.Sprint("incorrect argument type for '+' : ", arg))
-    }
-  }
-  if !isfloat {
-    return value.NewIntValue(val1)
-  } else {
-    return value.NewFloatValue(float64(val1) + val2)
+    sum = number.Add(sum, arg)
   }
+  return sum
 }
@@ -0,0 +1,21 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// the element data called with args at caller indexes, ok is false
+// when data isn't callable. the caller checks that the interpreter
+// lets data be called, see scope.SetApplicableData
+func ApplyData(data Value, args []Value, caller string) (result Value, ok bool) {
+  var ref *Builtin
+  switch data.(type) {
+  case *VectorValue:
+    ref = LookupBuiltin("vector-ref")
+  case *StringValue:
+    ref = LookupBuiltin("string-ref")
+  default:
+    return nil, false
+  }
+  return ref.ApplyAt(append([]Value{data}, args...), caller), true
+}
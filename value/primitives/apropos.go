@@ -0,0 +1,51 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "regexp"
+  "strings"
+)
+
+// (apropos "str") lists the bound names containing str,
+// (apropos "/re/") those matching the regular expression re.
+// names enumerates the bindings visible from the caller's scope
+type Apropos struct {
+  Primitive
+  names func() []string
+}
+
+func NewApropos(names func() []string) *Apropos {
+  return &Apropos{Primitive{"apropos"}, names}
+}
+
+func (self *Apropos) Apply(args []Value) Value {
+  var pattern string
+  switch args[0].(type) {
+  case *StringValue:
+    pattern = args[0].(*StringValue).Value
+  case *Symbol:
+    pattern = args[0].(*Symbol).Value
+  }
+
+  match := func(name string) bool { return strings.Contains(name, pattern) }
+  if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+    re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+    if err != nil {
+      panic(fmt.Sprint("apropos: ", err))
+    }
+    match = re.MatchString
+  }
+
+  if self.names == nil {
+    return NilPairValue
+  }
+  var found []Value
+  for _, name := range self.names() {
+    if match(name) {
+      found = append(found, NewSymbol(name))
+    }
+  }
+  return converter.SliceToPairValues(found)
+}
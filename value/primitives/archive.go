@@ -0,0 +1,292 @@
+package primitives
+
+import (
+  "archive/tar"
+  "archive/zip"
+  "compress/gzip"
+  "compress/zlib"
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+// closes a decompressing reader, then the file under it
+type closers []io.Closer
+
+func (self closers) Close() error {
+  var first error
+  for _, closer := range self {
+    if err := closer.Close(); err != nil && first == nil {
+      first = err
+    }
+  }
+  return first
+}
+
+type readCloser struct {
+  io.Reader
+  io.Closer
+}
+
+// (open-input-gzip-file path) and (open-input-zlib-file path) are
+// input ports reading the file decompressed as it is read
+type OpenCompressed struct {
+  Primitive
+  open func(io.Reader) (io.ReadCloser, error)
+}
+
+func NewOpenGzip() *OpenCompressed {
+  return &OpenCompressed{Primitive{"open-input-gzip-file"}, func(r io.Reader) (io.ReadCloser, error) {
+    return gzip.NewReader(r)
+  }}
+}
+
+func NewOpenZlib() *OpenCompressed {
+  return &OpenCompressed{Primitive{"open-input-zlib-file"}, zlib.NewReader}
+}
+
+func (self *OpenCompressed) Apply(args []Value) Value {
+  path := args[0].(*StringValue).Value
+  file, err := os.Open(path)
+  if err != nil {
+    raiseIOError(self.Name, err)
+  }
+  reader, err := self.open(file)
+  if err != nil {
+    file.Close()
+    raiseIOError(self.Name, err)
+  }
+  return NewInputPort(path, readCloser{reader, closers{reader, file}})
+}
+
+// archives are zip files or tar files, compressed with gzip
+// when their name ends with .gz or .tgz
+func isZip(path string) bool {
+  return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+func isGzip(path string) bool {
+  ext := strings.ToLower(filepath.Ext(path))
+  return ext == ".gz" || ext == ".tgz"
+}
+
+// calls each with the tar entries of the file at path until it returns
+// false, the reader reads the entry being visited
+func walkTar(name, path string, each func(*tar.Header, io.Reader) bool) {
+  file, err := os.Open(path)
+  if err != nil {
+    raiseIOError(name, err)
+  }
+  defer file.Close()
+  var reader io.Reader = file
+  if isGzip(path) {
+    gz, err := gzip.NewReader(file)
+    if err != nil {
+      raiseIOError(name, err)
+    }
+    defer gz.Close()
+    reader = gz
+  }
+  archive := tar.NewReader(reader)
+  for {
+    header, err := archive.Next()
+    if err == io.EOF {
+      return
+    } else if err != nil {
+      raiseIOError(name, err)
+    }
+    if !each(header, archive) {
+      return
+    }
+  }
+}
+
+// (archive-entries path) is the list of the names of
+// the files in the zip or tar archive
+type ArchiveEntries struct {
+  Primitive
+}
+
+func NewArchiveEntries() *ArchiveEntries {
+  return &ArchiveEntries{Primitive{"archive-entries"}}
+}
+
+func (self *ArchiveEntries) Apply(args []Value) Value {
+  path := args[0].(*StringValue).Value
+  var names []Value
+  if isZip(path) {
+    archive, err := zip.OpenReader(path)
+    if err != nil {
+      raiseIOError("archive-entries", err)
+    }
+    defer archive.Close()
+    for _, file := range archive.File {
+      names = append(names, NewStringValue(file.Name))
+    }
+  } else {
+    walkTar("archive-entries", path, func(header *tar.Header, _ io.Reader) bool {
+      names = append(names, NewStringValue(header.Name))
+      return true
+    })
+  }
+  return converter.SliceToPairValues(names)
+}
+
+// (open-input-archive-entry path name) is an input port reading
+// the file name of the archive, uncompressed
+type OpenArchiveEntry struct {
+  Primitive
+}
+
+func NewOpenArchiveEntry() *OpenArchiveEntry {
+  return &OpenArchiveEntry{Primitive{"open-input-archive-entry"}}
+}
+
+func (self *OpenArchiveEntry) Apply(args []Value) Value {
+  path, name := args[0].(*StringValue).Value, args[1].(*StringValue).Value
+  if isZip(path) {
+    archive, err := zip.OpenReader(path)
+    if err != nil {
+      raiseIOError("open-input-archive-entry", err)
+    }
+    for _, file := range archive.File {
+      if file.Name != name {
+        continue
+      }
+      reader, err := file.Open()
+      if err != nil {
+        archive.Close()
+        raiseIOError("open-input-archive-entry", err)
+      }
+      return NewInputPort(path+":"+name, readCloser{reader, closers{reader, archive}})
+    }
+    archive.Close()
+  } else {
+    // tar files can't be read from the middle, the entry is read whole
+    var data *strings.Builder
+    walkTar("open-input-archive-entry", path, func(header *tar.Header, reader io.Reader) bool {
+      if header.Name != name {
+        return true
+      }
+      data = new(strings.Builder)
+      if _, err := io.Copy(data, reader); err != nil {
+        raiseIOError("open-input-archive-entry", err)
+      }
+      return false
+    })
+    if data != nil {
+      return NewInputPort(path+":"+name, io.NopCloser(strings.NewReader(data.String())))
+    }
+  }
+  panic(fmt.Sprintf("open-input-archive-entry: %s: no entry named %s", path, name))
+}
+
+// (archive-create path files) writes a zip or tar archive of the
+// files, named in it by the paths given
+type ArchiveCreate struct {
+  Primitive
+}
+
+func NewArchiveCreate() *ArchiveCreate {
+  return &ArchiveCreate{Primitive{"archive-create"}}
+}
+
+func (self *ArchiveCreate) Apply(args []Value) Value {
+  path := args[0].(*StringValue).Value
+  var files []string
+  for _, file := range converter.PairsToSlice(args[1]) {
+    s, ok := file.(*StringValue)
+    if !ok {
+      panic(fmt.Sprint("archive-create: expected a list of paths, given: ", args[1]))
+    }
+    files = append(files, s.Value)
+  }
+  out, err := os.Create(path)
+  if err != nil {
+    raiseIOError("archive-create", err)
+  }
+  defer out.Close()
+  if isZip(path) {
+    err = writeZip(out, files)
+  } else {
+    err = writeTar(out, files, isGzip(path))
+  }
+  if err == nil {
+    err = out.Close()
+  }
+  if err != nil {
+    os.Remove(path)
+    raiseIOError("archive-create", err)
+  }
+  return nil
+}
+
+func writeZip(out io.Writer, files []string) error {
+  archive := zip.NewWriter(out)
+  for _, name := range files {
+    info, err := os.Stat(name)
+    if err != nil {
+      return err
+    }
+    header, err := zip.FileInfoHeader(info)
+    if err != nil {
+      return err
+    }
+    header.Name, header.Method = filepath.ToSlash(name), zip.Deflate
+    writer, err := archive.CreateHeader(header)
+    if err != nil {
+      return err
+    }
+    if err := copyFile(writer, name); err != nil {
+      return err
+    }
+  }
+  return archive.Close()
+}
+
+func writeTar(out io.Writer, files []string, compressed bool) error {
+  var gz *gzip.Writer
+  if compressed {
+    gz = gzip.NewWriter(out)
+    out = gz
+  }
+  archive := tar.NewWriter(out)
+  for _, name := range files {
+    info, err := os.Stat(name)
+    if err != nil {
+      return err
+    }
+    header, err := tar.FileInfoHeader(info, "")
+    if err != nil {
+      return err
+    }
+    header.Name = filepath.ToSlash(name)
+    if err := archive.WriteHeader(header); err != nil {
+      return err
+    }
+    if err := copyFile(archive, name); err != nil {
+      return err
+    }
+  }
+  if err := archive.Close(); err != nil {
+    return err
+  }
+  if gz != nil {
+    return gz.Close()
+  }
+  return nil
+}
+
+func copyFile(out io.Writer, name string) error {
+  file, err := os.Open(name)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+  _, err = io.Copy(out, file)
+  return err
+}
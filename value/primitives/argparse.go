@@ -0,0 +1,241 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "strconv"
+  "strings"
+)
+
+// (argparse program spec args) parses command-line arguments
+// against a list of declarations:
+//  (flag name [short] help)
+//  (option name type default help)
+//  (positional name type help)
+// where type is one of integer, float, string or bool.
+// returns an alist from names to values, or #f after printing
+// the usage when --help is given
+type ArgParse struct {
+  Primitive
+  help bool
+}
+
+func NewArgParse() *ArgParse {
+  return &ArgParse{Primitive{"argparse"}, false}
+}
+
+func NewArgParseHelp() *ArgParse {
+  return &ArgParse{Primitive{"argparse-help"}, true}
+}
+
+type argSpec struct {
+  kind     string
+  name     string
+  short    string
+  typ      string
+  fallback Value
+  help     string
+}
+
+func (self *ArgParse) Apply(args []Value) Value {
+  program := args[0].(*StringValue)
+  specs := parseArgSpecs(self.Name, args[1])
+  usage := argUsage(program.Value, specs)
+  if self.help {
+    return NewStringValue(usage)
+  }
+
+  var words []string
+  for _, arg := range converter.PairsToSlice(args[2]) {
+    if word, ok := arg.(*StringValue); ok {
+      words = append(words, word.Value)
+    } else {
+      panic(fmt.Sprint("argparse: expected list of strings, given: ", args[2]))
+    }
+  }
+  result, ok := parseArgs(specs, words)
+  if !ok {
+    fmt.Fprint(Output(), usage)
+    return NewBoolValue(false)
+  }
+  return result
+}
+
+func parseArgSpecs(name string, val Value) []*argSpec {
+  var specs []*argSpec
+  for _, decl := range converter.PairsToSlice(val) {
+    fields := converter.PairsToSlice(decl)
+    if len(fields) < 2 {
+      panic(fmt.Sprintf("%s: bad declaration: %s", name, decl))
+    }
+    var strs []string
+    for _, field := range fields[:len(fields)-1] {
+      switch field.(type) {
+      case *Symbol:
+        strs = append(strs, field.(*Symbol).Value)
+      case *StringValue:
+        strs = append(strs, field.(*StringValue).Value)
+      default:
+        strs = append(strs, "")
+      }
+    }
+    help, ok := fields[len(fields)-1].(*StringValue)
+    if !ok {
+      panic(fmt.Sprintf("%s: expected help string, given: %s", name, decl))
+    }
+    spec := &argSpec{kind: strs[0], help: help.Value}
+    switch {
+    case spec.kind == "flag" && (len(fields) == 3 || len(fields) == 4):
+      spec.name, spec.typ = strs[1], "bool"
+      spec.fallback = NewBoolValue(false)
+      if len(fields) == 4 {
+        spec.short = strs[2]
+      }
+    case spec.kind == "option" && len(fields) == 5:
+      spec.name, spec.typ, spec.fallback = strs[1], strs[2], fields[3]
+    case spec.kind == "positional" && len(fields) == 4:
+      spec.name, spec.typ = strs[1], strs[2]
+    default:
+      panic(fmt.Sprintf("%s: bad declaration: %s", name, decl))
+    }
+    switch spec.typ {
+    case "integer", "float", "string", "bool":
+    default:
+      panic(fmt.Sprintf("%s: unknown type `%s' in: %s", name, spec.typ, decl))
+    }
+    specs = append(specs, spec)
+  }
+  return specs
+}
+
+// returns false when help was requested
+func parseArgs(specs []*argSpec, words []string) (Value, bool) {
+  values := make(map[string]Value)
+  var positionals []*argSpec
+  for _, spec := range specs {
+    if spec.kind == "positional" {
+      positionals = append(positionals, spec)
+    } else {
+      values[spec.name] = spec.fallback
+    }
+  }
+
+  for i := 0; i < len(words); i++ {
+    word := words[i]
+    if word == "--" {
+      positionals = assignPositionals(positionals, words[i+1:], values)
+      break
+    }
+    if word == "-h" || word == "--help" {
+      return nil, false
+    }
+    if !strings.HasPrefix(word, "-") || word == "-" {
+      positionals = assignPositionals(positionals, words[i:i+1], values)
+      continue
+    }
+
+    name, arg, inline := word, "", false
+    if index := strings.Index(word, "="); index >= 0 {
+      name, arg, inline = word[:index], word[index+1:], true
+    }
+    spec := findArgSpec(specs, name)
+    if spec == nil {
+      panic(fmt.Sprint("argparse: unknown option: ", name))
+    }
+    if spec.kind == "flag" {
+      if inline {
+        panic(fmt.Sprintf("argparse: flag %s takes no value", name))
+      }
+      values[spec.name] = NewBoolValue(true)
+      continue
+    }
+    if !inline {
+      if i+1 >= len(words) {
+        panic(fmt.Sprintf("argparse: option %s requires a value", name))
+      }
+      i++
+      arg = words[i]
+    }
+    values[spec.name] = convertArg(spec, arg)
+  }
+  if len(positionals) > 0 {
+    panic(fmt.Sprint("argparse: missing argument: ", positionals[0].name))
+  }
+
+  var result []Value
+  for _, spec := range specs {
+    result = append(result, NewPairValue(NewSymbol(spec.name), values[spec.name]))
+  }
+  return converter.SliceToPairValues(result), true
+}
+
+func assignPositionals(positionals []*argSpec, words []string, values map[string]Value) []*argSpec {
+  for _, word := range words {
+    if len(positionals) == 0 {
+      panic(fmt.Sprint("argparse: unexpected argument: ", word))
+    }
+    values[positionals[0].name] = convertArg(positionals[0], word)
+    positionals = positionals[1:]
+  }
+  return positionals
+}
+
+func findArgSpec(specs []*argSpec, name string) *argSpec {
+  for _, spec := range specs {
+    if spec.kind == "positional" {
+      continue
+    }
+    if name == "--"+spec.name || (spec.short != "" && name == spec.short) {
+      return spec
+    }
+  }
+  return nil
+}
+
+func convertArg(spec *argSpec, arg string) Value {
+  switch spec.typ {
+  case "integer":
+    if n, err := strconv.ParseInt(arg, 10, 64); err == nil {
+      return NewIntValue(n)
+    }
+  case "float":
+    if f, err := strconv.ParseFloat(arg, 64); err == nil {
+      return NewFloatValue(f)
+    }
+  case "bool":
+    if b, err := strconv.ParseBool(arg); err == nil {
+      return NewBoolValue(b)
+    }
+  default:
+    return NewStringValue(arg)
+  }
+  panic(fmt.Sprintf("argparse: %s expects %s, given: %s", spec.name, spec.typ, arg))
+}
+
+func argUsage(program string, specs []*argSpec) string {
+  usage := fmt.Sprintf("usage: %s [options]", program)
+  var positionals, options []string
+  options = append(options, fmt.Sprintf("  %-20s %s\n", "-h, --help", "show this help message"))
+  for _, spec := range specs {
+    switch spec.kind {
+    case "positional":
+      usage += " " + spec.name
+      positionals = append(positionals, fmt.Sprintf("  %-20s %s\n", spec.name, spec.help))
+    case "flag":
+      name := "--" + spec.name
+      if spec.short != "" {
+        name = spec.short + ", " + name
+      }
+      options = append(options, fmt.Sprintf("  %-20s %s\n", name, spec.help))
+    case "option":
+      name := fmt.Sprintf("--%s %s", spec.name, strings.ToUpper(spec.typ))
+      options = append(options, fmt.Sprintf("  %-20s %s (default: %s)\n", name, spec.help, spec.fallback))
+    }
+  }
+  usage += "\n"
+  if len(positionals) > 0 {
+    usage += "\narguments:\n" + strings.Join(positionals, "")
+  }
+  return usage + "\noptions:\n" + strings.Join(options, "")
+}
@@ -0,0 +1,140 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (make-bitvector k [fill]) is k bits, all set when fill is #t
+type MakeBitvector struct {
+  Primitive
+}
+
+func NewMakeBitvector() *MakeBitvector {
+  return &MakeBitvector{Primitive{"make-bitvector"}}
+}
+
+func (self *MakeBitvector) Apply(args []Value) Value {
+  k := args[0].(*IntValue).Value
+  if k < 0 {
+    panic(fmt.Sprint("make-bitvector: expected a length, given: ", args[0]))
+  }
+  bv := NewBitvector(int(k))
+  if len(args) > 1 && args[1].(*BoolValue).Value {
+    for i := range bv.Words {
+      bv.Words[i] = ^uint64(0)
+    }
+    bv.Trim()
+  }
+  return bv
+}
+
+// a byte holds 8 bits
+func (self *MakeBitvector) Allocates(args []Value) int64 {
+  return lengthBytes(args, 1) / 8
+}
+
+type BitvectorLength struct {
+  Primitive
+}
+
+func NewBitvectorLength() *BitvectorLength {
+  return &BitvectorLength{Primitive{"bitvector-length"}}
+}
+
+func (self *BitvectorLength) Apply(args []Value) Value {
+  return NewIntValue(int64(args[0].(*Bitvector).Len))
+}
+
+// the index argument of a bitvector primitive, checked against its length
+func bitIndex(name string, bv *Bitvector, index Value) int {
+  k := index.(*IntValue).Value
+  if k < 0 || k >= int64(bv.Len) {
+    panic(fmt.Sprintf("%s: index %d out of bounds for a bitvector of length %d", name, k, bv.Len))
+  }
+  return int(k)
+}
+
+type BitvectorRef struct {
+  Primitive
+}
+
+func NewBitvectorRef() *BitvectorRef {
+  return &BitvectorRef{Primitive{"bitvector-ref"}}
+}
+
+func (self *BitvectorRef) Apply(args []Value) Value {
+  bv := args[0].(*Bitvector)
+  return NewBoolValue(bv.Get(bitIndex(self.Name, bv, args[1])))
+}
+
+type BitvectorSet struct {
+  Primitive
+}
+
+func NewBitvectorSet() *BitvectorSet {
+  return &BitvectorSet{Primitive{"bitvector-set!"}}
+}
+
+func (self *BitvectorSet) Apply(args []Value) Value {
+  bv := args[0].(*Bitvector)
+  bv.Set(bitIndex(self.Name, bv, args[1]), args[2].(*BoolValue).Value)
+  return nil
+}
+
+type BitvectorCount struct {
+  Primitive
+}
+
+func NewBitvectorCount() *BitvectorCount {
+  return &BitvectorCount{Primitive{"bitvector-count"}}
+}
+
+func (self *BitvectorCount) Apply(args []Value) Value {
+  return NewIntValue(int64(args[0].(*Bitvector).Count()))
+}
+
+// (bitvector-and bv...) and the like combine bitvectors
+// of the same length word by word into a new one
+type BitvectorOp struct {
+  Primitive
+  op func(x, y uint64) uint64
+}
+
+func NewBitvectorOp(name string, op func(x, y uint64) uint64) *BitvectorOp {
+  return &BitvectorOp{Primitive{name}, op}
+}
+
+func (self *BitvectorOp) Apply(args []Value) Value {
+  first := args[0].(*Bitvector)
+  result := NewBitvector(first.Len)
+  copy(result.Words, first.Words)
+  for _, arg := range args[1:] {
+    bv := arg.(*Bitvector)
+    if bv.Len != first.Len {
+      panic(fmt.Sprintf("%s: expected bitvectors of length %d, given one of length %d", self.Name, first.Len, bv.Len))
+    }
+    for i, word := range bv.Words {
+      result.Words[i] = self.op(result.Words[i], word)
+    }
+  }
+  return result
+}
+
+type BitvectorNot struct {
+  Primitive
+}
+
+func NewBitvectorNot() *BitvectorNot {
+  return &BitvectorNot{Primitive{"bitvector-not"}}
+}
+
+func (self *BitvectorNot) Apply(args []Value) Value {
+  bv := args[0].(*Bitvector)
+  result := NewBitvector(bv.Len)
+  for i, word := range bv.Words {
+    result.Words[i] = ^word
+  }
+  result.Trim()
+  return result
+}
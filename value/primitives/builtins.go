@@ -0,0 +1,540 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/constants"
+  "github.com/kedebug/LispEx/number"
+  "github.com/kedebug/LispEx/quickcheck"
+  . "github.com/kedebug/LispEx/value"
+  "math"
+  "os"
+  "strings"
+  "unicode"
+)
+
+// argument type of a builtin, checked before the builtin is applied
+type ArgType struct {
+  Name  string
+  Check func(Value) bool
+}
+
+var (
+  AnyArg = &ArgType{"any", func(Value) bool { return true }}
+
+  IntegerArg = &ArgType{"integer", func(val Value) bool {
+    _, ok := val.(*IntValue)
+    return ok
+  }}
+
+  // integers of any size, integral floats included
+  IntegralArg = &ArgType{"integer", number.IsInteger}
+
+  NumberArg = &ArgType{"number", number.IsNumber}
+
+  BoolArg = &ArgType{"bool", func(val Value) bool {
+    _, ok := val.(*BoolValue)
+    return ok
+  }}
+
+  StringArg = &ArgType{"string", func(val Value) bool {
+    _, ok := val.(*StringValue)
+    return ok
+  }}
+
+  CharArg = &ArgType{"char", func(val Value) bool {
+    _, ok := val.(*CharValue)
+    return ok
+  }}
+
+  CharSetArg = &ArgType{"string or char", func(val Value) bool {
+    switch val.(type) {
+    case *StringValue, *CharValue:
+      return true
+    }
+    return false
+  }}
+
+  CharMatchArg = &ArgType{"char or procedure", func(val Value) bool {
+    return CharArg.Check(val) || ProcedureArg.Check(val)
+  }}
+
+  NameArg = &ArgType{"string or symbol", func(val Value) bool {
+    switch val.(type) {
+    case *StringValue, *Symbol:
+      return true
+    }
+    return false
+  }}
+
+  SymbolArg = &ArgType{"symbol", func(val Value) bool {
+    _, ok := val.(*Symbol)
+    return ok
+  }}
+
+  ProcedureArg = &ArgType{"procedure", func(val Value) bool {
+    switch val.(type) {
+    case *Closure, *Contract, PrimFunc:
+      return true
+    }
+    return false
+  }}
+
+  PairArg = &ArgType{"pair", func(val Value) bool {
+    _, ok := val.(*PairValue)
+    return ok
+  }}
+
+  ListArg = &ArgType{"list", func(val Value) bool {
+    for {
+      switch val.(type) {
+      case *EmptyPairValue:
+        return true
+      case *PairValue:
+        val = val.(*PairValue).Second
+      default:
+        return false
+      }
+    }
+  }}
+
+  ChannelArg = &ArgType{"channel", func(val Value) bool {
+    _, ok := val.(*Channel)
+    return ok
+  }}
+
+  VectorArg = &ArgType{"vector", func(val Value) bool {
+    _, ok := val.(*VectorValue)
+    return ok
+  }}
+
+  BytevectorArg = &ArgType{"bytevector", func(val Value) bool {
+    _, ok := val.(*Bytevector)
+    return ok
+  }}
+
+  BitvectorArg = &ArgType{"bitvector", func(val Value) bool {
+    _, ok := val.(*Bitvector)
+    return ok
+  }}
+
+  HashArg = &ArgType{"hash table", func(val Value) bool {
+    _, ok := val.(*HashTable)
+    return ok
+  }}
+
+  PortArg = &ArgType{"port", func(val Value) bool {
+    _, ok := val.(*Port)
+    return ok
+  }}
+
+  InputPortArg = &ArgType{"input port", func(val Value) bool {
+    port, ok := val.(*Port)
+    return ok && port.Reader != nil
+  }}
+
+  OutputPortArg = &ArgType{"output port", func(val Value) bool {
+    port, ok := val.(*Port)
+    return ok && port.Writer != nil
+  }}
+
+  PortOrPathArg = &ArgType{"port or path", func(val Value) bool {
+    return InputPortArg.Check(val) || StringArg.Check(val)
+  }}
+
+  WebSocketArg = &ArgType{"websocket", func(val Value) bool {
+    _, ok := val.(*WebSocket)
+    return ok
+  }}
+
+  EnvironmentArg = &ArgType{"environment", func(val Value) bool {
+    _, ok := val.(*Environment)
+    return ok
+  }}
+
+  BindingsArg = &ArgType{"environment or association list", func(val Value) bool {
+    return EnvironmentArg.Check(val) || ListArg.Check(val)
+  }}
+
+  GeneratorArg = &ArgType{"generator", func(val Value) bool {
+    _, ok := val.(*quickcheck.Generator)
+    return ok
+  }}
+
+  ConditionArg = &ArgType{"condition", func(val Value) bool {
+    _, ok := val.(*Condition)
+    return ok
+  }}
+)
+
+// a builtin procedure together with its specification:
+// it accepts Min to Max arguments (Max is -1 when variadic)
+// whose types are given by Args, the last type repeating.
+// Proc is applied once they are checked and doesn't check them again
+type Builtin struct {
+  Name string
+  Min  int
+  Max  int
+  Args []*ArgType
+  Doc  string
+  Proc PrimFunc
+}
+
+var Builtins = []*Builtin{
+  {constants.ADD, 0, -1, []*ArgType{NumberArg}, "sum of the numbers", NewAdd()},
+  {constants.SUB, 1, -1, []*ArgType{NumberArg}, "difference of the numbers, or the negation of a single one", NewSub()},
+  {constants.MULT, 0, -1, []*ArgType{NumberArg}, "product of the numbers", NewMult()},
+  {constants.DIV, 1, -1, []*ArgType{NumberArg}, "quotient of the numbers, or the reciprocal of a single one", NewDiv()},
+  {"=", 2, -1, []*ArgType{NumberArg}, "whether the numbers are equal", NewEq()},
+  {">", 2, -1, []*ArgType{NumberArg}, "whether each number is greater than the next", NewGt()},
+  {">=", 2, -1, []*ArgType{NumberArg}, "whether each number is greater than or equal to the next", NewGtE()},
+  {"<", 2, -1, []*ArgType{NumberArg}, "whether each number is less than the next", NewLt()},
+  {"<=", 2, -1, []*ArgType{NumberArg}, "whether each number is less than or equal to the next", NewLtE()},
+  {"%", 2, 2, []*ArgType{IntegralArg}, "remainder of dividing the first integer by the second", NewNumberOp("%", number.Remainder)},
+  {"quotient", 2, 2, []*ArgType{IntegralArg}, "quotient of the integers, truncated toward zero", NewNumberOp("quotient", number.Quotient)},
+  {"remainder", 2, 2, []*ArgType{IntegralArg}, "remainder of dividing the first integer by the second, with the sign of the first", NewNumberOp("remainder", number.Remainder)},
+  {"modulo", 2, 2, []*ArgType{IntegralArg}, "modulo of the first integer by the second, with the sign of the second", NewNumberOp("modulo", number.Modulo)},
+  {"gcd", 0, -1, []*ArgType{IntegralArg}, "greatest common divisor of the integers, 0 for none", NewNumberFold("gcd", NewIntValue(0), number.Gcd)},
+  {"lcm", 0, -1, []*ArgType{IntegralArg}, "least common multiple of the integers, 1 for none", NewNumberFold("lcm", NewIntValue(1), number.Lcm)},
+  {"expt", 2, 2, []*ArgType{NumberArg}, "the first number raised to the power of the second, exact for an exact base and integer exponent", NewNumberOp("expt", number.Expt)},
+  {"exact->inexact", 1, 1, []*ArgType{NumberArg}, "the float nearest to the number", NewNumberFunc("exact->inexact", number.ExactToInexact)},
+  {"inexact->exact", 1, 1, []*ArgType{NumberArg}, "the exact number equal to the float", NewNumberFunc("inexact->exact", number.InexactToExact)},
+  {"numerator", 1, 1, []*ArgType{NumberArg}, "numerator of the number in lowest terms", NewNumberFunc("numerator", number.Numerator)},
+  {"denominator", 1, 1, []*ArgType{NumberArg}, "denominator of the number in lowest terms", NewNumberFunc("denominator", number.Denominator)},
+  {"eq?", 2, 2, []*ArgType{AnyArg}, "whether the objects are the same object, or atoms eqv? tells are the same", NewIsEq()},
+  {"eqv?", 2, 2, []*ArgType{AnyArg}, "whether the objects are the same", NewIsEqv()},
+  {"equal?", 2, 2, []*ArgType{AnyArg}, "whether the objects have the same structure", NewIsEqual()},
+  {"values", 0, -1, []*ArgType{AnyArg}, "the objects as multiple values, kept together when passed around until spread by apply or call-with-values", NewValues()},
+  {"call-with-values", 2, 2, []*ArgType{ProcedureArg}, "apply the second procedure to the values returned by the first", NewCallWithValues()},
+  {"dynamic-wind", 3, 3, []*ArgType{ProcedureArg}, "call the three thunks in turn, the last one even when the second raises an error, and return the value of the second", NewDynamicWind()},
+  {"raise", 1, 1, []*ArgType{AnyArg}, "raise the object as an error, for the handler of with-exception-handler or guard to handle", NewRaise()},
+  {"raise-continuable", 1, 1, []*ArgType{AnyArg}, "raise the object to the handler of with-exception-handler, returning what the handler returns", NewRaiseContinuable()},
+  {"error", 1, -1, []*ArgType{AnyArg}, "raise a condition with the message and irritants, preceded by the symbol naming the procedure raising it if any", NewRaiseError()},
+  {"with-exception-handler", 2, 2, []*ArgType{ProcedureArg}, "the value of the thunk, calling the handler with the objects raised while it runs", NewWithExceptionHandler()},
+  {"call-with-guard", 2, 2, []*ArgType{ProcedureArg}, "the value of the thunk, or of the handler applied to the object raised if the thunk raises an error", NewCallWithGuard()},
+  {"error?", 1, 1, []*ArgType{AnyArg}, "whether the object is a condition, as the errors caught are", NewTypePredicate("error?", ConditionArg.Check)},
+  {"condition-who", 1, 1, []*ArgType{ConditionArg}, "symbol naming the procedure which raised the condition, or #f", NewConditionField("condition-who", conditionWho)},
+  {"condition-message", 1, 1, []*ArgType{ConditionArg}, "message of the condition", NewConditionField("condition-message", conditionMessage)},
+  {"condition-irritants", 1, 1, []*ArgType{ConditionArg}, "list of the irritants of the condition", NewConditionField("condition-irritants", conditionIrritants)},
+  {"null?", 1, 1, []*ArgType{AnyArg}, "whether the object is the empty list", NewTypePredicate("null?", isNull)},
+  {"pair?", 1, 1, []*ArgType{AnyArg}, "whether the object is a pair", NewTypePredicate("pair?", PairArg.Check)},
+  {"list?", 1, 1, []*ArgType{AnyArg}, "whether the object is a proper list", NewTypePredicate("list?", ListArg.Check)},
+  {"symbol?", 1, 1, []*ArgType{AnyArg}, "whether the object is a symbol", NewTypePredicate("symbol?", SymbolArg.Check)},
+  {"string?", 1, 1, []*ArgType{AnyArg}, "whether the object is a string", NewTypePredicate("string?", StringArg.Check)},
+  {"char?", 1, 1, []*ArgType{AnyArg}, "whether the object is a character", NewTypePredicate("char?", CharArg.Check)},
+  {"number?", 1, 1, []*ArgType{AnyArg}, "whether the object is a number", NewTypePredicate("number?", NumberArg.Check)},
+  {"integer?", 1, 1, []*ArgType{AnyArg}, "whether the object is an integer, including integral floats", NewTypePredicate("integer?", number.IsInteger)},
+  {"rational?", 1, 1, []*ArgType{AnyArg}, "whether the object is a rational number, including finite floats", NewTypePredicate("rational?", number.IsRational)},
+  {"real?", 1, 1, []*ArgType{AnyArg}, "whether the object is a real number", NewTypePredicate("real?", NumberArg.Check)},
+  {"exact?", 1, 1, []*ArgType{NumberArg}, "whether the number is exact", NewTypePredicate("exact?", number.IsExact)},
+  {"inexact?", 1, 1, []*ArgType{NumberArg}, "whether the number is a float", NewTypePredicate("inexact?", func(val Value) bool { return !number.IsExact(val) })},
+  {"boolean?", 1, 1, []*ArgType{AnyArg}, "whether the object is #t or #f", NewTypePredicate("boolean?", BoolArg.Check)},
+  {"procedure?", 1, 1, []*ArgType{AnyArg}, "whether the object can be applied", NewTypePredicate("procedure?", ProcedureArg.Check)},
+  {"vector?", 1, 1, []*ArgType{AnyArg}, "whether the object is a vector", NewTypePredicate("vector?", VectorArg.Check)},
+  {"hash?", 1, 1, []*ArgType{AnyArg}, "whether the object is a hash table", NewTypePredicate("hash?", HashArg.Check)},
+  {"bytevector?", 1, 1, []*ArgType{AnyArg}, "whether the object is a bytevector", NewTypePredicate("bytevector?", BytevectorArg.Check)},
+  {"bitvector?", 1, 1, []*ArgType{AnyArg}, "whether the object is a bitvector", NewTypePredicate("bitvector?", BitvectorArg.Check)},
+  {"chan?", 1, 1, []*ArgType{AnyArg}, "whether the object is a channel", NewTypePredicate("chan?", ChannelArg.Check)},
+  {"promise?", 1, 1, []*ArgType{AnyArg}, "whether the object is a promise", NewTypePredicate("promise?", isPromise)},
+  {"port?", 1, 1, []*ArgType{AnyArg}, "whether the object is a port", NewTypePredicate("port?", PortArg.Check)},
+  {"input-port?", 1, 1, []*ArgType{AnyArg}, "whether the object is a port to read from", NewTypePredicate("input-port?", InputPortArg.Check)},
+  {"output-port?", 1, 1, []*ArgType{AnyArg}, "whether the object is a port to write to", NewTypePredicate("output-port?", OutputPortArg.Check)},
+  {"eof-object?", 1, 1, []*ArgType{AnyArg}, "whether the object is the eof object", NewTypePredicate("eof-object?", isEOF)},
+  {"char=?", 2, -1, []*ArgType{CharArg}, "whether the characters are equal", NewComparison("char=?", charKey, same)},
+  {"char<?", 2, -1, []*ArgType{CharArg}, "whether the characters are in increasing order", NewComparison("char<?", charKey, ascending)},
+  {"char>?", 2, -1, []*ArgType{CharArg}, "whether the characters are in decreasing order", NewComparison("char>?", charKey, descending)},
+  {"char<=?", 2, -1, []*ArgType{CharArg}, "whether the characters are in non-decreasing order", NewComparison("char<=?", charKey, nonDescending)},
+  {"char>=?", 2, -1, []*ArgType{CharArg}, "whether the characters are in non-increasing order", NewComparison("char>=?", charKey, nonAscending)},
+  {"char-ci=?", 2, -1, []*ArgType{CharArg}, "whether the characters are equal, ignoring case", NewComparison("char-ci=?", charKeyCI, same)},
+  {"char-ci<?", 2, -1, []*ArgType{CharArg}, "whether the characters are in increasing order, ignoring case", NewComparison("char-ci<?", charKeyCI, ascending)},
+  {"char-ci>?", 2, -1, []*ArgType{CharArg}, "whether the characters are in decreasing order, ignoring case", NewComparison("char-ci>?", charKeyCI, descending)},
+  {"char-ci<=?", 2, -1, []*ArgType{CharArg}, "whether the characters are in non-decreasing order, ignoring case", NewComparison("char-ci<=?", charKeyCI, nonDescending)},
+  {"char-ci>=?", 2, -1, []*ArgType{CharArg}, "whether the characters are in non-increasing order, ignoring case", NewComparison("char-ci>=?", charKeyCI, nonAscending)},
+  {"string=?", 2, -1, []*ArgType{StringArg}, "whether the strings are equal", NewComparison("string=?", stringKey, same)},
+  {"string<?", 2, -1, []*ArgType{StringArg}, "whether the strings are in increasing order", NewComparison("string<?", stringKey, ascending)},
+  {"string>?", 2, -1, []*ArgType{StringArg}, "whether the strings are in decreasing order", NewComparison("string>?", stringKey, descending)},
+  {"string<=?", 2, -1, []*ArgType{StringArg}, "whether the strings are in non-decreasing order", NewComparison("string<=?", stringKey, nonDescending)},
+  {"string>=?", 2, -1, []*ArgType{StringArg}, "whether the strings are in non-increasing order", NewComparison("string>=?", stringKey, nonAscending)},
+  {"string-ci=?", 2, -1, []*ArgType{StringArg}, "whether the strings are equal, ignoring case", NewComparison("string-ci=?", stringKeyCI, same)},
+  {"string-ci<?", 2, -1, []*ArgType{StringArg}, "whether the strings are in increasing order, ignoring case", NewComparison("string-ci<?", stringKeyCI, ascending)},
+  {"string-ci>?", 2, -1, []*ArgType{StringArg}, "whether the strings are in decreasing order, ignoring case", NewComparison("string-ci>?", stringKeyCI, descending)},
+  {"string-ci<=?", 2, -1, []*ArgType{StringArg}, "whether the strings are in non-decreasing order, ignoring case", NewComparison("string-ci<=?", stringKeyCI, nonDescending)},
+  {"string-ci>=?", 2, -1, []*ArgType{StringArg}, "whether the strings are in non-increasing order, ignoring case", NewComparison("string-ci>=?", stringKeyCI, nonAscending)},
+  {"string-collate=?", 2, -1, []*ArgType{StringArg}, "whether the strings are equal as human text, ignoring accents and case", NewComparison("string-collate=?", collationKey, same)},
+  {"string-collate<?", 2, -1, []*ArgType{StringArg}, "whether the strings are in increasing order as human text, as in a dictionary", NewComparison("string-collate<?", collationKey, ascending)},
+  {"string-collate>?", 2, -1, []*ArgType{StringArg}, "whether the strings are in decreasing order as human text, as in a dictionary", NewComparison("string-collate>?", collationKey, descending)},
+  {"char-foldcase", 1, 1, []*ArgType{CharArg}, "the character with its case folded", NewCharFoldcase()},
+  {"char-upcase", 1, 1, []*ArgType{CharArg}, "the upper case of the character", NewCharMap("char-upcase", unicode.ToUpper)},
+  {"char-downcase", 1, 1, []*ArgType{CharArg}, "the lower case of the character", NewCharMap("char-downcase", unicode.ToLower)},
+  {"char-alphabetic?", 1, 1, []*ArgType{CharArg}, "whether the character is a letter", NewTypePredicate("char-alphabetic?", charIs(unicode.IsLetter))},
+  {"char-numeric?", 1, 1, []*ArgType{CharArg}, "whether the character is a decimal digit", NewTypePredicate("char-numeric?", charIs(unicode.IsDigit))},
+  {"char-whitespace?", 1, 1, []*ArgType{CharArg}, "whether the character is white space", NewTypePredicate("char-whitespace?", charIs(unicode.IsSpace))},
+  {"char-upper-case?", 1, 1, []*ArgType{CharArg}, "whether the character is an upper case letter", NewTypePredicate("char-upper-case?", charIs(unicode.IsUpper))},
+  {"char-lower-case?", 1, 1, []*ArgType{CharArg}, "whether the character is a lower case letter", NewTypePredicate("char-lower-case?", charIs(unicode.IsLower))},
+  {"char->integer", 1, 1, []*ArgType{CharArg}, "the Unicode code point of the character", NewCharToInteger()},
+  {"integer->char", 1, 1, []*ArgType{IntegerArg}, "the character of the Unicode code point", NewIntegerToChar()},
+  {"digit-value", 1, 1, []*ArgType{CharArg}, "the value of the decimal digit, or #f for other characters", NewDigitValue()},
+  {"string-foldcase", 1, 1, []*ArgType{StringArg}, "the string with its case folded, for comparisons ignoring case", NewStringFoldcase()},
+  {"string->number", 1, 2, []*ArgType{StringArg, IntegerArg}, "the number the string denotes, in radix 2, 8, 10 or 16, or #f if it isn't one", NewStringToNumber()},
+  {"number->string", 1, 2, []*ArgType{NumberArg, IntegerArg}, "the digits of the number in radix 2, 8, 10 or 16, as read by string->number", NewNumberToString()},
+  {"string-length", 1, 1, []*ArgType{StringArg}, "number of characters in the string", NewStringLength()},
+  {"string-ref", 2, 2, []*ArgType{StringArg, IntegerArg}, "character at the index of the string", NewStringRef()},
+  {"string-append", 0, -1, []*ArgType{StringArg}, "a new string with the characters of the strings in turn", NewStringAppend()},
+  {"string", 0, -1, []*ArgType{CharArg}, "a new string of the characters", NewString()},
+  {"make-string", 1, 2, []*ArgType{IntegerArg, CharArg}, "a new string of the length filled with the character, spaces by default", NewMakeString()},
+  {"string->list", 1, 3, []*ArgType{StringArg, IntegerArg}, "list of the characters of the string from start to end", NewStringToList()},
+  {"list->string", 1, 1, []*ArgType{ListArg}, "a new string of the characters of the list", NewListToString()},
+  {"string->symbol", 1, 1, []*ArgType{StringArg}, "the symbol named by the string", NewStringToSymbol()},
+  {"symbol->string", 1, 1, []*ArgType{SymbolArg}, "the name of the symbol", NewSymbolToString()},
+  {"substring", 2, 3, []*ArgType{StringArg, IntegerArg}, "the characters of the string from start to end, sharing its storage", NewSubstring()},
+  {"string-copy", 1, 3, []*ArgType{StringArg, IntegerArg}, "a new string with the characters from start to end, copied when it is changed", NewStringCopy()},
+  {"string-set!", 3, 3, []*ArgType{StringArg, IntegerArg, CharArg}, "replace the character at the index of the string", NewStringSet()},
+  {"string-join", 1, 2, []*ArgType{ListArg, StringArg}, "the strings of the list joined by the separator, a space by default", NewStringJoin()},
+  {"string-trim", 1, 2, []*ArgType{StringArg, CharSetArg}, "the string without leading whitespace or the given characters", NewStringTrim()},
+  {"string-trim-right", 1, 2, []*ArgType{StringArg, CharSetArg}, "the string without trailing whitespace or the given characters", NewStringTrimRight()},
+  {"string-trim-both", 1, 2, []*ArgType{StringArg, CharSetArg}, "the string without leading and trailing whitespace or the given characters", NewStringTrimBoth()},
+  {"string-contains", 2, 2, []*ArgType{StringArg, StringArg}, "index of the first occurrence of the second string in the first, or #f", NewStringContains()},
+  {"string-index", 2, 2, []*ArgType{StringArg, CharMatchArg}, "index of the first character equal to the char or satisfying the predicate, or #f", NewStringIndex()},
+  {"string-starts-with?", 2, 2, []*ArgType{StringArg, StringArg}, "whether the string starts with the prefix", NewStringStartsWith()},
+  {"string-ends-with?", 2, 2, []*ArgType{StringArg, StringArg}, "whether the string ends with the suffix", NewStringEndsWith()},
+  {"type-of", 1, 1, []*ArgType{AnyArg}, "symbol naming the type of the object", NewTypeOf()},
+  {"display", 1, 2, []*ArgType{AnyArg, OutputPortArg}, "print the object, strings and characters as their text, to the port or the current output", NewDisplay()},
+  {"write", 1, 2, []*ArgType{AnyArg, OutputPortArg}, "print the object as it is read, strings quoted with their special characters escaped, to the port or the current output", NewWrite()},
+  {"newline", 0, 1, []*ArgType{OutputPortArg}, "print a line break to the port or the current output", NewNewline()},
+  {"current-output-port", 0, 0, nil, "output port of what the program prints", NewCurrentOutputPort()},
+  {"car", 1, 1, []*ArgType{PairArg}, "first element of the pair", NewCar()},
+  {"cdr", 1, 1, []*ArgType{PairArg}, "second element of the pair", NewCdr()},
+  {"cons", 2, 2, []*ArgType{AnyArg}, "new pair of the two objects", NewCons()},
+  {"make-hash", 0, 2, []*ArgType{ProcedureArg}, "new hash table comparing keys with equal? or the procedure, hashing them with the optional procedure", NewMakeHash()},
+  {"hash-ref", 2, 3, []*ArgType{HashArg, AnyArg}, "value of the key in the hash table, or the default", NewHashRef()},
+  {"hash-set!", 3, 3, []*ArgType{HashArg, AnyArg}, "set the value of the key in the hash table", NewHashSet()},
+  {"hash-count", 1, 1, []*ArgType{HashArg}, "number of keys in the hash table", NewHashCount()},
+  {"make-hash-table", 0, 2, []*ArgType{ProcedureArg}, "same as make-hash", NewMakeHashTable()},
+  {"make-eq-hash-table", 0, 0, nil, "new hash table comparing keys with eq?", NewMakeEqHashTable()},
+  {"hash-remove!", 2, 2, []*ArgType{HashArg, AnyArg}, "remove the key from the hash table, returns whether it was there", NewHashRemove()},
+  {"hash-keys", 1, 1, []*ArgType{HashArg}, "list of the keys of the hash table in the order they were added", NewHashKeys()},
+  {"hash-for-each", 2, 2, []*ArgType{HashArg, ProcedureArg}, "call the procedure with each key and value of the hash table", NewHashForEach()},
+  {"make-chan", 0, 1, []*ArgType{IntegerArg}, "new channel with an optional buffer size", NewMakeChan()},
+  {"close-chan", 1, 1, []*ArgType{ChannelArg}, "close the channel", NewCloseChan("close-chan")},
+  {"chan-close", 1, 1, []*ArgType{ChannelArg}, "close the channel, like close-chan", NewCloseChan("chan-close")},
+  {constants.CHAN_RECV, 1, 1, []*ArgType{ChannelArg}, "receive a value from the channel, or the eof object once it is closed", NewChanRecv()},
+  {"chan-recv-ok", 1, 1, []*ArgType{ChannelArg}, "the value received from the channel and #t, or the eof object and #f once it is closed", NewChanRecvOk()},
+  {constants.CHAN_SEND, 2, 2, []*ArgType{ChannelArg, AnyArg}, "send the value to the channel", NewChanSend()},
+  {"chan-try-send", 2, 2, []*ArgType{ChannelArg, AnyArg}, "send the value if the channel can take it without waiting, returns whether it was sent", NewChanTrySend()},
+  {"chan-try-recv", 1, 1, []*ArgType{ChannelArg}, "(#t . value) with a value ready on the channel, (#f . eof) once it is closed, else (#f . ()) without waiting", NewChanTryRecv()},
+  {constants.SLEEP, 1, 1, []*ArgType{IntegerArg}, "pause for the number of milliseconds", NewSleep(nil)},
+  {constants.RANDOM, 1, 1, []*ArgType{IntegerArg}, "random integer between 0 and the given one", NewRandom()},
+  {"random-bytes", 1, 1, []*ArgType{IntegerArg}, "bytevector of the number of random bytes, from a cryptographically secure source", NewRandomBytes()},
+  {"uuid", 0, 0, nil, "new random version 4 UUID as a string", NewUUID()},
+  {"vector", 0, -1, []*ArgType{AnyArg}, "a new vector of the objects", NewVector()},
+  {"make-vector", 1, 2, []*ArgType{IntegerArg, AnyArg}, "a new vector of the length filled with the object, #f by default", NewMakeVector()},
+  {"vector-length", 1, 1, []*ArgType{VectorArg}, "number of elements in the vector", NewVectorLength()},
+  {"vector-ref", 2, 2, []*ArgType{VectorArg, IntegerArg}, "the element at the index of the vector", NewVectorRef()},
+  {"vector-set!", 3, 3, []*ArgType{VectorArg, IntegerArg, AnyArg}, "store the object at the index of the vector", NewVectorSet()},
+  {"vector-fill!", 2, 2, []*ArgType{VectorArg, AnyArg}, "store the object at every index of the vector", NewVectorFill()},
+  {"vector->list", 1, 1, []*ArgType{VectorArg}, "list of the elements of the vector", NewVectorToList()},
+  {"list->vector", 1, 1, []*ArgType{ListArg}, "a new vector of the elements of the list", NewListToVector()},
+  {"bytevector-length", 1, 1, []*ArgType{BytevectorArg}, "number of bytes in the bytevector", NewBytevectorLength()},
+  {"bytevector-u8-ref", 2, 2, []*ArgType{BytevectorArg, IntegerArg}, "the byte at the index of the bytevector", NewBytevectorRef()},
+  {"make-bitvector", 1, 2, []*ArgType{IntegerArg, BoolArg}, "a new bitvector of the length, its bits set when the fill is #t", NewMakeBitvector()},
+  {"bitvector-length", 1, 1, []*ArgType{BitvectorArg}, "number of bits in the bitvector", NewBitvectorLength()},
+  {"bitvector-ref", 2, 2, []*ArgType{BitvectorArg, IntegerArg}, "whether the bit at the index of the bitvector is set", NewBitvectorRef()},
+  {"bitvector-set!", 3, 3, []*ArgType{BitvectorArg, IntegerArg, BoolArg}, "set the bit at the index of the bitvector, or clear it when given #f", NewBitvectorSet()},
+  {"bitvector-count", 1, 1, []*ArgType{BitvectorArg}, "number of bits set in the bitvector", NewBitvectorCount()},
+  {"bitvector-and", 1, -1, []*ArgType{BitvectorArg}, "a new bitvector of the bits set in all the bitvectors", NewBitvectorOp("bitvector-and", func(x, y uint64) uint64 { return x & y })},
+  {"bitvector-or", 1, -1, []*ArgType{BitvectorArg}, "a new bitvector of the bits set in any of the bitvectors", NewBitvectorOp("bitvector-or", func(x, y uint64) uint64 { return x | y })},
+  {"bitvector-xor", 1, -1, []*ArgType{BitvectorArg}, "a new bitvector of the bits set in an odd number of the bitvectors", NewBitvectorOp("bitvector-xor", func(x, y uint64) uint64 { return x ^ y })},
+  {"bitvector-not", 1, 1, []*ArgType{BitvectorArg}, "a new bitvector of the bits clear in the bitvector", NewBitvectorNot()},
+  {"xml->sxml", 1, 1, []*ArgType{StringArg}, "parse an XML document into SXML", NewXMLToSXML()},
+  {"html->sxml", 1, 1, []*ArgType{StringArg}, "parse an HTML document leniently into SXML", NewHTMLToSXML()},
+  {"sxml->xml", 1, 1, []*ArgType{AnyArg}, "serialize SXML as XML", NewSXMLToXML()},
+  {"sxml-select", 2, 2, []*ArgType{AnyArg, ListArg}, "nodes of an SXML tree at the path", NewSXMLSelect()},
+  {"sxml-text", 1, 1, []*ArgType{AnyArg}, "character data of an SXML node", NewSXMLText()},
+  {"yaml-read", 1, 1, []*ArgType{StringArg}, "parse a YAML document", NewYAMLRead()},
+  {"yaml-write", 1, 1, []*ArgType{AnyArg}, "serialize data as YAML", NewYAMLWrite()},
+  {"toml-read", 1, 1, []*ArgType{StringArg}, "parse a TOML document", NewTOMLRead()},
+  {"toml-write", 1, 1, []*ArgType{AnyArg}, "serialize an alist as TOML", NewTOMLWrite()},
+  {"template", 2, 2, []*ArgType{StringArg, AnyArg}, "fill a {{name}} template from an alist, html escaping values but those of {{{name}}}", NewTemplate()},
+  {"command-line", 0, 0, nil, "script name and arguments", NewCommandLine(nil)},
+  {"argparse", 3, 3, []*ArgType{StringArg, ListArg, ListArg}, "parse command-line arguments against declarations", NewArgParse()},
+  {"argparse-help", 2, 2, []*ArgType{StringArg, ListArg}, "usage text for declarations", NewArgParseHelp()},
+  {"http-get", 1, 1, []*ArgType{StringArg}, "input port streaming the body at the URL", NewHTTPGet(nil)},
+  {"read-line", 1, 1, []*ArgType{InputPortArg}, "next line from the port, or the eof object", NewReadLine()},
+  {"read-char", 1, 1, []*ArgType{InputPortArg}, "next character from the port, or the eof object", NewReadChar(false)},
+  {"peek-char", 1, 1, []*ArgType{InputPortArg}, "next character from the port, left to be read again, or the eof object", NewReadChar(true)},
+  {"read", 1, 1, []*ArgType{InputPortArg}, "next datum from the port as the reader reads it, or the eof object", NewRead(nil)},
+  {"open-input-file", 1, 1, []*ArgType{StringArg}, "input port reading the file", NewOpenInputFile()},
+  {"open-output-file", 1, 1, []*ArgType{StringArg}, "output port writing the file, created or truncated", NewOpenOutputFile()},
+  {"open-input-string", 1, 1, []*ArgType{StringArg}, "input port reading the string", NewOpenInputString()},
+  {"open-output-string", 0, 0, nil, "output port collecting what is written, see get-output-string", NewOpenOutputString()},
+  {"get-output-string", 1, 1, []*ArgType{OutputPortArg}, "what was written so far to the port of open-output-string", NewGetOutputString()},
+  {"for-each-line", 2, 2, []*ArgType{ProcedureArg, PortOrPathArg}, "call the procedure on each line of the port or file as it is read", NewForEachLine()},
+  {"read-file-bytes", 1, 3, []*ArgType{StringArg, IntegerArg}, "count bytes of the file from offset, or the rest of it, as a string, or the eof object past its end", NewReadFileBytes()},
+  {"open-input-gzip-file", 1, 1, []*ArgType{StringArg}, "input port reading the gzip file decompressed", NewOpenGzip()},
+  {"open-input-zlib-file", 1, 1, []*ArgType{StringArg}, "input port reading the zlib file decompressed", NewOpenZlib()},
+  {"archive-entries", 1, 1, []*ArgType{StringArg}, "names of the files in the zip, tar or tar.gz archive", NewArchiveEntries()},
+  {"open-input-archive-entry", 2, 2, []*ArgType{StringArg, StringArg}, "input port reading the named file of the archive", NewOpenArchiveEntry()},
+  {"archive-create", 2, 2, []*ArgType{StringArg, ListArg}, "write a zip, tar or tar.gz archive, by the extension of its name, of the files", NewArchiveCreate()},
+  {"close-port", 1, 1, []*ArgType{PortArg}, "close the port", NewClosePort()},
+  {"ws-connect", 1, 1, []*ArgType{StringArg}, "open a websocket to the URL", NewWSConnect(nil)},
+  {"ws-send!", 2, 2, []*ArgType{WebSocketArg, StringArg}, "send a text message", NewWSSend()},
+  {"ws-recv", 1, 1, []*ArgType{WebSocketArg}, "next message, or the eof object once closed", NewWSRecv(nil)},
+  {"ws-chan", 1, 1, []*ArgType{WebSocketArg}, "channel of incoming messages", NewWSChan()},
+  {"ws-close", 1, 1, []*ArgType{WebSocketArg}, "close the websocket", NewWSClose()},
+  {"apropos", 1, 1, []*ArgType{NameArg}, "bound names containing the string or matching /regexp/", NewApropos(nil)},
+  {"describe", 1, 1, []*ArgType{AnyArg}, "print the type, arity and documentation of the object", NewDescribe()},
+  {"inspect", 1, 1, []*ArgType{AnyArg}, "browse the fields of the object interactively, a level at a time", NewInspect(os.Stdin, Output())},
+  {"freeze!", 1, 1, []*ArgType{EnvironmentArg}, "make every binding of the environment constant", NewFreeze()},
+  {"environment->alist", 1, 1, []*ArgType{EnvironmentArg}, "association list of the bindings of the environment, without those it inherits", NewEnvironmentToAlist()},
+  {"alist->environment!", 2, 2, []*ArgType{EnvironmentArg, ListArg}, "define the bindings of the association list in the environment", NewAlistToEnvironment()},
+  {"environment-diff", 2, 2, []*ArgType{BindingsArg}, "bindings of the second environment which the first lacks or binds to another value", NewEnvironmentDiff()},
+  {"gen-integer", 0, 2, []*ArgType{IntegerArg}, "generator of integers, optionally between two bounds", NewGenInteger()},
+  {"gen-float", 0, 0, nil, "generator of floats", NewGenFloat()},
+  {"gen-boolean", 0, 0, nil, "generator of booleans", NewGenBoolean()},
+  {"gen-string", 0, 0, nil, "generator of printable strings", NewGenString()},
+  {"gen-list", 1, 1, []*ArgType{GeneratorArg}, "generator of lists of the generator's values", NewGenList()},
+  {"gen-elements", 1, 1, []*ArgType{ListArg}, "generator picking elements of the list", NewGenElements()},
+  {"gen-one-of", 1, -1, []*ArgType{GeneratorArg}, "generator picking one of the generators", NewGenOneOf()},
+  {"gen-map", 2, -1, []*ArgType{ProcedureArg, GeneratorArg}, "generator applying the procedure to values of the generators", NewGenMap()},
+  {"gen-such-that", 2, 2, []*ArgType{ProcedureArg, GeneratorArg}, "generator of the values satisfying the predicate", NewGenSuchThat()},
+  {"gen-sample", 1, 1, []*ArgType{GeneratorArg}, "a list of values of the generator", NewGenSample()},
+  {"check-property", 3, 5, []*ArgType{NameArg, GeneratorArg, ProcedureArg, IntegerArg}, "test the predicate against generated values, shrinking failures", NewCheckProperty()},
+  {"contracts-enabled", 0, 1, []*ArgType{BoolArg}, "whether define/contract checks calls, or turn the checks on or off", NewContractsEnabled(nil, nil)},
+  {"display-results", 0, 1, []*ArgType{BoolArg}, "whether the values of top-level forms are printed, or turn the printing on or off", NewDisplayResults(nil, nil)},
+  {"set-max-procs!", 1, 1, []*ArgType{IntegerArg}, "set how many threads run goroutines at once, returning the previous number", NewSetMaxProcs()},
+  {"par-map-isolated", 2, 2, []*ArgType{ProcedureArg, ListArg}, "map the procedure over the list on a worker interpreter per CPU, sharing no scope with the program", NewParMapIsolated(nil)},
+  {"set-go-pool-size!", 1, 1, []*ArgType{IntegerArg}, "bound how many goroutines started by go run at once, 0 for no bound", NewSetGoPoolSize()},
+  {"goroutine-count", 0, 0, nil, "number of goroutines started by go still running", NewGoroutineCount()},
+}
+
+func LookupBuiltin(name string) *Builtin {
+  for _, builtin := range Builtins {
+    if builtin.Name == name {
+      return builtin
+    }
+  }
+  return nil
+}
+
+// copy of the builtin applying proc, for builtins bound to some state
+func (self *Builtin) With(proc PrimFunc) *Builtin {
+  builtin := *self
+  builtin.Proc = proc
+  return &builtin
+}
+
+func (self *Builtin) Apply(args []Value) Value {
+  self.Check(args)
+  return self.Proc.Apply(args)
+}
+
+// a primitive told the source position of the call applying it
+type Located interface {
+  ApplyAt(args []Value, pos string) Value
+}
+
+// a primitive allocating memory in proportion to its arguments, like
+// make-vector, tells how many bytes it is about to allocate, so that
+// the memory limit is checked before anything is
+type Allocating interface {
+  Allocates(args []Value) int64
+}
+
+// the approximate bytes the builtin proc allocates when applied to
+// args, 0 for the others. args aren't checked yet, a length of the
+// wrong type counts as none
+func AllocationSize(proc Value, args []Value) int64 {
+  if builtin, ok := proc.(*Builtin); ok {
+    if allocating, ok := builtin.Proc.(Allocating); ok {
+      return allocating.Allocates(args)
+    }
+  }
+  return 0
+}
+
+// the bytes of the length given as first argument in units of
+// size, without overflowing
+func lengthBytes(args []Value, size int64) int64 {
+  if len(args) == 0 {
+    return 0
+  }
+  k, ok := args[0].(*IntValue)
+  if !ok || k.Value <= 0 {
+    return 0
+  }
+  if k.Value > math.MaxInt64/size {
+    return math.MaxInt64
+  }
+  return k.Value * size
+}
+
+// like Apply, for a call at pos
+func (self *Builtin) ApplyAt(args []Value, pos string) Value {
+  self.CheckAt(args, pos)
+  if proc, ok := self.Proc.(Located); ok {
+    return proc.ApplyAt(args, pos)
+  }
+  return self.Proc.Apply(args)
+}
+
+func (self *Builtin) Check(args []Value) {
+  self.CheckAt(args, "")
+}
+
+// like Check, the errors raised tell the position of the call
+func (self *Builtin) CheckAt(args []Value, pos string) {
+  if len(args) < self.Min || (self.Max >= 0 && len(args) > self.Max) {
+    panic(&ArityError{fmt.Sprintf("%s: arguments mismatch, expected %s, given: %d", self.Name, self.Arity(), len(args)), pos})
+  }
+  for i, arg := range args {
+    argType := self.Args[len(self.Args)-1]
+    if i < len(self.Args) {
+      argType = self.Args[i]
+    }
+    if !argType.Check(arg) {
+      panic(&TypeError{fmt.Sprintf("%s: expected %s, given: %s", self.Name, argType.Name, WrittenString(arg)), pos})
+    }
+  }
+}
+
+func (self *Builtin) Arity() string {
+  switch {
+  case self.Max < 0:
+    return fmt.Sprintf("at least %d", self.Min)
+  case self.Min == self.Max:
+    return fmt.Sprint(self.Min)
+  default:
+    return fmt.Sprintf("%d to %d", self.Min, self.Max)
+  }
+}
+
+// e.g. (make-chan [integer]) or (+ number ...)
+func (self *Builtin) Signature() string {
+  signature := "(" + self.Name
+  for i := 0; i < self.Min || (i < self.Max && i < len(self.Args)); i++ {
+    name := self.Args[len(self.Args)-1].Name
+    if i < len(self.Args) {
+      name = self.Args[i].Name
+    }
+    if i >= self.Min {
+      name = "[" + name + "]"
+    }
+    signature += " " + name
+  }
+  if self.Max < 0 {
+    signature += " " + self.Args[len(self.Args)-1].Name + " ..."
+  }
+  return signature + ")"
+}
+
+func (self *Builtin) String() string {
+  return self.Name
+}
+
+// reference of all builtins in Markdown
+func Documentation() string {
+  var doc []string
+  for _, builtin := range Builtins {
+    doc = append(doc, fmt.Sprintf("- `%s`: %s", builtin.Signature(), builtin.Doc))
+  }
+  return strings.Join(doc, "\n") + "\n"
+}
@@ -1,9 +1,9 @@
 package primitives
 
 import (
-  "fmt"
   "github.com/kedebug/LispEx/constants"
   "github.com/kedebug/LispEx/value"
+  "reflect"
 )
 
 // receive from channel
@@ -16,12 +16,40 @@ func NewChanRecv() *ChanRecv {
 }
 
 func (self *ChanRecv) Apply(args []value.Value) value.Value {
-  if len(args) != 1 {
-    panic(fmt.Sprintf("%s: arguments mismatch, expected 1", constants.CHAN_RECV))
+  return self.ApplyAt(args, "")
+}
+
+func (self *ChanRecv) ApplyAt(args []value.Value, pos string) value.Value {
+  channel := args[0].(*value.Channel)
+  recv := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.Value)}
+  _, val, ok := value.BlockingSelect([]reflect.SelectCase{recv}, channel.Local, site(constants.CHAN_RECV, pos))
+  if !ok {
+    return value.EOF
   }
-  if channel, ok := args[0].(*value.Channel); ok {
-    return <-channel.Value
-  } else {
-    panic(fmt.Sprintf("incorrect argument type for `%s', expected: channel, given: %s", constants.CHAN_RECV, args[0]))
+  return val.Interface().(value.Value)
+}
+
+// (chan-recv-ok c) receives like <-chan and returns two values, as Go's
+// v, ok := <-c does: the value and #t, or the eof object and #f once c
+// is closed, telling a closed channel from one which sent the eof object
+type ChanRecvOk struct {
+  value.Primitive
+}
+
+func NewChanRecvOk() *ChanRecvOk {
+  return &ChanRecvOk{value.Primitive{"chan-recv-ok"}}
+}
+
+func (self *ChanRecvOk) Apply(args []value.Value) value.Value {
+  return self.ApplyAt(args, "")
+}
+
+func (self *ChanRecvOk) ApplyAt(args []value.Value, pos string) value.Value {
+  channel := args[0].(*value.Channel)
+  recv := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.Value)}
+  _, val, ok := value.BlockingSelect([]reflect.SelectCase{recv}, channel.Local, site(self.Name, pos))
+  if !ok {
+    return value.NewMultipleValues([]value.Value{value.EOF, value.NewBoolValue(false)})
   }
+  return value.NewMultipleValues([]value.Value{val.Interface().(value.Value), value.NewBoolValue(true)})
 }
@@ -1,9 +1,9 @@
 package primitives
 
 import (
-  "fmt"
   "github.com/kedebug/LispEx/constants"
   "github.com/kedebug/LispEx/value"
+  "reflect"
 )
 
 // send to channel
@@ -16,13 +16,21 @@ func NewChanSend() *ChanSend {
 }
 
 func (self *ChanSend) Apply(args []value.Value) value.Value {
-  if len(args) != 2 {
-    panic(fmt.Sprintf("%s: arguments mismatch, expected 2", constants.CHAN_SEND))
-  }
-  if channel, ok := args[0].(*value.Channel); ok {
-    channel.Value <- args[1]
-  } else {
-    panic(fmt.Sprintf("incorrect argument type for `%s', expected: channel, given: %s", constants.CHAN_SEND, args[0]))
-  }
+  return self.ApplyAt(args, "")
+}
+
+func (self *ChanSend) ApplyAt(args []value.Value, pos string) value.Value {
+  channel := args[0].(*value.Channel)
+  defer value.CheckSendOnClosed(site(constants.CHAN_SEND, pos))
+  send := reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(channel.Value), Send: reflect.ValueOf(args[1])}
+  value.BlockingSelect([]reflect.SelectCase{send}, channel.Local, site(constants.CHAN_SEND, pos))
   return nil
 }
+
+// e.g. "<-chan at ping.ss:3"
+func site(name, pos string) string {
+  if pos == "" {
+    return name
+  }
+  return name + " at " + pos
+}
@@ -0,0 +1,48 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (chan-try-send c x) sends x only if a receiver is waiting or
+// the buffer of c has room, like a select with a default clause,
+// and returns whether it did
+type ChanTrySend struct {
+  Primitive
+}
+
+func NewChanTrySend() *ChanTrySend {
+  return &ChanTrySend{Primitive{"chan-try-send"}}
+}
+
+func (self *ChanTrySend) Apply(args []Value) Value {
+  defer CheckSendOnClosed(self.Name)
+  select {
+  case args[0].(*Channel).Value <- args[1]:
+    return NewBoolValue(true)
+  default:
+    return NewBoolValue(false)
+  }
+}
+
+// (chan-try-recv c) returns (#t . x) when a value x is ready on c,
+// (#f . eof) once c is closed and (#f . ()) without waiting otherwise
+type ChanTryRecv struct {
+  Primitive
+}
+
+func NewChanTryRecv() *ChanTryRecv {
+  return &ChanTryRecv{Primitive{"chan-try-recv"}}
+}
+
+func (self *ChanTryRecv) Apply(args []Value) Value {
+  select {
+  case val, ok := <-args[0].(*Channel).Value:
+    if ok {
+      return NewPairValue(NewBoolValue(true), val)
+    }
+    return NewPairValue(NewBoolValue(false), EOF)
+  default:
+  }
+  return NewPairValue(NewBoolValue(false), NilPairValue)
+}
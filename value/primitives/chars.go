@@ -0,0 +1,83 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "unicode"
+  "unicode/utf8"
+)
+
+// (char-upcase c), (char-downcase c): c mapped to another character
+type CharMap struct {
+  Primitive
+  mapping func(rune) rune
+}
+
+func NewCharMap(name string, mapping func(rune) rune) *CharMap {
+  return &CharMap{Primitive{name}, mapping}
+}
+
+func (self *CharMap) Apply(args []Value) Value {
+  return NewCharValue(self.mapping(args[0].(*CharValue).Value))
+}
+
+// a predicate of characters for NewTypePredicate, the
+// builtin checks its argument is a character first
+func charIs(class func(rune) bool) func(Value) bool {
+  return func(val Value) bool {
+    return class(val.(*CharValue).Value)
+  }
+}
+
+// (char->integer #\A) is 65, the Unicode code point
+type CharToInteger struct {
+  Primitive
+}
+
+func NewCharToInteger() *CharToInteger {
+  return &CharToInteger{Primitive{"char->integer"}}
+}
+
+func (self *CharToInteger) Apply(args []Value) Value {
+  return NewIntValue(int64(args[0].(*CharValue).Value))
+}
+
+// (integer->char 955) is #\λ, surrogates are no characters
+type IntegerToChar struct {
+  Primitive
+}
+
+func NewIntegerToChar() *IntegerToChar {
+  return &IntegerToChar{Primitive{"integer->char"}}
+}
+
+func (self *IntegerToChar) Apply(args []Value) Value {
+  code := args[0].(*IntValue).Value
+  if code < 0 || code > unicode.MaxRune || !utf8.ValidRune(rune(code)) {
+    panic(fmt.Sprint("integer->char: not the code of a character: ", args[0]))
+  }
+  return NewCharValue(rune(code))
+}
+
+// (digit-value #\7) is 7, #f for characters which aren't decimal digits
+type DigitValue struct {
+  Primitive
+}
+
+func NewDigitValue() *DigitValue {
+  return &DigitValue{Primitive{"digit-value"}}
+}
+
+func (self *DigitValue) Apply(args []Value) Value {
+  r := args[0].(*CharValue).Value
+  if !unicode.IsDigit(r) {
+    return NewBoolValue(false)
+  }
+  // decimal digits come in runs of ten from zero in Unicode,
+  // some of them adjacent like the mathematical digits
+  start := r
+  for unicode.IsDigit(start - 1) {
+    start--
+  }
+  return NewIntValue(int64((r - start) % 10))
+}
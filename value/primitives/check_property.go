@@ -0,0 +1,42 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/quickcheck"
+  . "github.com/kedebug/LispEx/value"
+  "time"
+)
+
+// (check-property name gen prop [tests [seed]]) calls prop with
+// values of gen, 100 unless told otherwise. prints a report and
+// returns whether prop held; a failure is reported with the smallest
+// counterexample found by shrinking and the seed reproducing it
+type CheckProperty struct {
+  Primitive
+}
+
+func NewCheckProperty() *CheckProperty {
+  return &CheckProperty{Primitive{"check-property"}}
+}
+
+func (self *CheckProperty) Apply(args []Value) Value {
+  generator := args[1].(*quickcheck.Generator)
+  tests, seed := int64(100), time.Now().UnixNano()
+  if len(args) > 3 {
+    tests = args[3].(*IntValue).Value
+  }
+  if len(args) > 4 {
+    seed = args[4].(*IntValue).Value
+  }
+  prop := args[2]
+  result := quickcheck.Check(generator, func(val Value) Value {
+    return Invoke(prop, []Value{val})
+  }, int(tests), seed)
+
+  name := args[0]
+  if str, ok := name.(*StringValue); ok {
+    name = NewSymbol(str.Value)
+  }
+  fmt.Fprint(Output(), result.Report(name.String()))
+  return NewBoolValue(result.Passed)
+}
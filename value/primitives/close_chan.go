@@ -5,22 +5,19 @@ import (
   "github.com/kedebug/LispEx/value"
 )
 
+// (close-chan c), also named chan-close as in Go's close(c):
+// receivers get the values left in c, then the eof object
 type CloseChan struct {
   value.Primitive
 }
 
-func NewCloseChan() *CloseChan {
-  return &CloseChan{value.Primitive{"close-chan"}}
+func NewCloseChan(name string) *CloseChan {
+  return &CloseChan{value.Primitive{name}}
 }
 
 func (self *CloseChan) Apply(args []value.Value) value.Value {
-  if len(args) != 1 {
-    panic(fmt.Sprint("close-chan: arguments mismatch, expected 1"))
-  }
-  if channel, ok := args[0].(*value.Channel); ok {
-    close(channel.Value)
-    return nil
-  } else {
-    panic(fmt.Sprint("incorrect argument type for `close-chan', expected: channel, given: ", args[0]))
+  if !args[0].(*value.Channel).Close() {
+    panic(fmt.Sprintf("%s: channel already closed", self.Name))
   }
+  return nil
 }
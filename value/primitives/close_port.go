@@ -0,0 +1,21 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type ClosePort struct {
+  Primitive
+}
+
+func NewClosePort() *ClosePort {
+  return &ClosePort{Primitive{"close-port"}}
+}
+
+func (self *ClosePort) Apply(args []Value) Value {
+  if err := args[0].(*Port).Close(); err != nil {
+    panic(fmt.Sprint("close-port: ", err))
+  }
+  return nil
+}
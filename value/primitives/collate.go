@@ -0,0 +1,69 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+  "unicode"
+)
+
+// accented latin letters decomposed into their base letter and accent,
+// by accent: the letters with it, then the base letters in the same order
+var accents = [][2]string{
+  {"ÀÈÌÒÙàèìòù", "AEIOUaeiou"},
+  {"ÁÉÍÓÚÝáéíóúýĆćĹĺŃńŔŕŚśŹź", "AEIOUYaeiouyCcLlNnRrSsZz"},
+  {"ÂÊÎÔÛâêîôûĈĉĜĝĤĥĴĵŜŝŴŵŶŷ", "AEIOUaeiouCcGgHhJjSsWwYy"},
+  {"ÃÑÕãñõĨĩŨũ", "ANOanoIiUu"},
+  {"ÄËÏÖÜäëïöüÿŸ", "AEIOUaeiouyY"},
+  {"ÅåŮů", "AaUu"},
+  {"ÇçĢģĶķĻļŅņŖŗŞşŢţ", "CcGgKkLlNnRrSsTt"},
+  {"ČčĎďĚěŇňŘřŠšŤťŽž", "CcDdEeNnRrSsTtZz"},
+  {"ĀāĒēĪīŌōŪū", "AaEeIiOoUu"},
+  {"ĂăĔĕĞğĬĭŎŏŬŭ", "AaEeGgIiOoUu"},
+  {"ĄąĘęĮįŲų", "AaEeIiUu"},
+  {"ĊċĖėĠġİŻż", "CcEeGgIZz"},
+  {"ŐőŰű", "OoUu"},
+  {"ØøĐđĦħŁł", "OoDdHhLl"},
+}
+
+type decomposition struct {
+  base   rune
+  accent rune
+}
+
+var decompositions = func() map[rune]decomposition {
+  table := make(map[rune]decomposition)
+  for i, accent := range accents {
+    bases := []rune(accent[1])
+    for j, r := range []rune(accent[0]) {
+      table[r] = decomposition{bases[j], rune(i + 2)}
+    }
+  }
+  return table
+}()
+
+// sort key comparing strings the way people expect, like a dictionary:
+// first by their letters ignoring accents and case, then by accents,
+// then by case with lower case first. each level is separated by a zero
+// byte, which sorts before any character, so that a shorter level ends
+// the comparison before the next level starts
+func collationKey(val Value) string {
+  var letters, marks, cases strings.Builder
+  for _, r := range val.(*StringValue).Value {
+    accent := rune(1)
+    if d, ok := decompositions[r]; ok {
+      r, accent = d.base, d.accent
+    }
+    if folded, ok := fullFolds[r]; ok {
+      letters.WriteString(folded)
+    } else {
+      letters.WriteRune(foldRune(r))
+    }
+    marks.WriteRune(accent)
+    if unicode.IsUpper(r) {
+      cases.WriteRune(2)
+    } else {
+      cases.WriteRune(1)
+    }
+  }
+  return letters.String() + "\x00" + marks.String() + "\x00" + cases.String()
+}
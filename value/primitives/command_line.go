@@ -0,0 +1,24 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// the script name followed by its arguments
+type CommandLine struct {
+  Primitive
+  args []string
+}
+
+func NewCommandLine(args []string) *CommandLine {
+  return &CommandLine{Primitive{"command-line"}, args}
+}
+
+func (self *CommandLine) Apply(args []Value) Value {
+  words := make([]Value, len(self.args))
+  for i, arg := range self.args {
+    words[i] = NewStringValue(arg)
+  }
+  return converter.SliceToPairValues(words)
+}
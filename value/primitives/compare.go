@@ -0,0 +1,76 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/number"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+)
+
+// (char<? a b c ...), (string=? a b ...) and the like: whether every
+// two neighbouring arguments are ordered, comparing them by their key
+type Comparison struct {
+  Primitive
+  key     func(Value) string
+  ordered func(int) bool
+}
+
+func NewComparison(name string, key func(Value) string, ordered func(int) bool) *Comparison {
+  return &Comparison{Primitive{name}, key, ordered}
+}
+
+func (self *Comparison) Apply(args []Value) Value {
+  for i := 1; i < len(args); i++ {
+    if !self.ordered(strings.Compare(self.key(args[i-1]), self.key(args[i]))) {
+      return NewBoolValue(false)
+    }
+  }
+  return NewBoolValue(true)
+}
+
+// (< a b c ...) and the other comparisons of numbers: whether every
+// two neighbouring numbers are ordered, never when one is a NaN
+func numbersOrdered(name string, args []Value, ordered func(int) bool) Value {
+  for i := 1; i < len(args); i++ {
+    if result, ok := number.Compare(args[i-1], args[i]); !ok || !ordered(result) {
+      return NewBoolValue(false)
+    }
+  }
+  return NewBoolValue(true)
+}
+
+// utf-8 strings compare like their sequences of code points
+func charKey(val Value) string {
+  return string(val.(*CharValue).Value)
+}
+
+func charKeyCI(val Value) string {
+  return string(foldRune(val.(*CharValue).Value))
+}
+
+func stringKey(val Value) string {
+  return val.(*StringValue).Value
+}
+
+func stringKeyCI(val Value) string {
+  return foldString(stringKey(val))
+}
+
+func same(c int) bool {
+  return c == 0
+}
+
+func ascending(c int) bool {
+  return c < 0
+}
+
+func descending(c int) bool {
+  return c > 0
+}
+
+func nonDescending(c int) bool {
+  return c <= 0
+}
+
+func nonAscending(c int) bool {
+  return c >= 0
+}
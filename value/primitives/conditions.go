@@ -0,0 +1,196 @@
+package primitives
+
+import (
+  "errors"
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (raise obj) raises obj as an error: the handler installed by the
+// innermost with-exception-handler is called with it where it is
+// raised, and an error is raised again if the handler returns. within
+// a guard, or without a handler, the guard or the caller of the
+// program get obj back as it is. conditions caught are raised again
+// with their position and backtrace
+type Raise struct {
+  Primitive
+  continuable bool
+}
+
+func NewRaise() *Raise {
+  return &Raise{Primitive{"raise"}, false}
+}
+
+// (raise-continuable obj) returns what the handler returns
+func NewRaiseContinuable() *Raise {
+  return &Raise{Primitive{"raise-continuable"}, true}
+}
+
+func (self *Raise) Apply(args []Value) Value {
+  return self.ApplyAt(args, "")
+}
+
+func (self *Raise) ApplyAt(args []Value, pos string) Value {
+  if condition, ok := args[0].(*Condition); ok {
+    return raiseIn(CurrentDynamic().Handlers, condition, condition, self.continuable)
+  }
+  return raiseIn(CurrentDynamic().Handlers, args[0], &Raised{args[0], pos}, self.continuable)
+}
+
+// offer obj, raised as err, to the innermost of handlers: it is called
+// with the handlers around it installed, and its value is that of a
+// continuable raise. for a raise which isn't, a handler returning
+// raises an error to the handlers around it. err is raised for the
+// guard at the top of handlers, or for the caller when there is none
+func raiseIn(handlers *Handler, obj Value, err error, continuable bool) Value {
+  if handlers == nil || handlers.Proc == nil {
+    panic(err)
+  }
+  dynamic := CurrentDynamic()
+  dynamic.Handlers = handlers.Outer
+  result := WithDynamic(dynamic, func() Value {
+    defer offerSignaled(handlers.Outer)
+    return Invoke(handlers.Proc, []Value{obj})
+  })
+  if continuable {
+    return result
+  }
+  condition := NewCondition("raise", "handler returned from a non-continuable exception:", []Value{obj})
+  return raiseIn(handlers.Outer, condition, condition, false)
+}
+
+// deferred where handlers are installed, offers the errors signaled
+// by builtins or the interpreter rather than raised by raise or error
+// to the innermost of handlers, once they have left the code the
+// handlers were installed for. being signaled, they can't be continued
+func offerSignaled(handlers *Handler) {
+  e := recover()
+  if e == nil {
+    return
+  }
+  obj, ok := ConditionOf(e)
+  // with no handler left the error goes on as it is, with its position
+  if !ok || raised(e) || handlers == nil || handlers.Proc == nil {
+    panic(e)
+  }
+  condition, ok := obj.(*Condition)
+  if !ok {
+    panic(e)
+  }
+  raiseIn(handlers, condition, condition, false)
+}
+
+// whether e was raised by raise or error, and so
+// already offered to the handlers installed then
+func raised(e interface{}) bool {
+  err, ok := e.(error)
+  if !ok {
+    return false
+  }
+  var raised *Raised
+  var condition *Condition
+  return errors.As(err, &raised) || errors.As(err, &condition)
+}
+
+// (error message irritant...) or, naming the procedure
+// raising it, (error 'who message irritant...)
+type RaiseError struct {
+  Primitive
+}
+
+func NewRaiseError() *RaiseError {
+  return &RaiseError{Primitive{"error"}}
+}
+
+func (self *RaiseError) Apply(args []Value) Value {
+  return self.ApplyAt(args, "")
+}
+
+func (self *RaiseError) ApplyAt(args []Value, pos string) Value {
+  var who string
+  if symbol, ok := args[0].(*Symbol); ok && len(args) > 1 {
+    who, args = symbol.Value, args[1:]
+  }
+  message, ok := args[0].(*StringValue)
+  if !ok {
+    panic(fmt.Sprint("error: expected string, given: ", args[0]))
+  }
+  condition := NewCondition(who, message.Value, append([]Value(nil), args[1:]...))
+  condition.Pos = pos
+  return raiseIn(CurrentDynamic().Handlers, condition, condition, false)
+}
+
+// (with-exception-handler handler thunk) returns the value of thunk,
+// with handler installed while it runs: raise calls it with the object
+// raised. the errors of builtins are given to it as conditions once
+// they have left thunk
+type WithExceptionHandler struct {
+  Primitive
+}
+
+func NewWithExceptionHandler() *WithExceptionHandler {
+  return &WithExceptionHandler{Primitive{"with-exception-handler"}}
+}
+
+func (self *WithExceptionHandler) Apply(args []Value) Value {
+  dynamic := CurrentDynamic()
+  dynamic.Handlers = &Handler{args[0], dynamic.Handlers}
+  defer offerSignaled(dynamic.Handlers)
+  return WithDynamic(dynamic, func() Value { return Invoke(args[1], nil) })
+}
+
+// (call-with-guard handler thunk) returns the value of thunk, or if it
+// raises an error, what handler returns given the object raised, once
+// the error has left thunk. guard is defined with it
+type CallWithGuard struct {
+  Primitive
+}
+
+func NewCallWithGuard() *CallWithGuard {
+  return &CallWithGuard{Primitive{"call-with-guard"}}
+}
+
+func (self *CallWithGuard) Apply(args []Value) (result Value) {
+  dynamic := CurrentDynamic()
+  dynamic.Handlers = &Handler{nil, dynamic.Handlers}
+  defer func() {
+    if e := recover(); e != nil {
+      obj, ok := ConditionOf(e)
+      if !ok {
+        panic(e)
+      }
+      result = Invoke(args[0], []Value{obj})
+    }
+  }()
+  return WithDynamic(dynamic, func() Value { return Invoke(args[1], nil) })
+}
+
+// an accessor of conditions
+type ConditionField struct {
+  Primitive
+  field func(*Condition) Value
+}
+
+func NewConditionField(name string, field func(*Condition) Value) *ConditionField {
+  return &ConditionField{Primitive{name}, field}
+}
+
+func (self *ConditionField) Apply(args []Value) Value {
+  return self.field(args[0].(*Condition))
+}
+
+func conditionWho(condition *Condition) Value {
+  if condition.Who == "" {
+    return NewBoolValue(false)
+  }
+  return NewSymbol(condition.Who)
+}
+
+func conditionMessage(condition *Condition) Value {
+  return NewStringValue(condition.Message)
+}
+
+func conditionIrritants(condition *Condition) Value {
+  return converter.SliceToPairValues(condition.Irritants)
+}
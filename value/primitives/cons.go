@@ -1,7 +1,6 @@
 package primitives
 
 import (
-  "fmt"
   "github.com/kedebug/LispEx/value"
 )
 
@@ -14,8 +13,5 @@ func NewCons() *Cons {
 }
 
 func (self *Cons) Apply(args []value.Value) value.Value {
-  if len(args) != 2 {
-    panic(fmt.Sprintf("cons: arguments mismatch, expected: 2, given: ", args))
-  }
   return value.NewPairValue(args[0], args[1])
 }
@@ -0,0 +1,25 @@
+package primitives
+
+import (
+  "context"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// I/O builtins run under the context returned by a function bound
+// for each root scope, so that embedders can impose deadlines on the
+// I/O started by scripts or cancel it. nil stands for no deadline
+func currentContext(context func() context.Context) context.Context {
+  if context == nil {
+    return contextBackground
+  }
+  return context()
+}
+
+var contextBackground = context.Background()
+
+// errors of I/O builtins wrap the go error, to be told apart
+// with errors.Is(err, context.DeadlineExceeded) for instance
+func raiseIOError(name string, err error) {
+  panic(&Error{fmt.Sprint(name, ": ", err), err})
+}
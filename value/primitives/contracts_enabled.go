@@ -0,0 +1,25 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (contracts-enabled) or (contracts-enabled #f), the setting
+// is kept by the root scope of the interpreter
+type ContractsEnabledPrimitive struct {
+  Primitive
+  get func() bool
+  set func(bool)
+}
+
+func NewContractsEnabled(get func() bool, set func(bool)) *ContractsEnabledPrimitive {
+  return &ContractsEnabledPrimitive{Primitive{"contracts-enabled"}, get, set}
+}
+
+func (self *ContractsEnabledPrimitive) Apply(args []Value) Value {
+  if len(args) == 1 {
+    self.set(args[0].(*BoolValue).Value)
+    return nil
+  }
+  return NewBoolValue(self.get())
+}
@@ -0,0 +1,80 @@
+package primitives
+
+import (
+  "crypto/rand"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// random bytes from crypto/rand, fit for identifiers and secrets
+func cryptoRandom(name string, n int64) []byte {
+  if n < 0 {
+    panic(fmt.Sprint(name, ": negative count: ", n))
+  }
+  buf := make([]byte, n)
+  if _, err := rand.Read(buf); err != nil {
+    raiseIOError(name, err)
+  }
+  return buf
+}
+
+type RandomBytes struct {
+  Primitive
+}
+
+func NewRandomBytes() *RandomBytes {
+  return &RandomBytes{Primitive{"random-bytes"}}
+}
+
+func (self *RandomBytes) Apply(args []Value) Value {
+  return NewBytevector(cryptoRandom("random-bytes", args[0].(*IntValue).Value))
+}
+
+func (self *RandomBytes) Allocates(args []Value) int64 {
+  return lengthBytes(args, 1)
+}
+
+// (uuid) is a random version 4 UUID as in RFC 4122,
+// e.g. "0d8e6bb2-5ec4-4f7b-9c1a-3b58e41c07f2"
+type UUID struct {
+  Primitive
+}
+
+func NewUUID() *UUID {
+  return &UUID{Primitive{"uuid"}}
+}
+
+func (self *UUID) Apply(args []Value) Value {
+  b := cryptoRandom("uuid", 16)
+  b[6] = b[6]&0x0f | 0x40
+  b[8] = b[8]&0x3f | 0x80
+  return NewStringValue(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+}
+
+type BytevectorLength struct {
+  Primitive
+}
+
+func NewBytevectorLength() *BytevectorLength {
+  return &BytevectorLength{Primitive{"bytevector-length"}}
+}
+
+func (self *BytevectorLength) Apply(args []Value) Value {
+  return NewIntValue(int64(len(args[0].(*Bytevector).Value)))
+}
+
+type BytevectorRef struct {
+  Primitive
+}
+
+func NewBytevectorRef() *BytevectorRef {
+  return &BytevectorRef{Primitive{"bytevector-u8-ref"}}
+}
+
+func (self *BytevectorRef) Apply(args []Value) Value {
+  bytes, k := args[0].(*Bytevector).Value, args[1].(*IntValue).Value
+  if k < 0 || k >= int64(len(bytes)) {
+    panic(fmt.Sprintf("bytevector-u8-ref: index %d out of bounds for a bytevector of length %d", k, len(bytes)))
+  }
+  return NewIntValue(int64(bytes[k]))
+}
@@ -0,0 +1,58 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+)
+
+type Describe struct {
+  Primitive
+}
+
+func NewDescribe() *Describe {
+  return &Describe{Primitive{"describe"}}
+}
+
+func (self *Describe) Apply(args []Value) Value {
+  fmt.Fprint(Output(), DescribeValue(args[0]))
+  return nil
+}
+
+func DescribeValue(val Value) string {
+  kind := NewTypeOf().Apply([]Value{val})
+  switch val.(type) {
+  case *Closure:
+    text := fmt.Sprintf("%s: compound procedure\n", kind)
+    if signature, ok := val.(*Closure).Body.(Signature); ok {
+      required, variadic := signature.Arity()
+      if variadic {
+        text += fmt.Sprintf("  arity: at least %d\n", required)
+      } else {
+        text += fmt.Sprintf("  arity: %d\n", required)
+      }
+      if doc := signature.Doc(); doc != "" {
+        text += fmt.Sprintf("  doc: %s\n", doc)
+      }
+      if pos := signature.Location(); pos != "" {
+        text += fmt.Sprintf("  defined at: %s\n", pos)
+      }
+    }
+    return text
+  case *Contract:
+    contract := val.(*Contract)
+    text := DescribeValue(contract.Proc)
+    contracts := append(append([]string{}, contract.DomainNames...), contract.RangeName)
+    return text + fmt.Sprintf("  contract: (-> %s)\n", strings.Join(contracts, " "))
+  case *Builtin:
+    builtin := val.(*Builtin)
+    text := fmt.Sprintf("%s: builtin procedure `%s'\n", kind, val)
+    text += fmt.Sprintf("  arity: %s\n", builtin.Arity())
+    text += fmt.Sprintf("  signature: %s\n", builtin.Signature())
+    return text + fmt.Sprintf("  doc: %s\n", builtin.Doc)
+  case PrimFunc:
+    return fmt.Sprintf("%s: builtin procedure `%s'\n", kind, val)
+  default:
+    return fmt.Sprintf("%s: %s\n", kind, val)
+  }
+}
@@ -1,8 +1,8 @@
 package primitives
 
 import (
-  "fmt"
   . "github.com/kedebug/LispEx/value"
+  "io"
 )
 
 type Display struct {
@@ -14,9 +14,33 @@ func NewDisplay() *Display {
 }
 
 func (self *Display) Apply(args []Value) Value {
-  if len(args) != 1 {
-    panic(fmt.Sprint("display: argument mismatch, expected 1"))
-  }
-  fmt.Print(args[0])
+  writeTo(self.Name, args[1:], DisplayString(args[0]))
   return nil
 }
+
+// (write obj) prints obj as it is read back: strings are quoted
+// and escaped, characters written as #\a
+type Write struct {
+  Primitive
+}
+
+func NewWrite() *Write {
+  return &Write{Primitive{"write"}}
+}
+
+func (self *Write) Apply(args []Value) Value {
+  writeTo(self.Name, args[1:], WrittenString(args[0]))
+  return nil
+}
+
+// write text to the output port among args, the current output if
+// there is none. errors of the port, like a closed file, are raised
+func writeTo(name string, args []Value, text string) {
+  writer := Output()
+  if len(args) > 0 {
+    writer = args[0].(*Port).Writer
+  }
+  if _, err := io.WriteString(writer, text); err != nil {
+    raiseIOError(name, err)
+  }
+}
@@ -0,0 +1,25 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (display-results) or (display-results #t), the setting
+// is kept by the root scope of the interpreter
+type DisplayResults struct {
+  Primitive
+  get func() bool
+  set func(bool)
+}
+
+func NewDisplayResults(get func() bool, set func(bool)) *DisplayResults {
+  return &DisplayResults{Primitive{"display-results"}, get, set}
+}
+
+func (self *DisplayResults) Apply(args []Value) Value {
+  if len(args) == 1 {
+    self.set(args[0].(*BoolValue).Value)
+    return nil
+  }
+  return NewBoolValue(self.get())
+}
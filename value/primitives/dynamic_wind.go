@@ -0,0 +1,23 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (dynamic-wind before thunk after) calls before, thunk and after,
+// returning the value of thunk. after is called however control
+// leaves thunk: when it returns, or when an error raised in it, a
+// deadlock of a channel operation included, unwinds out of it
+type DynamicWind struct {
+  Primitive
+}
+
+func NewDynamicWind() *DynamicWind {
+  return &DynamicWind{Primitive{"dynamic-wind"}}
+}
+
+func (self *DynamicWind) Apply(args []Value) Value {
+  Invoke(args[0], nil)
+  defer Invoke(args[2], nil)
+  return Invoke(args[1], nil)
+}
@@ -0,0 +1,43 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// procedures of an enum type named color: (color? obj),
+// (color->symbol c) and (symbol->color 'red), #f for no member
+func EnumBuiltins(et *EnumType) []*Builtin {
+  enumArg := &ArgType{et.Name, func(val Value) bool {
+    enum, ok := val.(*Enum)
+    return ok && enum.Type == et
+  }}
+
+  predicate := et.Name + "?"
+  toSymbol := et.Name + "->symbol"
+  fromSymbol := "symbol->" + et.Name
+  return []*Builtin{
+    {predicate, 1, 1, []*ArgType{AnyArg}, "whether the object is a " + et.Name, NewTypePredicate(predicate, enumArg.Check)},
+    {toSymbol, 1, 1, []*ArgType{enumArg}, "the name of the " + et.Name, &EnumToSymbol{Primitive{toSymbol}}},
+    {fromSymbol, 1, 1, []*ArgType{SymbolArg}, "the " + et.Name + " named by the symbol, or #f", &SymbolToEnum{Primitive{fromSymbol}, et}},
+  }
+}
+
+type EnumToSymbol struct {
+  Primitive
+}
+
+func (self *EnumToSymbol) Apply(args []Value) Value {
+  return NewSymbol(args[0].(*Enum).Name)
+}
+
+type SymbolToEnum struct {
+  Primitive
+  et *EnumType
+}
+
+func (self *SymbolToEnum) Apply(args []Value) Value {
+  if member := self.et.Member(args[0].(*Symbol).Value); member != nil {
+    return member
+  }
+  return NewBoolValue(false)
+}
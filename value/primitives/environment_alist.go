@@ -0,0 +1,125 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// the methods of a scope used to export and import its bindings,
+// the scope package depends on this one
+type bindings interface {
+  LocalNames() []string
+  LookupLocal(name string) interface{}
+  IsConstant(name string) bool
+  Audit(form, name string, val interface{}, pos string)
+  Put(name string, value interface{})
+}
+
+// (environment->alist (the-environment)) lists the bindings defined in
+// the environment as ((name . value) ...) sorted by name. at the top
+// level of the REPL these are the user's definitions, without the
+// builtins and the standard library bound in the enclosing scope
+type EnvironmentToAlist struct {
+  Primitive
+}
+
+func NewEnvironmentToAlist() *EnvironmentToAlist {
+  return &EnvironmentToAlist{Primitive{"environment->alist"}}
+}
+
+func (self *EnvironmentToAlist) Apply(args []Value) Value {
+  return environmentAlist(args[0].(*Environment))
+}
+
+func environmentAlist(env *Environment) Value {
+  scope := env.Scope.(bindings)
+  var entries []Value
+  for _, name := range scope.LocalNames() {
+    if val, ok := scope.LookupLocal(name).(Value); ok {
+      entries = append(entries, NewPairValue(NewSymbol(name), val))
+    }
+  }
+  return converter.SliceToPairValues(entries)
+}
+
+// (alist->environment! env alist) defines each (name . value) of alist
+// in env like define does, restoring bindings saved by environment->alist
+type AlistToEnvironment struct {
+  Primitive
+}
+
+func NewAlistToEnvironment() *AlistToEnvironment {
+  return &AlistToEnvironment{Primitive{"alist->environment!"}}
+}
+
+func (self *AlistToEnvironment) Apply(args []Value) Value {
+  scope := args[0].(*Environment).Scope.(bindings)
+  entries := converter.PairsToSlice(args[1])
+  names := make([]string, len(entries))
+  // check every entry first, not to restore half of them
+  for i, entry := range entries {
+    pair, ok := entry.(*PairValue)
+    if !ok || !NameArg.Check(pair.First) {
+      panic(&TypeError{Message: fmt.Sprint("alist->environment!: expected (name . value), given: ", entry)})
+    }
+    names[i] = bindingName(pair.First)
+    if scope.IsConstant(names[i]) {
+      panic(fmt.Sprintf("alist->environment!: cannot change constant: %s", names[i]))
+    }
+  }
+  for i, entry := range entries {
+    val := entry.(*PairValue).Second
+    scope.Audit("alist->environment!", names[i], val, "")
+    scope.Put(names[i], val)
+  }
+  return nil
+}
+
+func bindingName(val Value) string {
+  if symbol, ok := val.(*Symbol); ok {
+    return symbol.Value
+  }
+  return val.(*StringValue).Value
+}
+
+// (environment-diff saved (the-environment)) is the association list of
+// the bindings of the second environment which are missing from the first
+// or bound to a value which isn't equal?, i.e. what changed since saved.
+// either may be an environment or an association list
+type EnvironmentDiff struct {
+  Primitive
+}
+
+func NewEnvironmentDiff() *EnvironmentDiff {
+  return &EnvironmentDiff{Primitive{"environment-diff"}}
+}
+
+func (self *EnvironmentDiff) Apply(args []Value) Value {
+  old := make(map[string]Value)
+  for _, entry := range bindingEntries(args[0]) {
+    old[bindingName(entry.First)] = entry.Second
+  }
+  var changed []Value
+  for _, entry := range bindingEntries(args[1]) {
+    if val, ok := old[bindingName(entry.First)]; !ok || !isEqual(val, entry.Second) {
+      changed = append(changed, entry)
+    }
+  }
+  return converter.SliceToPairValues(changed)
+}
+
+func bindingEntries(val Value) []*PairValue {
+  if env, ok := val.(*Environment); ok {
+    val = environmentAlist(env)
+  }
+  var entries []*PairValue
+  for _, entry := range converter.PairsToSlice(val) {
+    pair, ok := entry.(*PairValue)
+    if !ok || !NameArg.Check(pair.First) {
+      panic(&TypeError{Message: fmt.Sprint("environment-diff: expected (name . value), given: ", entry)})
+    }
+    entries = append(entries, pair)
+  }
+  return entries
+}
@@ -0,0 +1,88 @@
+package primitives
+
+import (
+  "bufio"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "io"
+  "os"
+)
+
+// (for-each-line proc port-or-path) calls proc on each line as it
+// is read, so that the file is never held in memory as a whole.
+// a file opened from a path is closed afterwards, a port is left open
+type ForEachLine struct {
+  Primitive
+}
+
+func NewForEachLine() *ForEachLine {
+  return &ForEachLine{Primitive{"for-each-line"}}
+}
+
+func (self *ForEachLine) Apply(args []Value) Value {
+  var reader *bufio.Reader
+  switch args[1].(type) {
+  case *Port:
+    reader = args[1].(*Port).Reader
+  case *StringValue:
+    file, err := os.Open(args[1].(*StringValue).Value)
+    if err != nil {
+      panic(fmt.Sprint("for-each-line: ", err))
+    }
+    defer file.Close()
+    reader = bufio.NewReader(file)
+  }
+  for {
+    line := readLine("for-each-line", reader)
+    if line == EOF {
+      return nil
+    }
+    Invoke(args[0], []Value{line})
+  }
+}
+
+// (read-file-bytes path [offset [count]]) reads count bytes of the file
+// from offset, or the rest of it, as a string. reading past the end
+// gives the eof object, so a large file is read a chunk at a time
+type ReadFileBytes struct {
+  Primitive
+}
+
+func NewReadFileBytes() *ReadFileBytes {
+  return &ReadFileBytes{Primitive{"read-file-bytes"}}
+}
+
+func (self *ReadFileBytes) Apply(args []Value) Value {
+  file, err := os.Open(args[0].(*StringValue).Value)
+  if err != nil {
+    panic(fmt.Sprint("read-file-bytes: ", err))
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    panic(fmt.Sprint("read-file-bytes: ", err))
+  }
+  var offset int64
+  if len(args) > 1 {
+    offset = args[1].(*IntValue).Value
+  }
+  if offset < 0 {
+    panic(fmt.Sprint("read-file-bytes: negative offset: ", offset))
+  }
+  if offset >= info.Size() {
+    return EOF
+  }
+  count := info.Size() - offset
+  if len(args) > 2 && args[2].(*IntValue).Value < count {
+    count = args[2].(*IntValue).Value
+  }
+  if count < 0 {
+    panic(fmt.Sprint("read-file-bytes: negative count: ", count))
+  }
+  buf := make([]byte, count)
+  n, err := file.ReadAt(buf, offset)
+  if err != nil && err != io.EOF {
+    panic(fmt.Sprint("read-file-bytes: ", err))
+  }
+  return NewStringValue(string(buf[:n]))
+}
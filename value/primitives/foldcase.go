@@ -0,0 +1,64 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+  "unicode"
+)
+
+// characters whose case folding is a string, as in Straße -> strasse
+var fullFolds = map[rune]string{
+  '\u00df': "ss",
+  '\u0130': "i\u0307",
+  '\u0149': "\u02bcn",
+  '\u1e9e': "ss",
+  '\ufb00': "ff",
+  '\ufb01': "fi",
+  '\ufb02': "fl",
+  '\ufb03': "ffi",
+  '\ufb04': "ffl",
+  '\ufb05': "st",
+  '\ufb06': "st",
+}
+
+// simple case folding, locale independent: the lower case of the
+// upper case maps all the variants of a letter like σ, ς and Σ together
+func foldRune(r rune) rune {
+  return unicode.ToLower(unicode.ToUpper(r))
+}
+
+func foldString(s string) string {
+  var buf strings.Builder
+  for _, r := range s {
+    if folded, ok := fullFolds[r]; ok {
+      buf.WriteString(folded)
+    } else {
+      buf.WriteRune(foldRune(r))
+    }
+  }
+  return buf.String()
+}
+
+type StringFoldcase struct {
+  Primitive
+}
+
+func NewStringFoldcase() *StringFoldcase {
+  return &StringFoldcase{Primitive{"string-foldcase"}}
+}
+
+func (self *StringFoldcase) Apply(args []Value) Value {
+  return NewStringValue(foldString(args[0].(*StringValue).Value))
+}
+
+type CharFoldcase struct {
+  Primitive
+}
+
+func NewCharFoldcase() *CharFoldcase {
+  return &CharFoldcase{Primitive{"char-foldcase"}}
+}
+
+func (self *CharFoldcase) Apply(args []Value) Value {
+  return NewCharValue(foldRune(args[0].(*CharValue).Value))
+}
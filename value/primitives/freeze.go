@@ -0,0 +1,20 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+type Freeze struct {
+  Primitive
+}
+
+func NewFreeze() *Freeze {
+  return &Freeze{Primitive{"freeze!"}}
+}
+
+func (self *Freeze) Apply(args []Value) Value {
+  args[0].(*Environment).Scope.(interface {
+    Freeze()
+  }).Freeze()
+  return nil
+}
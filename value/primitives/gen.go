@@ -0,0 +1,102 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/quickcheck"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (gen-integer), (gen-list gen), ... build generators
+// for check-property, arguments are validated by the table
+type GenPrimitive struct {
+  Primitive
+  build func(args []Value) *quickcheck.Generator
+}
+
+func NewGenPrimitive(name string, build func(args []Value) *quickcheck.Generator) *GenPrimitive {
+  return &GenPrimitive{Primitive{name}, build}
+}
+
+func (self *GenPrimitive) Apply(args []Value) Value {
+  return self.build(args)
+}
+
+func NewGenInteger() *GenPrimitive {
+  return NewGenPrimitive("gen-integer", func(args []Value) *quickcheck.Generator {
+    switch len(args) {
+    case 0:
+      return quickcheck.Integer()
+    case 2:
+      return quickcheck.IntegerBetween(args[0].(*IntValue).Value, args[1].(*IntValue).Value)
+    }
+    panic(fmt.Sprint("gen-integer: arguments mismatch, expected 0 or 2, given: ", len(args)))
+  })
+}
+
+func NewGenFloat() *GenPrimitive {
+  return NewGenPrimitive("gen-float", func(args []Value) *quickcheck.Generator {
+    return quickcheck.Float()
+  })
+}
+
+func NewGenBoolean() *GenPrimitive {
+  return NewGenPrimitive("gen-boolean", func(args []Value) *quickcheck.Generator {
+    return quickcheck.Boolean()
+  })
+}
+
+func NewGenString() *GenPrimitive {
+  return NewGenPrimitive("gen-string", func(args []Value) *quickcheck.Generator {
+    return quickcheck.String()
+  })
+}
+
+func NewGenList() *GenPrimitive {
+  return NewGenPrimitive("gen-list", func(args []Value) *quickcheck.Generator {
+    return quickcheck.List(args[0].(*quickcheck.Generator))
+  })
+}
+
+func NewGenElements() *GenPrimitive {
+  return NewGenPrimitive("gen-elements", func(args []Value) *quickcheck.Generator {
+    return quickcheck.Elements(converter.PairsToSlice(args[0]))
+  })
+}
+
+func NewGenOneOf() *GenPrimitive {
+  return NewGenPrimitive("gen-one-of", func(args []Value) *quickcheck.Generator {
+    return quickcheck.OneOf(generators(args))
+  })
+}
+
+// (gen-map proc gen1 gen2 ...) applies proc to values of the generators
+func NewGenMap() *GenPrimitive {
+  return NewGenPrimitive("gen-map", func(args []Value) *quickcheck.Generator {
+    proc := args[0]
+    return quickcheck.Map(fmt.Sprint("map ", proc), func(values []Value) Value {
+      return Invoke(proc, values)
+    }, generators(args[1:]))
+  })
+}
+
+// (gen-such-that pred gen) keeps the values satisfying pred
+func NewGenSuchThat() *GenPrimitive {
+  return NewGenPrimitive("gen-such-that", func(args []Value) *quickcheck.Generator {
+    pred := args[0]
+    return quickcheck.SuchThat(func(val Value) bool {
+      if result, ok := Invoke(pred, []Value{val}).(*BoolValue); ok {
+        return result.Value
+      }
+      return true
+    }, args[1].(*quickcheck.Generator))
+  })
+}
+
+func generators(args []Value) []*quickcheck.Generator {
+  result := make([]*quickcheck.Generator, len(args))
+  for i, arg := range args {
+    result[i] = arg.(*quickcheck.Generator)
+  }
+  return result
+}
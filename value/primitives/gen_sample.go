@@ -0,0 +1,28 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/converter"
+  "github.com/kedebug/LispEx/quickcheck"
+  . "github.com/kedebug/LispEx/value"
+  "math/rand"
+  "time"
+)
+
+// (gen-sample gen) lists ten values of growing size
+type GenSample struct {
+  Primitive
+}
+
+func NewGenSample() *GenSample {
+  return &GenSample{Primitive{"gen-sample"}}
+}
+
+func (self *GenSample) Apply(args []Value) Value {
+  generator := args[0].(*quickcheck.Generator)
+  r := rand.New(rand.NewSource(time.Now().UnixNano()))
+  values := make([]Value, 10)
+  for i := range values {
+    values[i] = generator.Generate(r, 2*i).Value
+  }
+  return converter.SliceToPairValues(values)
+}
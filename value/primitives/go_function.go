@@ -0,0 +1,116 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// builtin calling a golang function, converting its arguments and
+// results with the converter package: registering a helper like
+//  env.Put("words", primitives.WrapGo("words", "the words of s", strings.Fields))
+// needs no adapter code. a trailing error result is raised when not nil,
+// wrapped in a value.Error,
+// several other results are returned as a list. values to keep as they
+// are, like a *sql.DB, are boxed by wrapping them with value.NewOpaque
+func WrapGo(name, doc string, fn interface{}) *Builtin {
+  f := reflect.ValueOf(fn)
+  if f.Kind() != reflect.Func {
+    panic(fmt.Sprintf("%s: expected a function, given: %T", name, fn))
+  }
+  t := f.Type()
+  builtin := &Builtin{name, t.NumIn(), t.NumIn(), nil, doc, &GoFunction{Primitive{name}, f}}
+  for i := 0; i < t.NumIn(); i++ {
+    param := t.In(i)
+    if t.IsVariadic() && i == t.NumIn()-1 {
+      param = param.Elem()
+      builtin.Min, builtin.Max = t.NumIn()-1, -1
+    }
+    builtin.Args = append(builtin.Args, goArgType(param))
+  }
+  if builtin.Args == nil {
+    builtin.Args = []*ArgType{AnyArg}
+  }
+  return builtin
+}
+
+func goArgType(t reflect.Type) *ArgType {
+  name := t.String()
+  switch t.Kind() {
+  case reflect.Bool:
+    name = "bool"
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+    reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    name = "integer"
+  case reflect.Float32, reflect.Float64:
+    name = "number"
+  case reflect.String:
+    name = "string"
+  case reflect.Slice:
+    name = "list of " + goArgType(t.Elem()).Name
+    if t.Elem().Kind() == reflect.Uint8 {
+      name = "string"
+    }
+  case reflect.Map:
+    name = "association list"
+  case reflect.Func:
+    name = "procedure"
+  case reflect.Chan:
+    name = "channel"
+  case reflect.Interface:
+    if t.NumMethod() == 0 {
+      name = "any"
+    }
+  }
+  return &ArgType{name, func(val Value) bool {
+    _, ok := converter.FromValue(val, t)
+    return ok
+  }}
+}
+
+// the function called by a builtin made by WrapGo,
+// which checks the arguments before
+type GoFunction struct {
+  Primitive
+  fn reflect.Value
+}
+
+func (self *GoFunction) Apply(args []Value) Value {
+  t := self.fn.Type()
+  in := make([]reflect.Value, len(args))
+  for i, arg := range args {
+    var param reflect.Type
+    if t.IsVariadic() && i >= t.NumIn()-1 {
+      param = t.In(t.NumIn() - 1).Elem()
+    } else {
+      param = t.In(i)
+    }
+    v, ok := converter.FromValue(arg, param)
+    if !ok {
+      panic(&TypeError{Message: fmt.Sprintf("%s: expected %s, given: %s", self.Name, goArgType(param).Name, arg)})
+    }
+    in[i] = v
+  }
+
+  out := self.fn.Call(in)
+  if n := len(out); n > 0 && t.Out(n-1) == errorType {
+    if err := out[n-1]; !err.IsNil() {
+      panic(&Error{fmt.Sprint(self.Name, ": ", err.Interface()), err.Interface().(error)})
+    }
+    out = out[:n-1]
+  }
+  switch len(out) {
+  case 0:
+    return nil
+  case 1:
+    return converter.ToValue(out[0].Interface())
+  }
+  results := make([]Value, len(out))
+  for i, result := range out {
+    results[i] = converter.ToValue(result.Interface())
+  }
+  return converter.SliceToPairValues(results)
+}
@@ -0,0 +1,57 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "runtime"
+)
+
+// (set-max-procs! n) sets the number of OS threads running goroutines
+// at once and returns the previous setting
+type SetMaxProcs struct {
+  Primitive
+}
+
+func NewSetMaxProcs() *SetMaxProcs {
+  return &SetMaxProcs{Primitive{"set-max-procs!"}}
+}
+
+func (self *SetMaxProcs) Apply(args []Value) Value {
+  n := args[0].(*IntValue).Value
+  if n <= 0 {
+    panic(fmt.Sprint("set-max-procs!: expected positive integer, given: ", n))
+  }
+  return NewIntValue(int64(runtime.GOMAXPROCS(int(n))))
+}
+
+// (set-go-pool-size! n) bounds the number of goroutines started by `go'
+// running at once, 0 removes the bound. returns the previous size
+type SetGoPoolSize struct {
+  Primitive
+}
+
+func NewSetGoPoolSize() *SetGoPoolSize {
+  return &SetGoPoolSize{Primitive{"set-go-pool-size!"}}
+}
+
+func (self *SetGoPoolSize) Apply(args []Value) Value {
+  n := args[0].(*IntValue).Value
+  if n < 0 {
+    panic(fmt.Sprint("set-go-pool-size!: expected non-negative integer, given: ", n))
+  }
+  previous := GoroutinePoolSize()
+  SetGoroutinePoolSize(int(n))
+  return NewIntValue(int64(previous))
+}
+
+type GoroutineCountPrimitive struct {
+  Primitive
+}
+
+func NewGoroutineCount() *GoroutineCountPrimitive {
+  return &GoroutineCountPrimitive{Primitive{"goroutine-count"}}
+}
+
+func (self *GoroutineCountPrimitive) Apply(args []Value) Value {
+  return NewIntValue(GoroutineCount())
+}
@@ -0,0 +1,156 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (make-hash [equal-proc [hash-proc]]) compares keys with equal? by
+// default. with an equality procedure but no hash procedure every key
+// lands in the same bucket and is compared one by one
+type MakeHash struct {
+  Primitive
+}
+
+func NewMakeHash() *MakeHash {
+  return &MakeHash{Primitive{"make-hash"}}
+}
+
+func NewMakeHashTable() *MakeHash {
+  return &MakeHash{Primitive{"make-hash-table"}}
+}
+
+func (self *MakeHash) Apply(args []Value) Value {
+  equal, hash := isEqual, func(key Value) string { return key.String() }
+  if len(args) > 0 {
+    proc := args[0]
+    equal = func(x, y Value) bool {
+      result, ok := Invoke(proc, []Value{x, y}).(*BoolValue)
+      return !ok || result.Value
+    }
+    hash = func(Value) string { return "" }
+  }
+  if len(args) > 1 {
+    proc := args[1]
+    hash = func(key Value) string { return Invoke(proc, []Value{key}).String() }
+  }
+  return NewHashTable(equal, hash)
+}
+
+// (make-eq-hash-table) compares keys with eq?: lists, vectors and
+// other objects are only the same key as themselves
+type MakeEqHashTable struct {
+  Primitive
+}
+
+func NewMakeEqHashTable() *MakeEqHashTable {
+  return &MakeEqHashTable{Primitive{"make-eq-hash-table"}}
+}
+
+func (self *MakeEqHashTable) Apply(args []Value) Value {
+  return NewHashTable(isEq, eqHash)
+}
+
+// objects that are eq? without being the same, numbers or
+// strings, are hashed by their text and the others by address
+func eqHash(key Value) string {
+  switch key.(type) {
+  case *IntValue, *BigIntValue, *RatValue, *FloatValue, *StringValue, *CharValue, *Symbol, *BoolValue, *EmptyPairValue:
+    return key.String()
+  }
+  return fmt.Sprintf("%p", key)
+}
+
+// (hash-ref h key [default]) is an error without a default
+// when the table has no value for the key
+type HashRef struct {
+  Primitive
+}
+
+func NewHashRef() *HashRef {
+  return &HashRef{Primitive{"hash-ref"}}
+}
+
+func (self *HashRef) Apply(args []Value) Value {
+  if val, ok := args[0].(*HashTable).Get(args[1]); ok {
+    return val
+  }
+  if len(args) > 2 {
+    return args[2]
+  }
+  panic(fmt.Sprint("hash-ref: no value for the key: ", args[1]))
+}
+
+type HashSet struct {
+  Primitive
+}
+
+func NewHashSet() *HashSet {
+  return &HashSet{Primitive{"hash-set!"}}
+}
+
+func (self *HashSet) Apply(args []Value) Value {
+  args[0].(*HashTable).Set(args[1], args[2])
+  return nil
+}
+
+type HashCount struct {
+  Primitive
+}
+
+func NewHashCount() *HashCount {
+  return &HashCount{Primitive{"hash-count"}}
+}
+
+func (self *HashCount) Apply(args []Value) Value {
+  return NewIntValue(int64(args[0].(*HashTable).Len()))
+}
+
+// (hash-remove! h key) is whether the table had a value for the key
+type HashRemove struct {
+  Primitive
+}
+
+func NewHashRemove() *HashRemove {
+  return &HashRemove{Primitive{"hash-remove!"}}
+}
+
+func (self *HashRemove) Apply(args []Value) Value {
+  return NewBoolValue(args[0].(*HashTable).Delete(args[1]))
+}
+
+// the keys in the order they were added
+type HashKeys struct {
+  Primitive
+}
+
+func NewHashKeys() *HashKeys {
+  return &HashKeys{Primitive{"hash-keys"}}
+}
+
+func (self *HashKeys) Apply(args []Value) Value {
+  var keys []Value
+  for _, pair := range args[0].(*HashTable).Pairs() {
+    keys = append(keys, pair[0])
+  }
+  return converter.SliceToPairValues(keys)
+}
+
+// (hash-for-each h proc) calls (proc key value) for the entries in the
+// order they were added. the procedure may change the table, the
+// entries it adds are not visited
+type HashForEach struct {
+  Primitive
+}
+
+func NewHashForEach() *HashForEach {
+  return &HashForEach{Primitive{"hash-for-each"}}
+}
+
+func (self *HashForEach) Apply(args []Value) Value {
+  for _, pair := range args[0].(*HashTable).Pairs() {
+    Invoke(args[1], []Value{pair[0], pair[1]})
+  }
+  return nil
+}
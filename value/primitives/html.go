@@ -0,0 +1,367 @@
+package primitives
+
+import (
+  "bytes"
+  "encoding/xml"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "strconv"
+  "strings"
+  "unicode/utf8"
+)
+
+// elements which have no content nor end tag
+var htmlVoid = map[string]bool{
+  "area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+  "input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// elements whose content is text up to their end tag, e.g. a
+// script: `<' starts no tag there. entities are decoded but in
+// script and style
+var htmlRawText = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// the start tag of an element ends the open elements listed, as far
+// as the elements of htmlScopes: <li> ends the previous <li> of its
+// list, a block element ends the paragraph it is written in
+var htmlImpliedEnds = map[string][]string{
+  "li":         {"li"},
+  "dt":         {"dt", "dd"},
+  "dd":         {"dt", "dd"},
+  "tr":         {"tr", "td", "th"},
+  "td":         {"td", "th"},
+  "th":         {"td", "th"},
+  "thead":      {"tbody", "tfoot", "tr", "td", "th"},
+  "tbody":      {"thead", "tfoot", "tr", "td", "th"},
+  "tfoot":      {"thead", "tbody", "tr", "td", "th"},
+  "option":     {"option"},
+  "p":          {"p"},
+  "div":        {"p"},
+  "ul":         {"p"},
+  "ol":         {"p"},
+  "dl":         {"p"},
+  "table":      {"p"},
+  "pre":        {"p"},
+  "form":       {"p"},
+  "section":    {"p"},
+  "article":    {"p"},
+  "header":     {"p"},
+  "footer":     {"p"},
+  "nav":        {"p"},
+  "aside":      {"p"},
+  "h1":         {"p"},
+  "h2":         {"p"},
+  "h3":         {"p"},
+  "h4":         {"p"},
+  "h5":         {"p"},
+  "h6":         {"p"},
+  "hr":         {"p"},
+  "blockquote": {"p"},
+}
+
+// implied end tags stop at these elements, the <li> of a nested
+// list doesn't end the <li> it is written in
+var htmlScopes = map[string]bool{
+  "ul": true, "ol": true, "dl": true, "table": true, "select": true,
+  "html": true, "body": true, "div": true, "td": true, "th": true,
+}
+
+// parse html the way browsers read it rather than as xml: tags and
+// attributes are case insensitive, attribute values may be unquoted,
+// void elements and implied end tags close elements, the content of
+// scripts is text, and the elements still open at the end of the
+// input are closed
+func DecodeHTML(input string) Value {
+  parser := &htmlParser{text: input, stack: [][]Value{{NewSymbol(SXML_TOP)}}}
+  parser.parse()
+  for len(parser.stack) > 1 {
+    parser.stack = closeSXMLElement(parser.stack)
+  }
+  return converter.SliceToPairValues(parser.stack[0])
+}
+
+type htmlParser struct {
+  text  string
+  pos   int
+  stack [][]Value
+}
+
+func (self *htmlParser) parse() {
+  for self.pos < len(self.text) {
+    start := self.pos
+    end := strings.IndexByte(self.text[start:], '<')
+    if end < 0 {
+      self.addText(decodeHTMLEntities(self.text[start:]))
+      return
+    }
+    self.pos = start + end
+    if self.pos > start {
+      self.addText(decodeHTMLEntities(self.text[start:self.pos]))
+    }
+    if !self.tag() {
+      // a `<' which starts no tag is text
+      self.addText("<")
+      self.pos++
+    }
+  }
+}
+
+// read the tag, comment or declaration at pos, false if
+// there is none and the `<' is text
+func (self *htmlParser) tag() bool {
+  rest := self.text[self.pos+1:]
+  switch {
+  case strings.HasPrefix(rest, "!--"):
+    self.skipPast("-->")
+  case strings.HasPrefix(rest, "!") || strings.HasPrefix(rest, "?"):
+    self.skipPast(">")
+  case strings.HasPrefix(rest, "/") && len(rest) > 1 && isHTMLNameStart(rest[1]):
+    self.pos += 2
+    name := self.name()
+    self.skipPast(">")
+    self.end(name)
+  case len(rest) > 0 && isHTMLNameStart(rest[0]):
+    self.pos++
+    self.start(self.name())
+  default:
+    return false
+  }
+  return true
+}
+
+func (self *htmlParser) start(name string) {
+  element := []Value{NewSymbol(name)}
+  attrs := []Value{NewSymbol(SXML_ATTRS)}
+  closed := false
+  for {
+    self.skipSpace()
+    if self.pos >= len(self.text) {
+      break
+    }
+    if self.text[self.pos] == '>' {
+      self.pos++
+      break
+    }
+    if strings.HasPrefix(self.text[self.pos:], "/>") {
+      self.pos += 2
+      closed = true
+      break
+    }
+    attr := self.name()
+    if attr == "" {
+      // a stray character like a quote, skipped
+      self.pos++
+      continue
+    }
+    self.skipSpace()
+    value := ""
+    if self.pos < len(self.text) && self.text[self.pos] == '=' {
+      self.pos++
+      self.skipSpace()
+      value = decodeHTMLEntities(self.attributeValue())
+    }
+    pair := []Value{NewSymbol(attr), NewStringValue(value)}
+    attrs = append(attrs, converter.SliceToPairValues(pair))
+  }
+  if len(attrs) > 1 {
+    element = append(element, converter.SliceToPairValues(attrs))
+  }
+
+  self.implyEnds(name)
+  self.stack = append(self.stack, element)
+  if closed || htmlVoid[name] {
+    self.stack = closeSXMLElement(self.stack)
+    return
+  }
+  if htmlRawText[name] {
+    self.rawText(name)
+  }
+}
+
+// the content of a raw text element up to its end tag, or
+// up to the end of the input if it is missing
+func (self *htmlParser) rawText(name string) {
+  rest := self.text[self.pos:]
+  end := strings.Index(strings.ToLower(rest), "</"+name)
+  if end < 0 {
+    end = len(rest)
+  }
+  text := rest[:end]
+  if name != "script" && name != "style" {
+    text = decodeHTMLEntities(text)
+  }
+  self.addText(text)
+  self.pos += end
+  if self.pos < len(self.text) {
+    self.skipPast(">")
+  }
+  self.stack = closeSXMLElement(self.stack)
+}
+
+// close the open elements the start tag of name ends
+func (self *htmlParser) implyEnds(name string) {
+  ends := htmlImpliedEnds[name]
+  if len(ends) == 0 {
+    return
+  }
+  for i := len(self.stack) - 1; i > 0; i-- {
+    open := self.stack[i][0].(*Symbol).Value
+    for _, end := range ends {
+      if open == end {
+        for len(self.stack) > i {
+          self.stack = closeSXMLElement(self.stack)
+        }
+        return
+      }
+    }
+    if htmlScopes[open] {
+      return
+    }
+  }
+}
+
+// an end tag closes the element it names and those open within it,
+// and is ignored if no such element is open
+func (self *htmlParser) end(name string) {
+  for i := len(self.stack) - 1; i > 0; i-- {
+    if self.stack[i][0].(*Symbol).Value == name {
+      for len(self.stack) > i {
+        self.stack = closeSXMLElement(self.stack)
+      }
+      return
+    }
+  }
+}
+
+func (self *htmlParser) addText(text string) {
+  if strings.TrimSpace(text) == "" {
+    return
+  }
+  top := len(self.stack) - 1
+  // text split by a stray `<' is joined again
+  if n := len(self.stack[top]); n > 1 {
+    if last, ok := self.stack[top][n-1].(*StringValue); ok {
+      self.stack[top][n-1] = NewStringValue(last.Value + text)
+      return
+    }
+  }
+  self.stack[top] = append(self.stack[top], NewStringValue(text))
+}
+
+// a tag or attribute name, lower case
+func (self *htmlParser) name() string {
+  start := self.pos
+  for self.pos < len(self.text) {
+    c := self.text[self.pos]
+    if c == '>' || c == '/' || c == '=' || c == '"' || c == '\'' || isHTMLSpace(c) {
+      break
+    }
+    self.pos++
+  }
+  return strings.ToLower(self.text[start:self.pos])
+}
+
+func (self *htmlParser) attributeValue() string {
+  if self.pos >= len(self.text) {
+    return ""
+  }
+  if quote := self.text[self.pos]; quote == '"' || quote == '\'' {
+    self.pos++
+    end := strings.IndexByte(self.text[self.pos:], quote)
+    if end < 0 {
+      end = len(self.text) - self.pos
+    }
+    value := self.text[self.pos : self.pos+end]
+    self.pos += end
+    if self.pos < len(self.text) {
+      self.pos++
+    }
+    return value
+  }
+  start := self.pos
+  for self.pos < len(self.text) && self.text[self.pos] != '>' && !isHTMLSpace(self.text[self.pos]) {
+    self.pos++
+  }
+  return self.text[start:self.pos]
+}
+
+func (self *htmlParser) skipSpace() {
+  for self.pos < len(self.text) && isHTMLSpace(self.text[self.pos]) {
+    self.pos++
+  }
+}
+
+func (self *htmlParser) skipPast(delimiter string) {
+  end := strings.Index(self.text[self.pos:], delimiter)
+  if end < 0 {
+    self.pos = len(self.text)
+  } else {
+    self.pos += end + len(delimiter)
+  }
+}
+
+func isHTMLNameStart(c byte) bool {
+  return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isHTMLSpace(c byte) bool {
+  return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// &amp;, &#38; and &#x26; are `&', unknown entities are left as written
+func decodeHTMLEntities(text string) string {
+  if strings.IndexByte(text, '&') < 0 {
+    return text
+  }
+  var buf bytes.Buffer
+  for {
+    start := strings.IndexByte(text, '&')
+    if start < 0 {
+      buf.WriteString(text)
+      return buf.String()
+    }
+    buf.WriteString(text[:start])
+    text = text[start:]
+    end := strings.IndexByte(text, ';')
+    if end < 0 || end > 32 {
+      buf.WriteByte('&')
+      text = text[1:]
+      continue
+    }
+    if decoded, ok := decodeHTMLEntity(text[1:end]); ok {
+      buf.WriteString(decoded)
+      text = text[end+1:]
+    } else {
+      buf.WriteByte('&')
+      text = text[1:]
+    }
+  }
+}
+
+func decodeHTMLEntity(name string) (string, bool) {
+  if strings.HasPrefix(name, "#") {
+    base, digits := 10, name[1:]
+    if strings.HasPrefix(digits, "x") || strings.HasPrefix(digits, "X") {
+      base, digits = 16, digits[1:]
+    }
+    code, err := strconv.ParseUint(digits, base, 32)
+    if err != nil || !utf8.ValidRune(rune(code)) {
+      return "", false
+    }
+    return string(rune(code)), true
+  }
+  switch name {
+  case "amp":
+    return "&", true
+  case "lt":
+    return "<", true
+  case "gt":
+    return ">", true
+  case "quot":
+    return "\"", true
+  case "apos":
+    return "'", true
+  }
+  decoded, ok := xml.HTMLEntity[name]
+  return decoded, ok
+}
@@ -0,0 +1,37 @@
+package primitives
+
+import (
+  "context"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "net/http"
+)
+
+// the response body is returned as an input port
+// so large responses can be consumed as they arrive
+type HTTPGet struct {
+  Primitive
+  context func() context.Context
+}
+
+func NewHTTPGet(context func() context.Context) *HTTPGet {
+  return &HTTPGet{Primitive{"http-get"}, context}
+}
+
+func (self *HTTPGet) Apply(args []Value) Value {
+  url := args[0].(*StringValue)
+  request, err := http.NewRequestWithContext(currentContext(self.context), "GET", url.Value, nil)
+  if err != nil {
+    raiseIOError("http-get", err)
+  }
+  // reading the body is bound to the context too
+  response, err := http.DefaultClient.Do(request)
+  if err != nil {
+    raiseIOError("http-get", err)
+  }
+  if response.StatusCode < 200 || response.StatusCode > 299 {
+    response.Body.Close()
+    panic(fmt.Sprint("http-get: ", url.Value, ": ", response.Status))
+  }
+  return NewInputPort(url.Value, response.Body)
+}
@@ -0,0 +1,139 @@
+package primitives
+
+import (
+  "bufio"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "io"
+  "strconv"
+  "strings"
+)
+
+// values are summarized on one line of at most this many characters
+const inspectWidth = 72
+
+// (inspect obj) browses a nested value a level at a time: it lists the
+// numbered fields of obj, typing a number inspects that field, u goes
+// back up and q quits. commands are read from in, the REPL passes its
+// own reader so that typed lines go to the inspector while it runs
+type Inspect struct {
+  Primitive
+  in  *bufio.Reader
+  out io.Writer
+}
+
+func NewInspect(in io.Reader, out io.Writer) *Inspect {
+  return &Inspect{Primitive{"inspect"}, bufio.NewReader(in), out}
+}
+
+// a part of an inspected value, Label is empty for list elements
+type Field struct {
+  Label string
+  Value Value
+}
+
+func (self *Inspect) Apply(args []Value) Value {
+  path := []Value{args[0]}
+  for {
+    current := path[len(path)-1]
+    fields := InspectFields(current)
+    self.show(current, fields)
+    fmt.Fprint(self.out, "inspect> ")
+    line, err := self.in.ReadString('\n')
+    command := strings.TrimSpace(line)
+    if n, convErr := strconv.Atoi(command); convErr == nil && n >= 0 && n < len(fields) {
+      path = append(path, fields[n].Value)
+      continue
+    }
+    switch {
+    case command == "q":
+      return nil
+    case err != nil && command == "":
+      fmt.Fprintln(self.out)
+      return nil
+    case command == "u" && len(path) > 1:
+      path = path[:len(path)-1]
+    case command == "u":
+      fmt.Fprintln(self.out, "already at the top")
+    case len(fields) == 0:
+      fmt.Fprintln(self.out, "no fields, type u to go up or q to quit")
+    default:
+      fmt.Fprintf(self.out, "type a field number from 0 to %d, u to go up or q to quit\n", len(fields)-1)
+    }
+  }
+}
+
+func (self *Inspect) show(val Value, fields []Field) {
+  fmt.Fprintf(self.out, "%s: %s\n", NewTypeOf().Apply([]Value{val}), summarize(val))
+  for i, field := range fields {
+    if field.Label == "" {
+      fmt.Fprintf(self.out, "  %d. %s\n", i, summarize(field.Value))
+    } else {
+      fmt.Fprintf(self.out, "  %d. %s: %s\n", i, field.Label, summarize(field.Value))
+    }
+  }
+}
+
+// the printed value, cut short when longer than a line
+func summarize(val Value) string {
+  text := []rune(fmt.Sprint(val))
+  if len(text) > inspectWidth {
+    return string(text[:inspectWidth-3]) + "..."
+  }
+  return string(text)
+}
+
+// the parts of a value the inspector can drill into: the elements of
+// a list or a vector, the car and cdr of other pairs, the bytes and
+// bits of bytevectors and bitvectors, the fields of a record, the
+// entries of a hash table and the bindings of an environment. other
+// values have none
+func InspectFields(val Value) []Field {
+  var fields []Field
+  switch val.(type) {
+  case *PairValue:
+    items, tail := val, val
+    for {
+      pair, ok := tail.(*PairValue)
+      if !ok {
+        break
+      }
+      fields = append(fields, Field{Value: pair.First})
+      tail = pair.Second
+    }
+    if tail != NilPairValue {
+      pair := items.(*PairValue)
+      return []Field{{"car", pair.First}, {"cdr", pair.Second}}
+    }
+  case *VectorValue:
+    for _, item := range val.(*VectorValue).Value {
+      fields = append(fields, Field{Value: item})
+    }
+  case *Bytevector:
+    for _, b := range val.(*Bytevector).Value {
+      fields = append(fields, Field{Value: NewIntValue(int64(b))})
+    }
+  case *Bitvector:
+    bits := val.(*Bitvector)
+    for i := 0; i < bits.Len; i++ {
+      fields = append(fields, Field{Value: NewBoolValue(bits.Get(i))})
+    }
+  case *Record:
+    record := val.(*Record)
+    for i, name := range record.Type.Fields {
+      fields = append(fields, Field{name, record.Values[i]})
+    }
+  case *HashTable:
+    for _, pair := range val.(*HashTable).Pairs() {
+      fields = append(fields, Field{pair[0].String(), pair[1]})
+    }
+  case *Environment:
+    scope := val.(*Environment).Scope.(bindings)
+    for _, name := range scope.LocalNames() {
+      if val, ok := scope.LookupLocal(name).(Value); ok {
+        fields = append(fields, Field{name, val})
+      }
+    }
+  }
+  return fields
+}
@@ -1,6 +1,7 @@
 package primitives
 
 import (
+  "bytes"
   "github.com/kedebug/LispEx/value"
 )
 
@@ -11,3 +12,61 @@ type IsEqual struct {
 func NewIsEqual() *IsEqual {
   return &IsEqual{value.Primitive{"equal?"}}
 }
+
+func (self *IsEqual) Apply(args []value.Value) value.Value {
+  return value.NewBoolValue(isEqual(args[0], args[1]))
+}
+
+// equal? recursively compares the contents of pairs and records,
+// and the bytes of bytevectors. other values are compared by eqv?
+func isEqual(x, y value.Value) bool {
+  if p1, ok := x.(*value.PairValue); ok {
+    if p2, ok := y.(*value.PairValue); ok {
+      return isEqual(p1.First, p2.First) && isEqual(p1.Second, p2.Second)
+    }
+    return false
+  }
+  if e1, ok := x.(*value.Enum); ok {
+    return e1 == y
+  }
+  if r1, ok := x.(*value.Record); ok {
+    if r2, ok := y.(*value.Record); ok && r1.Type == r2.Type {
+      for i := range r1.Values {
+        if !isEqual(r1.Values[i], r2.Values[i]) {
+          return false
+        }
+      }
+      return true
+    }
+    return false
+  }
+  if v1, ok := x.(*value.VectorValue); ok {
+    if v2, ok := y.(*value.VectorValue); ok && len(v1.Value) == len(v2.Value) {
+      for i := range v1.Value {
+        if !isEqual(v1.Value[i], v2.Value[i]) {
+          return false
+        }
+      }
+      return true
+    }
+    return false
+  }
+  if b1, ok := x.(*value.Bytevector); ok {
+    b2, ok := y.(*value.Bytevector)
+    return ok && bytes.Equal(b1.Value, b2.Value)
+  }
+  if b1, ok := x.(*value.Bitvector); ok {
+    b2, ok := y.(*value.Bitvector)
+    if !ok || b1.Len != b2.Len {
+      return false
+    }
+    for i := range b1.Words {
+      if b1.Words[i] != b2.Words[i] {
+        return false
+      }
+    }
+    return true
+  }
+  iseqv := NewIsEqv().Apply([]value.Value{x, y}).(*value.BoolValue)
+  return iseqv.Value
+}
@@ -1,10 +1,31 @@
 package primitives
 
 import (
-  "fmt"
   "github.com/kedebug/LispEx/value"
 )
 
+type IsEq struct {
+  value.Primitive
+}
+
+func NewIsEq() *IsEq {
+  return &IsEq{value.Primitive{"eq?"}}
+}
+
+func (self *IsEq) Apply(args []value.Value) value.Value {
+  return value.NewBoolValue(isEq(args[0], args[1]))
+}
+
+// the same object, or objects eqv? tells are the same
+func isEq(x, y value.Value) bool {
+  return x == y || Eqv(x, y)
+}
+
+// whether eqv? holds for x and y, as case compares its key
+func Eqv(x, y value.Value) bool {
+  return NewIsEqv().Apply([]value.Value{x, y}).(*value.BoolValue).Value
+}
+
 type IsEqv struct {
   value.Primitive
 }
@@ -14,9 +35,6 @@ func NewIsEqv() *IsEqv {
 }
 
 func (self *IsEqv) Apply(args []value.Value) value.Value {
-  if len(args) != 2 {
-    panic(fmt.Sprint("argument mismatch for `eqv?', expected 2, given: ", len(args)))
-  }
   typeof := NewTypeOf()
   symbol1 := typeof.Apply(args[0:1]).(*value.Symbol)
   symbol2 := typeof.Apply(args[1:2]).(*value.Symbol)
@@ -36,6 +54,14 @@ func (self *IsEqv) Apply(args []value.Value) value.Value {
     val1 := args[0].(*value.IntValue)
     val2 := args[1].(*value.IntValue)
     iseqv = val1.Value == val2.Value
+  case *value.BigIntValue:
+    val1 := args[0].(*value.BigIntValue)
+    val2 := args[1].(*value.BigIntValue)
+    iseqv = val1.Value.Cmp(val2.Value) == 0
+  case *value.RatValue:
+    val1 := args[0].(*value.RatValue)
+    val2 := args[1].(*value.RatValue)
+    iseqv = val1.Value.Cmp(val2.Value) == 0
   case *value.FloatValue:
     val1 := args[0].(*value.FloatValue)
     val2 := args[1].(*value.FloatValue)
@@ -44,10 +70,20 @@ func (self *IsEqv) Apply(args []value.Value) value.Value {
     val1 := args[0].(*value.StringValue)
     val2 := args[1].(*value.StringValue)
     iseqv = val1.Value == val2.Value
+  case *value.CharValue:
+    val1 := args[0].(*value.CharValue)
+    val2 := args[1].(*value.CharValue)
+    iseqv = val1.Value == val2.Value
   case *value.Symbol:
     val1 := args[0].(*value.Symbol)
     val2 := args[1].(*value.Symbol)
     iseqv = val1.Value == val2.Value
+  case *value.Enum:
+    iseqv = args[0] == args[1]
+  default:
+    // channels, vectors, procedures, hash tables, ports and the other
+    // objects with a state of their own are only eqv? to themselves
+    iseqv = args[0] == args[1]
   }
   return value.NewBoolValue(iseqv)
 }
@@ -1,7 +1,6 @@
 package primitives
 
 import (
-  "fmt"
   . "github.com/kedebug/LispEx/value"
 )
 
@@ -14,9 +13,6 @@ func NewNewline() *Newline {
 }
 
 func (self *Newline) Apply(args []Value) Value {
-  if len(args) != 0 {
-    panic(fmt.Sprint("newline: argument mismatch, expected 0"))
-  }
-  fmt.Print("\n")
+  writeTo(self.Name, args, "\n")
   return nil
 }
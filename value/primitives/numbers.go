@@ -0,0 +1,54 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (quotient n1 n2), (expt z1 z2), ... the operations
+// of the number package on two numbers
+type NumberOp struct {
+  Primitive
+  op func(x, y Value) Value
+}
+
+func NewNumberOp(name string, op func(x, y Value) Value) *NumberOp {
+  return &NumberOp{Primitive{name}, op}
+}
+
+func (self *NumberOp) Apply(args []Value) Value {
+  return self.op(args[0], args[1])
+}
+
+// (gcd n ...) and (lcm n ...) fold the op over their
+// arguments, starting from identity
+type NumberFold struct {
+  Primitive
+  identity Value
+  op       func(x, y Value) Value
+}
+
+func NewNumberFold(name string, identity Value, op func(x, y Value) Value) *NumberFold {
+  return &NumberFold{Primitive{name}, identity, op}
+}
+
+func (self *NumberFold) Apply(args []Value) Value {
+  result := self.identity
+  for _, arg := range args {
+    result = self.op(result, arg)
+  }
+  return result
+}
+
+// (exact->inexact z), (numerator q), ...
+type NumberFunc struct {
+  Primitive
+  f func(Value) Value
+}
+
+func NewNumberFunc(name string, f func(Value) Value) *NumberFunc {
+  return &NumberFunc{Primitive{name}, f}
+}
+
+func (self *NumberFunc) Apply(args []Value) Value {
+  return self.f(args[0])
+}
@@ -0,0 +1,37 @@
+package primitives
+
+import (
+  "io"
+  "os"
+  "sync"
+)
+
+// the writer display, newline and the other builtins printing
+// text write to, os.Stdout when nil. the REPL tees it while
+// recording a transcript
+var output struct {
+  sync.Mutex
+  writer io.Writer
+}
+
+func SetOutput(w io.Writer) {
+  output.Lock()
+  output.writer = w
+  output.Unlock()
+}
+
+type outputWriter struct{}
+
+func (outputWriter) Write(p []byte) (int, error) {
+  output.Lock()
+  defer output.Unlock()
+  if output.writer == nil {
+    return os.Stdout.Write(p)
+  }
+  return output.writer.Write(p)
+}
+
+// writes to the writer given to SetOutput at the time of each write
+func Output() io.Writer {
+  return outputWriter{}
+}
@@ -0,0 +1,73 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "runtime"
+  "sync"
+)
+
+// (par-map-isolated f list) applies f to the elements of the list on a
+// worker per CPU, in the order of the list. newWorker isolates f from
+// the program in a worker, the function it returns applies that copy
+// of f to an element
+type ParMapIsolated struct {
+  Primitive
+  newWorker func(proc Value) func(arg Value) Value
+}
+
+func NewParMapIsolated(newWorker func(proc Value) func(arg Value) Value) *ParMapIsolated {
+  return &ParMapIsolated{Primitive{"par-map-isolated"}, newWorker}
+}
+
+func (self *ParMapIsolated) Apply(args []Value) Value {
+  if self.newWorker == nil {
+    panic("par-map-isolated: no workers can be started from this scope")
+  }
+  items := converter.PairsToSlice(args[1])
+  workers := runtime.NumCPU()
+  if workers > len(items) {
+    workers = len(items)
+  }
+  results := make([]Value, len(items))
+  errs := make([]interface{}, len(items))
+  next := make(chan int)
+  var wg sync.WaitGroup
+  for i := 0; i < workers; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      defer Evaluating(SpawnedEvaluator)()
+      apply, err := self.start(args[0])
+      for k := range next {
+        if err != nil {
+          errs[k] = err
+          continue
+        }
+        results[k], errs[k] = applyRecovering(apply, items[k])
+      }
+    }()
+  }
+  for k := range items {
+    next <- k
+  }
+  close(next)
+  wg.Wait()
+  // the error of the first element failing, as map would raise it
+  for _, err := range errs {
+    if err != nil {
+      panic(err)
+    }
+  }
+  return converter.SliceToPairValues(results)
+}
+
+func (self *ParMapIsolated) start(proc Value) (apply func(Value) Value, err interface{}) {
+  defer func() { err = recover() }()
+  return self.newWorker(proc), nil
+}
+
+func applyRecovering(apply func(Value) Value, arg Value) (result Value, err interface{}) {
+  defer func() { err = recover() }()
+  return apply(arg), nil
+}
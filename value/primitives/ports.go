@@ -0,0 +1,150 @@
+package primitives
+
+import (
+  "bufio"
+  "bytes"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "io"
+  "io/ioutil"
+  "os"
+  "strings"
+)
+
+// the port of what display and write print by default, Output()
+var currentOutput = NewOutputPort("stdout", Output())
+
+type CurrentOutputPort struct {
+  Primitive
+}
+
+func NewCurrentOutputPort() *CurrentOutputPort {
+  return &CurrentOutputPort{Primitive{"current-output-port"}}
+}
+
+func (self *CurrentOutputPort) Apply(args []Value) Value {
+  return currentOutput
+}
+
+type OpenInputFile struct {
+  Primitive
+}
+
+func NewOpenInputFile() *OpenInputFile {
+  return &OpenInputFile{Primitive{"open-input-file"}}
+}
+
+func (self *OpenInputFile) Apply(args []Value) Value {
+  path := args[0].(*StringValue).Value
+  file, err := os.Open(path)
+  if err != nil {
+    raiseIOError(self.Name, err)
+  }
+  return NewInputPort(path, file)
+}
+
+type OpenOutputFile struct {
+  Primitive
+}
+
+func NewOpenOutputFile() *OpenOutputFile {
+  return &OpenOutputFile{Primitive{"open-output-file"}}
+}
+
+func (self *OpenOutputFile) Apply(args []Value) Value {
+  path := args[0].(*StringValue).Value
+  file, err := os.Create(path)
+  if err != nil {
+    raiseIOError(self.Name, err)
+  }
+  return NewOutputPort(path, file)
+}
+
+type OpenInputString struct {
+  Primitive
+}
+
+func NewOpenInputString() *OpenInputString {
+  return &OpenInputString{Primitive{"open-input-string"}}
+}
+
+func (self *OpenInputString) Apply(args []Value) Value {
+  return NewInputPort("string", ioutil.NopCloser(strings.NewReader(args[0].(*StringValue).Value)))
+}
+
+// string ports collect what is written in a buffer
+type OpenOutputString struct {
+  Primitive
+}
+
+func NewOpenOutputString() *OpenOutputString {
+  return &OpenOutputString{Primitive{"open-output-string"}}
+}
+
+func (self *OpenOutputString) Apply(args []Value) Value {
+  return NewOutputPort("string", new(bytes.Buffer))
+}
+
+type GetOutputString struct {
+  Primitive
+}
+
+func NewGetOutputString() *GetOutputString {
+  return &GetOutputString{Primitive{"get-output-string"}}
+}
+
+func (self *GetOutputString) Apply(args []Value) Value {
+  port := args[0].(*Port)
+  buffer, ok := port.Writer.(*bytes.Buffer)
+  if !ok {
+    panic(fmt.Sprint("get-output-string: expected a port of open-output-string, given: ", port))
+  }
+  return NewStringValue(buffer.String())
+}
+
+// (read-char port) and (peek-char port), which leaves the character
+// to be read again
+type ReadChar struct {
+  Primitive
+  peek bool
+}
+
+func NewReadChar(peek bool) *ReadChar {
+  if peek {
+    return &ReadChar{Primitive{"peek-char"}, true}
+  }
+  return &ReadChar{Primitive{"read-char"}, false}
+}
+
+func (self *ReadChar) Apply(args []Value) Value {
+  reader := args[0].(*Port).Reader
+  r, _, err := reader.ReadRune()
+  if err == io.EOF {
+    return EOF
+  } else if err != nil {
+    raiseIOError(self.Name, err)
+  }
+  if self.peek {
+    reader.UnreadRune()
+  }
+  return NewCharValue(r)
+}
+
+// (read port) reads the next datum with the reader of the language,
+// bound by the scope of the program since the reader comes after
+// the builtins
+type Read struct {
+  Primitive
+  read func(reader *bufio.Reader) Value
+}
+
+func NewRead(read func(reader *bufio.Reader) Value) *Read {
+  return &Read{Primitive{"read"}, read}
+}
+
+func (self *Read) Apply(args []Value) Value {
+  if self.read == nil {
+    panic("read: no reader in this scope")
+  }
+  return self.read(args[0].(*Port).Reader)
+}
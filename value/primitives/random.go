@@ -4,12 +4,14 @@ import (
   "fmt"
   . "github.com/kedebug/LispEx/value"
   "math/rand"
+  "sync"
   "time"
 )
 
 type Random struct {
   Primitive
-  rand *rand.Rand
+  rand  *rand.Rand
+  mutex sync.Mutex
 }
 
 func NewRandom() *Random {
@@ -18,14 +20,11 @@ func NewRandom() *Random {
 }
 
 func (self *Random) Apply(args []Value) Value {
-  if len(args) != 1 {
-    panic(fmt.Sprint("random: argument mismatch, expected 1"))
+  val := args[0].(*IntValue)
+  if val.Value <= 0 {
+    panic(fmt.Sprint("random: expected positive integer, given: ", val))
   }
-  if val, ok := args[0].(*IntValue); ok {
-    if val.Value <= 0 {
-      panic(fmt.Sprint("random: expected positive integer, given: ", val))
-    }
-    return NewIntValue(self.rand.Int63n(val.Value))
-  }
-  panic(fmt.Sprint("random: expected integer?, given: ", args[0]))
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+  return NewIntValue(self.rand.Int63n(val.Value))
 }
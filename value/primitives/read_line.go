@@ -0,0 +1,33 @@
+package primitives
+
+import (
+  "bufio"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "io"
+  "strings"
+)
+
+type ReadLine struct {
+  Primitive
+}
+
+func NewReadLine() *ReadLine {
+  return &ReadLine{Primitive{"read-line"}}
+}
+
+func (self *ReadLine) Apply(args []Value) Value {
+  return readLine("read-line", args[0].(*Port).Reader)
+}
+
+// the next line without its line break, or the eof object
+func readLine(name string, reader *bufio.Reader) Value {
+  line, err := reader.ReadString('\n')
+  if err == io.EOF && len(line) == 0 {
+    return EOF
+  } else if err != nil && err != io.EOF {
+    panic(fmt.Sprint(name, ": ", err))
+  }
+  line = strings.TrimSuffix(line, "\n")
+  return NewStringValue(strings.TrimSuffix(line, "\r"))
+}
@@ -0,0 +1,65 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// procedures of a record type named config with fields host and port:
+// (make-config host port), (config? obj), (config-host r)
+// and (set-config-host! r host), likewise for port
+func RecordBuiltins(rt *RecordType) []*Builtin {
+  recordArg := &ArgType{rt.Name, func(val Value) bool {
+    record, ok := val.(*Record)
+    return ok && record.Type == rt
+  }}
+  fieldArgs := make([]*ArgType, len(rt.Fields))
+  for i, index := range rt.Index {
+    fieldArgs[i] = goArgType(rt.Struct.Field(index).Type)
+  }
+
+  constructor := "make-" + rt.Name
+  predicate := rt.Name + "?"
+  builtins := []*Builtin{
+    {constructor, len(rt.Fields), len(rt.Fields), fieldArgs, "a new " + rt.Name + " record", &RecordConstructor{Primitive{constructor}, rt}},
+    {predicate, 1, 1, []*ArgType{AnyArg}, "whether the object is a " + rt.Name + " record", NewTypePredicate(predicate, recordArg.Check)},
+  }
+  if len(rt.Fields) == 0 {
+    builtins[0].Args = []*ArgType{AnyArg}
+  }
+  for i, field := range rt.Fields {
+    accessor := rt.Name + "-" + field
+    modifier := "set-" + rt.Name + "-" + field + "!"
+    builtins = append(builtins,
+      &Builtin{accessor, 1, 1, []*ArgType{recordArg}, "the " + field + " of the record", &RecordAccessor{Primitive{accessor}, i}},
+      &Builtin{modifier, 2, 2, []*ArgType{recordArg, fieldArgs[i]}, "set the " + field + " of the record", &RecordModifier{Primitive{modifier}, i}})
+  }
+  return builtins
+}
+
+type RecordConstructor struct {
+  Primitive
+  rt *RecordType
+}
+
+func (self *RecordConstructor) Apply(args []Value) Value {
+  return NewRecord(self.rt, append([]Value{}, args...))
+}
+
+type RecordAccessor struct {
+  Primitive
+  field int
+}
+
+func (self *RecordAccessor) Apply(args []Value) Value {
+  return args[0].(*Record).Values[self.field]
+}
+
+type RecordModifier struct {
+  Primitive
+  field int
+}
+
+func (self *RecordModifier) Apply(args []Value) Value {
+  args[0].(*Record).Values[self.field] = args[1]
+  return nil
+}
@@ -1,28 +1,30 @@
 package primitives
 
 import (
-  "fmt"
+  "context"
   "github.com/kedebug/LispEx/constants"
   . "github.com/kedebug/LispEx/value"
   "time"
 )
 
+// sleeping is interrupted when the context is done
 type Sleep struct {
   Primitive
+  context func() context.Context
 }
 
-func NewSleep() *Sleep {
-  return &Sleep{Primitive{constants.SLEEP}}
+func NewSleep(context func() context.Context) *Sleep {
+  return &Sleep{Primitive{constants.SLEEP}, context}
 }
 
 func (self *Sleep) Apply(args []Value) Value {
-  if len(args) != 1 {
-    panic(fmt.Sprintf("%s: arguments mismatch, expected 1", constants.SLEEP))
-  }
-  if val, ok := args[0].(*IntValue); ok {
-    time.Sleep(time.Duration(val.Value) * time.Millisecond)
-    return nil
-  } else {
-    panic(fmt.Sprintf("incorrect argument type for `%s', expected: integer?, given: %s", constants.SLEEP, args[0]))
+  ctx := currentContext(self.context)
+  timer := time.NewTimer(time.Duration(args[0].(*IntValue).Value) * time.Millisecond)
+  defer timer.Stop()
+  select {
+  case <-timer.C:
+  case <-ctx.Done():
+    raiseIOError(constants.SLEEP, ctx.Err())
   }
+  return nil
 }
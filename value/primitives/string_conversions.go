@@ -0,0 +1,158 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+  "unicode/utf8"
+)
+
+// (string-append s...) is a new string, whatever
+// the strings it is made of become
+type StringAppend struct {
+  Primitive
+}
+
+func NewStringAppend() *StringAppend {
+  return &StringAppend{Primitive{"string-append"}}
+}
+
+func (self *StringAppend) Apply(args []Value) Value {
+  var b strings.Builder
+  for _, arg := range args {
+    b.WriteString(arg.(*StringValue).Value)
+  }
+  return NewStringValue(b.String())
+}
+
+// (string-ref s k) is the character at index k, counting characters
+type StringRef struct {
+  Primitive
+}
+
+func NewStringRef() *StringRef {
+  return &StringRef{Primitive{"string-ref"}}
+}
+
+func (self *StringRef) Apply(args []Value) Value {
+  s, k := args[0].(*StringValue).Value, args[1].(*IntValue).Value
+  if k >= 0 {
+    for _, r := range s {
+      if k == 0 {
+        return NewCharValue(r)
+      }
+      k--
+    }
+  }
+  panic(fmt.Sprintf("string-ref: index %s out of bounds for a string of length %d", args[1], utf8.RuneCountInString(s)))
+}
+
+// (string #\a #\b) and (list->string '(#\a #\b)) are "ab"
+type CharsToString struct {
+  Primitive
+  list bool
+}
+
+func NewString() *CharsToString {
+  return &CharsToString{Primitive{"string"}, false}
+}
+
+func NewListToString() *CharsToString {
+  return &CharsToString{Primitive{"list->string"}, true}
+}
+
+func (self *CharsToString) Apply(args []Value) Value {
+  chars := args
+  if self.list {
+    chars = converter.PairsToSlice(args[0])
+  }
+  var b strings.Builder
+  for _, char := range chars {
+    c, ok := char.(*CharValue)
+    if !ok {
+      panic(fmt.Sprintf("%s: expected char, given: %s", self.Name, char))
+    }
+    b.WriteRune(c.Value)
+  }
+  return NewStringValue(b.String())
+}
+
+// (make-string k [char]) is k times the char, a space by default
+type MakeString struct {
+  Primitive
+}
+
+func NewMakeString() *MakeString {
+  return &MakeString{Primitive{"make-string"}}
+}
+
+func (self *MakeString) Apply(args []Value) Value {
+  k := args[0].(*IntValue).Value
+  if k < 0 {
+    panic(fmt.Sprint("make-string: expected a length, given: ", args[0]))
+  }
+  fill := " "
+  if len(args) > 1 {
+    fill = string(args[1].(*CharValue).Value)
+  }
+  return NewStringValue(strings.Repeat(fill, int(k)))
+}
+
+// a character takes up to 4 bytes
+func (self *MakeString) Allocates(args []Value) int64 {
+  size := 1
+  if len(args) > 1 {
+    if fill, ok := args[1].(*CharValue); ok {
+      size = utf8.RuneLen(fill.Value)
+    }
+  }
+  if size < 1 {
+    size = 1
+  }
+  return lengthBytes(args, int64(size))
+}
+
+// (string->list s [start [end]]) is the list of its characters
+type StringToList struct {
+  Primitive
+}
+
+func NewStringToList() *StringToList {
+  return &StringToList{Primitive{"string->list"}}
+}
+
+func (self *StringToList) Apply(args []Value) Value {
+  s := args[0].(*StringValue).Value
+  start, end := byteRange("string->list", s, args[1:])
+  var chars []Value
+  for _, r := range s[start:end] {
+    chars = append(chars, NewCharValue(r))
+  }
+  return converter.SliceToPairValues(chars)
+}
+
+// (string->symbol s) and (symbol->string 'sym)
+type StringToSymbol struct {
+  Primitive
+}
+
+func NewStringToSymbol() *StringToSymbol {
+  return &StringToSymbol{Primitive{"string->symbol"}}
+}
+
+func (self *StringToSymbol) Apply(args []Value) Value {
+  return NewSymbol(args[0].(*StringValue).Value)
+}
+
+type SymbolToString struct {
+  Primitive
+}
+
+func NewSymbolToString() *SymbolToString {
+  return &SymbolToString{Primitive{"symbol->string"}}
+}
+
+func (self *SymbolToString) Apply(args []Value) Value {
+  return NewStringValue(args[0].(*Symbol).Value)
+}
@@ -0,0 +1,168 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/number"
+  . "github.com/kedebug/LispEx/value"
+  "math/big"
+  "strconv"
+  "strings"
+)
+
+// (string->number "ff" 16) reads numbers like the reader does and more:
+// radix prefixes #x #o #b #d, exactness prefixes #e #i, exponents and
+// rationals like 1/3. returns #f for anything else
+type StringToNumber struct {
+  Primitive
+}
+
+func NewStringToNumber() *StringToNumber {
+  return &StringToNumber{Primitive{"string->number"}}
+}
+
+func (self *StringToNumber) Apply(args []Value) Value {
+  radix := 10
+  if len(args) > 1 {
+    radix = int(args[1].(*IntValue).Value)
+    if radix != 2 && radix != 8 && radix != 10 && radix != 16 {
+      panic(fmt.Sprint("string->number: expected radix 2, 8, 10 or 16, given: ", args[1]))
+    }
+  }
+  if number := parseNumber(args[0].(*StringValue).Value, radix); number != nil {
+    return number
+  }
+  return NewBoolValue(false)
+}
+
+// (number->string 255 16) is "ff", the inverse of string->number.
+// floats are only written in radix 10
+type NumberToString struct {
+  Primitive
+}
+
+func NewNumberToString() *NumberToString {
+  return &NumberToString{Primitive{"number->string"}}
+}
+
+func (self *NumberToString) Apply(args []Value) Value {
+  radix := 10
+  if len(args) > 1 {
+    radix = int(args[1].(*IntValue).Value)
+    if radix != 2 && radix != 8 && radix != 10 && radix != 16 {
+      panic(fmt.Sprint("number->string: expected radix 2, 8, 10 or 16, given: ", args[1]))
+    }
+  }
+  if radix == 10 {
+    return NewStringValue(args[0].String())
+  }
+  switch args[0].(type) {
+  case *IntValue:
+    return NewStringValue(strconv.FormatInt(args[0].(*IntValue).Value, radix))
+  case *BigIntValue:
+    return NewStringValue(args[0].(*BigIntValue).Value.Text(radix))
+  case *RatValue:
+    r := args[0].(*RatValue).Value
+    return NewStringValue(r.Num().Text(radix) + "/" + r.Denom().Text(radix))
+  }
+  panic(fmt.Sprintf("number->string: inexact numbers are only written in radix 10, given: %s", args[0]))
+}
+
+// nil unless text is a number in the given radix
+func parseNumber(text string, radix int) Value {
+  var exactness byte
+  radixSet := false
+  for len(text) >= 2 && text[0] == '#' {
+    switch prefix := text[1] | 0x20; prefix {
+    case 'b', 'o', 'd', 'x':
+      if radixSet {
+        return nil
+      }
+      radix, radixSet = map[byte]int{'b': 2, 'o': 8, 'd': 10, 'x': 16}[prefix], true
+    case 'e', 'i':
+      if exactness != 0 {
+        return nil
+      }
+      exactness = prefix
+    default:
+      return nil
+    }
+    text = text[2:]
+  }
+
+  parsed := parseReal(text, radix)
+  switch {
+  case parsed == nil:
+    return nil
+  case exactness == 'i':
+    return number.ExactToInexact(parsed)
+  case exactness == 'e':
+    if !number.IsRational(parsed) {
+      return nil
+    }
+    return number.InexactToExact(parsed)
+  }
+  return parsed
+}
+
+func parseReal(text string, radix int) Value {
+  sign := ""
+  if strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-") {
+    sign, text = text[:1], text[1:]
+  }
+  if i := strings.IndexByte(text, '/'); i >= 0 {
+    numerator, ok := parseDigits(sign+text[:i], radix)
+    denominator, ok2 := parseDigits(text[i+1:], radix)
+    if !ok || !ok2 || denominator.Sign() <= 0 || strings.ContainsAny(text[i+1:], "+-") {
+      return nil
+    }
+    return number.Rational(new(big.Rat).SetFrac(numerator, denominator))
+  }
+  if integer, ok := parseDigits(sign+text, radix); ok {
+    return number.Integer(integer)
+  }
+  if radix == 10 && sign != "" && (text == "inf.0" || text == "nan.0") {
+    f, _ := ParseFloat(sign + text)
+    return NewFloatValue(f)
+  }
+  if radix == 10 && isDecimal(text) {
+    // the syntax is checked, only the range may be wrong
+    f, _ := strconv.ParseFloat(sign+text, 64)
+    return NewFloatValue(f)
+  }
+  return nil
+}
+
+// an optionally signed integer, big.Int only takes
+// prefixes and underscores when the base is 0
+func parseDigits(text string, radix int) (*big.Int, bool) {
+  return new(big.Int).SetString(text, radix)
+}
+
+// digits with a decimal point and an exponent, at least one
+// digit before or after the point and in the exponent
+func isDecimal(text string) bool {
+  mantissa, exponent := text, ""
+  if i := strings.IndexAny(text, "eE"); i >= 0 {
+    mantissa, exponent = text[:i], text[i+1:]
+    if strings.HasPrefix(exponent, "+") || strings.HasPrefix(exponent, "-") {
+      exponent = exponent[1:]
+    }
+    if !allDigits(exponent) || exponent == "" {
+      return false
+    }
+  }
+  whole, fraction := mantissa, ""
+  if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+    whole, fraction = mantissa[:i], mantissa[i+1:]
+  }
+  return allDigits(whole) && allDigits(fraction) && whole+fraction != ""
+}
+
+func allDigits(text string) bool {
+  for _, r := range text {
+    if r < '0' || r > '9' {
+      return false
+    }
+  }
+  return true
+}
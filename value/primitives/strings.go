@@ -0,0 +1,137 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// indexes into strings count characters, not bytes
+func runeIndex(s string, byteIndex int) Value {
+  if byteIndex < 0 {
+    return NewBoolValue(false)
+  }
+  return NewIntValue(int64(utf8.RuneCountInString(s[:byteIndex])))
+}
+
+// (string-join '("a" "b") ", "), the separator defaults to a space
+type StringJoin struct {
+  Primitive
+}
+
+func NewStringJoin() *StringJoin {
+  return &StringJoin{Primitive{"string-join"}}
+}
+
+func (self *StringJoin) Apply(args []Value) Value {
+  separator := " "
+  if len(args) > 1 {
+    separator = args[1].(*StringValue).Value
+  }
+  elements := converter.PairsToSlice(args[0])
+  parts := make([]string, len(elements))
+  for i, element := range elements {
+    s, ok := element.(*StringValue)
+    if !ok {
+      panic(fmt.Sprint("string-join: expected list of strings, given: ", args[0]))
+    }
+    parts[i] = s.Value
+  }
+  return NewStringValue(strings.Join(parts, separator))
+}
+
+// (string-trim s) trims whitespace, (string-trim s "-_") or
+// (string-trim s #\-) the given characters instead
+type StringTrim struct {
+  Primitive
+  trim func(s string, cut func(rune) bool) string
+}
+
+func NewStringTrim() *StringTrim {
+  return &StringTrim{Primitive{"string-trim"}, strings.TrimLeftFunc}
+}
+
+func NewStringTrimRight() *StringTrim {
+  return &StringTrim{Primitive{"string-trim-right"}, strings.TrimRightFunc}
+}
+
+func NewStringTrimBoth() *StringTrim {
+  return &StringTrim{Primitive{"string-trim-both"}, strings.TrimFunc}
+}
+
+func (self *StringTrim) Apply(args []Value) Value {
+  cut := unicode.IsSpace
+  if len(args) > 1 {
+    cut = charSet(args[1])
+  }
+  return NewStringValue(self.trim(args[0].(*StringValue).Value, cut))
+}
+
+// a character, or the characters of a string
+func charSet(val Value) func(rune) bool {
+  switch val.(type) {
+  case *CharValue:
+    c := val.(*CharValue).Value
+    return func(r rune) bool { return r == c }
+  default:
+    chars := val.(*StringValue).Value
+    return func(r rune) bool { return strings.ContainsRune(chars, r) }
+  }
+}
+
+// (string-contains s pattern) is the index where pattern
+// first occurs in s, or #f
+type StringContains struct {
+  Primitive
+}
+
+func NewStringContains() *StringContains {
+  return &StringContains{Primitive{"string-contains"}}
+}
+
+func (self *StringContains) Apply(args []Value) Value {
+  s := args[0].(*StringValue).Value
+  return runeIndex(s, strings.Index(s, args[1].(*StringValue).Value))
+}
+
+// (string-index s #\c) or (string-index s pred) is the index
+// of the first character matching, or #f
+type StringIndex struct {
+  Primitive
+}
+
+func NewStringIndex() *StringIndex {
+  return &StringIndex{Primitive{"string-index"}}
+}
+
+func (self *StringIndex) Apply(args []Value) Value {
+  s := args[0].(*StringValue).Value
+  if c, ok := args[1].(*CharValue); ok {
+    return runeIndex(s, strings.IndexRune(s, c.Value))
+  }
+  return runeIndex(s, strings.IndexFunc(s, func(r rune) bool {
+    result, ok := Invoke(args[1], []Value{NewCharValue(r)}).(*BoolValue)
+    return !ok || result.Value
+  }))
+}
+
+// (string-starts-with? s prefix) and (string-ends-with? s suffix)
+type StringAffix struct {
+  Primitive
+  has func(s, affix string) bool
+}
+
+func NewStringStartsWith() *StringAffix {
+  return &StringAffix{Primitive{"string-starts-with?"}, strings.HasPrefix}
+}
+
+func NewStringEndsWith() *StringAffix {
+  return &StringAffix{Primitive{"string-ends-with?"}, strings.HasSuffix}
+}
+
+func (self *StringAffix) Apply(args []Value) Value {
+  return NewBoolValue(self.has(args[0].(*StringValue).Value, args[1].(*StringValue).Value))
+}
@@ -1,7 +1,7 @@
 package primitives
 
 import (
-  "fmt"
+  "github.com/kedebug/LispEx/number"
   "github.com/kedebug/LispEx/value"
 )
 
@@ -13,39 +13,19 @@ func NewSub() *Sub {
   return &Sub{value.Primitive{"-"}}
 }
 
+// exact numbers are subtracted exactly, the result is a float
+// as soon as one of the arguments is
 func (self *Sub) Apply(args []value.Value) value.Value {
-  var val float64
-  isfloat := false
-
-  if len(args) == 0 {
-    panic(fmt.Sprint("`-' argument unmatch: expected at least 1"))
-  } else if len(args) > 1 {
-    switch args[0].(type) {
-    case *value.IntValue:
-      val = float64(args[0].(*value.IntValue).Value)
-    case *value.FloatValue:
-      val = args[0].(*value.FloatValue).Value
-    default:
-      panic(fmt.Sprint("incorrect argument type for `-' : ", args[0]))
+  if len(args) == 1 {
+    if f, ok := args[0].(*value.FloatValue); ok {
+      // (- 0.0) is -0.0
+      return value.NewFloatValue(-f.Value)
     }
-    args = args[1:]
-  }
-
-  for _, arg := range args {
-    switch arg.(type) {
-    case *value.IntValue:
-      val -= float64(arg.(*value.IntValue).Value)
-    case *value.FloatValue:
-      isfloat = true
-      val -= arg.(*value.FloatValue).Value
-    default:
-      panic(fmt.Sprint("incorrect argument type for `-' : ", arg))
-    }
-
+    return number.Sub(value.NewIntValue(0), args[0])
   }
-  if isfloat {
-    return value.NewFloatValue(val)
-  } else {
-    return value.NewIntValue(int64(val))
+  difference := args[0]
+  for _, arg := range args[1:] {
+    difference = number.Sub(difference, arg)
   }
+  return difference
 }
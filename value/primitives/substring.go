@@ -0,0 +1,109 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+  "unicode/utf8"
+)
+
+// a substring at least this fraction of its string shares its bytes,
+// smaller ones are copied so that a short token doesn't keep a whole
+// file it was cut from alive
+const shareFraction = 4
+
+// byte offsets of the characters start to end of s, end defaults
+// to the length of s
+func byteRange(name string, s string, args []Value) (int, int) {
+  length := utf8.RuneCountInString(s)
+  start, end := 0, length
+  if len(args) > 0 {
+    start = int(args[0].(*IntValue).Value)
+  }
+  if len(args) > 1 {
+    end = int(args[1].(*IntValue).Value)
+  }
+  if start < 0 || end > length || start > end {
+    panic(fmt.Sprintf("%s: range %d to %d out of bounds for a string of length %d", name, start, end, length))
+  }
+  return byteOffset(s, start), byteOffset(s, end)
+}
+
+func byteOffset(s string, k int) int {
+  for i := range s {
+    if k == 0 {
+      return i
+    }
+    k--
+  }
+  return len(s)
+}
+
+// (substring s start [end]) shares the bytes of s, strings being
+// copied on write by string-set!, unless it is a small part of s
+type Substring struct {
+  Primitive
+}
+
+func NewSubstring() *Substring {
+  return &Substring{Primitive{"substring"}}
+}
+
+func (self *Substring) Apply(args []Value) Value {
+  s := args[0].(*StringValue).Value
+  start, end := byteRange("substring", s, args[1:])
+  if (end-start)*shareFraction < len(s) {
+    return NewStringValue(strings.Clone(s[start:end]))
+  }
+  return NewStringValue(s[start:end])
+}
+
+// (string-copy s [start [end]]) is a new string, which string-set!
+// changes without changing s. the bytes are shared until then
+type StringCopy struct {
+  Primitive
+}
+
+func NewStringCopy() *StringCopy {
+  return &StringCopy{Primitive{"string-copy"}}
+}
+
+func (self *StringCopy) Apply(args []Value) Value {
+  s := args[0].(*StringValue).Value
+  start, end := byteRange("string-copy", s, args[1:])
+  return NewStringValue(s[start:end])
+}
+
+// (string-set! s k char) puts new bytes in s,
+// leaving the strings sharing the old ones alone
+type StringSet struct {
+  Primitive
+}
+
+func NewStringSet() *StringSet {
+  return &StringSet{Primitive{"string-set!"}}
+}
+
+func (self *StringSet) Apply(args []Value) Value {
+  str := args[0].(*StringValue)
+  k := int(args[1].(*IntValue).Value)
+  if k < 0 || k >= utf8.RuneCountInString(str.Value) {
+    panic(fmt.Sprintf("string-set!: index %d out of bounds for a string of length %d", k, utf8.RuneCountInString(str.Value)))
+  }
+  i := byteOffset(str.Value, k)
+  _, size := utf8.DecodeRuneInString(str.Value[i:])
+  str.Value = str.Value[:i] + string(args[2].(*CharValue).Value) + str.Value[i+size:]
+  return nil
+}
+
+type StringLength struct {
+  Primitive
+}
+
+func NewStringLength() *StringLength {
+  return &StringLength{Primitive{"string-length"}}
+}
+
+func (self *StringLength) Apply(args []Value) Value {
+  return NewIntValue(int64(utf8.RuneCountInString(args[0].(*StringValue).Value)))
+}
@@ -0,0 +1,87 @@
+package primitives
+
+import (
+  "encoding/xml"
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "io"
+  "strings"
+)
+
+// SXML representation of a document:
+//  (*TOP* (tag (@ (attr "value") ...) child ...))
+// element names and attribute names are symbols,
+// character data are strings.
+const (
+  SXML_TOP   = "*TOP*"
+  SXML_ATTRS = "@"
+)
+
+func DecodeSXML(name, input string) Value {
+  decoder := xml.NewDecoder(strings.NewReader(input))
+
+  stack := [][]Value{{NewSymbol(SXML_TOP)}}
+  for {
+    token, err := decoder.Token()
+    if err == io.EOF {
+      break
+    } else if err != nil {
+      panic(fmt.Sprintf("%s: %s", name, err))
+    }
+    switch token.(type) {
+    case xml.StartElement:
+      start := token.(xml.StartElement)
+      element := []Value{NewSymbol(start.Name.Local)}
+      if len(start.Attr) > 0 {
+        attrs := []Value{NewSymbol(SXML_ATTRS)}
+        for _, attr := range start.Attr {
+          pair := []Value{NewSymbol(attr.Name.Local), NewStringValue(attr.Value)}
+          attrs = append(attrs, converter.SliceToPairValues(pair))
+        }
+        element = append(element, converter.SliceToPairValues(attrs))
+      }
+      stack = append(stack, element)
+    case xml.EndElement:
+      stack = closeSXMLElement(stack)
+    case xml.CharData:
+      text := string(token.(xml.CharData))
+      if strings.TrimSpace(text) != "" {
+        top := len(stack) - 1
+        stack[top] = append(stack[top], NewStringValue(text))
+      }
+    }
+  }
+  return converter.SliceToPairValues(stack[0])
+}
+
+func closeSXMLElement(stack [][]Value) [][]Value {
+  top := len(stack) - 1
+  element := converter.SliceToPairValues(stack[top])
+  stack = stack[:top]
+  stack[top-1] = append(stack[top-1], element)
+  return stack
+}
+
+// split an element into its name, attributes and children
+func SplitSXML(node Value) (string, []Value, []Value) {
+  pair, ok := node.(*PairValue)
+  if !ok {
+    panic(fmt.Sprint("sxml: expected element, given: ", node))
+  }
+  name, ok := pair.First.(*Symbol)
+  if !ok {
+    panic(fmt.Sprint("sxml: expected element name, given: ", pair.First))
+  }
+  var attrs []Value
+  children := converter.PairsToSlice(pair.Second)
+  if len(children) > 0 {
+    if attr, ok := children[0].(*PairValue); ok {
+      if symbol, ok := attr.First.(*Symbol); ok && symbol.Value == SXML_ATTRS {
+        attrs = converter.PairsToSlice(attr.Second)
+        children = children[1:]
+      }
+    }
+  }
+  return name.Value, attrs, children
+}
@@ -0,0 +1,53 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+  "strings"
+)
+
+// (sxml-select node '(html body a @href))
+//  each step of the path selects the child elements with the given name,
+//  `*' selects every child element and a trailing `@name' step
+//  selects the values of that attribute.
+type SXMLSelect struct {
+  Primitive
+}
+
+func NewSXMLSelect() *SXMLSelect {
+  return &SXMLSelect{Primitive{"sxml-select"}}
+}
+
+func (self *SXMLSelect) Apply(args []Value) Value {
+  nodes := []Value{args[0]}
+  for _, step := range converter.PairsToSlice(args[1]) {
+    symbol, ok := step.(*Symbol)
+    if !ok {
+      panic(fmt.Sprint("sxml-select: expected symbol in path, given: ", step))
+    }
+    var selected []Value
+    for _, node := range nodes {
+      _, attrs, children := SplitSXML(node)
+      if strings.HasPrefix(symbol.Value, SXML_ATTRS) && symbol.Value != SXML_ATTRS {
+        for _, attr := range attrs {
+          name, _, values := SplitSXML(attr)
+          if name == symbol.Value[1:] && len(values) > 0 {
+            selected = append(selected, values[0])
+          }
+        }
+        continue
+      }
+      for _, child := range children {
+        if _, ok := child.(*PairValue); !ok {
+          continue
+        }
+        if name, _, _ := SplitSXML(child); symbol.Value == "*" || name == symbol.Value {
+          selected = append(selected, child)
+        }
+      }
+    }
+    nodes = selected
+  }
+  return converter.SliceToPairValues(nodes)
+}
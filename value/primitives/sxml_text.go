@@ -0,0 +1,34 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// concatenated character data of an element and its descendants
+type SXMLText struct {
+  Primitive
+}
+
+func NewSXMLText() *SXMLText {
+  return &SXMLText{Primitive{"sxml-text"}}
+}
+
+func (self *SXMLText) Apply(args []Value) Value {
+  return NewStringValue(sxmlText(args[0]))
+}
+
+func sxmlText(node Value) string {
+  switch node.(type) {
+  case *StringValue:
+    return node.(*StringValue).Value
+  case *PairValue:
+    var text string
+    _, _, children := SplitSXML(node)
+    for _, child := range children {
+      text += sxmlText(child)
+    }
+    return text
+  default:
+    return node.String()
+  }
+}
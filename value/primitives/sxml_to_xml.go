@@ -0,0 +1,63 @@
+package primitives
+
+import (
+  "bytes"
+  "encoding/xml"
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type SXMLToXML struct {
+  Primitive
+}
+
+func NewSXMLToXML() *SXMLToXML {
+  return &SXMLToXML{Primitive{"sxml->xml"}}
+}
+
+func (self *SXMLToXML) Apply(args []Value) Value {
+  var buf bytes.Buffer
+  encodeSXML(&buf, args[0])
+  return NewStringValue(buf.String())
+}
+
+func encodeSXML(buf *bytes.Buffer, node Value) {
+  switch node.(type) {
+  case *StringValue:
+    xml.EscapeText(buf, []byte(node.(*StringValue).Value))
+  case *IntValue, *BigIntValue, *RatValue, *FloatValue, *Symbol:
+    xml.EscapeText(buf, []byte(node.String()))
+  case *PairValue:
+    name, attrs, children := SplitSXML(node)
+    if name == SXML_TOP {
+      for _, child := range children {
+        encodeSXML(buf, child)
+      }
+      return
+    }
+    buf.WriteString("<" + name)
+    for _, attr := range attrs {
+      name, _, values := SplitSXML(attr)
+      buf.WriteString(" " + name + "=\"")
+      for _, val := range values {
+        if str, ok := val.(*StringValue); ok {
+          xml.EscapeText(buf, []byte(str.Value))
+        } else {
+          xml.EscapeText(buf, []byte(val.String()))
+        }
+      }
+      buf.WriteString("\"")
+    }
+    if len(children) == 0 {
+      buf.WriteString("/>")
+      return
+    }
+    buf.WriteString(">")
+    for _, child := range children {
+      encodeSXML(buf, child)
+    }
+    buf.WriteString("</" + name + ">")
+  default:
+    panic(fmt.Sprint("sxml->xml: unexpected node: ", node))
+  }
+}
@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/codec"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type Template struct {
+  Primitive
+}
+
+func NewTemplate() *Template {
+  return &Template{Primitive{"template"}}
+}
+
+func (self *Template) Apply(args []Value) Value {
+  return NewStringValue(codec.RenderTemplate(args[0].(*StringValue).Value, args[1]))
+}
@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/codec"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type TOMLRead struct {
+  Primitive
+}
+
+func NewTOMLRead() *TOMLRead {
+  return &TOMLRead{Primitive{"toml-read"}}
+}
+
+func (self *TOMLRead) Apply(args []Value) Value {
+  return codec.ReadTOML(args[0].(*StringValue).Value)
+}
@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/codec"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type TOMLWrite struct {
+  Primitive
+}
+
+func NewTOMLWrite() *TOMLWrite {
+  return &TOMLWrite{Primitive{"toml-write"}}
+}
+
+func (self *TOMLWrite) Apply(args []Value) Value {
+  return NewStringValue(codec.WriteTOML(args[0]))
+}
@@ -0,0 +1,34 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (null? obj), (pair? obj), ... whether obj is of some type
+type TypePredicate struct {
+  Primitive
+  check func(Value) bool
+}
+
+func NewTypePredicate(name string, check func(Value) bool) *TypePredicate {
+  return &TypePredicate{Primitive{name}, check}
+}
+
+func (self *TypePredicate) Apply(args []Value) Value {
+  return NewBoolValue(self.check(args[0]))
+}
+
+func isNull(val Value) bool {
+  _, ok := val.(*EmptyPairValue)
+  return ok
+}
+
+func isPromise(val Value) bool {
+  _, ok := val.(*Promise)
+  return ok
+}
+
+func isEOF(val Value) bool {
+  _, ok := val.(*EOFObject)
+  return ok
+}
@@ -1,7 +1,7 @@
 package primitives
 
 import (
-  "fmt"
+  "github.com/kedebug/LispEx/quickcheck"
   "github.com/kedebug/LispEx/value"
 )
 
@@ -14,27 +14,60 @@ func NewTypeOf() *TypeOf {
 }
 
 func (self *TypeOf) Apply(args []value.Value) value.Value {
-  if len(args) != 1 {
-    panic(fmt.Sprint("argument mismatch for `type-of', expected 1, given: ", len(args)))
-  }
   symbol := "unknown"
   switch args[0].(type) {
-  case *value.IntValue:
+  case *value.IntValue, *value.BigIntValue:
     symbol = "integer"
+  case *value.RatValue:
+    symbol = "rational"
   case *value.FloatValue:
     symbol = "float"
   case *value.BoolValue:
     symbol = "bool"
   case *value.StringValue:
     symbol = "string"
+  case *value.CharValue:
+    symbol = "char"
   case *value.Channel:
     symbol = "channel"
+  case *value.VectorValue:
+    symbol = "vector"
+  case *value.Bytevector:
+    symbol = "bytevector"
+  case *value.Bitvector:
+    symbol = "bitvector"
+  case *value.HashTable:
+    symbol = "hash"
+  case *value.Port:
+    symbol = "port"
+  case *value.WebSocket:
+    symbol = "websocket"
+  case *value.EOFObject:
+    symbol = "eof"
+  case *value.Environment:
+    symbol = "environment"
+  case *value.Promise:
+    symbol = "promise"
+  case *value.Condition:
+    symbol = "condition"
   case *value.EmptyPairValue:
     symbol = "nilpair"
   case *value.PairValue:
     symbol = "pair"
   case *value.Closure:
     symbol = "procedure"
+  case *value.Contract:
+    symbol = "procedure"
+  case *value.Record:
+    symbol = args[0].(*value.Record).Type.Name
+  case *value.Enum:
+    symbol = args[0].(*value.Enum).Type.Name
+  case *value.MultipleValues:
+    symbol = "values"
+  case *value.Opaque:
+    symbol = "opaque"
+  case *quickcheck.Generator:
+    symbol = "generator"
   case value.PrimFunc:
     symbol = "procedure"
   case *value.Symbol:
@@ -0,0 +1,31 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+type Values struct {
+  Primitive
+}
+
+func NewValues() *Values {
+  return &Values{Primitive{"values"}}
+}
+
+func (self *Values) Apply(args []Value) Value {
+  return NewMultipleValues(append([]Value{}, args...))
+}
+
+// (call-with-values producer consumer) applies consumer
+// to the values returned by calling producer
+type CallWithValues struct {
+  Primitive
+}
+
+func NewCallWithValues() *CallWithValues {
+  return &CallWithValues{Primitive{"call-with-values"}}
+}
+
+func (self *CallWithValues) Apply(args []Value) Value {
+  return Invoke(args[1], SpreadValues(Invoke(args[0], nil)))
+}
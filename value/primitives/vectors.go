@@ -0,0 +1,134 @@
+package primitives
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/converter"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// (vector obj...) and (list->vector '(obj...)) are #(obj...)
+type ValuesToVector struct {
+  Primitive
+  list bool
+}
+
+func NewVector() *ValuesToVector {
+  return &ValuesToVector{Primitive{"vector"}, false}
+}
+
+func NewListToVector() *ValuesToVector {
+  return &ValuesToVector{Primitive{"list->vector"}, true}
+}
+
+func (self *ValuesToVector) Apply(args []Value) Value {
+  if self.list {
+    return NewVectorValue(converter.PairsToSlice(args[0]))
+  }
+  return NewVectorValue(append([]Value(nil), args...))
+}
+
+// (make-vector k [fill]) is k times the fill, #f by default
+type MakeVector struct {
+  Primitive
+}
+
+func NewMakeVector() *MakeVector {
+  return &MakeVector{Primitive{"make-vector"}}
+}
+
+func (self *MakeVector) Apply(args []Value) Value {
+  k := args[0].(*IntValue).Value
+  if k < 0 {
+    panic(fmt.Sprint("make-vector: expected a length, given: ", args[0]))
+  }
+  var fill Value = NewBoolValue(false)
+  if len(args) > 1 {
+    fill = args[1]
+  }
+  items := make([]Value, k)
+  for i := range items {
+    items[i] = fill
+  }
+  return NewVectorValue(items)
+}
+
+// each element is an interface of two words
+func (self *MakeVector) Allocates(args []Value) int64 {
+  return lengthBytes(args, 16)
+}
+
+type VectorLength struct {
+  Primitive
+}
+
+func NewVectorLength() *VectorLength {
+  return &VectorLength{Primitive{"vector-length"}}
+}
+
+func (self *VectorLength) Apply(args []Value) Value {
+  return NewIntValue(int64(len(args[0].(*VectorValue).Value)))
+}
+
+// the index argument of a vector primitive, checked against its length
+func vectorIndex(name string, vector *VectorValue, index Value) int64 {
+  k := index.(*IntValue).Value
+  if k < 0 || k >= int64(len(vector.Value)) {
+    panic(fmt.Sprintf("%s: index %d out of bounds for a vector of length %d", name, k, len(vector.Value)))
+  }
+  return k
+}
+
+type VectorRef struct {
+  Primitive
+}
+
+func NewVectorRef() *VectorRef {
+  return &VectorRef{Primitive{"vector-ref"}}
+}
+
+func (self *VectorRef) Apply(args []Value) Value {
+  vector := args[0].(*VectorValue)
+  return vector.Value[vectorIndex(self.Name, vector, args[1])]
+}
+
+type VectorSet struct {
+  Primitive
+}
+
+func NewVectorSet() *VectorSet {
+  return &VectorSet{Primitive{"vector-set!"}}
+}
+
+func (self *VectorSet) Apply(args []Value) Value {
+  vector := args[0].(*VectorValue)
+  vector.Value[vectorIndex(self.Name, vector, args[1])] = args[2]
+  return nil
+}
+
+type VectorFill struct {
+  Primitive
+}
+
+func NewVectorFill() *VectorFill {
+  return &VectorFill{Primitive{"vector-fill!"}}
+}
+
+func (self *VectorFill) Apply(args []Value) Value {
+  items := args[0].(*VectorValue).Value
+  for i := range items {
+    items[i] = args[1]
+  }
+  return nil
+}
+
+type VectorToList struct {
+  Primitive
+}
+
+func NewVectorToList() *VectorToList {
+  return &VectorToList{Primitive{"vector->list"}}
+}
+
+func (self *VectorToList) Apply(args []Value) Value {
+  return converter.SliceToPairValues(args[0].(*VectorValue).Value)
+}
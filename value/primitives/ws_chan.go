@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// channel of incoming messages, for use with `<-chan' and `select'
+type WSChan struct {
+  Primitive
+}
+
+func NewWSChan() *WSChan {
+  return &WSChan{Primitive{"ws-chan"}}
+}
+
+func (self *WSChan) Apply(args []Value) Value {
+  return args[0].(*WebSocket).Recv
+}
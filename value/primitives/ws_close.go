@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+type WSClose struct {
+  Primitive
+}
+
+func NewWSClose() *WSClose {
+  return &WSClose{Primitive{"ws-close"}}
+}
+
+func (self *WSClose) Apply(args []Value) Value {
+  args[0].(*WebSocket).Close()
+  return nil
+}
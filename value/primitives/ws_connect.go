@@ -0,0 +1,25 @@
+package primitives
+
+import (
+  "context"
+  . "github.com/kedebug/LispEx/value"
+  "github.com/kedebug/LispEx/websocket"
+)
+
+type WSConnect struct {
+  Primitive
+  context func() context.Context
+}
+
+func NewWSConnect(context func() context.Context) *WSConnect {
+  return &WSConnect{Primitive{"ws-connect"}, context}
+}
+
+func (self *WSConnect) Apply(args []Value) Value {
+  url := args[0].(*StringValue)
+  conn, err := websocket.DialContext(currentContext(self.context), url.Value)
+  if err != nil {
+    raiseIOError("ws-connect", err)
+  }
+  return NewWebSocket(url.Value, conn)
+}
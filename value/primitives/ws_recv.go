@@ -0,0 +1,31 @@
+package primitives
+
+import (
+  "context"
+  . "github.com/kedebug/LispEx/value"
+)
+
+// blocks until the next message arrives,
+// returns the eof object once the connection is closed
+type WSRecv struct {
+  Primitive
+  context func() context.Context
+}
+
+func NewWSRecv(context func() context.Context) *WSRecv {
+  return &WSRecv{Primitive{"ws-recv"}, context}
+}
+
+func (self *WSRecv) Apply(args []Value) Value {
+  ws := args[0].(*WebSocket)
+  ctx := currentContext(self.context)
+  select {
+  case message, ok := <-ws.Recv.Value:
+    if ok {
+      return message
+    }
+  case <-ctx.Done():
+    raiseIOError("ws-recv", ctx.Err())
+  }
+  return EOF
+}
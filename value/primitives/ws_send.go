@@ -0,0 +1,23 @@
+package primitives
+
+import (
+  "fmt"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type WSSend struct {
+  Primitive
+}
+
+func NewWSSend() *WSSend {
+  return &WSSend{Primitive{"ws-send!"}}
+}
+
+func (self *WSSend) Apply(args []Value) Value {
+  ws := args[0].(*WebSocket)
+  message := args[1].(*StringValue)
+  if err := ws.Conn.WriteText(message.Value); err != nil {
+    panic(fmt.Sprint("ws-send!: ", err))
+  }
+  return nil
+}
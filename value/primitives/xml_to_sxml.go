@@ -0,0 +1,27 @@
+package primitives
+
+import (
+  . "github.com/kedebug/LispEx/value"
+)
+
+// parse xml, or html read as browsers do, into SXML
+type XMLToSXML struct {
+  Primitive
+  html bool
+}
+
+func NewXMLToSXML() *XMLToSXML {
+  return &XMLToSXML{Primitive: Primitive{"xml->sxml"}}
+}
+
+func NewHTMLToSXML() *XMLToSXML {
+  return &XMLToSXML{Primitive: Primitive{"html->sxml"}, html: true}
+}
+
+func (self *XMLToSXML) Apply(args []Value) Value {
+  text := args[0].(*StringValue).Value
+  if self.html {
+    return DecodeHTML(text)
+  }
+  return DecodeSXML(self.Name, text)
+}
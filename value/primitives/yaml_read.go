@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/codec"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type YAMLRead struct {
+  Primitive
+}
+
+func NewYAMLRead() *YAMLRead {
+  return &YAMLRead{Primitive{"yaml-read"}}
+}
+
+func (self *YAMLRead) Apply(args []Value) Value {
+  return codec.ReadYAML(args[0].(*StringValue).Value)
+}
@@ -0,0 +1,18 @@
+package primitives
+
+import (
+  "github.com/kedebug/LispEx/codec"
+  . "github.com/kedebug/LispEx/value"
+)
+
+type YAMLWrite struct {
+  Primitive
+}
+
+func NewYAMLWrite() *YAMLWrite {
+  return &YAMLWrite{Primitive{"yaml-write"}}
+}
+
+func (self *YAMLWrite) Apply(args []Value) Value {
+  return NewStringValue(codec.WriteYAML(args[0]))
+}
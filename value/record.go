@@ -0,0 +1,33 @@
+package value
+
+import (
+  "fmt"
+  "reflect"
+)
+
+// type of the records mirroring a registered Go struct,
+// Fields are the lisp names of its fields at Index
+type RecordType struct {
+  Name   string
+  Fields []string
+  Struct reflect.Type
+  Index  []int
+}
+
+type Record struct {
+  Type   *RecordType
+  Values []Value
+}
+
+func NewRecord(rt *RecordType, values []Value) *Record {
+  return &Record{Type: rt, Values: values}
+}
+
+// e.g. #<config host: "localhost" port: 8080>
+func (self *Record) String() string {
+  s := "#<" + self.Type.Name
+  for i, field := range self.Type.Fields {
+    s += fmt.Sprintf(" %s: %s", field, self.Values[i])
+  }
+  return s + ">"
+}
@@ -0,0 +1,29 @@
+package value
+
+import (
+  "sync/atomic"
+)
+
+// a setting of an interpreter, kept by its root scope. the values
+// made under it may refer to it, seeing it change later
+type Setting struct {
+  on int32
+}
+
+func NewSetting(on bool) *Setting {
+  setting := &Setting{}
+  setting.Set(on)
+  return setting
+}
+
+func (self *Setting) On() bool {
+  return atomic.LoadInt32(&self.on) == 1
+}
+
+func (self *Setting) Set(on bool) {
+  if on {
+    atomic.StoreInt32(&self.on, 1)
+  } else {
+    atomic.StoreInt32(&self.on, 0)
+  }
+}
@@ -1,6 +1,10 @@
 package value
 
-import "fmt"
+import (
+  "fmt"
+  "strings"
+  "unicode"
+)
 
 type StringValue struct {
   Value string
@@ -11,5 +15,26 @@ func NewStringValue(val string) *StringValue {
 }
 
 func (self *StringValue) String() string {
-  return fmt.Sprintf("\"%s\"", self.Value)
+  return QuoteString(self.Value)
+}
+
+// s written as read: quotes and backslashes are escaped, characters
+// which can't be printed written by their code, e.g. "a\x7;b" for a
+// string with an alarm. spaces are printed
+func QuoteString(s string) string {
+  var b strings.Builder
+  b.WriteByte('"')
+  for _, r := range s {
+    switch {
+    case r == '"' || r == '\\':
+      b.WriteByte('\\')
+      b.WriteRune(r)
+    case r == ' ' || unicode.IsPrint(r):
+      b.WriteRune(r)
+    default:
+      fmt.Fprintf(&b, "\\x%x;", r)
+    }
+  }
+  b.WriteByte('"')
+  return b.String()
 }
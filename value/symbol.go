@@ -1,5 +1,15 @@
 package value
 
+import (
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// symbols aren't interned: each is a value of its own, eqv? to the
+// others of the same name, so there is no symbol table to grow and
+// those no longer referred to are collected like any other value,
+// however many a program makes with string->symbol or reads
 type Symbol struct {
   Value string
 }
@@ -8,6 +18,43 @@ func NewSymbol(value string) *Symbol {
   return &Symbol{Value: value}
 }
 
+// written between bars, |like this|, when the reader
+// would not read the bare name back as this symbol
 func (self *Symbol) String() string {
-  return self.Value
+  if plainSymbol(self.Value) {
+    return self.Value
+  }
+  var b strings.Builder
+  b.WriteByte('|')
+  for _, r := range self.Value {
+    if r == '|' || r == '\\' {
+      b.WriteByte('\\')
+    }
+    b.WriteRune(r)
+  }
+  b.WriteByte('|')
+  return b.String()
+}
+
+// whether name reads as an identifier: made of the characters
+// of identifiers and not starting like a number or like the
+// literals written with #, e.g. #t, #\a or #(1 2)
+func plainSymbol(name string) bool {
+  switch name {
+  case "", ".", "+inf.0", "-inf.0", "+nan.0", "-nan.0":
+    return false
+  }
+  first, size := utf8.DecodeRuneInString(name)
+  if unicode.IsDigit(first) || first == '#' {
+    return false
+  }
+  if (first == '+' || first == '-') && len(name) > size && strings.ContainsRune("0123456789.", rune(name[size])) {
+    return false
+  }
+  for _, r := range name {
+    if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("!#$%&*+-./:<=>?@^_~", r) {
+      return false
+    }
+  }
+  return true
 }
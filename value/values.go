@@ -0,0 +1,45 @@
+package value
+
+import (
+  "fmt"
+  "strings"
+)
+
+// the results of (values a b ...). they are passed around as one
+// object, e.g. sent through a channel as a tuple, until apply or
+// call-with-values spreads them over the arguments of a procedure
+type MultipleValues struct {
+  Values []Value
+}
+
+// a single value stands for itself and no value is nil,
+// like the value of a define
+func NewMultipleValues(values []Value) Value {
+  switch len(values) {
+  case 0:
+    return nil
+  case 1:
+    return values[0]
+  }
+  return &MultipleValues{values}
+}
+
+// the values val stands for, as arguments of a procedure
+func SpreadValues(val Value) []Value {
+  switch val.(type) {
+  case nil:
+    return nil
+  case *MultipleValues:
+    return val.(*MultipleValues).Values
+  }
+  return []Value{val}
+}
+
+// one value per line, like the REPL prints the values of several forms
+func (self *MultipleValues) String() string {
+  lines := make([]string, len(self.Values))
+  for i, val := range self.Values {
+    lines[i] = fmt.Sprint(val)
+  }
+  return strings.Join(lines, "\n")
+}
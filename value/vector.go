@@ -0,0 +1,28 @@
+package value
+
+import (
+  "strings"
+)
+
+// a fixed length sequence of values indexed from 0
+type VectorValue struct {
+  Value []Value
+}
+
+func NewVectorValue(val []Value) *VectorValue {
+  return &VectorValue{Value: val}
+}
+
+// e.g. #(1 "a" (b))
+func (self *VectorValue) String() string {
+  return self.format(WrittenString)
+}
+
+// the vector with its elements written by str
+func (self *VectorValue) format(str func(Value) string) string {
+  items := make([]string, len(self.Value))
+  for i, item := range self.Value {
+    items[i] = str(item)
+  }
+  return "#(" + strings.Join(items, " ") + ")"
+}
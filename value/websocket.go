@@ -0,0 +1,43 @@
+package value
+
+import (
+  "fmt"
+  "github.com/kedebug/LispEx/websocket"
+)
+
+// incoming messages are read on a separate goroutine and delivered
+// to Recv, which is closed when the peer hangs up or on Close
+type WebSocket struct {
+  URL  string
+  Conn *websocket.Conn
+  Recv *Channel
+}
+
+func NewWebSocket(url string, conn *websocket.Conn) *WebSocket {
+  ws := &WebSocket{URL: url, Conn: conn, Recv: NewChannel(0)}
+  go func() {
+    for {
+      message, err := conn.ReadMessage()
+      if err != nil {
+        ws.Recv.Close()
+        return
+      }
+      // the messages left unread once closed are dropped
+      if !ws.Recv.Forward(NewStringValue(message)) {
+        return
+      }
+    }
+  }()
+  return ws
+}
+
+// close the connection and Recv, stopping the reader
+// even while it waits to deliver a message
+func (self *WebSocket) Close() {
+  self.Conn.Close()
+  self.Recv.Close()
+}
+
+func (self *WebSocket) String() string {
+  return fmt.Sprintf("#<websocket %s>", self.URL)
+}
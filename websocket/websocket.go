@@ -0,0 +1,294 @@
+package websocket
+
+import (
+  "bufio"
+  "context"
+  "crypto/rand"
+  "crypto/sha1"
+  "crypto/tls"
+  "encoding/base64"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "net"
+  "net/http"
+  "net/url"
+  "strings"
+  "sync"
+  "time"
+)
+
+// minimal RFC 6455 implementation, enough for text and binary
+// messages over ws:// and wss://, with control frames handled
+// transparently while reading
+
+const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+  opContinuation = 0x0
+  opText         = 0x1
+  opBinary       = 0x2
+  opClose        = 0x8
+  opPing         = 0x9
+  opPong         = 0xa
+)
+
+// the largest message read, fragments included: a peer sending a
+// longer one fails the connection instead of having it buffered whole
+const MaxMessageSize = 16 << 20
+
+// a control frame carries at most 125 bytes
+const maxControlSize = 125
+
+var ErrMessageTooBig = errors.New("websocket: message too big")
+
+type Conn struct {
+  conn   net.Conn
+  reader *bufio.Reader
+  client bool
+  mutex  sync.Mutex
+}
+
+func Dial(rawurl string) (*Conn, error) {
+  return DialContext(context.Background(), rawurl)
+}
+
+// like Dial, giving up when ctx is done before the handshake completes
+func DialContext(ctx context.Context, rawurl string) (*Conn, error) {
+  u, err := url.Parse(rawurl)
+  if err != nil {
+    return nil, err
+  }
+
+  var conn net.Conn
+  host := u.Host
+  switch u.Scheme {
+  case "ws":
+    if u.Port() == "" {
+      host += ":80"
+    }
+    conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+  case "wss":
+    if u.Port() == "" {
+      host += ":443"
+    }
+    dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+    conn, err = dialer.DialContext(ctx, "tcp", host)
+  default:
+    return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+  }
+  if err != nil {
+    return nil, err
+  }
+  // interrupt the handshake once ctx is done
+  stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+  defer stop()
+  fail := func(err error) (*Conn, error) {
+    conn.Close()
+    if ctx.Err() != nil {
+      return nil, ctx.Err()
+    }
+    return nil, err
+  }
+
+  nonce := make([]byte, 16)
+  rand.Read(nonce)
+  key := base64.StdEncoding.EncodeToString(nonce)
+
+  path := u.RequestURI()
+  request := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\n", path, u.Host)
+  request += "Upgrade: websocket\r\nConnection: Upgrade\r\n"
+  request += fmt.Sprintf("Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", key)
+  if _, err := io.WriteString(conn, request); err != nil {
+    return fail(err)
+  }
+
+  reader := bufio.NewReader(conn)
+  response, err := http.ReadResponse(reader, nil)
+  if err != nil {
+    return fail(err)
+  }
+  if response.StatusCode != http.StatusSwitchingProtocols {
+    return fail(fmt.Errorf("handshake failed: %s", response.Status))
+  }
+  if response.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+    return fail(errors.New("handshake failed: bad Sec-WebSocket-Accept"))
+  }
+  if !stop() {
+    // ctx was done just as the handshake completed
+    return fail(nil)
+  }
+  return &Conn{conn: conn, reader: reader, client: true}, nil
+}
+
+// server side of the handshake, mainly useful for tests
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+  if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+    http.Error(w, "expected websocket upgrade", http.StatusBadRequest)
+    return nil, errors.New("not a websocket handshake")
+  }
+  hijacker, ok := w.(http.Hijacker)
+  if !ok {
+    return nil, errors.New("connection cannot be hijacked")
+  }
+  conn, buffer, err := hijacker.Hijack()
+  if err != nil {
+    return nil, err
+  }
+  response := "HTTP/1.1 101 Switching Protocols\r\n"
+  response += "Upgrade: websocket\r\nConnection: Upgrade\r\n"
+  response += fmt.Sprintf("Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(r.Header.Get("Sec-WebSocket-Key")))
+  if _, err := io.WriteString(conn, response); err != nil {
+    conn.Close()
+    return nil, err
+  }
+  return &Conn{conn: conn, reader: buffer.Reader, client: false}, nil
+}
+
+func acceptKey(key string) string {
+  hash := sha1.Sum([]byte(key + guid))
+  return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func (self *Conn) WriteText(text string) error {
+  return self.writeFrame(opText, []byte(text))
+}
+
+func (self *Conn) WriteBinary(data []byte) error {
+  return self.writeFrame(opBinary, data)
+}
+
+// read the next data message, answering pings on the way;
+// returns io.EOF once the peer closes the connection, and
+// ErrMessageTooBig after failing it for a message too long
+func (self *Conn) ReadMessage() (string, error) {
+  var message []byte
+  for {
+    fin, opcode, payload, err := self.readFrame(MaxMessageSize - len(message))
+    if err == ErrMessageTooBig {
+      self.fail()
+    }
+    if err != nil {
+      return "", err
+    }
+    switch opcode {
+    case opPing:
+      if err := self.writeFrame(opPong, payload); err != nil {
+        return "", err
+      }
+    case opPong:
+    case opClose:
+      self.writeFrame(opClose, payload)
+      return "", io.EOF
+    default:
+      message = append(message, payload...)
+      if fin {
+        return string(message), nil
+      }
+    }
+  }
+}
+
+func (self *Conn) Close() error {
+  self.writeFrame(opClose, nil)
+  return self.conn.Close()
+}
+
+// close the connection with status 1009, the message was too big
+func (self *Conn) fail() {
+  status := make([]byte, 2)
+  binary.BigEndian.PutUint16(status, 1009)
+  self.writeFrame(opClose, status)
+  self.conn.Close()
+}
+
+func (self *Conn) writeFrame(opcode byte, payload []byte) error {
+  self.mutex.Lock()
+  defer self.mutex.Unlock()
+
+  header := []byte{0x80 | opcode, 0}
+  length := len(payload)
+  switch {
+  case length < 126:
+    header[1] = byte(length)
+  case length <= 0xffff:
+    header[1] = 126
+    header = append(header, 0, 0)
+    binary.BigEndian.PutUint16(header[2:], uint16(length))
+  default:
+    header[1] = 127
+    header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+    binary.BigEndian.PutUint64(header[2:], uint64(length))
+  }
+
+  // frames sent by a client must be masked
+  if self.client {
+    header[1] |= 0x80
+    mask := make([]byte, 4)
+    rand.Read(mask)
+    header = append(header, mask...)
+    masked := make([]byte, length)
+    for i := range payload {
+      masked[i] = payload[i] ^ mask[i%4]
+    }
+    payload = masked
+  }
+  if _, err := self.conn.Write(header); err != nil {
+    return err
+  }
+  _, err := self.conn.Write(payload)
+  return err
+}
+
+// the payload of a data frame is at most limit bytes long, the
+// length is checked before anything is allocated for it
+func (self *Conn) readFrame(limit int) (bool, byte, []byte, error) {
+  header := make([]byte, 2)
+  if _, err := io.ReadFull(self.reader, header); err != nil {
+    return false, 0, nil, err
+  }
+  fin := header[0]&0x80 != 0
+  opcode := header[0] & 0x0f
+  masked := header[1]&0x80 != 0
+
+  length := uint64(header[1] & 0x7f)
+  switch length {
+  case 126:
+    extended := make([]byte, 2)
+    if _, err := io.ReadFull(self.reader, extended); err != nil {
+      return false, 0, nil, err
+    }
+    length = uint64(binary.BigEndian.Uint16(extended))
+  case 127:
+    extended := make([]byte, 8)
+    if _, err := io.ReadFull(self.reader, extended); err != nil {
+      return false, 0, nil, err
+    }
+    length = binary.BigEndian.Uint64(extended)
+  }
+  if opcode >= opClose {
+    limit = maxControlSize
+  }
+  if length > uint64(limit) {
+    return false, 0, nil, ErrMessageTooBig
+  }
+
+  var mask []byte
+  if masked {
+    mask = make([]byte, 4)
+    if _, err := io.ReadFull(self.reader, mask); err != nil {
+      return false, 0, nil, err
+    }
+  }
+  payload := make([]byte, length)
+  if _, err := io.ReadFull(self.reader, payload); err != nil {
+    return false, 0, nil, err
+  }
+  if masked {
+    for i := range payload {
+      payload[i] ^= mask[i%4]
+    }
+  }
+  return fin, opcode, payload, nil
+}